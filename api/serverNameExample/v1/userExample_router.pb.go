@@ -25,12 +25,13 @@ type UserExampleLogicer interface {
 type UserExampleOption func(*userExampleOptions)
 
 type userExampleOptions struct {
-	isFromRPC  bool
-	responser  errcode.Responser
-	zapLog     *zap.Logger
-	httpErrors []*errcode.Error
-	rpcStatus  []*errcode.RPCStatus
-	wrapCtxFn  func(c *gin.Context) context.Context
+	isFromRPC            bool
+	responser            errcode.Responser
+	zapLog               *zap.Logger
+	httpErrors           []*errcode.Error
+	rpcStatus            []*errcode.RPCStatus
+	wrapCtxFn            func(c *gin.Context) context.Context
+	permissionMiddleware func(permissions []string) gin.HandlerFunc
 }
 
 func (o *userExampleOptions) apply(opts ...UserExampleOption) {
@@ -81,6 +82,14 @@ func WithUserExampleWrapCtx(wrapCtxFn func(c *gin.Context) context.Context) User
 	}
 }
 
+// WithUserExamplePermissionMiddleware set the middleware to check the permissions declared by option (rbac.permission),
+// e.g. middleware.Permission(enforcer)
+func WithUserExamplePermissionMiddleware(fn func(permissions []string) gin.HandlerFunc) UserExampleOption {
+	return func(o *userExampleOptions) {
+		o.permissionMiddleware = fn
+	}
+}
+
 func RegisterUserExampleRouter(
 	iRouter gin.IRouter,
 	groupPathMiddlewares map[string][]gin.HandlerFunc,
@@ -106,6 +115,7 @@ func RegisterUserExampleRouter(
 		iResponse:             o.responser,
 		zapLog:                o.zapLog,
 		wrapCtxFn:             o.wrapCtxFn,
+		permissionMiddleware:  o.permissionMiddleware,
 	}
 	r.register()
 }
@@ -118,6 +128,7 @@ type userExampleRouter struct {
 	iResponse             errcode.Responser
 	zapLog                *zap.Logger
 	wrapCtxFn             func(c *gin.Context) context.Context
+	permissionMiddleware  func(permissions []string) gin.HandlerFunc
 }
 
 func (r *userExampleRouter) register() {
@@ -129,7 +140,7 @@ func (r *userExampleRouter) register() {
 
 }
 
-func (r *userExampleRouter) withMiddleware(method string, path string, fn gin.HandlerFunc) []gin.HandlerFunc {
+func (r *userExampleRouter) withMiddleware(method string, path string, fn gin.HandlerFunc, permissions ...string) []gin.HandlerFunc {
 	handlerFns := []gin.HandlerFunc{}
 
 	// determine if a route group is hit or miss, left prefix rule
@@ -153,6 +164,23 @@ func (r *userExampleRouter) withMiddleware(method string, path string, fn gin.Ha
 		handlerFns = append(handlerFns, fns...)
 	}
 
+	// check the permissions declared by option (rbac.permission), the route is denied if the permission middleware is not set
+	if len(permissions) > 0 {
+		if r.permissionMiddleware != nil {
+			handlerFns = append(handlerFns, r.permissionMiddleware(permissions))
+		} else {
+			r.zapLog.Error("permission middleware is not set, the route is denied",
+				zap.String("route", key), zap.Strings("permissions", permissions))
+			handlerFns = append(handlerFns, func(c *gin.Context) {
+				c.AbortWithStatusJSON(errcode.Forbidden.ToHTTPCode(), gin.H{
+					"code": errcode.Forbidden.Code(),
+					"msg":  errcode.Forbidden.Msg(),
+					"data": struct{}{},
+				})
+			})
+		}
+	}
+
 	return append(handlerFns, fn)
 }
 
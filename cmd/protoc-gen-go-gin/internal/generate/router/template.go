@@ -54,6 +54,7 @@ type {{$.LowerName}}Options struct {
 	httpErrors []*errcode.Error
 	rpcStatus  []*errcode.RPCStatus
 	wrapCtxFn  func(c *gin.Context) context.Context
+	permissionMiddleware func(permissions []string) gin.HandlerFunc
 }
 
 func (o *{{$.LowerName}}Options) apply(opts ...{{$.Name}}Option) {
@@ -104,6 +105,14 @@ func With{{$.Name}}WrapCtx(wrapCtxFn func(c *gin.Context) context.Context) {{$.N
 	}
 }
 
+// With{{$.Name}}PermissionMiddleware set the middleware to check the permissions declared by option (rbac.permission),
+// e.g. middleware.Permission(enforcer)
+func With{{$.Name}}PermissionMiddleware(fn func(permissions []string) gin.HandlerFunc) {{$.Name}}Option {
+	return func(o *{{$.LowerName}}Options) {
+		o.permissionMiddleware = fn
+	}
+}
+
 func Register{{$.Name}}Router(
 	iRouter gin.IRouter,
 	groupPathMiddlewares map[string][]gin.HandlerFunc,
@@ -129,6 +138,7 @@ func Register{{$.Name}}Router(
 		iResponse:             o.responser,
 		zapLog:                o.zapLog,
 		wrapCtxFn:             o.wrapCtxFn,
+		permissionMiddleware:  o.permissionMiddleware,
 	}
 	r.register()
 }
@@ -141,14 +151,15 @@ type {{$.LowerName}}Router struct {
 	iResponse             errcode.Responser
 	zapLog                *zap.Logger
 	wrapCtxFn             func(c *gin.Context) context.Context
+	permissionMiddleware  func(permissions []string) gin.HandlerFunc
 }
 
 func (r *{{$.LowerName}}Router) register() {
-{{range .Methods}}	{{if eq .InvokeType 0}}{{if .Path}}r.iRouter.Handle("{{.Method}}", "{{.Path}}", r.withMiddleware("{{.Method}}", "{{.Path}}", r.{{ .HandlerName }})...){{end}}{{end}}
+{{range .Methods}}	{{if eq .InvokeType 0}}{{if .Path}}r.iRouter.Handle("{{.Method}}", "{{.Path}}", r.withMiddleware("{{.Method}}", "{{.Path}}", r.{{ .HandlerName }}{{range .Permissions}}, {{printf "%q" .}}{{end}})...){{end}}{{end}}
 {{end}}
 }
 
-func (r *{{$.LowerName}}Router) withMiddleware(method string, path string, fn gin.HandlerFunc) []gin.HandlerFunc {
+func (r *{{$.LowerName}}Router) withMiddleware(method string, path string, fn gin.HandlerFunc, permissions ...string) []gin.HandlerFunc {
 	handlerFns := []gin.HandlerFunc{}
 
 	// determine if a route group is hit or miss, left prefix rule
@@ -172,6 +183,23 @@ func (r *{{$.LowerName}}Router) withMiddleware(method string, path string, fn gi
 		handlerFns = append(handlerFns, fns...)
 	}
 
+	// check the permissions declared by option (rbac.permission), the route is denied if the permission middleware is not set
+	if len(permissions) > 0 {
+		if r.permissionMiddleware != nil {
+			handlerFns = append(handlerFns, r.permissionMiddleware(permissions))
+		} else {
+			r.zapLog.Error("permission middleware is not set, the route is denied",
+				zap.String("route", key), zap.Strings("permissions", permissions))
+			handlerFns = append(handlerFns, func(c *gin.Context) {
+				c.AbortWithStatusJSON(errcode.Forbidden.ToHTTPCode(), gin.H{
+					"code": errcode.Forbidden.Code(),
+					"msg":  errcode.Forbidden.Msg(),
+					"data": struct{}{},
+				})
+			})
+		}
+	}
+
 	return append(handlerFns, fn)
 }
 
@@ -8,6 +8,8 @@ import (
 	"google.golang.org/genproto/googleapis/api/annotations"
 	"google.golang.org/protobuf/compiler/protogen"
 	"google.golang.org/protobuf/proto"
+
+	"github.com/zhufuyi/sponge/pkg/rbac/rbacpb"
 )
 
 var methodSets = make(map[string]int)
@@ -95,6 +97,9 @@ func buildMethodDesc(m *protogen.Method, httpMethod, path string, customKind str
 		ProtoSelfPkgPath:     protoSelfPkgPath,
 		ImportPkgPaths:       importPkgPaths,
 	}
+	if perm, ok := proto.GetExtension(m.Desc.Options(), rbacpb.E_Permission).(*rbacpb.Permission); ok && perm != nil {
+		md.Permissions = perm.GetRequired()
+	}
 	md.checkCustomKind()
 	md.checkSelector()
 	md.InitPathParams()
@@ -124,6 +129,9 @@ type RPCMethod struct {
 	// if true, ignore c.ShouldBindXXX for this method, you must use c.ShouldBindXXX() in rpc method
 	IsIgnoreShouldBind bool
 
+	// the permissions required to call the method, declared by option (rbac.permission)
+	Permissions []string
+
 	RequestImportPkgName string // e.g. empty or userV1
 	ReplyImportPkgName   string // e.g. empty or userV1
 
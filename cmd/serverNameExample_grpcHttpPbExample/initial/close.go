@@ -25,8 +25,15 @@ func Close(servers []app.IServer) []app.Close {
 	//	return model.CloseDB()
 	//})
 
+	// close the multilevel cache before redis
+	//if config.Get().App.CacheType == "multilevel" {
+	//	closes = append(closes, func() error {
+	//		return model.CloseCache()
+	//	})
+	//}
+
 	// close redis
-	//if config.Get().App.CacheType == "redis" {
+	//if config.Get().App.CacheType == "redis" || config.Get().App.CacheType == "multilevel" {
 	//	closes = append(closes, func() error {
 	//		return model.CloseRedis()
 	//	})
@@ -287,7 +287,7 @@ const docTemplate = `{
             "type": "object",
             "properties": {
                 "exp": {
-                    "description": "expressions, which default to = when the value is null, have =, !=, \u003e, \u003e=, \u003c, \u003c=, like",
+                    "description": "expressions, which default to = when the value is null, have =, !=, \u003e, \u003e=, \u003c, \u003c=, like, in, notin, notlike, prefix, suffix, between, isnull, notnull",
                     "type": "string"
                 },
                 "logic": {
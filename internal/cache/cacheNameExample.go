@@ -3,6 +3,7 @@ package cache
 import (
 	"context"
 	"fmt"
+	"io"
 	"strings"
 	"time"
 
@@ -54,6 +55,10 @@ func NewCacheNameExampleCache(cacheType *model.CacheType) CacheNameExampleCache
 	case "memory":
 		c := cache.NewMemoryCache(cachePrefix, jsonEncoding, newObject)
 		return &cacheNameExampleCache{cache: c}
+	case "multilevel":
+		c := cache.NewMultilevelCache(cacheType.Rdb, cachePrefix, jsonEncoding, newObject, cache.MultilevelWithName("cacheNameExample"))
+		model.AddCacheCloser(c.(io.Closer)) // stop receiving the invalidation messages after service exit
+		return &cacheNameExampleCache{cache: c}
 	}
 
 	panic(fmt.Sprintf("unsupported cache type='%s'", cacheType.CType))
@@ -2,6 +2,7 @@ package cache
 
 import (
 	"context"
+	"io"
 	"strings"
 	"time"
 
@@ -46,12 +47,19 @@ func NewUserExampleCache(cacheType *model.CacheType) UserExampleCache {
 	case "redis":
 		c := cache.NewRedisCache(cacheType.Rdb, cachePrefix, jsonEncoding, func() interface{} {
 			return &model.UserExample{}
-		})
+		}, cache.WithJitter(0.1)) // add up to 10% random jitter to the expiration time, so that the keys do not expire at the same time
 		return &userExampleCache{cache: c}
 	case "memory":
 		c := cache.NewMemoryCache(cachePrefix, jsonEncoding, func() interface{} {
 			return &model.UserExample{}
-		})
+		}, cache.WithJitter(0.1), cache.WithName("userExample"), cache.WithEvictionPolicy(cache.EvictionTinyLFU))
+		return &userExampleCache{cache: c}
+	case "multilevel":
+		// local memory cache in front of redis, the local cache of all replicas is invalidated by redis pub/sub when writing
+		c := cache.NewMultilevelCache(cacheType.Rdb, cachePrefix, jsonEncoding, func() interface{} {
+			return &model.UserExample{}
+		}, cache.MultilevelWithName("userExample"), cache.MultilevelWithJitter(0.1))
+		model.AddCacheCloser(c.(io.Closer)) // stop receiving the invalidation messages after service exit
 		return &userExampleCache{cache: c}
 	}
 
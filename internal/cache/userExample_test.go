@@ -141,4 +141,12 @@ func TestNewUserExampleCache(t *testing.T) {
 		CType: "redis",
 	})
 	assert.NotNil(t, c)
+
+	rc := gotest.NewCache(nil)
+	defer rc.Close()
+	c = NewUserExampleCache(&model.CacheType{
+		CType: "multilevel",
+		Rdb:   rc.RedisClient,
+	})
+	assert.NotNil(t, c)
 }
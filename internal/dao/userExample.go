@@ -18,6 +18,9 @@ import (
 
 var _ UserExampleDao = (*userExampleDao)(nil)
 
+// only the columns of the model are allowed in the query conditions and sort fields, to prevent sql injection
+var userExampleQueryOption = query.WithModelColumns(&model.UserExample{})
+
 // UserExampleDao defining the dao interface
 type UserExampleDao interface {
 	Create(ctx context.Context, table *model.UserExample) error
@@ -188,10 +191,14 @@ func (d *userExampleDao) GetByID(ctx context.Context, id uint64) (*model.UserExa
 // query parameters (not required):
 //
 //	name: column name
-//	exp: expressions, which default is "=",  support =, !=, >, >=, <, <=, like, in
-//	value: column value, if exp=in, multiple values are separated by commas
+//	exp: expressions, which default is "=",  support =, !=, >, >=, <, <=, like, in, notin, notlike, prefix, suffix, between, isnull, notnull
+//	value: column value, if exp=in, notin or between, the value is an array or multiple values separated by commas, if exp=isnull or notnull, the value is ignored
 //	logic: logical type, defaults to and when value is null, only &(and), ||(or)
 //
+// nested query parameters (not required), joined with the query parameters by and:
+//
+//	group: conditions group, e.g. {"logic":"or", "columns":[...], "groups":[...]}, each sub group is wrapped in parentheses
+//
 // example: search for a male over 20 years of age
 //
 //	params = &query.Params{
@@ -209,9 +216,13 @@ func (d *userExampleDao) GetByID(ctx context.Context, id uint64) (*model.UserExa
 //		},
 //	}
 func (d *userExampleDao) GetByColumns(ctx context.Context, params *query.Params) ([]*model.UserExample, int64, error) {
-	queryStr, args, err := params.ConvertToGormConditions()
+	err := query.CheckSort(params.Sort, userExampleQueryOption)
+	if err != nil {
+		return nil, 0, fmt.Errorf("query params error: %w", err)
+	}
+	queryStr, args, err := params.ConvertToGormConditions(userExampleQueryOption)
 	if err != nil {
-		return nil, 0, errors.New("query params error: " + err.Error())
+		return nil, 0, fmt.Errorf("query params error: %w", err)
 	}
 
 	var total int64
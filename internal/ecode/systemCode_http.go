@@ -34,6 +34,8 @@ var (
 	OutOfRange         = errcode.OutOfRange
 	Unimplemented      = errcode.Unimplemented
 	DataLoss           = errcode.DataLoss
+
+	InvalidQueryColumn = errcode.InvalidQueryColumn
 )
 
 var SkipResponse = errcode.SkipResponse
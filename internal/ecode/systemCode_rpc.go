@@ -32,6 +32,8 @@ var (
 	StatusMethodNotAllowed = errcode.StatusMethodNotAllowed
 	StatusAccessDenied     = errcode.StatusAccessDenied
 	StatusConflict         = errcode.StatusConflict
+
+	StatusInvalidQueryColumn = errcode.StatusInvalidQueryColumn
 )
 
 // Any kev-value
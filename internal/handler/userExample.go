@@ -6,6 +6,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/copier"
 
+	"github.com/zhufuyi/sponge/pkg/ggorm/query"
 	"github.com/zhufuyi/sponge/pkg/gin/middleware"
 	"github.com/zhufuyi/sponge/pkg/gin/response"
 	"github.com/zhufuyi/sponge/pkg/logger"
@@ -218,6 +219,11 @@ func (h *userExampleHandler) List(c *gin.Context) {
 	ctx := middleware.WrapCtx(c)
 	userExamples, total, err := h.iDao.GetByColumns(ctx, &form.Params)
 	if err != nil {
+		if errors.Is(err, query.ErrInvalidColumn) {
+			logger.Warn("GetByColumns error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
+			response.Error(c, ecode.InvalidQueryColumn)
+			return
+		}
 		logger.Error("GetByColumns error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
 		response.Output(c, ecode.InternalServerError.ToHTTPCode())
 		return
@@ -148,6 +148,10 @@ func (h *userExamplePbHandler) List(ctx context.Context, req *serverNameExampleV
 
 	records, total, err := h.userExampleDao.GetByColumns(ctx, params)
 	if err != nil {
+		if errors.Is(err, query.ErrInvalidColumn) {
+			logger.Warn("GetByColumns error", logger.Err(err), logger.Any("params", params), middleware.CtxRequestIDField(ctx))
+			return nil, ecode.InvalidQueryColumn.Err()
+		}
 		if strings.Contains(err.Error(), "query params error:") {
 			logger.Warn("GetByColumns error", logger.Err(err), logger.Any("params", params), middleware.CtxRequestIDField(ctx))
 			return nil, ecode.InvalidParams.Err()
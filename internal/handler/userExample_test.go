@@ -16,6 +16,7 @@ import (
 
 	"github.com/zhufuyi/sponge/internal/cache"
 	"github.com/zhufuyi/sponge/internal/dao"
+	"github.com/zhufuyi/sponge/internal/ecode"
 	"github.com/zhufuyi/sponge/internal/model"
 	"github.com/zhufuyi/sponge/internal/types"
 )
@@ -248,12 +249,21 @@ func Test_userExampleHandler_List(t *testing.T) {
 	err = httpcli.Post(result, h.GetRequestURL("List"), nil)
 	assert.NoError(t, err)
 
-	// get error test
+	// invalid column error test
 	err = httpcli.Post(result, h.GetRequestURL("List"), &types.ListUserExamplesRequest{query.Params{
 		Page:  0,
 		Limit: 10,
 		Sort:  "unknown-column",
 	}})
+	assert.NoError(t, err)
+	assert.Equal(t, ecode.InvalidQueryColumn.Code(), result.Code)
+
+	// get error test
+	err = httpcli.Post(result, h.GetRequestURL("List"), &types.ListUserExamplesRequest{query.Params{
+		Page:  0,
+		Limit: 10,
+		Sort:  "name",
+	}})
 	assert.Error(t, err)
 }
 
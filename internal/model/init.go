@@ -2,6 +2,8 @@
 package model
 
 import (
+	"errors"
+	"io"
 	"strings"
 	"sync"
 	"time"
@@ -35,12 +37,15 @@ var (
 
 	cacheType *CacheType
 	once3     sync.Once
+
+	cacheClosers   []io.Closer
+	cacheClosersMu sync.Mutex
 )
 
 // CacheType cache type
 type CacheType struct {
-	CType string        // cache type  memory or redis
-	Rdb   *redis.Client // if CType=redis, Rdb cannot be empty
+	CType string        // cache type  memory, redis or multilevel
+	Rdb   *redis.Client // if CType=redis or multilevel, Rdb cannot be empty
 }
 
 // InitCache initial cache
@@ -49,7 +54,7 @@ func InitCache(cType string) {
 		CType: cType,
 	}
 
-	if cType == "redis" {
+	if cType == "redis" || cType == "multilevel" {
 		cacheType.Rdb = GetRedisCli()
 	}
 }
@@ -108,6 +113,33 @@ func CloseRedis() error {
 	return nil
 }
 
+// AddCacheCloser add the cache that needs to be closed after service exit, e.g. the multilevel cache
+func AddCacheCloser(c io.Closer) {
+	cacheClosersMu.Lock()
+	cacheClosers = append(cacheClosers, c)
+	cacheClosersMu.Unlock()
+}
+
+// CloseCache close the caches added by AddCacheCloser
+func CloseCache() error {
+	cacheClosersMu.Lock()
+	closers := cacheClosers
+	cacheClosers = nil
+	cacheClosersMu.Unlock()
+
+	var errs []string
+	for _, c := range closers {
+		if err := c.Close(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, " || "))
+	}
+
+	return nil
+}
+
 // ------------------------------------------------------------------------------------------
 
 // todo generate initialisation database code here
@@ -2,6 +2,7 @@ package model
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -107,6 +108,29 @@ func TestInitRedis(t *testing.T) {
 	_ = GetRedisCli()
 }
 
+type testCloser struct {
+	err    error
+	closed bool
+}
+
+func (c *testCloser) Close() error {
+	c.closed = true
+	return c.err
+}
+
+func TestCloseCache(t *testing.T) {
+	c1, c2 := &testCloser{}, &testCloser{err: errors.New("close error")}
+	AddCacheCloser(c1)
+	AddCacheCloser(c2)
+	err := CloseCache()
+	assert.Error(t, err)
+	assert.True(t, c1.closed)
+	assert.True(t, c2.closed)
+
+	// closed only once
+	assert.NoError(t, CloseCache())
+}
+
 func TestTableName(t *testing.T) {
 	t.Log(new(UserExample).TableName())
 }
@@ -160,6 +160,10 @@ func (s *userExample) List(ctx context.Context, req *serverNameExampleV1.ListUse
 
 	records, total, err := s.iDao.GetByColumns(ctx, params)
 	if err != nil {
+		if errors.Is(err, query.ErrInvalidColumn) {
+			logger.Warn("GetByColumns error", logger.Err(err), logger.Any("params", params), interceptor.ServerCtxRequestIDField(ctx))
+			return nil, ecode.StatusInvalidQueryColumn.Err()
+		}
 		if strings.Contains(err.Error(), "query params error:") {
 			logger.Warn("GetByColumns error", logger.Err(err), logger.Any("params", params), interceptor.ServerCtxRequestIDField(ctx))
 			return nil, ecode.StatusInvalidParams.Err()
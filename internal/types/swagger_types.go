@@ -19,16 +19,34 @@ type Params struct {
 	Sort  string `json:"sort,omitempty"` // sorted fields, multi-column sorting separated by commas
 
 	Columns []Column `json:"columns,omitempty"` // query conditions
+	Group   *Group   `json:"group,omitempty"`   // nested query conditions, joined with columns by and
+}
+
+// CursorParams cursor paging query parameters
+type CursorParams struct {
+	Cursor string `json:"cursor"`         // cursor returned by the previous query, empty means the first page
+	Limit  int    `json:"limit"`          // lines per page
+	Sort   string `json:"sort,omitempty"` // sorted fields, multi-column sorting separated by commas, must be the same as the sort fields of the cursor
+
+	Columns []Column `json:"columns,omitempty"` // query conditions
+	Group   *Group   `json:"group,omitempty"`   // nested query conditions, joined with columns by and
 }
 
 // Column information
 type Column struct {
 	Name  string      `json:"name"`  // column name
-	Exp   string      `json:"exp"`   // expressions, which default to = when the value is null, have =, !=, >, >=, <, <=, like
+	Exp   string      `json:"exp"`   // expressions, which default to = when the value is null, have =, !=, >, >=, <, <=, like, in, notin, notlike, prefix, suffix, between, isnull, notnull
 	Value interface{} `json:"value"` // column value
 	Logic string      `json:"logic"` // logical type, defaults to and when value is null, only &(and), ||(or)
 }
 
+// Group nested query conditions
+type Group struct {
+	Logic   string   `json:"logic"`             // logical type of the items in the group, defaults to and when value is null, only &(and), ||(or)
+	Columns []Column `json:"columns,omitempty"` // query conditions
+	Groups  []Group  `json:"groups,omitempty"`  // sub groups, each sub group is wrapped in parentheses
+}
+
 // Conditions query conditions
 type Conditions struct {
 	Columns []Column `json:"columns"` // columns info
@@ -0,0 +1,223 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// prefix of the lock key used to rebuild the cache, the lock key is lockKeyPrefix + cache key
+const lockKeyPrefix = "lock:"
+
+// LoadFunc load the value from the data source (e.g. database) when the cache misses or the value is stale
+type LoadFunc func(ctx context.Context) (interface{}, error)
+
+// Loader cache-aside loader with stampede protection, when the value of a key is not in the cache,
+// only one caller loads it from the data source and writes it to the cache, the others wait for the result:
+//
+//   - within the process, loading is deduplicated by singleflight.
+//   - across processes, loading is deduplicated by the distributed lock, see LoaderWithLocker.
+//   - when stale-while-revalidate is enabled, the stale value is returned immediately and refreshed in background, see LoaderWithStaleTime.
+//   - random jitter is added to the expiration time, so that the keys do not expire at the same time, see LoaderWithJitter.
+//
+// NOTE: the value written by Loader contains the expiration time, the keys must be read by GetOrLoad instead of Cache.Get,
+// use Cache.Del to invalidate the keys.
+type Loader struct {
+	cache      Cache
+	sfg        *singleflight.Group
+	refreshing sync.Map
+	opts       *loaderOptions
+}
+
+// value stored in the cache
+type loaderEntry struct {
+	Data     []byte `json:"data"`
+	ExpireAt int64  `json:"expireAt"` // unix nano, the value is stale after this time, 0 means never stale
+}
+
+// NewLoader create a loader based on the cache
+func NewLoader(c Cache, opts ...LoaderOption) *Loader {
+	o := defaultLoaderOptions()
+	o.apply(opts...)
+	return &Loader{
+		cache: c,
+		sfg:   new(singleflight.Group),
+		opts:  o,
+	}
+}
+
+// GetOrLoad get the value of the key from the cache to val, if the value is not in the cache,
+// load it by fn and write it to the cache with the expiration time.
+//
+// if fn returns one of the errors set by LoaderWithNotFoundErrs, a placeholder is cached and
+// an error wrapped with ErrPlaceholder and the original error is returned, then GetOrLoad returns
+// ErrPlaceholder until the placeholder expires, use errors.Is(err, ErrPlaceholder) to determine.
+func (l *Loader) GetOrLoad(ctx context.Context, key string, val interface{}, expiration time.Duration, fn LoadFunc) error {
+	entry, err := l.get(ctx, key)
+	if err == nil {
+		err = l.opts.encoding.Unmarshal(entry.Data, val)
+		if err == nil {
+			if entry.isStale() {
+				l.refresh(key, expiration, fn)
+			}
+			return nil
+		}
+	} else if errors.Is(err, ErrPlaceholder) {
+		return ErrPlaceholder
+	}
+
+	data, err := l.load(ctx, key, expiration, fn)
+	if err != nil {
+		return err
+	}
+	return l.opts.encoding.Unmarshal(data, val)
+}
+
+func (l *Loader) get(ctx context.Context, key string) (*loaderEntry, error) {
+	entry := &loaderEntry{}
+	err := l.cache.Get(ctx, key, entry)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Data == nil {
+		return nil, CacheNotFound
+	}
+	return entry, nil
+}
+
+func (l *Loader) load(ctx context.Context, key string, expiration time.Duration, fn LoadFunc) ([]byte, error) {
+	v, err, _ := l.sfg.Do(key, func() (interface{}, error) {
+		locker := l.opts.locker
+		if locker == nil {
+			return l.loadAndSet(ctx, key, expiration, fn)
+		}
+
+		lockKey := lockKeyPrefix + key
+		token, ok, err := locker.TryLock(ctx, lockKey, l.opts.lockExpiration)
+		if err != nil { // the lock is unavailable, load directly
+			return l.loadAndSet(ctx, key, expiration, fn)
+		}
+		if ok {
+			defer func() { _ = locker.Unlock(context.Background(), lockKey, token) }()
+			// double check, the value may have been rebuilt by another caller before acquiring the lock
+			if entry, err := l.get(ctx, key); err == nil && !entry.isStale() {
+				return entry.Data, nil
+			}
+			return l.loadAndSet(ctx, key, expiration, fn)
+		}
+
+		// the lock is held by another caller, wait for the rebuilt value
+		data, err := l.wait(ctx, key)
+		if err == nil || errors.Is(err, ErrPlaceholder) {
+			return data, err
+		}
+		// timeout, load from the data source without writing to the cache
+		value, err := fn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return l.opts.encoding.Marshal(value)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]byte), nil
+}
+
+func (l *Loader) wait(ctx context.Context, key string) ([]byte, error) {
+	if l.opts.waitTimeout <= 0 {
+		return nil, CacheNotFound
+	}
+
+	ticker := time.NewTicker(l.opts.waitInterval)
+	defer ticker.Stop()
+	timer := time.NewTimer(l.opts.waitTimeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+			return nil, CacheNotFound
+		case <-ticker.C:
+			entry, err := l.get(ctx, key)
+			if err == nil {
+				return entry.Data, nil
+			}
+			if errors.Is(err, ErrPlaceholder) {
+				return nil, err
+			}
+		}
+	}
+}
+
+func (l *Loader) loadAndSet(ctx context.Context, key string, expiration time.Duration, fn LoadFunc) ([]byte, error) {
+	value, err := fn(ctx)
+	if err != nil {
+		if l.isNotFound(err) {
+			_ = l.cache.SetCacheWithNotFound(ctx, key)
+			return nil, fmt.Errorf("%w: %w", ErrPlaceholder, err)
+		}
+		return nil, err
+	}
+
+	data, err := l.opts.encoding.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("encoding.Marshal error: %v, key=%s", err, key)
+	}
+
+	entry := &loaderEntry{Data: data}
+	ttl := expiration
+	if expiration > 0 {
+		expiration = JitterExpiration(expiration, l.opts.jitterRatio)
+		entry.ExpireAt = time.Now().Add(expiration).UnixNano()
+		ttl = expiration + l.opts.staleTime
+	}
+	err = l.cache.Set(ctx, key, entry, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// refresh the stale value in background, only one caller refreshes the value of a key at the same time
+func (l *Loader) refresh(key string, expiration time.Duration, fn LoadFunc) {
+	if _, loaded := l.refreshing.LoadOrStore(key, struct{}{}); loaded {
+		return
+	}
+
+	go func() {
+		defer l.refreshing.Delete(key)
+		ctx, cancel := context.WithTimeout(context.Background(), l.opts.refreshTimeout)
+		defer cancel()
+
+		if locker := l.opts.locker; locker != nil {
+			lockKey := lockKeyPrefix + key
+			token, ok, err := locker.TryLock(ctx, lockKey, l.opts.lockExpiration)
+			if err != nil || !ok {
+				return
+			}
+			defer func() { _ = locker.Unlock(context.Background(), lockKey, token) }()
+		}
+		_, _ = l.loadAndSet(ctx, key, expiration, fn)
+	}()
+}
+
+func (l *Loader) isNotFound(err error) bool {
+	for _, e := range l.opts.notFoundErrs {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *loaderEntry) isStale() bool {
+	return e.ExpireAt > 0 && time.Now().UnixNano() >= e.ExpireAt
+}
@@ -0,0 +1,210 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zhufuyi/sponge/pkg/encoding"
+)
+
+type loaderUser struct {
+	ID   uint64
+	Name string
+}
+
+func newLoaderCache() (Cache, func()) {
+	c := newRedisCache()
+	return c.ICache.(Cache), c.Close
+}
+
+func TestLoader_GetOrLoad(t *testing.T) {
+	c, closeFn := newLoaderCache()
+	defer closeFn()
+	ctx := context.Background()
+
+	var count int32
+	fn := func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&count, 1)
+		return &loaderUser{ID: 1, Name: "foo"}, nil
+	}
+
+	l := NewLoader(c)
+	for i := 0; i < 3; i++ {
+		val := &loaderUser{}
+		err := l.GetOrLoad(ctx, "user:1", val, time.Minute, fn)
+		assert.NoError(t, err)
+		assert.Equal(t, "foo", val.Name)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+
+	// load error
+	loadErr := errors.New("load error")
+	err := l.GetOrLoad(ctx, "user:2", &loaderUser{}, time.Minute, func(ctx context.Context) (interface{}, error) {
+		return nil, loadErr
+	})
+	assert.ErrorIs(t, err, loadErr)
+
+	// invalidate
+	err = c.Del(ctx, "user:1")
+	assert.NoError(t, err)
+	err = l.GetOrLoad(ctx, "user:1", &loaderUser{}, time.Minute, fn)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&count))
+}
+
+func TestLoader_GetOrLoad_NotFound(t *testing.T) {
+	c, closeFn := newLoaderCache()
+	defer closeFn()
+	ctx := context.Background()
+
+	errRecordNotFound := errors.New("record not found")
+	var count int32
+	fn := func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&count, 1)
+		return nil, errRecordNotFound
+	}
+
+	l := NewLoader(c, LoaderWithNotFoundErrs(errRecordNotFound))
+	err := l.GetOrLoad(ctx, "user:1", &loaderUser{}, time.Minute, fn)
+	assert.ErrorIs(t, err, ErrPlaceholder)
+	assert.ErrorIs(t, err, errRecordNotFound)
+
+	err = l.GetOrLoad(ctx, "user:1", &loaderUser{}, time.Minute, fn)
+	assert.ErrorIs(t, err, ErrPlaceholder)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+}
+
+func TestLoader_GetOrLoad_Locker(t *testing.T) {
+	rc := newRedisCache()
+	defer rc.Close()
+	c := rc.ICache.(Cache)
+	ctx := context.Background()
+
+	var count int32
+	fn := func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&count, 1)
+		time.Sleep(time.Millisecond * 200)
+		return &loaderUser{ID: 1, Name: "foo"}, nil
+	}
+
+	// two loaders simulate two replicas, loading is deduplicated by the distributed lock
+	locker := NewRedisLocker(rc.RedisClient)
+	loaders := []*Loader{
+		NewLoader(c, LoaderWithLocker(locker), LoaderWithWait(time.Second*2, time.Millisecond*10)),
+		NewLoader(c, LoaderWithLocker(locker), LoaderWithWait(time.Second*2, time.Millisecond*10)),
+	}
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			val := &loaderUser{}
+			err := loaders[i%2].GetOrLoad(ctx, "user:1", val, time.Minute, fn)
+			assert.NoError(t, err)
+			assert.Equal(t, "foo", val.Name)
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+
+	// wait timeout, load from the data source directly
+	token, ok, err := locker.TryLock(ctx, lockKeyPrefix+"user:2", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	l := NewLoader(c, LoaderWithLocker(locker), LoaderWithWait(time.Millisecond*50, time.Millisecond*10))
+	val := &loaderUser{}
+	err = l.GetOrLoad(ctx, "user:2", val, time.Minute, fn)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", val.Name)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&count))
+	_ = locker.Unlock(ctx, lockKeyPrefix+"user:2", token)
+}
+
+func TestLoader_GetOrLoad_Stale(t *testing.T) {
+	c, closeFn := newLoaderCache()
+	defer closeFn()
+	ctx := context.Background()
+
+	var count int32
+	fn := func(ctx context.Context) (interface{}, error) {
+		n := atomic.AddInt32(&count, 1)
+		if n == 1 {
+			return &loaderUser{ID: 1, Name: "foo"}, nil
+		}
+		return &loaderUser{ID: 1, Name: "bar"}, nil
+	}
+
+	l := NewLoader(c,
+		LoaderWithStaleTime(time.Minute),
+		LoaderWithJitter(0),
+		LoaderWithLocker(NewMemoryLocker()),
+		LoaderWithRefreshTimeout(time.Second),
+		LoaderWithLockExpiration(time.Second),
+		LoaderWithEncoding(encoding.JSONEncoding{}),
+	)
+	val := &loaderUser{}
+	err := l.GetOrLoad(ctx, "user:1", val, time.Millisecond*50, fn)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", val.Name)
+
+	// the stale value is returned and refreshed in background
+	time.Sleep(time.Millisecond * 100)
+	val = &loaderUser{}
+	err = l.GetOrLoad(ctx, "user:1", val, time.Millisecond*50, fn)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", val.Name)
+
+	time.Sleep(time.Millisecond * 30)
+	val = &loaderUser{}
+	err = l.GetOrLoad(ctx, "user:1", val, time.Minute, fn)
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", val.Name)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&count))
+}
+
+func TestLocker(t *testing.T) {
+	rc := newRedisCache()
+	defer rc.Close()
+	ctx := context.Background()
+
+	for _, locker := range []Locker{NewRedisLocker(rc.RedisClient), NewMemoryLocker()} {
+		token, ok, err := locker.TryLock(ctx, "lock:foo", time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		_, ok, err = locker.TryLock(ctx, "lock:foo", time.Minute)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+
+		// only the holder can release the lock
+		err = locker.Unlock(ctx, "lock:foo", "unknown")
+		assert.NoError(t, err)
+		_, ok, _ = locker.TryLock(ctx, "lock:foo", time.Minute)
+		assert.False(t, ok)
+
+		err = locker.Unlock(ctx, "lock:foo", token)
+		assert.NoError(t, err)
+		_, ok, _ = locker.TryLock(ctx, "lock:foo", time.Minute)
+		assert.True(t, ok)
+	}
+}
+
+func TestJitterExpiration(t *testing.T) {
+	assert.Equal(t, time.Minute, JitterExpiration(time.Minute, 0))
+	assert.Equal(t, time.Duration(0), JitterExpiration(0, 0.1))
+	for i := 0; i < 100; i++ {
+		d := JitterExpiration(time.Minute, 0.1)
+		assert.True(t, d >= time.Minute && d <= time.Minute+time.Second*6)
+	}
+
+	o := defaultOptions()
+	o.apply(WithJitter(2))
+	assert.Equal(t, float64(1), o.jitterRatio)
+}
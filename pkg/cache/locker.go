@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Locker distributed lock, used to ensure that only one caller rebuilds the cache of a key at the same time
+type Locker interface {
+	// TryLock try to acquire the lock without blocking, ok is false if the lock is held by others,
+	// the returned token is used to release the lock.
+	TryLock(ctx context.Context, key string, expiration time.Duration) (token string, ok bool, err error)
+	// Unlock release the lock, only the holder of the token can release it.
+	Unlock(ctx context.Context, key string, token string) error
+}
+
+// release the lock only if the value is the token of the holder
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+type redisLocker struct {
+	client redis.Cmdable
+}
+
+// NewRedisLocker create a distributed lock based on redis, the client can be *redis.Client or *redis.ClusterClient
+func NewRedisLocker(client redis.Cmdable) Locker {
+	return &redisLocker{client: client}
+}
+
+// TryLock try to acquire the lock
+func (l *redisLocker) TryLock(ctx context.Context, key string, expiration time.Duration) (string, bool, error) {
+	token := newLockToken()
+	ok, err := l.client.SetNX(ctx, key, token, expiration).Result()
+	if err != nil {
+		return "", false, err
+	}
+	return token, ok, nil
+}
+
+// Unlock release the lock
+func (l *redisLocker) Unlock(ctx context.Context, key string, token string) error {
+	return unlockScript.Run(ctx, l.client, []string{key}, token).Err()
+}
+
+// -------------------------------------------------------------------------------------------
+
+type memoryLock struct {
+	token    string
+	expireAt time.Time
+}
+
+type memoryLocker struct {
+	mu    sync.Mutex
+	locks map[string]memoryLock
+}
+
+// NewMemoryLocker create a lock that only works within the process, used with memory cache or for testing
+func NewMemoryLocker() Locker {
+	return &memoryLocker{locks: make(map[string]memoryLock)}
+}
+
+// TryLock try to acquire the lock
+func (l *memoryLocker) TryLock(_ context.Context, key string, expiration time.Duration) (string, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if lock, ok := l.locks[key]; ok && now.Before(lock.expireAt) {
+		return "", false, nil
+	}
+	token := newLockToken()
+	l.locks[key] = memoryLock{token: token, expireAt: now.Add(expiration)}
+	return token, true, nil
+}
+
+// Unlock release the lock
+func (l *memoryLocker) Unlock(_ context.Context, key string, token string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if lock, ok := l.locks[key]; ok && lock.token == token {
+		delete(l.locks, key)
+	}
+	return nil
+}
+
+func newLockToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -7,34 +7,35 @@ import (
 	"reflect"
 	"time"
 
-	"github.com/dgraph-io/ristretto"
-
 	"github.com/zhufuyi/sponge/pkg/encoding"
 )
 
 type memoryCache struct {
-	client            *ristretto.Cache
+	client            memoryStore
 	KeyPrefix         string
 	encoding          encoding.Encoding
 	DefaultExpireTime time.Duration
 	newObject         func() interface{}
+	jitterRatio       float64
+	name              string
 }
 
-// NewMemoryCache create a memory cache
-func NewMemoryCache(keyPrefix string, encode encoding.Encoding, newObject func() interface{}) Cache {
-	// see: https://dgraph.io/blog/post/introducing-ristretto-high-perf-go-cache/
-	//		https://www.start.io/blog/we-chose-ristretto-cache-for-go-heres-why/
-	config := &ristretto.Config{
-		NumCounters: 1e7,     // number of keys to track frequency of (10M).
-		MaxCost:     1 << 30, // maximum cost of cache (1GB).
-		BufferItems: 64,      // number of keys per Get buffer.
-	}
-	store, _ := ristretto.NewCache(config)
+// NewMemoryCache create a memory cache, the size of the cache is limited by WithMaxEntries and WithMaxBytes,
+// when the limit is reached, the entries are evicted by the policy set by WithEvictionPolicy.
+func NewMemoryCache(keyPrefix string, encode encoding.Encoding, newObject func() interface{}, opts ...Option) Cache {
+	o := defaultOptions()
+	o.apply(opts...)
+	registerMetrics()
+
 	return &memoryCache{
-		client:    store,
-		KeyPrefix: keyPrefix,
-		encoding:  encode,
-		newObject: newObject,
+		client: newMemoryStore(o, func() {
+			evictionCount.WithLabelValues(o.name).Inc()
+		}),
+		KeyPrefix:   keyPrefix,
+		encoding:    encode,
+		newObject:   newObject,
+		jitterRatio: o.jitterRatio,
+		name:        o.name,
 	}
 }
 
@@ -48,9 +49,9 @@ func (m *memoryCache) Set(_ context.Context, key string, val interface{}, expira
 	if err != nil {
 		return fmt.Errorf("BuildCacheKey error: %v, key=%s", err, key)
 	}
-	ok := m.client.SetWithTTL(cacheKey, buf, 0, expiration)
+	ok := m.client.Set(cacheKey, buf, JitterExpiration(expiration, m.jitterRatio))
 	if !ok {
-		return errors.New("SetWithTTL failed")
+		return errors.New("set memory cache failed")
 	}
 
 	return nil
@@ -65,17 +66,20 @@ func (m *memoryCache) Get(_ context.Context, key string, val interface{}) error
 
 	data, ok := m.client.Get(cacheKey)
 	if !ok {
+		recordRequest(m.name, levelLocal, false)
 		return CacheNotFound
 	}
 
-	if string(data.([]byte)) == NotFoundPlaceholder {
+	if string(data) == NotFoundPlaceholder {
+		recordRequest(m.name, levelLocal, false) // the placeholder is not a hit
 		return ErrPlaceholder
 	}
+	recordRequest(m.name, levelLocal, true)
 
-	err = encoding.Unmarshal(m.encoding, data.([]byte), val)
+	err = encoding.Unmarshal(m.encoding, data, val)
 	if err != nil {
 		return fmt.Errorf("encoding.Unmarshal error: %v, key=%s, cacheKey=%s, type=%v, json=%+v ",
-			err, key, cacheKey, reflect.TypeOf(val), string(data.([]byte)))
+			err, key, cacheKey, reflect.TypeOf(val), string(data))
 	}
 	return nil
 }
@@ -130,9 +134,9 @@ func (m *memoryCache) SetCacheWithNotFound(_ context.Context, key string) error
 		return fmt.Errorf("BuildCacheKey error: %v, key=%s", err, key)
 	}
 
-	ok := m.client.SetWithTTL(cacheKey, []byte(NotFoundPlaceholder), 0, DefaultNotFoundExpireTime)
+	ok := m.client.Set(cacheKey, []byte(NotFoundPlaceholder), DefaultNotFoundExpireTime)
 	if !ok {
-		return errors.New("SetWithTTL failed")
+		return errors.New("set memory cache failed")
 	}
 
 	return nil
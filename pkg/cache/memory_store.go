@@ -0,0 +1,375 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/ristretto"
+)
+
+// EvictionPolicy eviction policy of the memory cache when the size limit is reached
+type EvictionPolicy string
+
+const (
+	// EvictionLRU evict the least recently used entry
+	EvictionLRU EvictionPolicy = "lru"
+	// EvictionLFU evict the least frequently used entry, the least recently used one is evicted among the entries of the same frequency
+	EvictionLFU EvictionPolicy = "lfu"
+	// EvictionTinyLFU admission and eviction based on the approximate frequency of the keys (ristretto), suitable for large caches
+	EvictionTinyLFU EvictionPolicy = "tinylfu"
+)
+
+// memoryStore storage of the memory cache
+type memoryStore interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte, ttl time.Duration) bool
+	Del(key string)
+}
+
+func newMemoryStore(o *options, onEvict func()) memoryStore {
+	switch o.evictionPolicy {
+	case EvictionLRU:
+		return newLRUStore(o.maxEntries, o.maxBytes, onEvict)
+	case EvictionLFU:
+		return newLFUStore(o.maxEntries, o.maxBytes, onEvict)
+	default:
+		return newTinyLFUStore(o.maxEntries, o.maxBytes, onEvict)
+	}
+}
+
+func entrySize(key string, value []byte) int64 {
+	return int64(len(key) + len(value))
+}
+
+// -------------------------------------------------------------------------------------------
+
+type tinyLFUStore struct {
+	client *ristretto.Cache
+	byCost bool
+}
+
+// ristretto supports only one cost dimension, when maxBytes is set, the cost of an entry is its byte size,
+// otherwise the cost is 1 and the capacity is maxEntries, maxEntries is also used to estimate the number of counters.
+func newTinyLFUStore(maxEntries int64, maxBytes int64, onEvict func()) *tinyLFUStore {
+	// see: https://dgraph.io/blog/post/introducing-ristretto-high-perf-go-cache/
+	//		https://www.start.io/blog/we-chose-ristretto-cache-for-go-heres-why/
+	config := &ristretto.Config{
+		NumCounters:        1e7,     // number of keys to track frequency of (10M).
+		MaxCost:            1 << 30, // maximum cost of cache (1GB).
+		BufferItems:        64,      // number of keys per Get buffer.
+		IgnoreInternalCost: true,
+		OnEvict: func(item *ristretto.Item) {
+			// expired entries are also removed by OnEvict, they are not counted as evictions
+			if item.Expiration.IsZero() || time.Now().Before(item.Expiration) {
+				onEvict()
+			}
+		},
+	}
+	if maxEntries > 0 {
+		config.NumCounters = maxEntries * 10 // 10x the number of items expected to keep when full
+	}
+	byCost := true
+	switch {
+	case maxBytes > 0:
+		config.MaxCost = maxBytes
+	case maxEntries > 0:
+		config.MaxCost = maxEntries
+		byCost = false
+	}
+
+	client, _ := ristretto.NewCache(config)
+	return &tinyLFUStore{client: client, byCost: byCost}
+}
+
+func (s *tinyLFUStore) Get(key string) ([]byte, bool) {
+	data, ok := s.client.Get(key)
+	if !ok {
+		return nil, false
+	}
+	return data.([]byte), true
+}
+
+func (s *tinyLFUStore) Set(key string, value []byte, ttl time.Duration) bool {
+	var cost int64 = 1
+	if s.byCost {
+		cost = entrySize(key, value)
+	}
+	return s.client.SetWithTTL(key, value, cost, ttl)
+}
+
+func (s *tinyLFUStore) Del(key string) {
+	s.client.Del(key)
+}
+
+// -------------------------------------------------------------------------------------------
+
+type memoryEntry struct {
+	key      string
+	value    []byte
+	expireAt time.Time // zero means never expire
+	freq     int       // only used by LFU
+}
+
+func (e *memoryEntry) isExpired(now time.Time) bool {
+	return !e.expireAt.IsZero() && !now.Before(e.expireAt)
+}
+
+func newMemoryEntry(key string, value []byte, ttl time.Duration) *memoryEntry {
+	e := &memoryEntry{key: key, value: value}
+	if ttl > 0 {
+		e.expireAt = time.Now().Add(ttl)
+	}
+	return e
+}
+
+// size limits shared by LRU and LFU, 0 means no limit
+type storeLimit struct {
+	maxEntries int64
+	maxBytes   int64
+	entries    int64
+	bytes      int64
+}
+
+func (l *storeLimit) add(e *memoryEntry) {
+	l.entries++
+	l.bytes += entrySize(e.key, e.value)
+}
+
+func (l *storeLimit) remove(e *memoryEntry) {
+	l.entries--
+	l.bytes -= entrySize(e.key, e.value)
+}
+
+func (l *storeLimit) isOverflow() bool {
+	return (l.maxEntries > 0 && l.entries > l.maxEntries) || (l.maxBytes > 0 && l.bytes > l.maxBytes)
+}
+
+func (l *storeLimit) isTooLarge(key string, value []byte) bool {
+	return l.maxBytes > 0 && entrySize(key, value) > l.maxBytes
+}
+
+// -------------------------------------------------------------------------------------------
+
+type lruStore struct {
+	mu      sync.Mutex
+	ll      *list.List // front is the most recently used
+	items   map[string]*list.Element
+	limit   storeLimit
+	onEvict func()
+}
+
+func newLRUStore(maxEntries int64, maxBytes int64, onEvict func()) *lruStore {
+	return &lruStore{
+		ll:      list.New(),
+		items:   make(map[string]*list.Element),
+		limit:   storeLimit{maxEntries: maxEntries, maxBytes: maxBytes},
+		onEvict: onEvict,
+	}
+}
+
+func (s *lruStore) Get(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := elem.Value.(*memoryEntry)
+	if e.isExpired(time.Now()) {
+		s.removeElement(elem)
+		return nil, false
+	}
+	s.ll.MoveToFront(elem)
+	return e.value, true
+}
+
+func (s *lruStore) Set(key string, value []byte, ttl time.Duration) bool {
+	if s.limit.isTooLarge(key, value) {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, ok := s.items[key]; ok {
+		s.removeElement(elem)
+	}
+	e := newMemoryEntry(key, value, ttl)
+	s.items[key] = s.ll.PushFront(e)
+	s.limit.add(e)
+
+	for s.limit.isOverflow() {
+		elem := s.ll.Back()
+		if elem == nil {
+			break
+		}
+		expired := elem.Value.(*memoryEntry).isExpired(time.Now())
+		s.removeElement(elem)
+		if !expired {
+			s.onEvict()
+		}
+	}
+	return true
+}
+
+func (s *lruStore) Del(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, ok := s.items[key]; ok {
+		s.removeElement(elem)
+	}
+}
+
+func (s *lruStore) removeElement(elem *list.Element) {
+	e := s.ll.Remove(elem).(*memoryEntry)
+	delete(s.items, e.key)
+	s.limit.remove(e)
+}
+
+// -------------------------------------------------------------------------------------------
+
+type lfuStore struct {
+	mu      sync.Mutex
+	items   map[string]*list.Element
+	freqs   map[int]*list.List // frequency --> entries, front is the most recently used, empty lists are removed
+	minFreq int
+	limit   storeLimit
+	onEvict func()
+}
+
+func newLFUStore(maxEntries int64, maxBytes int64, onEvict func()) *lfuStore {
+	return &lfuStore{
+		items:   make(map[string]*list.Element),
+		freqs:   make(map[int]*list.List),
+		limit:   storeLimit{maxEntries: maxEntries, maxBytes: maxBytes},
+		onEvict: onEvict,
+	}
+}
+
+func (s *lfuStore) Get(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := elem.Value.(*memoryEntry)
+	if e.isExpired(time.Now()) {
+		s.removeElement(elem)
+		return nil, false
+	}
+
+	s.unlink(elem)
+	e.freq++
+	s.link(e)
+	return e.value, true
+}
+
+func (s *lfuStore) Set(key string, value []byte, ttl time.Duration) bool {
+	if s.limit.isTooLarge(key, value) {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	freq := 1
+	if elem, ok := s.items[key]; ok {
+		freq = elem.Value.(*memoryEntry).freq + 1 // keep the frequency when updating
+		s.removeElement(elem)
+	}
+	e := newMemoryEntry(key, value, ttl)
+	e.freq = freq
+	s.link(e)
+	s.limit.add(e)
+
+	for s.limit.isOverflow() {
+		elem := s.victim(key)
+		if elem == nil {
+			break
+		}
+		expired := elem.Value.(*memoryEntry).isExpired(time.Now())
+		s.removeElement(elem)
+		if !expired {
+			s.onEvict()
+		}
+	}
+	return true
+}
+
+func (s *lfuStore) Del(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, ok := s.items[key]; ok {
+		s.removeElement(elem)
+	}
+}
+
+// the least recently used entry of the minimum frequency, the entry just written is evicted only if it is the only one
+func (s *lfuStore) victim(newKey string) *list.Element {
+	if l, ok := s.freqs[s.minFreq]; !ok || l.Len() == 0 {
+		s.minFreq = 0
+		for freq := range s.freqs {
+			if s.minFreq == 0 || freq < s.minFreq {
+				s.minFreq = freq
+			}
+		}
+	}
+	l, ok := s.freqs[s.minFreq]
+	if !ok {
+		return nil
+	}
+	elem := l.Back()
+	if elem.Value.(*memoryEntry).key == newKey && len(s.items) > 1 {
+		if elem.Prev() != nil {
+			return elem.Prev()
+		}
+		// the new entry is the only one of the minimum frequency, evict from the next frequency
+		next := 0
+		for freq := range s.freqs {
+			if freq > s.minFreq && (next == 0 || freq < next) {
+				next = freq
+			}
+		}
+		if next > 0 {
+			return s.freqs[next].Back()
+		}
+	}
+	return elem
+}
+
+func (s *lfuStore) link(e *memoryEntry) {
+	l, ok := s.freqs[e.freq]
+	if !ok {
+		l = list.New()
+		s.freqs[e.freq] = l
+	}
+	s.items[e.key] = l.PushFront(e)
+	if s.minFreq != 0 && e.freq < s.minFreq { // 0 means unknown, recalculated when evicting
+		s.minFreq = e.freq
+	}
+}
+
+func (s *lfuStore) unlink(elem *list.Element) {
+	e := elem.Value.(*memoryEntry)
+	l := s.freqs[e.freq]
+	l.Remove(elem)
+	delete(s.items, e.key)
+	if l.Len() == 0 {
+		delete(s.freqs, e.freq)
+		if s.minFreq == e.freq {
+			s.minFreq = 0
+		}
+	}
+}
+
+func (s *lfuStore) removeElement(elem *list.Element) {
+	e := elem.Value.(*memoryEntry)
+	s.unlink(elem)
+	s.limit.remove(e)
+}
@@ -0,0 +1,147 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zhufuyi/sponge/pkg/encoding"
+)
+
+func TestLRUStore(t *testing.T) {
+	evictions := 0
+	s := newLRUStore(2, 0, func() { evictions++ })
+
+	assert.True(t, s.Set("a", []byte("1"), 0))
+	assert.True(t, s.Set("b", []byte("2"), 0))
+	_, ok := s.Get("a") // b becomes the least recently used
+	assert.True(t, ok)
+	assert.True(t, s.Set("c", []byte("3"), 0))
+	_, ok = s.Get("b")
+	assert.False(t, ok)
+	_, ok = s.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, evictions)
+
+	// update does not evict
+	assert.True(t, s.Set("c", []byte("33"), 0))
+	v, _ := s.Get("c")
+	assert.Equal(t, "33", string(v))
+	assert.Equal(t, 1, evictions)
+
+	s.Del("c")
+	_, ok = s.Get("c")
+	assert.False(t, ok)
+	assert.Equal(t, int64(1), s.limit.entries)
+
+	// expired
+	assert.True(t, s.Set("d", []byte("4"), time.Millisecond))
+	time.Sleep(time.Millisecond * 5)
+	_, ok = s.Get("d")
+	assert.False(t, ok)
+}
+
+func TestLRUStore_MaxBytes(t *testing.T) {
+	evictions := 0
+	s := newLRUStore(0, 10, func() { evictions++ })
+
+	assert.True(t, s.Set("a", []byte("1234"), 0)) // 5 bytes
+	assert.True(t, s.Set("b", []byte("1234"), 0)) // 10 bytes
+	assert.True(t, s.Set("c", []byte("12"), 0))   // 13 bytes, evict a
+	_, ok := s.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, int64(8), s.limit.bytes)
+	assert.Equal(t, 1, evictions)
+
+	// larger than the limit
+	assert.False(t, s.Set("d", []byte("1234567890"), 0))
+}
+
+func TestLFUStore(t *testing.T) {
+	evictions := 0
+	s := newLFUStore(2, 0, func() { evictions++ })
+
+	assert.True(t, s.Set("a", []byte("1"), 0))
+	assert.True(t, s.Set("b", []byte("2"), 0))
+	for i := 0; i < 3; i++ {
+		_, _ = s.Get("a")
+	}
+	_, _ = s.Get("b")
+	assert.True(t, s.Set("c", []byte("3"), 0)) // evict b, the new entry is kept
+	_, ok := s.Get("b")
+	assert.False(t, ok)
+	_, ok = s.Get("c")
+	assert.True(t, ok)
+	_, ok = s.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, evictions)
+
+	// c has the minimum frequency
+	assert.True(t, s.Set("d", []byte("4"), 0))
+	_, ok = s.Get("c")
+	assert.False(t, ok)
+	_, ok = s.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 2, evictions)
+
+	s.Del("a")
+	s.Del("d")
+	assert.Equal(t, int64(0), s.limit.entries)
+	assert.Len(t, s.freqs, 0)
+
+	// expired
+	assert.True(t, s.Set("e", []byte("5"), time.Millisecond))
+	time.Sleep(time.Millisecond * 5)
+	_, ok = s.Get("e")
+	assert.False(t, ok)
+	assert.False(t, newLFUStore(0, 2, func() {}).Set("f", []byte("66"), 0))
+}
+
+func TestTinyLFUStore(t *testing.T) {
+	s := newTinyLFUStore(100, 0, func() {})
+	assert.True(t, s.Set("a", []byte("1"), time.Minute))
+	time.Sleep(time.Millisecond * 10)
+	v, ok := s.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, "1", string(v))
+	s.Del("a")
+	_, ok = s.Get("a")
+	assert.False(t, ok)
+
+	s = newTinyLFUStore(0, 1024, func() {})
+	assert.True(t, s.byCost)
+	assert.True(t, s.Set("a", []byte("1"), time.Minute))
+}
+
+func TestMemoryCache_Eviction(t *testing.T) {
+	ctx := context.Background()
+	for _, policy := range []EvictionPolicy{EvictionLRU, EvictionLFU} {
+		name := "eviction_" + string(policy)
+		before := testutil.ToFloat64(evictionCount.WithLabelValues(name))
+		c := NewMemoryCache("", encoding.JSONEncoding{}, func() interface{} {
+			return &memoryUser{}
+		}, WithName(name), WithMaxEntries(10), WithMaxBytes(1<<20), WithEvictionPolicy(policy))
+
+		for i := 0; i < 20; i++ {
+			err := c.Set(ctx, "user:"+string(rune('a'+i)), &memoryUser{ID: uint64(i)}, time.Minute)
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, before+10, testutil.ToFloat64(evictionCount.WithLabelValues(name)))
+
+		hits := testutil.ToFloat64(requestCount.WithLabelValues(name, levelLocal, resultHit))
+		misses := testutil.ToFloat64(requestCount.WithLabelValues(name, levelLocal, resultMiss))
+		assert.Error(t, c.Get(ctx, "user:a", &memoryUser{}))
+		assert.NoError(t, c.Get(ctx, "user:t", &memoryUser{}))
+		assert.Equal(t, hits+1, testutil.ToFloat64(requestCount.WithLabelValues(name, levelLocal, resultHit)))
+		assert.Equal(t, misses+1, testutil.ToFloat64(requestCount.WithLabelValues(name, levelLocal, resultMiss)))
+	}
+
+	o := defaultOptions()
+	o.apply(WithEvictionPolicy("unknown"), WithMaxEntries(-1), WithMaxBytes(-1), WithName(""))
+	assert.Equal(t, EvictionTinyLFU, o.evictionPolicy)
+	assert.Equal(t, int64(1<<30), o.maxBytes)
+	assert.Equal(t, "default", o.name)
+}
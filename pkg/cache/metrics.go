@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	levelLocal  = "local"
+	levelRemote = "remote"
+
+	resultHit  = "hit"
+	resultMiss = "miss"
+)
+
+var (
+	metricsNamespace = "cache"
+
+	// hit ratio of a level: sum(rate(cache_requests_total{result="hit"}[5m])) by (name, level) / sum(rate(cache_requests_total[5m])) by (name, level)
+	requestCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "requests_total",
+			Help:      "Total number of cache requests, partitioned by cache name, level and result.",
+		}, []string{"name", "level", "result"},
+	)
+
+	evictionCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "evictions_total",
+			Help:      "Total number of entries evicted from the memory cache because of the size limit.",
+		}, []string{"name"},
+	)
+
+	registerOnce sync.Once
+)
+
+// register the cache metrics to the prometheus default registry, it can be exported by the metrics middleware of gin
+func registerMetrics() {
+	registerOnce.Do(func() {
+		_ = prometheus.Register(requestCount)
+		_ = prometheus.Register(evictionCount)
+	})
+}
+
+func recordRequest(name string, level string, hit bool) {
+	result := resultMiss
+	if hit {
+		result = resultHit
+	}
+	requestCount.WithLabelValues(name, level, result).Inc()
+}
@@ -0,0 +1,293 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/zhufuyi/sponge/pkg/encoding"
+)
+
+// multilevelCache two-level cache, the local memory cache (L1) in front of the redis cache (L2).
+//
+// reading checks L1 first, then L2, the value hit in L2 is written back to L1. writing (Set, MultiSet, Del,
+// SetCacheWithNotFound) writes L2 and then publishes an invalidation message via redis pub/sub, so that the
+// other replicas delete the keys from their L1. if the message is lost, the value in L1 expires after
+// the local expiration time at most, see MultilevelWithLocalExpiration.
+type multilevelCache struct {
+	local  Cache
+	remote Cache
+
+	client     *redis.Client
+	pubSub     *redis.PubSub
+	instanceID string
+	keyPrefix  string
+	opts       *multilevelOptions
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+}
+
+// invalidation message published to the other replicas
+type invalidateMessage struct {
+	InstanceID string   `json:"i"`
+	KeyPrefix  string   `json:"p"`
+	Keys       []string `json:"k"`
+}
+
+// NewMultilevelCache create a two-level cache of local memory and redis, the keys in the local cache of all
+// replicas are invalidated by redis pub/sub when the keys are written or deleted on any replica, the channel is
+// subscribed in background, call Close to stop receiving the invalidation messages.
+func NewMultilevelCache(client *redis.Client, keyPrefix string, encode encoding.Encoding, newObject func() interface{}, opts ...MultilevelOption) Cache {
+	o := defaultMultilevelOptions()
+	o.apply(opts...)
+	if o.zapLog == nil {
+		o.zapLog, _ = zap.NewProduction()
+	}
+	registerMetrics()
+
+	c := &multilevelCache{
+		local:      NewMemoryCache(keyPrefix, encode, newObject, append([]Option{WithName(o.name)}, o.localOpts...)...),
+		remote:     NewRedisCache(client, keyPrefix, encode, newObject, WithJitter(o.jitterRatio)),
+		client:     client,
+		instanceID: newLockToken(),
+		keyPrefix:  keyPrefix,
+		opts:       o,
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+
+	c.pubSub = client.Subscribe(c.ctx) // subscribe the channel in background, creating the cache is not blocked
+	go c.subscribe()
+
+	return c
+}
+
+// Set write L2 and L1, and invalidate L1 of the other replicas
+func (c *multilevelCache) Set(ctx context.Context, key string, val interface{}, expiration time.Duration) error {
+	err := c.remote.Set(ctx, key, val, expiration)
+	if err != nil {
+		return err
+	}
+	_ = c.local.Set(ctx, key, val, c.localExpiration(expiration))
+	c.publish(ctx, key)
+	return nil
+}
+
+// Get read L1 first, then L2
+func (c *multilevelCache) Get(ctx context.Context, key string, val interface{}) error {
+	err := c.local.Get(ctx, key, val) // the requests of L1 are recorded by the memory cache
+	if err == nil || errors.Is(err, ErrPlaceholder) {
+		return err
+	}
+
+	err = c.remote.Get(ctx, key, val)
+	if err != nil {
+		recordRequest(c.opts.name, levelRemote, false) // the placeholder is not a hit
+		return err
+	}
+	recordRequest(c.opts.name, levelRemote, true)
+
+	_ = c.local.Set(ctx, key, val, c.localExpiration(c.remoteTTLs(ctx, key)[0]))
+	return nil
+}
+
+// MultiSet write L2 and L1, and invalidate L1 of the other replicas
+func (c *multilevelCache) MultiSet(ctx context.Context, valMap map[string]interface{}, expiration time.Duration) error {
+	if len(valMap) == 0 {
+		return nil
+	}
+
+	err := c.remote.MultiSet(ctx, valMap, expiration)
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(valMap))
+	for key, val := range valMap {
+		_ = c.local.Set(ctx, key, val, c.localExpiration(expiration))
+		keys = append(keys, key)
+	}
+	c.publish(ctx, keys...)
+	return nil
+}
+
+// MultiGet read L1 first, the missing keys are read from L2
+func (c *multilevelCache) MultiGet(ctx context.Context, keys []string, valueMap interface{}) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	// the key of the map is the cache key, which is consistent with the redis cache
+	mapValue := reflect.ValueOf(valueMap)
+	var missKeys []string
+	for _, key := range keys {
+		cacheKey, err := BuildCacheKey(c.keyPrefix, key)
+		if err != nil {
+			return fmt.Errorf("BuildCacheKey error: %v, key=%s", err, key)
+		}
+		object := reflect.New(mapValue.Type().Elem())
+		err = c.local.Get(ctx, key, object.Interface())
+		if err != nil {
+			missKeys = append(missKeys, key)
+			continue
+		}
+		mapValue.SetMapIndex(reflect.ValueOf(cacheKey), object.Elem())
+	}
+	if len(missKeys) == 0 {
+		return nil
+	}
+
+	remoteMap := reflect.MakeMap(mapValue.Type())
+	err := c.remote.MultiGet(ctx, missKeys, remoteMap.Interface())
+	if err != nil {
+		return err
+	}
+	ttls := c.remoteTTLs(ctx, missKeys...)
+	for i, key := range missKeys {
+		cacheKey, _ := BuildCacheKey(c.keyPrefix, key)
+		val := remoteMap.MapIndex(reflect.ValueOf(cacheKey))
+		if !val.IsValid() {
+			recordRequest(c.opts.name, levelRemote, false)
+			continue
+		}
+		recordRequest(c.opts.name, levelRemote, true)
+		mapValue.SetMapIndex(reflect.ValueOf(cacheKey), val)
+		_ = c.local.Set(ctx, key, val.Interface(), c.localExpiration(ttls[i]))
+	}
+
+	return nil
+}
+
+// Del delete from L2 and L1, and invalidate L1 of the other replicas
+func (c *multilevelCache) Del(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	err := c.remote.Del(ctx, keys...)
+	if err != nil {
+		return err
+	}
+	c.deleteLocal(keys)
+	c.publish(ctx, keys...)
+	return nil
+}
+
+// SetCacheWithNotFound set the placeholder to L2, the placeholder is not cached in L1,
+// so that it is removed in time when the data is created.
+func (c *multilevelCache) SetCacheWithNotFound(ctx context.Context, key string) error {
+	err := c.remote.SetCacheWithNotFound(ctx, key)
+	if err != nil {
+		return err
+	}
+	c.deleteLocal([]string{key})
+	c.publish(ctx, key)
+	return nil
+}
+
+// Close stop receiving the invalidation messages and close the redis pub/sub
+func (c *multilevelCache) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		c.cancel()
+		err = c.pubSub.Close()
+	})
+	return err
+}
+
+func (c *multilevelCache) localExpiration(expiration time.Duration) time.Duration {
+	if expiration <= 0 || expiration > c.opts.localExpiration {
+		return c.opts.localExpiration
+	}
+	return expiration
+}
+
+// remoteTTLs get the remaining expiration time of the keys in L2, so that the values loaded
+// from L2 do not live longer in L1 than in L2, 0 means unknown or no expiration.
+func (c *multilevelCache) remoteTTLs(ctx context.Context, keys ...string) []time.Duration {
+	ttls := make([]time.Duration, len(keys))
+	cmds := make([]*redis.DurationCmd, len(keys))
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cacheKey, err := BuildCacheKey(c.keyPrefix, key)
+			if err != nil {
+				return err
+			}
+			cmds[i] = pipe.PTTL(ctx, cacheKey)
+		}
+		return nil
+	})
+	if err != nil {
+		return ttls
+	}
+	for i, cmd := range cmds {
+		if ttl := cmd.Val(); ttl > 0 {
+			ttls[i] = ttl
+		}
+	}
+	return ttls
+}
+
+func (c *multilevelCache) deleteLocal(keys []string) {
+	for _, key := range keys {
+		_ = c.local.Del(context.Background(), key)
+	}
+}
+
+func (c *multilevelCache) publish(ctx context.Context, keys ...string) {
+	data, err := json.Marshal(&invalidateMessage{
+		InstanceID: c.instanceID,
+		KeyPrefix:  c.keyPrefix,
+		Keys:       keys,
+	})
+	if err != nil {
+		return
+	}
+	err = c.client.Publish(ctx, c.opts.channel, data).Err()
+	if err != nil {
+		c.opts.zapLog.Warn("multilevel cache publish error", zap.Error(err),
+			zap.String("channel", c.opts.channel), zap.Strings("keys", keys))
+	}
+}
+
+// receive the invalidation messages of the other replicas and delete the keys from L1
+func (c *multilevelCache) subscribe() {
+	// the messages published before the subscription is confirmed are not received
+	err := c.pubSub.Subscribe(c.ctx, c.opts.channel)
+	if err == nil {
+		_, err = c.pubSub.Receive(c.ctx)
+	}
+	if err != nil {
+		if c.ctx.Err() != nil {
+			return
+		}
+		c.opts.zapLog.Warn("multilevel cache subscribe error", zap.Error(err), zap.String("channel", c.opts.channel))
+	}
+
+	ch := c.pubSub.Channel()
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			m := &invalidateMessage{}
+			if err := json.Unmarshal([]byte(msg.Payload), m); err != nil {
+				continue
+			}
+			if m.InstanceID == c.instanceID || m.KeyPrefix != c.keyPrefix {
+				continue
+			}
+			c.deleteLocal(m.Keys)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/zhufuyi/sponge/pkg/encoding"
+	"github.com/zhufuyi/sponge/pkg/gotest"
+)
+
+func newMultilevelCache(c *gotest.Cache, name string) Cache {
+	return NewMultilevelCache(c.RedisClient, "", encoding.JSONEncoding{}, func() interface{} {
+		return &redisUser{}
+	}, MultilevelWithName(name), MultilevelWithLocalExpiration(time.Minute), MultilevelWithChannel("cache:test"), MultilevelWithJitter(0.1),
+		MultilevelWithLocalCache(WithMaxEntries(1000), WithEvictionPolicy(EvictionLRU)), MultilevelWithLogger(zap.NewNop()))
+}
+
+func TestMultilevelCache(t *testing.T) {
+	c := newRedisCache()
+	defer c.Close()
+	mc := newMultilevelCache(c, "test")
+	defer mc.(*multilevelCache).Close()
+	ctx := context.Background()
+	counter := func(level string, result string) float64 {
+		return testutil.ToFloat64(requestCount.WithLabelValues("test", level, result))
+	}
+	localHit, localMiss, remoteHit := counter(levelLocal, resultHit), counter(levelLocal, resultMiss), counter(levelRemote, resultHit)
+
+	testData := c.TestDataSlice[0].(*redisUser)
+	err := mc.Set(ctx, "user:1", testData, time.Hour)
+	assert.NoError(t, err)
+	time.Sleep(time.Millisecond * 10) // wait for the local cache to be written
+
+	val := &redisUser{}
+	err = mc.Get(ctx, "user:1", val)
+	assert.NoError(t, err)
+	assert.Equal(t, testData.Name, val.Name)
+	assert.Equal(t, localHit+1, counter(levelLocal, resultHit))
+
+	// miss in L1, hit in L2
+	mc.(*multilevelCache).deleteLocal([]string{"user:1"})
+	err = mc.Get(ctx, "user:1", &redisUser{})
+	assert.NoError(t, err)
+	assert.Equal(t, localMiss+1, counter(levelLocal, resultMiss))
+	assert.Equal(t, remoteHit+1, counter(levelRemote, resultHit))
+
+	// multiple set and get
+	valMap := map[string]interface{}{"user:1": c.TestDataSlice[0], "user:2": c.TestDataSlice[1]}
+	err = mc.MultiSet(ctx, valMap, time.Hour)
+	assert.NoError(t, err)
+	mc.(*multilevelCache).deleteLocal([]string{"user:2"})
+	time.Sleep(time.Millisecond * 10)
+	users := make(map[string]*redisUser)
+	err = mc.MultiGet(ctx, []string{"user:1", "user:2", "user:3"}, users)
+	assert.NoError(t, err)
+	assert.Len(t, users, 2)
+
+	// delete
+	err = mc.Del(ctx, "user:1")
+	assert.NoError(t, err)
+	err = mc.Get(ctx, "user:1", &redisUser{})
+	assert.ErrorIs(t, err, CacheNotFound)
+
+	// placeholder, it is not counted as a hit
+	err = mc.SetCacheWithNotFound(ctx, "user:3")
+	assert.NoError(t, err)
+	remoteHit, remoteMiss := counter(levelRemote, resultHit), counter(levelRemote, resultMiss)
+	err = mc.Get(ctx, "user:3", &redisUser{})
+	assert.ErrorIs(t, err, ErrPlaceholder)
+	assert.Equal(t, remoteHit, counter(levelRemote, resultHit))
+	assert.Equal(t, remoteMiss+1, counter(levelRemote, resultMiss))
+
+	err = mc.MultiSet(ctx, nil, time.Hour)
+	assert.NoError(t, err)
+	err = mc.MultiGet(ctx, nil, users)
+	assert.NoError(t, err)
+	err = mc.Del(ctx)
+	assert.NoError(t, err)
+}
+
+func TestMultilevelCache_Invalidate(t *testing.T) {
+	c := newRedisCache()
+	defer c.Close()
+	ctx := context.Background()
+
+	// two caches simulate two replicas
+	mc1 := newMultilevelCache(c, "replica1")
+	defer mc1.(*multilevelCache).Close()
+	mc2 := newMultilevelCache(c, "replica2")
+	defer mc2.(*multilevelCache).Close()
+
+	err := mc1.Set(ctx, "user:1", &redisUser{ID: 1, Name: "foo"}, time.Hour)
+	assert.NoError(t, err)
+	val := &redisUser{}
+	err = mc2.Get(ctx, "user:1", val) // write back to L1 of replica2
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", val.Name)
+	time.Sleep(time.Millisecond * 10)
+
+	// update on replica1, the L1 of replica2 is invalidated
+	err = mc1.Set(ctx, "user:1", &redisUser{ID: 1, Name: "bar"}, time.Hour)
+	assert.NoError(t, err)
+	time.Sleep(time.Millisecond * 50)
+	val = &redisUser{}
+	err = mc2.Get(ctx, "user:1", val)
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", val.Name)
+	time.Sleep(time.Millisecond * 10)
+
+	// delete on replica1
+	err = mc1.Del(ctx, "user:1")
+	assert.NoError(t, err)
+	time.Sleep(time.Millisecond * 50)
+	err = mc2.Get(ctx, "user:1", &redisUser{})
+	assert.ErrorIs(t, err, CacheNotFound)
+
+	assert.NoError(t, mc1.(*multilevelCache).Close())
+	assert.NoError(t, mc1.(*multilevelCache).Close())
+}
+
+func TestMultilevelCache_remoteTTLs(t *testing.T) {
+	c := newRedisCache()
+	defer c.Close()
+	ctx := context.Background()
+	mc := newMultilevelCache(c, "ttl")
+	defer mc.(*multilevelCache).Close()
+
+	err := mc.Set(ctx, "user:1", &redisUser{ID: 1, Name: "foo"}, time.Second*2)
+	assert.NoError(t, err)
+	ttls := mc.(*multilevelCache).remoteTTLs(ctx, "user:1", "user:2")
+	assert.True(t, ttls[0] > 0 && ttls[0] <= time.Millisecond*2200) // up to 10% jitter
+	assert.Equal(t, time.Duration(0), ttls[1])
+
+	// the value loaded from L2 does not live longer in L1 than in L2
+	assert.Equal(t, ttls[0], mc.(*multilevelCache).localExpiration(ttls[0]))
+	assert.Equal(t, time.Minute, mc.(*multilevelCache).localExpiration(ttls[1]))
+}
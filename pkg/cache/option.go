@@ -0,0 +1,297 @@
+package cache
+
+import (
+	"math/rand"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/zhufuyi/sponge/pkg/encoding"
+)
+
+// Option set the cache options.
+type Option func(*options)
+
+type options struct {
+	jitterRatio float64 // default 0, no jitter
+
+	// the following options are only valid for the memory cache
+	maxEntries     int64          // default 0, no limit
+	maxBytes       int64          // default 1GB
+	evictionPolicy EvictionPolicy // default EvictionTinyLFU
+	name           string         // default "default", label value of the metrics
+}
+
+func (o *options) apply(opts ...Option) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+func defaultOptions() *options {
+	return &options{
+		maxBytes:       1 << 30,
+		evictionPolicy: EvictionTinyLFU,
+		name:           "default",
+	}
+}
+
+// WithJitter set the ratio of random jitter added to the expiration time, e.g. ratio=0.1 and expiration=10m,
+// the actual expiration time is a random value between 10m and 11m, so that the keys written in the same
+// batch (e.g. MultiSet) do not expire at the same time, the ratio range is (0, 1].
+func WithJitter(ratio float64) Option {
+	return func(o *options) {
+		if ratio > 1 {
+			ratio = 1
+		}
+		o.jitterRatio = ratio
+	}
+}
+
+// WithMaxEntries set the maximum number of entries of the memory cache, 0 means no limit.
+func WithMaxEntries(n int64) Option {
+	return func(o *options) {
+		if n >= 0 {
+			o.maxEntries = n
+		}
+	}
+}
+
+// WithMaxBytes set the maximum byte size (key and encoded value) of the memory cache, 0 means no limit.
+func WithMaxBytes(n int64) Option {
+	return func(o *options) {
+		if n >= 0 {
+			o.maxBytes = n
+		}
+	}
+}
+
+// WithEvictionPolicy set the eviction policy of the memory cache when the size limit is reached,
+// supported EvictionLRU, EvictionLFU and EvictionTinyLFU.
+//
+// NOTE: EvictionTinyLFU limits the size by only one dimension, the byte size if WithMaxBytes is set,
+// otherwise the number of entries, and it may reject a new entry whose key is accessed less frequently
+// than the entries to be evicted.
+func WithEvictionPolicy(policy EvictionPolicy) Option {
+	return func(o *options) {
+		switch policy {
+		case EvictionLRU, EvictionLFU, EvictionTinyLFU:
+			o.evictionPolicy = policy
+		}
+	}
+}
+
+// WithName set the name of the memory cache, used as the label value of the metrics.
+func WithName(name string) Option {
+	return func(o *options) {
+		if name != "" {
+			o.name = name
+		}
+	}
+}
+
+// JitterExpiration add random jitter to the expiration time, the jitter is between 0 and expiration*ratio.
+func JitterExpiration(expiration time.Duration, ratio float64) time.Duration {
+	if expiration <= 0 || ratio <= 0 {
+		return expiration
+	}
+	n := int64(float64(expiration) * ratio)
+	if n <= 0 {
+		return expiration
+	}
+	return expiration + time.Duration(rand.Int63n(n+1)) //nolint
+}
+
+// -------------------------------------------------------------------------------------------
+
+// LoaderOption set the loader options.
+type LoaderOption func(*loaderOptions)
+
+type loaderOptions struct {
+	locker         Locker            // default nil, only deduplicate loading within the process
+	lockExpiration time.Duration     // default 10s
+	waitTimeout    time.Duration     // default 1s, time to wait for another caller to rebuild the cache
+	waitInterval   time.Duration     // default 50ms
+	staleTime      time.Duration     // default 0, disable stale-while-revalidate
+	refreshTimeout time.Duration     // default 10s, timeout for refreshing stale value in background
+	jitterRatio    float64           // default 0.1
+	encoding       encoding.Encoding // default JSONEncoding
+	notFoundErrs   []error           // default nil
+}
+
+func (o *loaderOptions) apply(opts ...LoaderOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+func defaultLoaderOptions() *loaderOptions {
+	return &loaderOptions{
+		lockExpiration: time.Second * 10,
+		waitTimeout:    time.Second,
+		waitInterval:   time.Millisecond * 50,
+		refreshTimeout: time.Second * 10,
+		jitterRatio:    0.1,
+		encoding:       encoding.JSONEncoding{},
+	}
+}
+
+// LoaderWithLocker set the distributed lock, only the caller holding the lock rebuilds the cache of a key,
+// the other callers (including other replicas) wait for the rebuilt value, e.g. NewRedisLocker(rdb).
+func LoaderWithLocker(locker Locker) LoaderOption {
+	return func(o *loaderOptions) {
+		o.locker = locker
+	}
+}
+
+// LoaderWithLockExpiration set the expiration time of the lock, it should be longer than the load time.
+func LoaderWithLockExpiration(d time.Duration) LoaderOption {
+	return func(o *loaderOptions) {
+		if d > 0 {
+			o.lockExpiration = d
+		}
+	}
+}
+
+// LoaderWithWait set the time to wait for another caller to rebuild the cache and the interval of checking,
+// if the value is still not in the cache after the timeout, load it from the data source directly.
+func LoaderWithWait(timeout time.Duration, interval time.Duration) LoaderOption {
+	return func(o *loaderOptions) {
+		if timeout >= 0 {
+			o.waitTimeout = timeout
+		}
+		if interval > 0 {
+			o.waitInterval = interval
+		}
+	}
+}
+
+// LoaderWithStaleTime enable stale-while-revalidate, after the value expires, it is still kept in the cache
+// for staleTime, during this time the stale value is returned immediately while one caller refreshes it in background.
+func LoaderWithStaleTime(d time.Duration) LoaderOption {
+	return func(o *loaderOptions) {
+		if d >= 0 {
+			o.staleTime = d
+		}
+	}
+}
+
+// LoaderWithRefreshTimeout set the timeout for refreshing the stale value in background.
+func LoaderWithRefreshTimeout(d time.Duration) LoaderOption {
+	return func(o *loaderOptions) {
+		if d > 0 {
+			o.refreshTimeout = d
+		}
+	}
+}
+
+// LoaderWithJitter set the ratio of random jitter added to the expiration time, 0 means no jitter.
+func LoaderWithJitter(ratio float64) LoaderOption {
+	return func(o *loaderOptions) {
+		if ratio > 1 {
+			ratio = 1
+		}
+		o.jitterRatio = ratio
+	}
+}
+
+// LoaderWithEncoding set the encoding of the value.
+func LoaderWithEncoding(e encoding.Encoding) LoaderOption {
+	return func(o *loaderOptions) {
+		if e != nil {
+			o.encoding = e
+		}
+	}
+}
+
+// LoaderWithNotFoundErrs set the errors that indicate the data does not exist in the data source,
+// e.g. gorm.ErrRecordNotFound, when the load function returns one of them, a placeholder is cached
+// to prevent cache penetration, and GetOrLoad returns ErrPlaceholder until it expires.
+func LoaderWithNotFoundErrs(errs ...error) LoaderOption {
+	return func(o *loaderOptions) {
+		o.notFoundErrs = errs
+	}
+}
+
+// -------------------------------------------------------------------------------------------
+
+// MultilevelOption set the multilevel cache options.
+type MultilevelOption func(*multilevelOptions)
+
+type multilevelOptions struct {
+	localExpiration time.Duration // default 1m, the maximum time a value is kept in the local cache
+	channel         string        // default "cache:invalidate", redis pub/sub channel of invalidation messages
+	name            string        // default "default", label value of the metrics
+	jitterRatio     float64       // default 0
+	localOpts       []Option      // default nil, options of the local memory cache
+	zapLog          *zap.Logger   // default zap.NewProduction()
+}
+
+func (o *multilevelOptions) apply(opts ...MultilevelOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+func defaultMultilevelOptions() *multilevelOptions {
+	return &multilevelOptions{
+		localExpiration: time.Minute,
+		channel:         "cache:invalidate",
+		name:            "default",
+	}
+}
+
+// MultilevelWithLocalExpiration set the maximum time a value is kept in the local cache, it is the upper limit
+// of inconsistency when the invalidation message is lost (e.g. redis reconnecting).
+func MultilevelWithLocalExpiration(d time.Duration) MultilevelOption {
+	return func(o *multilevelOptions) {
+		if d > 0 {
+			o.localExpiration = d
+		}
+	}
+}
+
+// MultilevelWithChannel set the redis pub/sub channel of invalidation messages,
+// the replicas sharing the same redis and the same keys must use the same channel.
+func MultilevelWithChannel(channel string) MultilevelOption {
+	return func(o *multilevelOptions) {
+		if channel != "" {
+			o.channel = channel
+		}
+	}
+}
+
+// MultilevelWithName set the name of the cache, used as the label value of the metrics.
+func MultilevelWithName(name string) MultilevelOption {
+	return func(o *multilevelOptions) {
+		if name != "" {
+			o.name = name
+		}
+	}
+}
+
+// MultilevelWithJitter set the ratio of random jitter added to the expiration time, see WithJitter.
+func MultilevelWithJitter(ratio float64) MultilevelOption {
+	return func(o *multilevelOptions) {
+		if ratio > 1 {
+			ratio = 1
+		}
+		o.jitterRatio = ratio
+	}
+}
+
+// MultilevelWithLocalCache set the options of the local memory cache, e.g. WithMaxEntries, WithEvictionPolicy.
+func MultilevelWithLocalCache(opts ...Option) MultilevelOption {
+	return func(o *multilevelOptions) {
+		o.localOpts = opts
+	}
+}
+
+// MultilevelWithLogger set the logger of the errors of subscribing and publishing invalidation messages.
+func MultilevelWithLogger(l *zap.Logger) MultilevelOption {
+	return func(o *multilevelOptions) {
+		if l != nil {
+			o.zapLog = l
+		}
+	}
+}
@@ -23,15 +23,19 @@ type redisCache struct {
 	encoding          encoding.Encoding
 	DefaultExpireTime time.Duration
 	newObject         func() interface{}
+	jitterRatio       float64
 }
 
 // NewRedisCache new a cache, client parameter can be passed in for unit testing
-func NewRedisCache(client *redis.Client, keyPrefix string, encode encoding.Encoding, newObject func() interface{}) Cache {
+func NewRedisCache(client *redis.Client, keyPrefix string, encode encoding.Encoding, newObject func() interface{}, opts ...Option) Cache {
+	o := defaultOptions()
+	o.apply(opts...)
 	return &redisCache{
-		client:    client,
-		KeyPrefix: keyPrefix,
-		encoding:  encode,
-		newObject: newObject,
+		client:      client,
+		KeyPrefix:   keyPrefix,
+		encoding:    encode,
+		newObject:   newObject,
+		jitterRatio: o.jitterRatio,
 	}
 }
 
@@ -49,7 +53,7 @@ func (c *redisCache) Set(ctx context.Context, key string, val interface{}, expir
 	//if expiration == 0 {
 	//	expiration = DefaultExpireTime
 	//}
-	err = c.client.Set(ctx, cacheKey, buf, expiration).Err()
+	err = c.client.Set(ctx, cacheKey, buf, JitterExpiration(expiration, c.jitterRatio)).Err()
 	if err != nil {
 		return fmt.Errorf("c.client.Set error: %v, cacheKey=%s", err, cacheKey)
 	}
@@ -118,7 +122,7 @@ func (c *redisCache) MultiSet(ctx context.Context, valueMap map[string]interface
 	for i := 0; i < len(paris); i = i + 2 {
 		switch paris[i].(type) {
 		case []byte:
-			pipeline.Expire(ctx, string(paris[i].([]byte)), expiration)
+			pipeline.Expire(ctx, string(paris[i].([]byte)), JitterExpiration(expiration, c.jitterRatio))
 		default:
 			fmt.Printf("redis expire is unsupported key type: %+v\n", reflect.TypeOf(paris[i]))
 		}
@@ -219,15 +223,19 @@ type redisClusterCache struct {
 	encoding          encoding.Encoding
 	DefaultExpireTime time.Duration
 	newObject         func() interface{}
+	jitterRatio       float64
 }
 
 // NewRedisClusterCache new a cache
-func NewRedisClusterCache(client *redis.ClusterClient, keyPrefix string, encode encoding.Encoding, newObject func() interface{}) Cache {
+func NewRedisClusterCache(client *redis.ClusterClient, keyPrefix string, encode encoding.Encoding, newObject func() interface{}, opts ...Option) Cache {
+	o := defaultOptions()
+	o.apply(opts...)
 	return &redisClusterCache{
-		client:    client,
-		KeyPrefix: keyPrefix,
-		encoding:  encode,
-		newObject: newObject,
+		client:      client,
+		KeyPrefix:   keyPrefix,
+		encoding:    encode,
+		newObject:   newObject,
+		jitterRatio: o.jitterRatio,
 	}
 }
 
@@ -245,7 +253,7 @@ func (c *redisClusterCache) Set(ctx context.Context, key string, val interface{}
 	//if expiration == 0 {
 	//	expiration = DefaultExpireTime
 	//}
-	err = c.client.Set(ctx, cacheKey, buf, expiration).Err()
+	err = c.client.Set(ctx, cacheKey, buf, JitterExpiration(expiration, c.jitterRatio)).Err()
 	if err != nil {
 		return fmt.Errorf("c.client.Set error: %v, cacheKey=%s", err, cacheKey)
 	}
@@ -311,7 +319,7 @@ func (c *redisClusterCache) MultiSet(ctx context.Context, valueMap map[string]in
 	for i := 0; i < len(paris); i = i + 2 {
 		switch paris[i].(type) {
 		case []byte:
-			pipeline.Expire(ctx, string(paris[i].([]byte)), expiration)
+			pipeline.Expire(ctx, string(paris[i].([]byte)), JitterExpiration(expiration, c.jitterRatio))
 		default:
 			fmt.Printf("redis expire is unsupported key type: %+v\n", reflect.TypeOf(paris[i]))
 		}
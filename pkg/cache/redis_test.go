@@ -49,7 +49,7 @@ func newRedisClusterCache() *gotest.RCCache {
 	cachePrefix := ""
 	c.ICache = NewRedisClusterCache(c.RedisClient, cachePrefix, encoding.JSONEncoding{}, func() interface{} {
 		return &redisUser{}
-	})
+	}, WithJitter(0.1))
 
 	return c
 }
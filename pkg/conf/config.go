@@ -0,0 +1,377 @@
+package conf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-playground/validator/v10"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+var defaultLogger, _ = zap.NewProduction()
+
+// Option set the config options.
+type Option func(*options)
+
+type options struct {
+	file      string
+	sources   []Source
+	isEnv     bool
+	envPrefix string
+	flags     *pflag.FlagSet
+	validate  func(obj interface{}) error
+	onChange  func(newObj interface{})
+	zapLog    *zap.Logger
+}
+
+func defaultOptions() *options {
+	return &options{
+		validate: validateStruct,
+		zapLog:   defaultLogger,
+	}
+}
+
+func (o *options) apply(opts ...Option) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithFile set the local configuration file, yaml, json, toml, etc. are supported
+func WithFile(file string) Option {
+	return func(o *options) {
+		o.file = file
+	}
+}
+
+// WithSources set the sources, e.g. remote configuration center, they are merged in order after the file,
+// the later source overrides the former.
+func WithSources(sources ...Source) Option {
+	return func(o *options) {
+		o.sources = append(o.sources, sources...)
+	}
+}
+
+// WithEnv override the configuration by environment variables with the prefix, the variable name is
+// the upper case key with "." replaced by "_", e.g. the key "app.name" is overridden by APP_APP_NAME if prefix is "APP".
+func WithEnv(prefix string) Option {
+	return func(o *options) {
+		o.isEnv = true
+		o.envPrefix = prefix
+	}
+}
+
+// WithFlags override the configuration by the command line flags that are set, the flag name is the key,
+// e.g. --app.name=foo
+func WithFlags(flags *pflag.FlagSet) Option {
+	return func(o *options) {
+		o.flags = flags
+	}
+}
+
+// WithValidate set the function to validate the configuration, by default the struct is validated by
+// the "validate" tags of github.com/go-playground/validator.
+func WithValidate(fn func(obj interface{}) error) Option {
+	return func(o *options) {
+		if fn != nil {
+			o.validate = fn
+		}
+	}
+}
+
+// WithWatch watch the file and sources, onChange is called with the new configuration after it changes,
+// the invalid configuration is rejected and the old one is kept.
+func WithWatch(onChange func(newObj interface{})) Option {
+	return func(o *options) {
+		o.onChange = onChange
+	}
+}
+
+// WithLogger set logger
+func WithLogger(log *zap.Logger) Option {
+	return func(o *options) {
+		if log != nil {
+			o.zapLog = log
+		}
+	}
+}
+
+var structValidator = validator.New()
+
+func validateStruct(obj interface{}) error {
+	if reflect.Indirect(reflect.ValueOf(obj)).Kind() != reflect.Struct {
+		return nil
+	}
+	return structValidator.Struct(obj)
+}
+
+// Config the configuration loaded from multiple layers, the priority from low to high is:
+// file < sources < environment variables < command line flags.
+// each Config has an independent viper instance, the global viper is not used.
+type Config struct {
+	opts    *options
+	objType reflect.Type
+
+	mu      sync.RWMutex
+	current interface{}
+
+	reloadMu sync.Mutex
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// New load the configuration to obj, obj must be a pointer to struct or map. the new configuration is
+// parsed to a new object of the same type after it changes, get the latest one by Get.
+func New(obj interface{}, opts ...Option) (*Config, error) {
+	rv := reflect.ValueOf(obj)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return nil, errors.New("obj must be a non-nil pointer")
+	}
+
+	o := defaultOptions()
+	o.apply(opts...)
+	if o.file == "" && len(o.sources) == 0 {
+		return nil, errors.New("no configuration file or source, usage 'conf.New(obj, conf.WithFile(file))'")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Config{
+		opts:    o,
+		objType: rv.Elem().Type(),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+
+	newObj, err := c.load()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	rv.Elem().Set(reflect.ValueOf(newObj).Elem())
+	c.current = obj
+
+	if o.onChange != nil {
+		if err = c.watch(); err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// Get the latest configuration, it is a pointer of the same type as obj passed to New
+func (c *Config) Get() interface{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.current
+}
+
+// Reload load the configuration from all layers, if it is invalid, the old one is kept and return error.
+// onChange is called if the configuration changes.
+func (c *Config) Reload() error {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+
+	newObj, err := c.load()
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	isChanged := !reflect.DeepEqual(c.current, newObj)
+	if isChanged {
+		c.current = newObj
+	}
+	c.mu.Unlock()
+
+	if isChanged && c.opts.onChange != nil {
+		c.opts.onChange(newObj)
+	}
+	return nil
+}
+
+// Close stop watching
+func (c *Config) Close() error {
+	c.cancel()
+	c.wg.Wait()
+	return nil
+}
+
+func (c *Config) load() (interface{}, error) {
+	v := viper.New()
+
+	if c.opts.file != "" {
+		v.SetConfigFile(c.opts.file)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, source := range c.opts.sources {
+		ctx, cancel := context.WithTimeout(c.ctx, time.Second*10)
+		data, format, err := source.Load(ctx)
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("load source %s error: %v", source, err)
+		}
+		v.SetConfigType(format)
+		if err = v.MergeConfig(bytes.NewReader(data)); err != nil {
+			return nil, fmt.Errorf("merge source %s error: %v", source, err)
+		}
+	}
+
+	if c.opts.isEnv {
+		v.SetEnvPrefix(c.opts.envPrefix)
+		v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+		v.AutomaticEnv()
+		// the keys not in the file are also overridden
+		for _, key := range structKeys(c.objType, "") {
+			_ = v.BindEnv(key)
+		}
+	}
+
+	if c.opts.flags != nil {
+		if err := v.BindPFlags(c.opts.flags); err != nil {
+			return nil, err
+		}
+	}
+
+	newObj := reflect.New(c.objType).Interface()
+	if err := v.Unmarshal(newObj); err != nil {
+		return nil, err
+	}
+	if err := c.opts.validate(newObj); err != nil {
+		return nil, fmt.Errorf("invalid configuration, %v", err)
+	}
+	return newObj, nil
+}
+
+// get all the keys of the struct fields, the key is the mapstructure tag or the lower case field name
+func structKeys(t reflect.Type, prefix string) []string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, opt, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+		if name == "-" {
+			continue
+		}
+		if opt == "squash" {
+			keys = append(keys, structKeys(field.Type, prefix)...)
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		key := prefix + name
+
+		ft := field.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct && ft != reflect.TypeOf(time.Time{}) {
+			keys = append(keys, structKeys(ft, key+".")...)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (c *Config) reload(from string) {
+	if err := c.Reload(); err != nil {
+		c.opts.zapLog.Error("[conf] reload configuration error, keep the old configuration",
+			zap.String("from", from), zap.Error(err))
+		return
+	}
+	c.opts.zapLog.Info("[conf] reload configuration", zap.String("from", from))
+}
+
+func (c *Config) watch() error {
+	if c.opts.file != "" {
+		if err := c.watchFile(); err != nil {
+			return err
+		}
+	}
+
+	for _, source := range c.opts.sources {
+		source := source
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			err := source.Watch(c.ctx, func() { c.reload(source.String()) })
+			if err != nil && c.ctx.Err() == nil {
+				c.opts.zapLog.Error("[conf] watch source error", zap.String("source", source.String()), zap.Error(err))
+			}
+		}()
+	}
+	return nil
+}
+
+// watch the directory of the file, so that the file replaced by editors or kubernetes configmap is also watched
+func (c *Config) watchFile() error {
+	file, err := filepath.Abs(c.opts.file)
+	if err != nil {
+		return err
+	}
+	realFile, _ := filepath.EvalSymlinks(file)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err = watcher.Add(filepath.Dir(file)); err != nil {
+		_ = watcher.Close()
+		return err
+	}
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer watcher.Close() //nolint
+		for {
+			select {
+			case <-c.ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				currentFile, _ := filepath.EvalSymlinks(file)
+				isFileChanged := filepath.Clean(event.Name) == file && event.Op&(fsnotify.Write|fsnotify.Create) != 0
+				isLinkChanged := currentFile != "" && currentFile != realFile
+				if isFileChanged || isLinkChanged {
+					realFile = currentFile
+					c.reload(file)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				c.opts.zapLog.Warn("[conf] watch file error", zap.String("file", file), zap.Error(err))
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,298 @@
+package conf
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+
+	"github.com/zhufuyi/sponge/pkg/nacoscli"
+)
+
+type testConfig struct {
+	App struct {
+		Name string `validate:"required"`
+		Port int    `validate:"min=1,max=65535"`
+	}
+	Redis struct {
+		DSN         string `mapstructure:"dsn"`
+		DialTimeout int    `mapstructure:"dialTimeout"`
+	}
+	Roles []string
+}
+
+// memorySource the source in memory, used for testing
+type memorySource struct {
+	mu      sync.Mutex
+	data    string
+	changed chan struct{}
+}
+
+func newMemorySource(data string) *memorySource {
+	return &memorySource{data: data, changed: make(chan struct{}, 1)}
+}
+
+func (s *memorySource) set(data string) {
+	s.mu.Lock()
+	s.data = data
+	s.mu.Unlock()
+	s.changed <- struct{}{}
+}
+
+func (s *memorySource) Load(_ context.Context) ([]byte, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return []byte(s.data), "yaml", nil
+}
+
+func (s *memorySource) Watch(ctx context.Context, onChange func()) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.changed:
+			onChange()
+		}
+	}
+}
+
+func (s *memorySource) String() string {
+	return "memory"
+}
+
+func writeFile(t *testing.T, file string, content string) {
+	if err := os.WriteFile(file, []byte(content), 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.yml")
+	writeFile(t, file, "app:\n  name: foo\n  port: 8080\nredis:\n  dsn: localhost:6379\n")
+
+	// file < source < env < flags
+	source := newMemorySource("app:\n  port: 8081\nroles: [a, b]\n")
+	t.Setenv("TEST_APP_NAME", "bar")
+	t.Setenv("TEST_REDIS_DIALTIMEOUT", "10") // not in the file
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.Int("app.port", 0, "")
+	flags.String("redis.dsn", "", "")
+	assert.NoError(t, flags.Parse([]string{"--app.port=8082"}))
+
+	cfg := &testConfig{}
+	c, err := New(cfg, WithFile(file), WithSources(source), WithEnv("TEST"), WithFlags(flags), WithLogger(zap.NewNop()))
+	assert.NoError(t, err)
+	defer c.Close() //nolint
+	assert.Equal(t, cfg, c.Get())
+	assert.Equal(t, "bar", cfg.App.Name)
+	assert.Equal(t, 8082, cfg.App.Port)
+	assert.Equal(t, "localhost:6379", cfg.Redis.DSN) // the flag is not set
+	assert.Equal(t, 10, cfg.Redis.DialTimeout)
+	assert.Equal(t, []string{"a", "b"}, cfg.Roles)
+
+	// without env and flags
+	cfg2 := &testConfig{}
+	_, err = New(cfg2, WithFile(file), WithSources(source))
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", cfg2.App.Name)
+	assert.Equal(t, 8081, cfg2.App.Port)
+}
+
+func TestNew_Error(t *testing.T) {
+	_, err := New(nil, WithFile("test.yml"))
+	assert.Error(t, err)
+	_, err = New(testConfig{}, WithFile("test.yml"))
+	assert.Error(t, err)
+	_, err = New(&testConfig{})
+	assert.Error(t, err)
+	_, err = New(&testConfig{}, WithFile("not-found.yml"))
+	assert.Error(t, err)
+
+	// validate error
+	source := newMemorySource("app:\n  port: 8080\n")
+	_, err = New(&testConfig{}, WithSources(source))
+	assert.Error(t, err)
+	_, err = New(&testConfig{}, WithSources(source), WithValidate(func(obj interface{}) error { return nil }))
+	assert.NoError(t, err)
+	_, err = New(&testConfig{}, WithSources(newMemorySource("app:\n  name: foo\n  port: 8080\n")),
+		WithValidate(func(obj interface{}) error { return errors.New("invalid") }))
+	assert.Error(t, err)
+
+	// map is not validated
+	m := map[string]interface{}{}
+	_, err = New(&m, WithSources(source))
+	assert.NoError(t, err)
+}
+
+func TestConfig_WatchFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.yml")
+	writeFile(t, file, "app:\n  name: foo\n  port: 8080\n")
+
+	changed := make(chan *testConfig, 10)
+	cfg := &testConfig{}
+	c, err := New(cfg, WithFile(file), WithLogger(zap.NewNop()), WithWatch(func(newObj interface{}) {
+		changed <- newObj.(*testConfig)
+	}))
+	assert.NoError(t, err)
+	defer c.Close() //nolint
+
+	time.Sleep(time.Millisecond * 100)
+	writeFile(t, file, "app:\n  name: bar\n  port: 8080\n")
+	select {
+	case newCfg := <-changed:
+		assert.Equal(t, "bar", newCfg.App.Name)
+		assert.Equal(t, newCfg, c.Get())
+		assert.Equal(t, "foo", cfg.App.Name) // the old object is not changed
+	case <-time.After(time.Second * 3):
+		t.Fatal("not changed")
+	}
+
+	// invalid configuration is rejected, the old one is kept
+	writeFile(t, file, "app:\n  name: bar\n  port: 70000\n")
+	time.Sleep(time.Millisecond * 300)
+	assert.Equal(t, 8080, c.Get().(*testConfig).App.Port)
+	writeFile(t, file, "app: [")
+	time.Sleep(time.Millisecond * 300)
+	assert.Equal(t, "bar", c.Get().(*testConfig).App.Name)
+	assert.Len(t, changed, 0)
+}
+
+func TestConfig_WatchSource(t *testing.T) {
+	source := newMemorySource("app:\n  name: foo\n  port: 8080\n")
+	changed := make(chan *testConfig, 10)
+	c, err := New(&testConfig{}, WithSources(source), WithLogger(zap.NewNop()), WithWatch(func(newObj interface{}) {
+		changed <- newObj.(*testConfig)
+	}))
+	assert.NoError(t, err)
+
+	source.set("app:\n  name: bar\n  port: 8080\n")
+	select {
+	case newCfg := <-changed:
+		assert.Equal(t, "bar", newCfg.App.Name)
+	case <-time.After(time.Second * 3):
+		t.Fatal("not changed")
+	}
+
+	// invalid
+	source.set("app:\n  port: 8080\n")
+	time.Sleep(time.Millisecond * 100)
+	assert.Equal(t, "bar", c.Get().(*testConfig).App.Name)
+
+	// not changed
+	source.set("app:\n  name: bar\n  port: 8080\n")
+	time.Sleep(time.Millisecond * 100)
+	assert.NoError(t, c.Reload())
+	assert.Len(t, changed, 0)
+
+	assert.NoError(t, c.Close())
+}
+
+func Test_structKeys(t *testing.T) {
+	type Base struct {
+		ID int
+	}
+	type config struct {
+		Base   `mapstructure:",squash"`
+		Name   string `mapstructure:"appName"`
+		Ignore string `mapstructure:"-"`
+		DB     *struct {
+			DSN string
+		}
+		CreatedAt time.Time
+		private   string //nolint
+	}
+	keys := structKeys(reflect.TypeOf(&config{}), "")
+	assert.Equal(t, []string{"id", "appName", "db.dsn", "createdat"}, keys)
+}
+
+func TestSources(t *testing.T) {
+	assert.Equal(t, "yml", formatOf("/config/app.yml", ""))
+	assert.Equal(t, "json", formatOf("/config/app", "JSON"))
+	assert.Equal(t, "yaml", formatOf("app", ""))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*200)
+	defer cancel()
+
+	etcdCli, err := clientv3.New(clientv3.Config{Endpoints: []string{"127.0.0.1:1"}, DialTimeout: time.Millisecond * 100, Logger: zap.NewNop()})
+	if err == nil {
+		s := NewEtcdSource(etcdCli, "/config/app.yml", "")
+		assert.Equal(t, "etcd:/config/app.yml", s.String())
+		_, _, err = s.Load(ctx)
+		assert.Error(t, err)
+		_ = etcdCli.Close()
+	}
+
+	consulCli, err := api.NewClient(&api.Config{Address: "127.0.0.1:1"})
+	assert.NoError(t, err)
+	s := NewConsulSource(consulCli, "config/app.yml", "")
+	assert.Equal(t, "consul:config/app.yml", s.String())
+	_, _, err = s.Load(ctx)
+	assert.Error(t, err)
+	err = s.Watch(ctx, func() {})
+	assert.Error(t, err)
+
+	_, err = NewNacosSource(&nacoscli.Params{})
+	assert.Error(t, err)
+}
+
+// each watch sends the responses and then the channel is closed, the last watch blocks until ctx is done
+type fakeEtcdWatcher struct {
+	mu        sync.Mutex
+	responses [][]clientv3.WatchResponse
+	watches   int
+}
+
+func (w *fakeEtcdWatcher) Watch(ctx context.Context, _ string, _ ...clientv3.OpOption) clientv3.WatchChan {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	ch := make(chan clientv3.WatchResponse, 10)
+	if w.watches < len(w.responses) {
+		for _, resp := range w.responses[w.watches] {
+			ch <- resp
+		}
+		close(ch)
+	} else {
+		go func() {
+			<-ctx.Done()
+			close(ch)
+		}()
+	}
+	w.watches++
+	return ch
+}
+
+func (w *fakeEtcdWatcher) RequestProgress(_ context.Context) error { return nil }
+
+func (w *fakeEtcdWatcher) Close() error { return nil }
+
+func TestEtcdSource_Watch(t *testing.T) {
+	w := &fakeEtcdWatcher{
+		responses: [][]clientv3.WatchResponse{
+			{{Events: []*clientv3.Event{{}}}, {Canceled: true}}, // changed, then the watch fails
+			{{CompactRevision: 10}},                             // the revision has been compacted
+			{{Events: []*clientv3.Event{{}}}},                   // changed, then the channel is closed
+		},
+	}
+	s := &etcdSource{key: "/config/app.yml", watcher: w, retryInterval: time.Millisecond * 10}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*300)
+	defer cancel()
+	var changes int
+	err := s.Watch(ctx, func() { changes++ })
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 3, changes)
+	w.mu.Lock()
+	assert.Equal(t, 4, w.watches) // the watch is re-established
+	w.mu.Unlock()
+}
@@ -1,4 +1,5 @@
-// Package conf is parsing yaml, json, toml configuration files to go struct.
+// Package conf is parsing yaml, json, toml configuration files to go struct, and loading layered configuration
+// from file, remote sources, environment variables and command line flags.
 package conf
 
 import (
@@ -8,13 +9,16 @@ import (
 	"fmt"
 	"path"
 	"path/filepath"
+	"reflect"
 	"strings"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/viper"
+	"go.uber.org/zap"
 )
 
-// Parse configuration files to struct, including yaml, toml, json, etc., and turn on listening for configuration file changes if fs is not empty
+// Parse configuration files to struct, including yaml, toml, json, etc., and turn on listening for configuration file changes if fs is not empty,
+// when the file changes, obj is updated only if the new configuration passes the validate tags.
 func Parse(configFile string, obj interface{}, reloads ...func()) error {
 	confFileAbs, err := filepath.Abs(configFile)
 	if err != nil {
@@ -71,17 +75,30 @@ func watchConfig(obj interface{}, reloads ...func()) {
 
 	// Note: OnConfigChange is called twice on Windows
 	viper.OnConfigChange(func(e fsnotify.Event) {
-		err := viper.Unmarshal(obj)
-		if err != nil {
-			fmt.Println("viper.Unmarshal error: ", err)
-		} else {
-			for _, reload := range reloads {
-				reload()
-			}
+		if err := reloadConfig(viper.GetViper(), obj, reloads...); err != nil {
+			defaultLogger.Warn("reload config error, keep the old configuration", zap.String("file", e.Name), zap.Error(err))
 		}
 	})
 }
 
+// parse and validate a new object first, obj is overwritten only if the new configuration is valid
+func reloadConfig(v *viper.Viper, obj interface{}, reloads ...func()) error {
+	newObj := reflect.New(reflect.TypeOf(obj).Elem()).Interface()
+	if err := v.Unmarshal(newObj); err != nil {
+		return err
+	}
+	if err := validateStruct(newObj); err != nil {
+		return err
+	}
+	if err := v.Unmarshal(obj); err != nil {
+		return err
+	}
+	for _, reload := range reloads {
+		reload()
+	}
+	return nil
+}
+
 // Show print configuration information (hide sensitive fields)
 func Show(obj interface{}, fields ...string) string {
 	var out string
@@ -1,10 +1,14 @@
 package conf
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"testing"
 	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
 )
 
 var c = make(map[string]interface{})
@@ -96,3 +100,24 @@ func TestParseConfigData(t *testing.T) {
 	_ = os.WriteFile("test.yml", content, 0666) // recovery documents
 	time.Sleep(time.Millisecond * 100)
 }
+
+func Test_reloadConfig(t *testing.T) {
+	type config struct {
+		Name string `mapstructure:"name" validate:"required"`
+	}
+	cfg := &config{Name: "foo"}
+	reloaded := 0
+	v := viper.New()
+	v.SetConfigType("yaml")
+
+	// invalid configuration, keep the old one
+	assert.NoError(t, v.ReadConfig(bytes.NewBufferString("name: \"\"\n")))
+	assert.Error(t, reloadConfig(v, cfg, func() { reloaded++ }))
+	assert.Equal(t, "foo", cfg.Name)
+	assert.Equal(t, 0, reloaded)
+
+	assert.NoError(t, v.ReadConfig(bytes.NewBufferString("name: bar\n")))
+	assert.NoError(t, reloadConfig(v, cfg, func() { reloaded++ }))
+	assert.Equal(t, "bar", cfg.Name)
+	assert.Equal(t, 1, reloaded)
+}
@@ -0,0 +1,225 @@
+package conf
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/nacos-group/nacos-sdk-go/v2/clients/config_client"
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/zhufuyi/sponge/pkg/nacoscli"
+)
+
+// Source the source of configuration, e.g. remote configuration center
+type Source interface {
+	// Load get the configuration data and format, the format is yaml, json, toml, etc.
+	Load(ctx context.Context) (data []byte, format string, err error)
+	// Watch call onChange after the configuration changes, until ctx is done
+	Watch(ctx context.Context, onChange func()) error
+	// String the name of the source
+	String() string
+}
+
+func formatOf(key string, format string) string {
+	if format != "" {
+		return strings.ToLower(format)
+	}
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		return key[i+1:]
+	}
+	return "yaml"
+}
+
+// ---------------------------------------- etcd ----------------------------------------
+
+type etcdSource struct {
+	cli    *clientv3.Client
+	key    string
+	format string
+
+	watcher       clientv3.Watcher
+	retryInterval time.Duration // interval of re-watching after the watch fails
+}
+
+// NewEtcdSource create a source from the key of etcd, the client can be created by pkg/etcdcli,
+// if format is empty, it is the suffix of the key, e.g. "/config/user.yml".
+func NewEtcdSource(cli *clientv3.Client, key string, format string) Source {
+	return &etcdSource{
+		cli:           cli,
+		key:           key,
+		format:        formatOf(key, format),
+		watcher:       cli,
+		retryInterval: time.Second * 3,
+	}
+}
+
+func (s *etcdSource) Load(ctx context.Context) ([]byte, string, error) {
+	resp, err := s.cli.Get(ctx, s.key)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, "", fmt.Errorf("key %s not found", s.key)
+	}
+	return resp.Kvs[0].Value, s.format, nil
+}
+
+// Watch the key, it is watched again after the watch fails or the watch channel is closed, until ctx is done.
+// watching is resumed from the next revision, if the revision has been compacted, watching starts from the
+// latest revision and onChange is called, because the changes in between may be missed.
+func (s *etcdSource) Watch(ctx context.Context, onChange func()) error {
+	var (
+		nextRev     int64
+		isCompacted bool
+	)
+	for {
+		nextRev, isCompacted = s.watch(ctx, nextRev, onChange)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if isCompacted {
+			onChange()
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(s.retryInterval):
+		}
+	}
+}
+
+// watch the key from the revision until the watch fails or the channel is closed, 0 means the latest revision,
+// return the revision to resume watching from, and whether the revision has been compacted.
+func (s *etcdSource) watch(ctx context.Context, rev int64, onChange func()) (int64, bool) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var opts []clientv3.OpOption
+	if rev > 0 {
+		opts = append(opts, clientv3.WithRev(rev))
+	}
+	for resp := range s.watcher.Watch(ctx, s.key, opts...) {
+		if resp.CompactRevision != 0 {
+			return 0, true
+		}
+		if resp.Err() != nil {
+			return rev, false
+		}
+		if resp.Header.Revision > 0 {
+			rev = resp.Header.Revision + 1
+		}
+		if len(resp.Events) > 0 {
+			onChange()
+		}
+	}
+	return rev, false
+}
+
+func (s *etcdSource) String() string {
+	return "etcd:" + s.key
+}
+
+// ---------------------------------------- consul ----------------------------------------
+
+type consulSource struct {
+	cli    *api.Client
+	key    string
+	format string
+}
+
+// NewConsulSource create a source from the key of consul kv, the client can be created by pkg/consulcli,
+// if format is empty, it is the suffix of the key, e.g. "config/user.yml".
+func NewConsulSource(cli *api.Client, key string, format string) Source {
+	return &consulSource{cli: cli, key: key, format: formatOf(key, format)}
+}
+
+func (s *consulSource) Load(ctx context.Context) ([]byte, string, error) {
+	pair, _, err := s.cli.KV().Get(s.key, (&api.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return nil, "", err
+	}
+	if pair == nil {
+		return nil, "", fmt.Errorf("key %s not found", s.key)
+	}
+	return pair.Value, s.format, nil
+}
+
+// Watch by blocking query, it returns when the index of the key changes or the wait time expires
+func (s *consulSource) Watch(ctx context.Context, onChange func()) error {
+	var lastIndex uint64
+	for {
+		_, meta, err := s.cli.KV().Get(s.key, (&api.QueryOptions{WaitIndex: lastIndex}).WithContext(ctx))
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Second * 3):
+			}
+			continue
+		}
+
+		if lastIndex != 0 && meta.LastIndex != lastIndex {
+			onChange()
+		}
+		lastIndex = meta.LastIndex
+	}
+}
+
+func (s *consulSource) String() string {
+	return "consul:" + s.key
+}
+
+// ---------------------------------------- nacos ----------------------------------------
+
+type nacosSource struct {
+	cli    config_client.IConfigClient
+	params *nacoscli.Params
+}
+
+// NewNacosSource create a source from the configuration of nacos, the parameters are the same as nacoscli.GetConfig
+func NewNacosSource(params *nacoscli.Params, opts ...nacoscli.Option) (Source, error) {
+	cli, err := nacoscli.NewConfigClient(params, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &nacosSource{cli: cli, params: params}, nil
+}
+
+func (s *nacosSource) Load(_ context.Context) ([]byte, string, error) {
+	data, err := s.cli.GetConfig(vo.ConfigParam{
+		DataId: s.params.DataID,
+		Group:  s.params.Group,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return []byte(data), s.params.Format, nil
+}
+
+func (s *nacosSource) Watch(ctx context.Context, onChange func()) error {
+	param := vo.ConfigParam{
+		DataId: s.params.DataID,
+		Group:  s.params.Group,
+		OnChange: func(namespace, group, dataID, data string) {
+			onChange()
+		},
+	}
+	if err := s.cli.ListenConfig(param); err != nil {
+		return err
+	}
+	<-ctx.Done()
+	_ = s.cli.CancelListenConfig(param)
+	return ctx.Err()
+}
+
+func (s *nacosSource) String() string {
+	return "nacos:" + s.params.Group + "/" + s.params.DataID
+}
@@ -131,7 +131,7 @@ func (e *Error) ToHTTPCode() int {
 		return http.StatusOK
 	case InternalServerError.Code():
 		return http.StatusInternalServerError
-	case InvalidParams.Code():
+	case InvalidParams.Code(), InvalidQueryColumn.Code():
 		return http.StatusBadRequest
 	}
 
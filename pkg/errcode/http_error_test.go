@@ -36,6 +36,7 @@ var errorsCodes = []*Error{
 	OutOfRange,
 	Unimplemented,
 	StatusBadGateway,
+	InvalidQueryColumn,
 }
 
 func TestNewError(t *testing.T) {
@@ -26,7 +26,8 @@ var (
 	Unimplemented      = NewError(10021, "Unimplemented")
 	DataLoss           = NewError(10022, "Data Loss")
 
-	StatusBadGateway = NewError(10023, "Bad Gateway")
+	StatusBadGateway   = NewError(10023, "Bad Gateway")
+	InvalidQueryColumn = NewError(10024, "Invalid Query Column")
 
 	// Deprecated: use Conflict instead
 	AlreadyExists = NewError(10005, "Already Exists")
@@ -90,7 +90,7 @@ func (s *RPCStatus) ErrToHTTP(desc ...string) error {
 // if there is a parameter 'desc', it will replace the original message.
 func (s *RPCStatus) ToRPCErr(desc ...string) error {
 	switch s.status.Code() {
-	case StatusInvalidParams.status.Code():
+	case StatusInvalidParams.status.Code(), StatusInvalidQueryColumn.status.Code():
 		return toRPCErr(codes.InvalidArgument, desc...)
 	case StatusInternalServerError.status.Code():
 		return toRPCErr(codes.Internal, desc...)
@@ -149,7 +149,7 @@ func toRPCErr(code codes.Code, descs ...string) error {
 // ToRPCCode converted to standard RPC error code
 func (s *RPCStatus) ToRPCCode() codes.Code {
 	switch s.status.Code() {
-	case StatusInvalidParams.status.Code():
+	case StatusInvalidParams.status.Code(), StatusInvalidQueryColumn.status.Code():
 		return codes.InvalidArgument
 	case StatusInternalServerError.status.Code():
 		return codes.Internal
@@ -200,7 +200,7 @@ func convertToHTTPCode(code codes.Code) int {
 	switch code {
 	case StatusSuccess.status.Code():
 		return http.StatusOK
-	case codes.InvalidArgument, StatusInvalidParams.status.Code():
+	case codes.InvalidArgument, StatusInvalidParams.status.Code(), StatusInvalidQueryColumn.status.Code():
 		return http.StatusBadRequest
 	case codes.Internal, StatusInternalServerError.status.Code():
 		return http.StatusInternalServerError
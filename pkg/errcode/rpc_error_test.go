@@ -38,6 +38,7 @@ var rpcStatus = []*RPCStatus{
 	StatusMethodNotAllowed,
 	StatusAccessDenied,
 	StatusConflict,
+	StatusInvalidQueryColumn,
 }
 
 func TestRPCStatus(t *testing.T) {
@@ -28,4 +28,6 @@ var (
 	StatusMethodNotAllowed = NewRPCStatus(30021, "Method Not Allowed")
 	StatusAccessDenied     = NewRPCStatus(30022, "Access Denied")
 	StatusConflict         = NewRPCStatus(30023, "Conflict")
+
+	StatusInvalidQueryColumn = NewRPCStatus(30024, "Invalid Query Column")
 )
@@ -0,0 +1,96 @@
+package query
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type userExample struct {
+	ID        uint64    `gorm:"column:id;primary_key"`
+	Name      string    `gorm:"column:name;type:varchar(40)"`
+	Age       int       `gorm:"column:age"`
+	LoginAt   int64     `gorm:"column:login_at"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+	Ignore    string    `gorm:"-"`
+}
+
+func TestGetColumnNames(t *testing.T) {
+	names, err := GetColumnNames(&userExample{})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"id", "name", "age", "login_at", "created_at"}, names)
+
+	_, err = GetColumnNames(nil)
+	assert.Error(t, err)
+}
+
+func TestCheckSort(t *testing.T) {
+	opt := WithModelColumns(&userExample{})
+
+	assert.NoError(t, CheckSort("", opt))
+	assert.NoError(t, CheckSort("ignore count", opt))
+	assert.NoError(t, CheckSort("-id, name", opt))
+	assert.NoError(t, CheckSort("user.name"))
+
+	err := CheckSort("gender", opt)
+	assert.True(t, errors.Is(err, ErrInvalidColumn))
+	err = CheckSort("id;drop table user", opt)
+	assert.True(t, errors.Is(err, ErrInvalidColumn))
+	err = CheckSort("(select 1)")
+	assert.True(t, errors.Is(err, ErrInvalidColumn))
+
+	// model parse error
+	err = CheckSort("id", WithModelColumns(nil))
+	assert.Error(t, err)
+}
+
+func TestParams_CheckValid(t *testing.T) {
+	opt := WithModelColumns(&userExample{})
+	params := &Params{
+		Page:  0,
+		Limit: 10,
+		Sort:  "-age",
+		Columns: []Column{
+			{Name: "name", Value: "ZhangSan"},
+		},
+		Group: &Group{
+			Logic:   OR,
+			Columns: []Column{{Name: "age", Exp: Gt, Value: 20}, {Name: "login_at", Exp: IsNull}},
+		},
+	}
+	assert.NoError(t, params.CheckValid(opt))
+
+	// sql injection in column name
+	params.Columns[0].Name = "1=1 OR name"
+	err := params.CheckValid()
+	assert.True(t, errors.Is(err, ErrInvalidColumn))
+	_, _, err = params.ConvertToGormConditions()
+	assert.True(t, errors.Is(err, ErrInvalidColumn))
+
+	// column not in model
+	params.Columns[0].Name = "password"
+	err = params.CheckValid(opt)
+	assert.True(t, errors.Is(err, ErrInvalidColumn))
+
+	// sort not in model
+	params.Columns[0].Name = "name"
+	params.Sort = "password"
+	err = params.CheckValid(opt)
+	assert.True(t, errors.Is(err, ErrInvalidColumn))
+
+	// invalid sort fields are ignored when converting to page
+	params.Sort = "age;delete from user"
+	order, _, _ := params.ConvertToPage()
+	assert.Equal(t, "id DESC", order)
+	params.Sort = "age,(select 1)"
+	order, _, _ = params.ConvertToPage()
+	assert.Equal(t, "age ASC", order)
+}
+
+func TestConditions_CheckValid_AllowedColumns(t *testing.T) {
+	c := &Conditions{Columns: []Column{{Name: "name", Value: "ZhangSan"}}}
+	assert.NoError(t, c.CheckValid(WithModelColumns(&userExample{})))
+	assert.Error(t, c.CheckValid(WithAllowedColumns("age")))
+}
@@ -0,0 +1,421 @@
+package query
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/schema"
+)
+
+// ErrInvalidCursor the cursor is malformed or does not match the sort fields,
+// use errors.Is(err, ErrInvalidCursor) to determine.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// primary key column name, it is appended to the sort fields as a tie-breaker
+const primaryKeyName = "id"
+
+// CursorParams cursor (keyset) paging query parameters, unlike offset paging, the query
+// cost does not grow with the page number, it is suitable for tables with a large amount of data.
+//
+// the first page is requested with an empty cursor, the next or previous page is requested
+// with the nextCursor or prevCursor returned by the previous query, example:
+//
+//	{"limit": 20, "sort": "-created_at", "columns": [{"name": "status", "value": 1}]}
+//	{"cursor": "eyJzIjoiLWNyZWF0ZWRfYXQsLWlkIiwidiI6...", "limit": 20, "columns": [{"name": "status", "value": 1}]}
+type CursorParams struct {
+	Cursor string `json:"cursor" form:"cursor"`                  // opaque cursor returned by the previous query, empty means the first page
+	Limit  int    `json:"limit" form:"limit" binding:"gte=1"`    // number per page
+	Sort   string `json:"sort,omitempty" form:"sort" binding:""` // sort fields, default is -id, must be the same as the sort fields of the cursor
+
+	Columns []Column `json:"columns,omitempty" form:"columns"` // not required
+	Group   *Group   `json:"group,omitempty" form:"group"`     // nested conditions, not required, joined with Columns by and
+}
+
+// CursorPage cursors of the adjacent pages, an empty cursor means there is no adjacent page
+type CursorPage struct {
+	NextCursor string `json:"nextCursor"`
+	PrevCursor string `json:"prevCursor"`
+}
+
+// CursorQuery gorm query parameters converted from CursorParams, example:
+//
+//	cq, err := params.ConvertToCursor()
+//	records := []*model.UserExample{}
+//	err = db.Where(cq.Where, cq.Args...).Order(cq.Order).Limit(cq.Limit).Find(&records).Error
+//	page, err := cq.Paginate(&records)
+type CursorQuery struct {
+	Where string        // query conditions, including the keyset condition of the cursor
+	Args  []interface{} // arguments of the query conditions
+	Order string        // sort fields
+	Limit int           // number of records to query, one more than the number per page to determine whether there is an adjacent page
+
+	keys      []sortKey
+	limit     int
+	isPrev    bool
+	hasCursor bool
+}
+
+type sortKey struct {
+	name string
+	desc bool
+}
+
+// encoded content of the cursor
+type cursor struct {
+	Sort   string        `json:"s"`
+	Values []cursorValue `json:"v"`
+	IsPrev bool          `json:"p,omitempty"`
+}
+
+// typed value in the cursor, the type is kept to restore the value as it is
+type cursorValue struct {
+	Type  string `json:"t"`
+	Value string `json:"v"`
+}
+
+// the number per page if the limit is not set
+const defaultCursorLimit = 10
+
+// ConvertToCursor conversion to gorm-compliant parameters of cursor paging,
+// the allowed columns can be restricted by options, e.g. WithModelColumns(&model.UserExample{})
+func (p *CursorParams) ConvertToCursor(opts ...Option) (*CursorQuery, error) {
+	o := defaultOptions()
+	o.apply(opts...)
+
+	limit := p.Limit
+	if limit > defaultMaxSize {
+		limit = defaultMaxSize
+	} else if limit < 1 {
+		limit = defaultCursorLimit
+	}
+
+	sort := strings.Replace(p.Sort, " ", "", -1)
+	var c *cursor
+	if p.Cursor != "" {
+		var err error
+		c, err = decodeCursor(p.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if sort != "" && getSortKeysStr(sort) != c.Sort {
+			return nil, fmt.Errorf("%w, the sort fields '%s' do not match the cursor", ErrInvalidCursor, sort)
+		}
+		sort = c.Sort
+	}
+
+	keys, err := parseSortKeys(sort, o)
+	if err != nil {
+		return nil, err
+	}
+
+	where, args, err := (&Params{Columns: p.Columns, Group: p.Group}).ConvertToGormConditions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	cq := &CursorQuery{
+		Args:      args,
+		Limit:     limit + 1,
+		keys:      keys,
+		limit:     limit,
+		hasCursor: c != nil,
+	}
+
+	if c != nil {
+		cq.isPrev = c.IsPrev
+		keysetStr, keysetArgs, err := keysetCondition(keys, c, c.IsPrev)
+		if err != nil {
+			return nil, err
+		}
+		if where == "" {
+			where = keysetStr
+		} else {
+			where = "(" + where + ") AND (" + keysetStr + ")"
+		}
+		cq.Args = append(cq.Args, keysetArgs...)
+	}
+	cq.Where = where
+	cq.Order = orderStr(keys, cq.isPrev)
+
+	return cq, nil
+}
+
+// Paginate trim the records to the number per page and generate the cursors of the adjacent pages,
+// the parameter records must be a pointer to the slice of the queried gorm models, e.g. *[]*model.UserExample,
+// the records are restored to the order of the sort fields when querying the previous page.
+func (q *CursorQuery) Paginate(records interface{}) (*CursorPage, error) {
+	rv := reflect.ValueOf(records)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		return nil, fmt.Errorf("records must be a pointer to slice, but got '%T'", records)
+	}
+	list := rv.Elem()
+
+	hasMore := list.Len() > q.limit
+	if hasMore {
+		list.Set(list.Slice(0, q.limit))
+	}
+	if q.isPrev {
+		reverseSlice(list)
+	}
+
+	page := &CursorPage{}
+	if list.Len() == 0 {
+		return page, nil
+	}
+
+	s, err := schema.Parse(list.Index(0).Interface(), schemaCache, schema.NamingStrategy{})
+	if err != nil {
+		return nil, fmt.Errorf("parse model error: %v", err)
+	}
+
+	hasNext, hasPrev := hasMore, q.hasCursor
+	if q.isPrev {
+		hasNext, hasPrev = true, hasMore
+	}
+	if hasNext {
+		page.NextCursor, err = q.encode(s, list.Index(list.Len()-1), false)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if hasPrev {
+		page.PrevCursor, err = q.encode(s, list.Index(0), true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return page, nil
+}
+
+func (q *CursorQuery) encode(s *schema.Schema, record reflect.Value, isPrev bool) (string, error) {
+	record = reflect.Indirect(record)
+	c := &cursor{Sort: sortKeysStr(q.keys), IsPrev: isPrev}
+	for _, key := range q.keys {
+		name := key.name
+		if i := strings.LastIndex(name, "."); i >= 0 { // remove the table name prefix
+			name = name[i+1:]
+		}
+		field := s.LookUpField(name)
+		if field == nil {
+			return "", fmt.Errorf("column '%s' not found in model '%s'", key.name, s.Name)
+		}
+		value, _ := field.ValueOf(context.Background(), record)
+		cv, err := toCursorValue(value)
+		if err != nil {
+			return "", fmt.Errorf("column '%s': %v", key.name, err)
+		}
+		c.Values = append(c.Values, cv)
+	}
+	return encodeCursor(c)
+}
+
+// keyset condition, e.g. sort=-age,id: age < ? OR (age = ? AND id > ?)
+func keysetCondition(keys []sortKey, c *cursor, isPrev bool) (string, []interface{}, error) {
+	if len(c.Values) != len(keys) {
+		return "", nil, fmt.Errorf("%w, the number of values does not match the sort fields", ErrInvalidCursor)
+	}
+	values := make([]interface{}, 0, len(c.Values))
+	for _, cv := range c.Values {
+		v, err := cv.parse()
+		if err != nil {
+			return "", nil, err
+		}
+		values = append(values, v)
+	}
+
+	items := make([]string, 0, len(keys))
+	args := []interface{}{}
+	for i, key := range keys {
+		op := " > "
+		if key.desc != isPrev {
+			op = " < "
+		}
+		conds := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			conds = append(conds, keys[j].name+" = ?")
+			args = append(args, values[j])
+		}
+		conds = append(conds, key.name+op+"?")
+		args = append(args, values[i])
+		if len(conds) == 1 {
+			items = append(items, conds[0])
+		} else {
+			items = append(items, "("+strings.Join(conds, " AND ")+")")
+		}
+	}
+
+	return strings.Join(items, " OR "), args, nil
+}
+
+// parse the sort fields, the primary key is appended as a tie-breaker if it is not included
+func parseSortKeys(sort string, o *options) ([]sortKey, error) {
+	if sort == "" {
+		sort = "-" + primaryKeyName
+	}
+
+	keys := []sortKey{}
+	hasPrimaryKey := false
+	for _, name := range strings.Split(sort, ",") {
+		if name == "" {
+			continue
+		}
+		key := sortKey{name: name}
+		if name[0] == '-' {
+			key.name, key.desc = name[1:], true
+		}
+		if err := o.checkColumn(key.name); err != nil {
+			return nil, err
+		}
+		if key.name == primaryKeyName {
+			hasPrimaryKey = true
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		keys = append(keys, sortKey{name: primaryKeyName, desc: true})
+		hasPrimaryKey = true
+	}
+	if !hasPrimaryKey {
+		keys = append(keys, sortKey{name: primaryKeyName, desc: keys[len(keys)-1].desc})
+	}
+
+	return keys, nil
+}
+
+func getSortKeysStr(sort string) string {
+	keys, err := parseSortKeys(sort, &options{})
+	if err != nil {
+		return ""
+	}
+	return sortKeysStr(keys)
+}
+
+func sortKeysStr(keys []sortKey) string {
+	names := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if key.desc {
+			names = append(names, "-"+key.name)
+		} else {
+			names = append(names, key.name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// the sort direction is reversed when querying the previous page
+func orderStr(keys []sortKey, isPrev bool) string {
+	strs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if key.desc != isPrev {
+			strs = append(strs, key.name+" DESC")
+		} else {
+			strs = append(strs, key.name+" ASC")
+		}
+	}
+	return strings.Join(strs, ", ")
+}
+
+func reverseSlice(list reflect.Value) {
+	swap := reflect.Swapper(list.Interface())
+	for i, j := 0, list.Len()-1; i < j; i, j = i+1, j-1 {
+		swap(i, j)
+	}
+}
+
+func encodeCursor(c *cursor) (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(str string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(str)
+	if err != nil {
+		return nil, fmt.Errorf("%w, %v", ErrInvalidCursor, err)
+	}
+	c := &cursor{}
+	if err = json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("%w, %v", ErrInvalidCursor, err)
+	}
+	return c, nil
+}
+
+func toCursorValue(value interface{}) (cursorValue, error) {
+	if v, ok := value.(driver.Valuer); ok {
+		dv, err := v.Value()
+		if err != nil {
+			return cursorValue{}, err
+		}
+		value = dv
+	}
+
+	switch v := value.(type) {
+	case time.Time:
+		return cursorValue{Type: "t", Value: v.Format(time.RFC3339Nano)}, nil
+	case []byte:
+		return cursorValue{Type: "s", Value: string(v)}, nil
+	}
+
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cursorValue{Type: "i", Value: strconv.FormatInt(rv.Int(), 10)}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cursorValue{Type: "u", Value: strconv.FormatUint(rv.Uint(), 10)}, nil
+	case reflect.Float32, reflect.Float64:
+		return cursorValue{Type: "f", Value: strconv.FormatFloat(rv.Float(), 'g', -1, 64)}, nil
+	case reflect.String:
+		return cursorValue{Type: "s", Value: rv.String()}, nil
+	case reflect.Bool:
+		return cursorValue{Type: "b", Value: strconv.FormatBool(rv.Bool())}, nil
+	case reflect.Struct:
+		if t, ok := rv.Interface().(time.Time); ok {
+			return toCursorValue(t)
+		}
+	case reflect.Invalid, reflect.Ptr:
+		return cursorValue{}, errors.New("null value cannot be used as cursor, the sort fields must be not null")
+	}
+
+	return cursorValue{}, fmt.Errorf("unsupported cursor value type '%T'", value)
+}
+
+func (v cursorValue) parse() (interface{}, error) {
+	var (
+		value interface{}
+		err   error
+	)
+	switch v.Type {
+	case "i":
+		value, err = strconv.ParseInt(v.Value, 10, 64)
+	case "u":
+		value, err = strconv.ParseUint(v.Value, 10, 64)
+	case "f":
+		value, err = strconv.ParseFloat(v.Value, 64)
+	case "s":
+		value = v.Value
+	case "b":
+		value, err = strconv.ParseBool(v.Value)
+	case "t":
+		value, err = time.Parse(time.RFC3339Nano, v.Value)
+	default:
+		return nil, fmt.Errorf("%w, unknown value type '%s'", ErrInvalidCursor, v.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w, %v", ErrInvalidCursor, err)
+	}
+	return value, nil
+}
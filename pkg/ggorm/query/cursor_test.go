@@ -0,0 +1,124 @@
+package query
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type cursorUser struct {
+	ID        uint64    `gorm:"column:id;primary_key"`
+	Name      string    `gorm:"column:name"`
+	Age       int       `gorm:"column:age"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+}
+
+func TestCursorParams_ConvertToCursor(t *testing.T) {
+	// first page
+	params := &CursorParams{Limit: 2, Sort: "-age", Columns: []Column{{Name: "name", Exp: Like, Value: "Li"}}}
+	cq, err := params.ConvertToCursor()
+	assert.NoError(t, err)
+	assert.Equal(t, "name LIKE ?", cq.Where)
+	assert.Equal(t, []interface{}{"%Li%"}, cq.Args)
+	assert.Equal(t, "age DESC, id DESC", cq.Order)
+	assert.Equal(t, 3, cq.Limit)
+
+	records := []*cursorUser{
+		{ID: 5, Name: "LiSi", Age: 30},
+		{ID: 4, Name: "LiSi", Age: 20},
+		{ID: 3, Name: "LiSi", Age: 20},
+	}
+	page, err := cq.Paginate(&records)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(records))
+	assert.NotEmpty(t, page.NextCursor)
+	assert.Empty(t, page.PrevCursor)
+
+	// next page
+	params.Cursor = page.NextCursor
+	cq, err = params.ConvertToCursor()
+	assert.NoError(t, err)
+	assert.Equal(t, "(name LIKE ?) AND (age < ? OR (age = ? AND id < ?))", cq.Where)
+	assert.Equal(t, []interface{}{"%Li%", int64(20), int64(20), uint64(4)}, cq.Args)
+	assert.Equal(t, "age DESC, id DESC", cq.Order)
+
+	records = []*cursorUser{{ID: 3, Name: "LiSi", Age: 20}}
+	page, err = cq.Paginate(&records)
+	assert.NoError(t, err)
+	assert.Empty(t, page.NextCursor)
+	assert.NotEmpty(t, page.PrevCursor)
+
+	// previous page, the sort direction is reversed and the records are restored
+	params.Cursor = page.PrevCursor
+	cq, err = params.ConvertToCursor()
+	assert.NoError(t, err)
+	assert.Equal(t, "(name LIKE ?) AND (age > ? OR (age = ? AND id > ?))", cq.Where)
+	assert.Equal(t, "age ASC, id ASC", cq.Order)
+
+	records = []*cursorUser{
+		{ID: 4, Name: "LiSi", Age: 20},
+		{ID: 5, Name: "LiSi", Age: 30},
+	}
+	page, err = cq.Paginate(&records)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), records[0].ID)
+	assert.NotEmpty(t, page.NextCursor)
+	assert.Empty(t, page.PrevCursor)
+}
+
+func TestCursorParams_ConvertToCursor_Time(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	cq, err := (&CursorParams{Limit: 1, Sort: "created_at"}).ConvertToCursor()
+	assert.NoError(t, err)
+	assert.Equal(t, "", cq.Where)
+	assert.Equal(t, "created_at ASC, id ASC", cq.Order)
+
+	records := []cursorUser{{ID: 1, CreatedAt: now}, {ID: 2, CreatedAt: now}}
+	page, err := cq.Paginate(&records)
+	assert.NoError(t, err)
+
+	cq, err = (&CursorParams{Cursor: page.NextCursor, Limit: 1}).ConvertToCursor()
+	assert.NoError(t, err)
+	assert.Equal(t, "created_at > ? OR (created_at = ? AND id > ?)", cq.Where)
+	assert.True(t, now.Equal(cq.Args[0].(time.Time)))
+}
+
+func TestCursorParams_ConvertToCursor_Error(t *testing.T) {
+	// invalid cursor
+	_, err := (&CursorParams{Cursor: "abc"}).ConvertToCursor()
+	assert.True(t, errors.Is(err, ErrInvalidCursor))
+	c, _ := encodeCursor(&cursor{Sort: "-id", Values: []cursorValue{{Type: "x", Value: "1"}}})
+	_, err = (&CursorParams{Cursor: c}).ConvertToCursor()
+	assert.True(t, errors.Is(err, ErrInvalidCursor))
+	c, _ = encodeCursor(&cursor{Sort: "-age,-id", Values: []cursorValue{{Type: "i", Value: "1"}}})
+	_, err = (&CursorParams{Cursor: c}).ConvertToCursor()
+	assert.True(t, errors.Is(err, ErrInvalidCursor))
+
+	// sort does not match the cursor
+	c, _ = encodeCursor(&cursor{Sort: "-id", Values: []cursorValue{{Type: "u", Value: "1"}}})
+	_, err = (&CursorParams{Cursor: c, Sort: "name"}).ConvertToCursor()
+	assert.True(t, errors.Is(err, ErrInvalidCursor))
+	_, err = (&CursorParams{Cursor: c, Sort: "-id"}).ConvertToCursor()
+	assert.NoError(t, err)
+
+	// the sort fields of the cursor are not allowed
+	c, _ = encodeCursor(&cursor{Sort: "password,id", Values: []cursorValue{{Type: "s", Value: "a"}, {Type: "u", Value: "1"}}})
+	_, err = (&CursorParams{Cursor: c}).ConvertToCursor(WithAllowedColumns("id", "name"))
+	assert.True(t, errors.Is(err, ErrInvalidColumn))
+	_, err = (&CursorParams{Sort: "name;drop"}).ConvertToCursor()
+	assert.True(t, errors.Is(err, ErrInvalidColumn))
+
+	// default limit
+	cq, err := (&CursorParams{}).ConvertToCursor()
+	assert.NoError(t, err)
+	assert.Equal(t, defaultCursorLimit+1, cq.Limit)
+
+	// invalid records
+	cq, _ = (&CursorParams{Limit: 1, Sort: "unknown"}).ConvertToCursor()
+	_, err = cq.Paginate([]*cursorUser{})
+	assert.Error(t, err)
+	_, err = cq.Paginate(&[]*cursorUser{{ID: 1}, {ID: 2}})
+	assert.Error(t, err)
+}
@@ -91,12 +91,24 @@ func getSort(columnNames string) string {
 	names := strings.Split(columnNames, ",")
 	strs := make([]string, 0, len(names))
 	for _, name := range names {
+		if name == "" {
+			continue
+		}
 		if name[0] == '-' && len(name) > 1 {
+			if !isValidColumnName(name[1:]) { // ignore invalid column names to prevent sql injection
+				continue
+			}
 			strs = append(strs, name[1:]+" DESC")
 		} else {
+			if !isValidColumnName(name) {
+				continue
+			}
 			strs = append(strs, name+" ASC")
 		}
 	}
+	if len(strs) == 0 {
+		return "id DESC"
+	}
 
 	return strings.Join(strs, ", ")
 }
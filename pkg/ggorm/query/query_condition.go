@@ -3,6 +3,7 @@ package query
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 )
 
@@ -23,6 +24,20 @@ const (
 	Like = "like"
 	// In include
 	In = "in"
+	// NotIn not include
+	NotIn = "notin"
+	// NotLike fuzzy lookup that does not match
+	NotLike = "notlike"
+	// Prefix fuzzy lookup by prefix, can use the index
+	Prefix = "prefix"
+	// Suffix fuzzy lookup by suffix
+	Suffix = "suffix"
+	// Between in the range, including the boundary values
+	Between = "between"
+	// IsNull is null, the value is ignored
+	IsNull = "isnull"
+	// IsNotNull is not null, the value is ignored
+	IsNotNull = "notnull"
 
 	// AND logic and
 	AND string = "and"
@@ -40,6 +55,14 @@ var expMap = map[string]string{
 	Like: " LIKE ",
 	In:   " IN ",
 
+	NotIn:     " NOT IN ",
+	NotLike:   " NOT LIKE ",
+	Prefix:    " LIKE ",
+	Suffix:    " LIKE ",
+	Between:   " BETWEEN ",
+	IsNull:    " IS NULL",
+	IsNotNull: " IS NOT NULL",
+
 	"=":  " = ",
 	"!=": " <> ",
 	">":  " > ",
@@ -67,6 +90,7 @@ type Params struct {
 	Sort  string `json:"sort,omitempty" form:"sort" binding:""`
 
 	Columns []Column `json:"columns,omitempty" form:"columns"` // not required
+	Group   *Group   `json:"group,omitempty" form:"group"`     // nested conditions, not required, joined with Columns by and
 
 	// Deprecated: use Limit instead in sponge version v1.8.6, will remove in the future
 	Size int `json:"size" form:"size"`
@@ -75,7 +99,7 @@ type Params struct {
 // Column query info
 type Column struct {
 	Name  string      `json:"name" form:"name"`   // column name
-	Exp   string      `json:"exp" form:"exp"`     // expressions, which default to = when the value is null, have =, !=, >, >=, <, <=, like, in
+	Exp   string      `json:"exp" form:"exp"`     // expressions, which default to = when the value is null, have =, !=, >, >=, <, <=, like, in, notin, notlike, prefix, suffix, between, isnull, notnull
 	Value interface{} `json:"value" form:"value"` // column value
 	Logic string      `json:"logic" form:"logic"` // logical type, defaults to and when the value is null, with &(and), ||(or)
 }
@@ -84,33 +108,49 @@ func (c *Column) checkValid() error {
 	if c.Name == "" {
 		return fmt.Errorf("field 'name' cannot be empty")
 	}
-	if c.Value == nil {
+	if c.Value == nil && !isNullExp(c.Exp) {
 		return fmt.Errorf("field 'value' cannot be nil")
 	}
 	return nil
 }
 
+func isNullExp(exp string) bool {
+	exp = strings.ToLower(exp)
+	return exp == IsNull || exp == IsNotNull
+}
+
 // converting ExpType to sql expressions and LogicType to sql using characters
 func (c *Column) convert() error {
 	if c.Exp == "" {
 		c.Exp = Eq
 	}
-	if v, ok := expMap[strings.ToLower(c.Exp)]; ok { //nolint
+	exp := strings.ToLower(c.Exp)
+	if v, ok := expMap[exp]; ok { //nolint
 		c.Exp = v
-		if c.Exp == " LIKE " {
+		switch exp {
+		case Like, NotLike:
 			c.Value = fmt.Sprintf("%%%v%%", c.Value)
-		}
-		if c.Exp == " IN " {
-			val, ok := c.Value.(string)
-			if !ok {
-				return fmt.Errorf("invalid value type '%s'", c.Value)
+		case Prefix:
+			c.Value = fmt.Sprintf("%v%%", c.Value)
+		case Suffix:
+			c.Value = fmt.Sprintf("%%%v", c.Value)
+		case In, NotIn:
+			values, err := toSlice(c.Value)
+			if err != nil {
+				return err
+			}
+			c.Value = values
+		case Between:
+			values, err := toSlice(c.Value)
+			if err != nil {
+				return err
 			}
-			iVal := []interface{}{}
-			ss := strings.Split(val, ",")
-			for _, s := range ss {
-				iVal = append(iVal, s)
+			if len(values) != 2 {
+				return fmt.Errorf("exp type 'between' requires 2 values, but got %d", len(values))
 			}
-			c.Value = iVal
+			c.Value = values
+		case IsNull, IsNotNull:
+			c.Value = nil
 		}
 	} else {
 		return fmt.Errorf("unknown exp type '%s'", c.Exp)
@@ -128,6 +168,44 @@ func (c *Column) convert() error {
 	return nil
 }
 
+// sql expression and arguments of the converted column
+func (c *Column) toSQL() (string, []interface{}) {
+	switch c.Exp {
+	case " IN ", " NOT IN ":
+		return c.Name + c.Exp + "(?)", []interface{}{c.Value}
+	case " BETWEEN ":
+		values, _ := c.Value.([]interface{})
+		return c.Name + c.Exp + "? AND ?", values
+	case " IS NULL", " IS NOT NULL":
+		return c.Name + c.Exp, nil
+	}
+	return c.Name + c.Exp + "?", []interface{}{c.Value}
+}
+
+// convert the value to slice, the value can be a slice or a comma-separated string, e.g. "a,b,c"
+func toSlice(value interface{}) ([]interface{}, error) {
+	switch v := value.(type) {
+	case []interface{}:
+		return v, nil
+	case string:
+		values := []interface{}{}
+		for _, s := range strings.Split(v, ",") {
+			values = append(values, s)
+		}
+		return values, nil
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("invalid value type '%T', must be a slice or a comma-separated string", value)
+	}
+	values := make([]interface{}, 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		values = append(values, rv.Index(i).Interface())
+	}
+	return values, nil
+}
+
 // ConvertToPage converted to page
 func (p *Params) ConvertToPage() (order string, limit int, offset int) { //nolint
 	page := NewPage(p.Page, p.Limit, p.Sort)
@@ -137,9 +215,49 @@ func (p *Params) ConvertToPage() (order string, limit int, offset int) { //nolin
 	return //nolint
 }
 
-// ConvertToGormConditions conversion to gorm-compliant parameters based on the Columns parameter
-// ignore the logical type of the last column, whether it is a one-column or multi-column query
-func (p *Params) ConvertToGormConditions() (string, []interface{}, error) {
+// CheckValid check that the column names, group and sort fields are valid,
+// the allowed columns can be restricted by options, e.g. WithModelColumns(&model.UserExample{})
+func (p *Params) CheckValid(opts ...Option) error {
+	o := defaultOptions()
+	o.apply(opts...)
+
+	if err := checkSort(p.Sort, o); err != nil {
+		return err
+	}
+	_, _, err := p.ConvertToGormConditions(opts...)
+	return err
+}
+
+// ConvertToGormConditions conversion to gorm-compliant parameters based on the Columns and Group parameter,
+// ignore the logical type of the last column, whether it is a one-column or multi-column query,
+// if both Columns and Group are not empty, they are joined by and.
+func (p *Params) ConvertToGormConditions(opts ...Option) (string, []interface{}, error) {
+	o := defaultOptions()
+	o.apply(opts...)
+
+	str, args, err := p.convertColumns(o)
+	if err != nil {
+		return "", nil, err
+	}
+	if p.Group == nil {
+		return str, args, nil
+	}
+
+	groupStr, groupArgs, err := p.Group.convert(1, o)
+	if err != nil {
+		return "", nil, err
+	}
+	if groupStr == "" {
+		return str, args, nil
+	}
+	if str == "" {
+		return groupStr, groupArgs, nil
+	}
+
+	return "(" + str + ") AND (" + groupStr + ")", append(args, groupArgs...), nil
+}
+
+func (p *Params) convertColumns(o *options) (string, []interface{}, error) {
 	str := ""
 	args := []interface{}{}
 	l := len(p.Columns)
@@ -157,22 +275,22 @@ func (p *Params) ConvertToGormConditions() (string, []interface{}, error) {
 		if err := column.checkValid(); err != nil {
 			return "", nil, err
 		}
+		if err := o.checkColumn(column.Name); err != nil {
+			return "", nil, err
+		}
 
 		err := column.convert()
 		if err != nil {
 			return "", nil, err
 		}
 
-		symbol := "?"
-		if column.Exp == " IN " {
-			symbol = "(?)"
-		}
+		expStr, values := column.toSQL()
 		if i == l-1 { // ignore the logical type of the last column
-			str += column.Name + column.Exp + symbol
+			str += expStr
 		} else {
-			str += column.Name + column.Exp + symbol + column.Logic
+			str += expStr + column.Logic
 		}
-		args = append(args, column.Value)
+		args = append(args, values...)
 
 		// when multiple columns are the same, determine whether the use of IN
 		if isUseIN {
@@ -199,17 +317,22 @@ type Conditions struct {
 	Columns []Column `json:"columns" form:"columns" binding:"min=1"` // columns info
 }
 
-// CheckValid check valid
-func (c *Conditions) CheckValid() error {
+// CheckValid check valid, the allowed columns can be restricted by options
+func (c *Conditions) CheckValid(opts ...Option) error {
 	if len(c.Columns) == 0 {
 		return fmt.Errorf("field 'columns' cannot be empty")
 	}
 
+	o := defaultOptions()
+	o.apply(opts...)
 	for _, column := range c.Columns {
 		err := column.checkValid()
 		if err != nil {
 			return err
 		}
+		if err = o.checkColumn(column.Name); err != nil {
+			return err
+		}
 		if column.Exp != "" {
 			if _, ok := expMap[column.Exp]; !ok {
 				return fmt.Errorf("unknown exp type '%s'", column.Exp)
@@ -227,7 +350,7 @@ func (c *Conditions) CheckValid() error {
 
 // ConvertToGorm conversion to gorm-compliant parameters based on the Columns parameter
 // ignore the logical type of the last column, whether it is a one-column or multi-column query
-func (c *Conditions) ConvertToGorm() (string, []interface{}, error) {
+func (c *Conditions) ConvertToGorm(opts ...Option) (string, []interface{}, error) {
 	p := &Params{Columns: c.Columns}
-	return p.ConvertToGormConditions()
+	return p.ConvertToGormConditions(opts...)
 }
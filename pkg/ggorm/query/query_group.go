@@ -0,0 +1,88 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Group nested query conditions, the columns and sub groups in a group are joined by the logic of the group,
+// each sub group is wrapped in parentheses, example: (a = 1 OR b = 2) AND c > 3
+//
+//	{
+//	    "logic": "and",
+//	    "columns": [{"name": "c", "exp": ">", "value": 3}],
+//	    "groups": [
+//	        {
+//	            "logic": "or",
+//	            "columns": [{"name": "a", "value": 1}, {"name": "b", "value": 2}]
+//	        }
+//	    ]
+//	}
+type Group struct {
+	Logic   string   `json:"logic" form:"logic"`               // logical type of the items in the group, defaults to and when the value is null, with &(and), ||(or)
+	Columns []Column `json:"columns,omitempty" form:"columns"` // column conditions, the logic field of the column is ignored
+	Groups  []Group  `json:"groups,omitempty" form:"groups"`   // sub groups
+}
+
+// CheckValid check valid
+func (g *Group) CheckValid(opts ...Option) error {
+	_, _, err := g.ConvertToGorm(opts...)
+	return err
+}
+
+// ConvertToGorm conversion to gorm-compliant parameters based on the group
+func (g *Group) ConvertToGorm(opts ...Option) (string, []interface{}, error) {
+	o := defaultOptions()
+	o.apply(opts...)
+	return g.convert(1, o)
+}
+
+func (g *Group) convert(depth int, o *options) (string, []interface{}, error) {
+	if depth > o.maxDepth {
+		return "", nil, fmt.Errorf("the nesting depth of the group exceeds the maximum %d", o.maxDepth)
+	}
+
+	logic := g.Logic
+	if logic == "" {
+		logic = AND
+	}
+	logicStr, ok := logicMap[strings.ToLower(logic)]
+	if !ok {
+		return "", nil, fmt.Errorf("unknown logic type '%s'", g.Logic)
+	}
+
+	items := []string{}
+	args := []interface{}{}
+	for _, column := range g.Columns {
+		if err := column.checkValid(); err != nil {
+			return "", nil, err
+		}
+		if err := o.checkColumn(column.Name); err != nil {
+			return "", nil, err
+		}
+		if err := column.convert(); err != nil {
+			return "", nil, err
+		}
+		expStr, values := column.toSQL()
+		items = append(items, expStr)
+		args = append(args, values...)
+	}
+
+	for _, group := range g.Groups {
+		str, values, err := group.convert(depth+1, o)
+		if err != nil {
+			return "", nil, err
+		}
+		if str == "" {
+			continue
+		}
+		items = append(items, "("+str+")")
+		args = append(args, values...)
+	}
+
+	if len(items) == 0 {
+		return "", nil, nil
+	}
+
+	return strings.Join(items, logicStr), args, nil
+}
@@ -0,0 +1,126 @@
+package query
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroup_ConvertToGorm(t *testing.T) {
+	// (a = 1 OR b = 2) AND c > 3
+	g := &Group{
+		Columns: []Column{
+			{Name: "c", Exp: Gt, Value: 3},
+		},
+		Groups: []Group{
+			{
+				Logic: OR,
+				Columns: []Column{
+					{Name: "a", Value: 1},
+					{Name: "b", Value: 2},
+				},
+			},
+		},
+	}
+	str, args, err := g.ConvertToGorm()
+	assert.NoError(t, err)
+	assert.Equal(t, "c > ? AND (a = ? OR b = ?)", str)
+	assert.Equal(t, []interface{}{3, 1, 2}, args)
+
+	// nested group
+	g = &Group{
+		Logic: "||",
+		Groups: []Group{
+			{
+				Columns: []Column{
+					{Name: "name", Value: "ZhangSan"},
+					{Name: "age", Exp: Gte, Value: 20},
+				},
+			},
+			{
+				Groups: []Group{
+					{
+						Logic: OR,
+						Columns: []Column{
+							{Name: "gender", Value: "male"},
+							{Name: "name", Exp: In, Value: "LiSi,WangWu"},
+						},
+					},
+				},
+			},
+			{}, // empty group is ignored
+		},
+	}
+	str, args, err = g.ConvertToGorm()
+	assert.NoError(t, err)
+	assert.Equal(t, "(name = ? AND age >= ?) OR ((gender = ? OR name IN (?)))", str)
+	assert.Equal(t, 4, len(args))
+
+	// empty
+	str, args, err = (&Group{}).ConvertToGorm()
+	assert.NoError(t, err)
+	assert.Equal(t, "", str)
+	assert.Nil(t, args)
+}
+
+func TestGroup_CheckValid(t *testing.T) {
+	g := &Group{
+		Groups: []Group{
+			{
+				Groups: []Group{
+					{Columns: []Column{{Name: "name", Value: "ZhangSan"}}},
+				},
+			},
+		},
+	}
+	assert.NoError(t, g.CheckValid())
+	// depth error
+	assert.Error(t, g.CheckValid(WithMaxDepth(2)))
+	// column not allowed
+	assert.Error(t, g.CheckValid(WithAllowedColumns("age")))
+	assert.NoError(t, g.CheckValid(WithAllowedColumns("name", "age")))
+
+	// logic error
+	g = &Group{Logic: "xor", Columns: []Column{{Name: "name", Value: "ZhangSan"}}}
+	assert.Error(t, g.CheckValid())
+
+	// value error
+	g = &Group{Columns: []Column{{Name: "name"}}}
+	assert.Error(t, g.CheckValid())
+
+	SetMaxDepth(0)
+	assert.Equal(t, 1, defaultMaxDepth)
+	SetMaxDepth(5)
+}
+
+func TestParams_ConvertToGormConditions_Group(t *testing.T) {
+	data := `{
+	"page": 0,
+	"limit": 10,
+	"columns": [{"name": "status", "value": 1}, {"name": "status", "value": 2}],
+	"group": {
+		"logic": "or",
+		"columns": [{"name": "a", "value": 1}, {"name": "b", "value": 2}]
+	}
+}`
+	params := &Params{}
+	err := json.Unmarshal([]byte(data), params)
+	assert.NoError(t, err)
+
+	str, args, err := params.ConvertToGormConditions()
+	assert.NoError(t, err)
+	assert.Equal(t, "(status IN (?)) AND (a = ? OR b = ?)", str)
+	assert.Equal(t, 3, len(args))
+
+	// only group
+	params.Columns = nil
+	str, args, err = params.ConvertToGormConditions()
+	assert.NoError(t, err)
+	assert.Equal(t, "a = ? OR b = ?", str)
+	assert.Equal(t, 2, len(args))
+
+	// column not allowed
+	_, _, err = params.ConvertToGormConditions(WithAllowedColumns("a"))
+	assert.Error(t, err)
+}
@@ -13,6 +13,9 @@ import (
 const (
 	// HeaderAuthorizationKey http header authorization key
 	HeaderAuthorizationKey = "Authorization"
+
+	// CtxClaimsKey the key of *jwt.CustomClaims in gin.Context, it is set by AuthCustom
+	CtxClaimsKey = "claims"
 )
 
 type jwtOptions struct {
@@ -131,6 +134,8 @@ func AuthCustom(verify VerifyCustomFn, opts ...JwtOption) gin.HandlerFunc {
 			return
 		}
 
+		c.Set(CtxClaimsKey, claims)
+
 		tokenTail10 := token[len(token)-10:]
 		if err = verify(claims, tokenTail10, c); err != nil {
 			logger.Warn("verify error", logger.Err(err), logger.Any("fields", claims.Fields))
@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/zhufuyi/sponge/pkg/errcode"
+	"github.com/zhufuyi/sponge/pkg/gin/response"
+	"github.com/zhufuyi/sponge/pkg/jwt"
+	"github.com/zhufuyi/sponge/pkg/logger"
+	"github.com/zhufuyi/sponge/pkg/rbac"
+)
+
+type rbacOptions struct {
+	isSwitchHTTPCode bool
+	rolesFn          func(c *gin.Context) []string
+}
+
+// RBACOption set the rbac options.
+type RBACOption func(*rbacOptions)
+
+func (o *rbacOptions) apply(opts ...RBACOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+func defaultRBACOptions() *rbacOptions {
+	return &rbacOptions{
+		isSwitchHTTPCode: false,
+		rolesFn:          rolesFromCtx,
+	}
+}
+
+// WithRBACSwitchHTTPCode switch to http code
+func WithRBACSwitchHTTPCode() RBACOption {
+	return func(o *rbacOptions) {
+		o.isSwitchHTTPCode = true
+	}
+}
+
+// WithRBACRolesFn set the function to get the roles of the request, by default the roles are got from
+// the claims set by AuthCustom, see rbac.RolesFromClaims.
+func WithRBACRolesFn(fn func(c *gin.Context) []string) RBACOption {
+	return func(o *rbacOptions) {
+		if fn != nil {
+			o.rolesFn = fn
+		}
+	}
+}
+
+func rolesFromCtx(c *gin.Context) []string {
+	v, ok := c.Get(CtxClaimsKey)
+	if !ok {
+		return nil
+	}
+	claims, _ := v.(*jwt.CustomClaims)
+	return rbac.RolesFromClaims(claims)
+}
+
+func responseForbidden(c *gin.Context, isSwitchHTTPCode bool) {
+	if isSwitchHTTPCode {
+		response.Out(c, errcode.Forbidden)
+	} else {
+		response.Error(c, errcode.Forbidden)
+	}
+}
+
+// RBAC authorization by the roles of the request, the policies of the enforcer map the roles to the
+// http routes, it is used after AuthCustom.
+func RBAC(e *rbac.Enforcer, opts ...RBACOption) gin.HandlerFunc {
+	o := defaultRBACOptions()
+	o.apply(opts...)
+
+	return func(c *gin.Context) {
+		roles := o.rolesFn(c)
+		if !e.Enforce(roles, c.Request.URL.Path, c.Request.Method) {
+			logger.Warn("rbac forbidden", logger.Any("roles", roles), logger.String("method", c.Request.Method),
+				logger.String("path", c.Request.URL.Path))
+			responseForbidden(c, o.isSwitchHTTPCode)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// Permission returns a function that creates the middleware to check whether the roles of the request
+// have all the permissions, it is used in the routes generated by protoc-gen-go-gin which declare
+// the permissions, or used directly, e.g.
+//
+//	r.GET("/user/:id", middleware.Permission(e)([]string{"user:read"}), handler)
+func Permission(e *rbac.Enforcer, opts ...RBACOption) func(permissions []string) gin.HandlerFunc {
+	o := defaultRBACOptions()
+	o.apply(opts...)
+
+	return func(permissions []string) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			roles := o.rolesFn(c)
+			if !e.HasPermissions(roles, permissions...) {
+				logger.Warn("permission denied", logger.Any("roles", roles), logger.Any("permissions", permissions))
+				responseForbidden(c, o.isSwitchHTTPCode)
+				c.Abort()
+				return
+			}
+			c.Next()
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zhufuyi/sponge/pkg/errcode"
+	"github.com/zhufuyi/sponge/pkg/gin/response"
+	"github.com/zhufuyi/sponge/pkg/jwt"
+	"github.com/zhufuyi/sponge/pkg/rbac"
+)
+
+func newRBACRouter(opts ...RBACOption) *gin.Engine {
+	e := rbac.NewEnforcer(rbac.WithDenyByDefault(), rbac.WithPolicies(
+		&rbac.Policy{Role: "admin", Resource: "*"},
+		&rbac.Policy{Role: "user", Resource: "/api/v1/user/:id", Action: "GET"},
+		&rbac.Policy{Role: "user", Resource: "user:read"},
+	))
+
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	setRoles := func(c *gin.Context) {
+		roles := c.GetHeader("X-Roles")
+		c.Set(CtxClaimsKey, &jwt.CustomClaims{Fields: jwt.KV{"roles": roles}})
+	}
+	hello := func(c *gin.Context) { response.Success(c, "hello") }
+
+	r.Use(setRoles)
+	r.GET("/api/v1/user/:id", RBAC(e, opts...), hello)
+	r.DELETE("/api/v1/user/:id", RBAC(e, opts...), hello)
+	perm := Permission(e, opts...)
+	r.GET("/api/v1/article/:id", perm([]string{"user:read"}), hello)
+	r.DELETE("/api/v1/article/:id", perm([]string{"user:read", "article:delete"}), hello)
+	return r
+}
+
+func doRBACRequest(r *gin.Engine, method string, path string, roles string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("X-Roles", roles)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRBAC(t *testing.T) {
+	r := newRBACRouter(WithRBACSwitchHTTPCode())
+
+	tests := []struct {
+		method string
+		path   string
+		roles  string
+		code   int
+	}{
+		{http.MethodGet, "/api/v1/user/1", "user", http.StatusOK},
+		{http.MethodDelete, "/api/v1/user/1", "user", http.StatusForbidden},
+		{http.MethodDelete, "/api/v1/user/1", "user,admin", http.StatusOK},
+		{http.MethodGet, "/api/v1/user/1", "", http.StatusForbidden},
+		{http.MethodGet, "/api/v1/article/1", "user", http.StatusOK},
+		{http.MethodDelete, "/api/v1/article/1", "user", http.StatusForbidden},
+		{http.MethodDelete, "/api/v1/article/1", "admin", http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := doRBACRequest(r, tt.method, tt.path, tt.roles)
+		assert.Equal(t, tt.code, w.Code, tt.method+" "+tt.path+" "+tt.roles)
+	}
+
+	// response with code in body
+	r = newRBACRouter()
+	w := doRBACRequest(r, http.MethodDelete, "/api/v1/user/1", "user")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), errcode.Forbidden.Msg())
+}
+
+func TestWithRBACRolesFn(t *testing.T) {
+	r := newRBACRouter(WithRBACSwitchHTTPCode(), WithRBACRolesFn(func(c *gin.Context) []string {
+		return []string{"admin"}
+	}))
+	w := doRBACRequest(r, http.MethodDelete, "/api/v1/user/1", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zhufuyi/sponge/pkg/logger"
+	"github.com/zhufuyi/sponge/pkg/signature"
+)
+
+// CtxAppIDKey the key of app id in gin.Context, it is set by Signature
+const CtxAppIDKey = "appID"
+
+// the default max size of the request body that is read to verify the signature
+const defaultSignatureMaxBodySize = 4 << 20
+
+type signatureOptions struct {
+	isSwitchHTTPCode bool
+	maxBodySize      int64
+}
+
+// SignatureOption set the signature options.
+type SignatureOption func(*signatureOptions)
+
+func (o *signatureOptions) apply(opts ...SignatureOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+func defaultSignatureOptions() *signatureOptions {
+	return &signatureOptions{
+		isSwitchHTTPCode: false,
+		maxBodySize:      defaultSignatureMaxBodySize,
+	}
+}
+
+// WithSignatureSwitchHTTPCode switch to http code
+func WithSignatureSwitchHTTPCode() SignatureOption {
+	return func(o *signatureOptions) {
+		o.isSwitchHTTPCode = true
+	}
+}
+
+// WithSignatureMaxBodySize set the max size of the request body, default 4MB, the request with
+// larger body is rejected before verifying the signature.
+func WithSignatureMaxBodySize(size int64) SignatureOption {
+	return func(o *signatureOptions) {
+		if size > 0 {
+			o.maxBodySize = size
+		}
+	}
+}
+
+// Signature authentication by the HMAC signature of the request, the request is signed by
+// signature.Signer in client side, e.g. httpcli.New().SetSigner(signer)
+func Signature(v *signature.Verifier, opts ...SignatureOption) gin.HandlerFunc {
+	o := defaultSignatureOptions()
+	o.apply(opts...)
+
+	return func(c *gin.Context) {
+		var body []byte
+		if c.Request.Body != nil {
+			var err error
+			body, err = io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, o.maxBodySize))
+			if err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					logger.Warn("request body too large", logger.Int64("limit", maxBytesErr.Limit),
+						logger.String("path", c.Request.URL.Path))
+				} else {
+					logger.Warn("read body error", logger.Err(err))
+				}
+				responseUnauthorized(c, o.isSwitchHTTPCode)
+				c.Abort()
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		credential := signature.CredentialFromHeader(c.Request.Header)
+		err := v.Verify(c.Request.Context(), credential, c.Request.Method, c.Request.URL.EscapedPath(), c.Request.URL.Query(), body)
+		if err != nil {
+			logger.Warn("verify signature error", logger.Err(err), logger.String("appID", credential.AppID),
+				logger.String("path", c.Request.URL.Path))
+			responseUnauthorized(c, o.isSwitchHTTPCode)
+			c.Abort()
+			return
+		}
+
+		c.Set(CtxAppIDKey, credential.AppID)
+		c.Next()
+	}
+}
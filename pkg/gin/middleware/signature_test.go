@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zhufuyi/sponge/pkg/errcode"
+	"github.com/zhufuyi/sponge/pkg/gin/response"
+	"github.com/zhufuyi/sponge/pkg/signature"
+)
+
+func newSignatureRouter(opts ...SignatureOption) *gin.Engine {
+	v := signature.NewVerifier(signature.StaticSecrets(map[string]string{"app1": "secret1"}))
+
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(Signature(v, opts...))
+	r.POST("/api/v1/user", func(c *gin.Context) {
+		body := map[string]string{}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			response.Error(c, errcode.InvalidParams)
+			return
+		}
+		response.Success(c, c.GetString(CtxAppIDKey)+" "+body["name"])
+	})
+	return r
+}
+
+func doSignatureRequest(r *gin.Engine, signer *signature.Signer, body []byte) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/user?id=1", bytes.NewReader(body))
+	if signer != nil {
+		_ = signer.SignRequest(req, body)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestSignature(t *testing.T) {
+	r := newSignatureRouter(WithSignatureSwitchHTTPCode())
+	signer := signature.NewSigner("app1", "secret1")
+	body := []byte(`{"name":"foo"}`)
+
+	w := doSignatureRequest(r, signer, body)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "app1 foo") // the body can be read again
+
+	w = doSignatureRequest(r, nil, body)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = doSignatureRequest(r, signature.NewSigner("app1", "secret2"), body)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// replay
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/user", bytes.NewReader(body))
+	_ = signer.SignRequest(req, body)
+	header := req.Header.Clone()
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/user", bytes.NewReader(body))
+	req.Header = header
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// the body is too large
+	r = newSignatureRouter(WithSignatureSwitchHTTPCode(), WithSignatureMaxBodySize(8))
+	w = doSignatureRequest(r, signer, body)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// response with code in body
+	r = newSignatureRouter()
+	w = doSignatureRequest(r, nil, body)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "app1")
+}
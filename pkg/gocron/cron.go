@@ -2,21 +2,15 @@
 package gocron
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"strings"
-	"sync"
-
-	"github.com/robfig/cron/v3"
+	"sync/atomic"
+	"time"
 )
 
-var (
-	c *cron.Cron
-	// task name and id mapping, used to add, delete, modify and query tasks
-	nameID = sync.Map{}
-	// id and task name mapping, used in log printing
-	idName = sync.Map{}
-)
+// the default scheduler used by the package-level functions
+var defaultScheduler atomic.Pointer[Scheduler]
 
 // Task scheduled task
 type Task struct {
@@ -25,113 +19,118 @@ type Task struct {
 	// "0 15,45 9-12 * * * "  indicates execution at the 15th and 45th minutes from 9 a.m. to 12 a.m. each day
 	TimeSpec string
 
-	Name      string // task name
-	Fn        func() // task function
-	IsRunOnce bool   // if the task is only run once
+	Name      string                          // task name
+	Fn        func()                          // task function, either Fn or ContextFn is required
+	ContextFn func(ctx context.Context) error // task function with context, ctx is canceled after Timeout
+	IsRunOnce bool                            // if the task is only run once
+
+	// max running time, the run is recorded as timeout if exceeded, the task function should return when
+	// the ctx is done. it is also the ttl of the distributed lock, default 1 minute. 0 means no timeout.
+	Timeout time.Duration
+	// what to do when the task is triggered while the previous run is still running, default AllowOverlap
+	Policy ConcurrencyPolicy
 }
 
-// Init initialize and start timed tasks
+// Init initialize and start the default scheduler, the package-level functions use it.
 func Init(opts ...Option) error {
 	o := defaultOptions()
 	o.apply(opts...)
+	setLogger(o.zapLog)
 
-	log := &zapLog{zapLog: o.zapLog}
-	cronOpts := []cron.Option{
-		cron.WithSeconds(), // second-level granularity, default is minute-level granularity
-		cron.WithLogger(log),
-		cron.WithChain(
-			cron.Recover(log),
-		),
-	}
-
-	c = cron.New(cronOpts...)
-	c.Start()
-
+	defaultScheduler.Store(NewScheduler(opts...))
 	return nil
 }
 
+// Default get the default scheduler, nil if it is not initialized
+func Default() *Scheduler {
+	return defaultScheduler.Load()
+}
+
 // Run the tasks
 func Run(tasks ...*Task) error {
-	if c == nil {
+	s := Default()
+	if s == nil {
 		return errors.New("cron is not initialized")
 	}
+	return s.Run(tasks...)
+}
 
-	var errs []string
-	for _, task := range tasks {
-		if IsRunningTask(task.Name) {
-			errs = append(errs, fmt.Sprintf("task '%s' is already exists", task.Name))
-			continue
-		}
-
-		if err := checkRunOnce(task); err != nil {
-			errs = append(errs, err.Error())
-			continue
-		}
-
-		id, err := c.AddFunc(task.TimeSpec, task.Fn)
-		if err != nil {
-			errs = append(errs, fmt.Sprintf("run task '%s' error: %v", task.Name, err))
-			continue
-		}
-		idName.Store(id, task.Name)
-		nameID.Store(task.Name, id)
+// IsRunningTask determine if the task is running
+func IsRunningTask(name string) bool {
+	s := Default()
+	if s == nil {
+		return false
 	}
+	return s.IsRunningTask(name)
+}
 
-	if len(errs) > 0 {
-		return errors.New(strings.Join(errs, " || "))
+// GetRunningTasks gets a list of running task names
+func GetRunningTasks() []string {
+	s := Default()
+	if s == nil {
+		return nil
 	}
+	return s.GetRunningTasks()
+}
 
-	return nil
+// DeleteTask stop and delete the specified task
+func DeleteTask(name string) {
+	if s := Default(); s != nil {
+		s.DeleteTask(name)
+	}
 }
 
-func checkRunOnce(task *Task) error {
-	if task.Fn == nil {
-		return fmt.Errorf("task '%s' is nil", task.Name)
+// GetTasks gets the runtime information of the tasks
+func GetTasks() []*TaskInfo {
+	s := Default()
+	if s == nil {
+		return nil
 	}
-	if task.IsRunOnce {
-		job := task.Fn
-		task.Fn = func() {
-			job()
-			DeleteTask(task.Name)
-		}
+	return s.GetTasks()
+}
+
+// GetTaskRecords gets the latest execution records of the task, newest first
+func GetTaskRecords(name string) ([]*Record, error) {
+	s := Default()
+	if s == nil {
+		return nil, ErrTaskNotFound
 	}
-	return nil
+	return s.GetTaskRecords(name)
 }
 
-// IsRunningTask determine if the task is running
-func IsRunningTask(name string) bool {
-	_, ok := nameID.Load(name)
-	return ok
+// TriggerTask run the task immediately in background
+func TriggerTask(name string) error {
+	s := Default()
+	if s == nil {
+		return ErrTaskNotFound
+	}
+	return s.TriggerTask(name)
 }
 
-// GetRunningTasks gets a list of running task names
-func GetRunningTasks() []string {
-	var names []string
-	nameID.Range(func(key, value interface{}) bool {
-		names = append(names, key.(string))
-		return true
-	})
-	return names
+// PauseTask pause the scheduled runs of the task
+func PauseTask(name string) error {
+	s := Default()
+	if s == nil {
+		return ErrTaskNotFound
+	}
+	return s.PauseTask(name)
 }
 
-// DeleteTask stop and delete the specified task
-func DeleteTask(name string) {
-	if id, ok := nameID.Load(name); ok {
-		entryID, isOk := id.(cron.EntryID)
-		if !isOk {
-			return
-		}
-		c.Remove(entryID)
-		nameID.Delete(name)
-		idName.Delete(entryID)
+// ResumeTask resume the scheduled runs of the task
+func ResumeTask(name string) error {
+	s := Default()
+	if s == nil {
+		return ErrTaskNotFound
 	}
+	return s.ResumeTask(name)
 }
 
-// Stop all scheduled tasks
-func Stop() {
-	if c != nil {
-		c.Stop()
+// Stop all scheduled tasks, and wait for the runs in progress to finish within the stop timeout
+func Stop() error {
+	if s := Default(); s != nil {
+		return s.Stop()
 	}
+	return nil
 }
 
 // EverySecond every second size (1~59)
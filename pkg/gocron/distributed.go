@@ -0,0 +1,333 @@
+package gocron
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+	"go.uber.org/zap"
+
+	"github.com/zhufuyi/sponge/pkg/krand"
+)
+
+const (
+	defaultLockTTL       = time.Minute
+	defaultLockKeyPrefix = "gocron:"
+)
+
+// Locker distributed lock, each run of the task is locked by a key, only the instance
+// that acquires the lock runs the task, the lock expires after ttl.
+type Locker interface {
+	// TryLock return false if the lock is held by another instance
+	TryLock(ctx context.Context, key string, ttl time.Duration) (bool, error)
+}
+
+// Elector leader election, the tasks run only on the leader instance.
+type Elector interface {
+	IsLeader() bool
+}
+
+// runGuard decides whether the task runs on this instance
+type runGuard struct {
+	locker    Locker
+	elector   Elector
+	keyPrefix string
+	log       *zap.Logger
+}
+
+func (g *runGuard) logger() *zap.Logger {
+	if g.log != nil {
+		return g.log
+	}
+	return getLogger()
+}
+
+// wrap the task, it runs only if the instance acquires the lock of this run, or it is the leader,
+// scheduledTime returns the time the run is scheduled for.
+func (g *runGuard) wrap(task *Task, scheduledTime func() time.Time, fn func()) func() {
+	if g.locker == nil && g.elector == nil {
+		return fn
+	}
+
+	return func() {
+		if g.elector != nil && !g.elector.IsLeader() {
+			g.logger().Info("cron_skip run", zap.String("task", task.Name), zap.String("reason", "not leader"))
+			return
+		}
+
+		if g.locker != nil {
+			ttl := task.Timeout
+			if ttl <= 0 {
+				ttl = defaultLockTTL
+			}
+			// the key of each run is the scheduled time, the instances whose clocks are synchronized get the same key
+			// even if the run starts late
+			key := g.keyPrefix + task.Name + ":" + strconv.FormatInt(scheduledTime().Unix(), 10)
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+			ok, err := g.locker.TryLock(ctx, key, ttl)
+			cancel()
+			if err != nil {
+				g.logger().Warn("cron_skip run", zap.String("task", task.Name), zap.String("reason", "lock error"), zap.Error(err))
+				return
+			}
+			if !ok {
+				g.logger().Info("cron_skip run", zap.String("task", task.Name), zap.String("reason", "locked by another instance"), zap.String("key", key))
+				return
+			}
+		}
+
+		fn()
+	}
+}
+
+// -------------------------------------------------------------------------------------------
+
+// RedisLocker distributed lock based on redis, the client can be created by pkg/goredis.
+type RedisLocker struct {
+	client redis.UniversalClient
+	value  string
+}
+
+// NewRedisLocker create a redis locker
+func NewRedisLocker(client redis.UniversalClient) *RedisLocker {
+	return &RedisLocker{
+		client: client,
+		value:  krand.String(krand.R_All, 16),
+	}
+}
+
+// TryLock set the key if not exists
+func (l *RedisLocker) TryLock(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	return l.client.SetNX(ctx, key, l.value, ttl).Result()
+}
+
+// EtcdLocker distributed lock based on etcd lease, the client can be created by pkg/etcdcli.
+type EtcdLocker struct {
+	client *clientv3.Client
+}
+
+// NewEtcdLocker create an etcd locker
+func NewEtcdLocker(client *clientv3.Client) *EtcdLocker {
+	return &EtcdLocker{client: client}
+}
+
+// TryLock put the key with a lease of ttl if not exists, the ttl is rounded up to seconds
+func (l *EtcdLocker) TryLock(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	seconds := int64((ttl + time.Second - 1) / time.Second)
+	lease, err := l.client.Grant(ctx, seconds)
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := l.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).
+		Then(clientv3.OpPut(key, "", clientv3.WithLease(lease.ID))).
+		Commit()
+	if err != nil || !resp.Succeeded {
+		_, _ = l.client.Revoke(context.Background(), lease.ID)
+		return false, err
+	}
+	return true, nil
+}
+
+// -------------------------------------------------------------------------------------------
+
+// renew the leadership if the value of the key is the id of the instance
+var renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+// release the leadership if the value of the key is the id of the instance
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// RedisElector leader election based on redis, the leader holds the key and renews it every ttl/3,
+// if the leader crashes, another instance becomes the leader after ttl.
+type RedisElector struct {
+	client redis.UniversalClient
+	key    string
+	id     string
+	ttl    time.Duration
+
+	isLeader  atomic.Bool
+	exit      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewRedisElector create a redis elector and start campaigning, the instances use the same key
+func NewRedisElector(client redis.UniversalClient, key string, ttl time.Duration) *RedisElector {
+	if ttl <= 0 {
+		ttl = time.Second * 15
+	}
+	e := &RedisElector{
+		client: client,
+		key:    key,
+		id:     krand.String(krand.R_All, 16),
+		ttl:    ttl,
+		exit:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+func (e *RedisElector) run() {
+	defer close(e.done)
+	ticker := time.NewTicker(e.ttl / 3)
+	defer ticker.Stop()
+	for {
+		e.campaign()
+		select {
+		case <-e.exit:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *RedisElector) campaign() {
+	ctx, cancel := context.WithTimeout(context.Background(), e.ttl/3)
+	defer cancel()
+
+	if e.isLeader.Load() {
+		n, err := renewScript.Run(ctx, e.client, []string{e.key}, e.id, e.ttl.Milliseconds()).Int()
+		if err == nil && n == 1 {
+			return
+		}
+		e.isLeader.Store(false)
+		getLogger().Warn("cron_lose leadership", zap.String("key", e.key))
+	}
+
+	ok, err := e.client.SetNX(ctx, e.key, e.id, e.ttl).Result()
+	if err == nil && ok {
+		e.isLeader.Store(true)
+		getLogger().Info("cron_become leader", zap.String("key", e.key))
+	}
+}
+
+// IsLeader whether the instance is the leader
+func (e *RedisElector) IsLeader() bool {
+	return e.isLeader.Load()
+}
+
+// Close stop campaigning and release the leadership
+func (e *RedisElector) Close() {
+	e.closeOnce.Do(func() {
+		close(e.exit)
+		<-e.done
+		if e.isLeader.Load() {
+			e.isLeader.Store(false)
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+			defer cancel()
+			_ = releaseScript.Run(ctx, e.client, []string{e.key}, e.id).Err()
+		}
+	})
+}
+
+// EtcdElector leader election based on etcd election, if the session of the leader expires,
+// another instance becomes the leader.
+type EtcdElector struct {
+	client *clientv3.Client
+	key    string
+	ttl    int
+
+	mu       sync.Mutex
+	election *concurrency.Election
+
+	isLeader atomic.Bool
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// NewEtcdElector create an etcd elector and start campaigning, the instances use the same key prefix,
+// ttl is the ttl of the session in seconds.
+func NewEtcdElector(client *clientv3.Client, key string, ttl int) *EtcdElector {
+	if ttl <= 0 {
+		ttl = 15
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	e := &EtcdElector{
+		client: client,
+		key:    key,
+		ttl:    ttl,
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+func (e *EtcdElector) run() {
+	defer close(e.done)
+	for {
+		if err := e.campaign(); err != nil && e.ctx.Err() == nil {
+			getLogger().Warn("cron_campaign error", zap.String("key", e.key), zap.Error(err))
+		}
+
+		select {
+		case <-e.ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// campaign until becoming the leader, then wait until the session expires
+func (e *EtcdElector) campaign() error {
+	session, err := concurrency.NewSession(e.client, concurrency.WithTTL(e.ttl), concurrency.WithContext(e.ctx))
+	if err != nil {
+		return err
+	}
+	defer session.Close() //nolint
+
+	election := concurrency.NewElection(session, e.key)
+	if err = election.Campaign(e.ctx, krand.String(krand.R_All, 16)); err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	e.election = election
+	e.mu.Unlock()
+	e.isLeader.Store(true)
+	getLogger().Info("cron_become leader", zap.String("key", e.key))
+
+	select {
+	case <-e.ctx.Done():
+	case <-session.Done():
+		getLogger().Warn("cron_lose leadership", zap.String("key", e.key))
+	}
+	e.isLeader.Store(false)
+	return nil
+}
+
+// IsLeader whether the instance is the leader
+func (e *EtcdElector) IsLeader() bool {
+	return e.isLeader.Load()
+}
+
+// Close stop campaigning and resign the leadership
+func (e *EtcdElector) Close() {
+	e.mu.Lock()
+	election := e.election
+	e.mu.Unlock()
+	if election != nil && e.isLeader.Load() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+		_ = election.Resign(ctx)
+		cancel()
+	}
+	e.cancel()
+	<-e.done
+}
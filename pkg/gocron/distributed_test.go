@@ -0,0 +1,173 @@
+package gocron
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/zhufuyi/sponge/pkg/etcdcli"
+)
+
+type fakeElector struct {
+	isLeader bool
+}
+
+func (e *fakeElector) IsLeader() bool { return e.isLeader }
+
+func newRedisClient(t *testing.T) (*redis.Client, *miniredis.Miniredis) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return redis.NewClient(&redis.Options{Addr: mr.Addr()}), mr
+}
+
+func TestRedisLocker(t *testing.T) {
+	client, mr := newRedisClient(t)
+	defer mr.Close()
+	defer client.Close()
+
+	l1, l2 := NewRedisLocker(client), NewRedisLocker(client)
+	ok, err := l1.TryLock(context.Background(), "gocron:task1:1", time.Second)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = l2.TryLock(context.Background(), "gocron:task1:1", time.Second)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// expired
+	mr.FastForward(time.Second * 2)
+	ok, err = l2.TryLock(context.Background(), "gocron:task1:1", time.Second)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestRunGuard(t *testing.T) {
+	setLogger(zap.NewNop())
+	client, mr := newRedisClient(t)
+	defer mr.Close()
+	defer client.Close()
+
+	// the instances run the same task at the same time, only one of them runs it
+	var count int32
+	task := &Task{Name: "task1", Timeout: time.Second}
+	fn := func() { atomic.AddInt32(&count, 1) }
+	scheduled := time.Now().Truncate(time.Second)
+	scheduledTime := func() time.Time { return scheduled }
+	g1 := &runGuard{locker: NewRedisLocker(client), keyPrefix: defaultLockKeyPrefix}
+	g2 := &runGuard{locker: NewRedisLocker(client), keyPrefix: defaultLockKeyPrefix}
+	run1, run2 := g1.wrap(task, scheduledTime, fn), g2.wrap(task, scheduledTime, fn)
+	run1()
+	time.Sleep(time.Millisecond * 1100) // the run of the second instance starts late
+	run2()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+
+	// the next run
+	scheduled = scheduled.Add(time.Second)
+	run2()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&count))
+
+	// lock error
+	mr.SetError("server error")
+	scheduled = scheduled.Add(time.Second)
+	run1()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&count))
+	mr.SetError("")
+
+	// leader only
+	elector := &fakeElector{}
+	g := &runGuard{elector: elector}
+	run := g.wrap(task, scheduledTime, fn)
+	run()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&count))
+	elector.isLeader = true
+	run()
+	assert.Equal(t, int32(3), atomic.LoadInt32(&count))
+
+	// no guard
+	g = &runGuard{}
+	g.wrap(task, scheduledTime, fn)()
+	assert.Equal(t, int32(4), atomic.LoadInt32(&count))
+}
+
+func TestRedisElector(t *testing.T) {
+	setLogger(zap.NewNop())
+	client, mr := newRedisClient(t)
+	defer mr.Close()
+	defer client.Close()
+
+	e1 := NewRedisElector(client, "gocron:leader", time.Millisecond*300)
+	time.Sleep(time.Millisecond * 50)
+	e2 := NewRedisElector(client, "gocron:leader", time.Millisecond*300)
+	defer e2.Close()
+	time.Sleep(time.Millisecond * 50)
+	assert.True(t, e1.IsLeader())
+	assert.False(t, e2.IsLeader())
+
+	// the leadership is renewed
+	time.Sleep(time.Millisecond * 250)
+	assert.True(t, e1.IsLeader())
+	assert.False(t, e2.IsLeader())
+
+	// the leadership is released, another instance becomes the leader
+	e1.Close()
+	assert.False(t, e1.IsLeader())
+	time.Sleep(time.Millisecond * 200)
+	assert.True(t, e2.IsLeader())
+
+	// lose the leadership when the key is taken by others
+	mr.Set("gocron:leader", "other")
+	time.Sleep(time.Millisecond * 200)
+	assert.False(t, e2.IsLeader())
+}
+
+func TestInitWithDistributedLock(t *testing.T) {
+	client, mr := newRedisClient(t)
+	defer mr.Close()
+	defer client.Close()
+
+	err := Init(WithLog(zap.NewNop()), WithDistributedLock(NewRedisLocker(client)), WithLockKeyPrefix("test:"))
+	assert.NoError(t, err)
+	defer Stop()
+
+	var count int32
+	err = Run(&Task{Name: "distributed-task", TimeSpec: "* * * * * *", Fn: func() { atomic.AddInt32(&count, 1) }})
+	assert.NoError(t, err)
+	defer DeleteTask("distributed-task")
+	time.Sleep(time.Millisecond * 1500)
+	assert.True(t, atomic.LoadInt32(&count) >= 1)
+	assert.NotEmpty(t, mr.Keys())
+	assert.Contains(t, mr.Keys()[0], "test:distributed-task:")
+
+	err = Run(&Task{Name: "nil-task", TimeSpec: "* * * * * *"})
+	assert.Error(t, err)
+}
+
+func TestEtcd(t *testing.T) {
+	client, err := etcdcli.Init([]string{"127.0.0.1:2379"}, etcdcli.WithDialTimeout(time.Second), etcdcli.WithLog(zap.NewNop()))
+	if err != nil {
+		t.Log(err)
+		return
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	ok, err := NewEtcdLocker(client).TryLock(ctx, "gocron:etcd-task:1", time.Second)
+	if err != nil {
+		t.Log(err)
+		return
+	}
+	t.Log(ok)
+
+	e := NewEtcdElector(client, "gocron-leader", 5)
+	time.Sleep(time.Millisecond * 500)
+	t.Log(e.IsLeader())
+	e.Close()
+}
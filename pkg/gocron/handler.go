@@ -0,0 +1,84 @@
+package gocron
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/zhufuyi/sponge/pkg/errcode"
+	"github.com/zhufuyi/sponge/pkg/gin/response"
+)
+
+// RegisterRoutes register the task management api to the gin router, the router is usually
+// a group with authorization, e.g. r.Group("/admin/cron", middleware.Auth()).
+//
+//	GET    /tasks                list the tasks
+//	GET    /tasks/:name/records  list the latest execution records of the task
+//	POST   /tasks/:name/trigger  run the task immediately
+//	POST   /tasks/:name/pause    pause the scheduled runs of the task
+//	POST   /tasks/:name/resume   resume the scheduled runs of the task
+//	DELETE /tasks/:name          delete the task
+func RegisterRoutes(r gin.IRouter) {
+	registerRoutes(r, Default)
+}
+
+// RegisterRoutes register the task management api of the scheduler to the gin router, see the
+// package-level RegisterRoutes for the api.
+func (s *Scheduler) RegisterRoutes(r gin.IRouter) {
+	registerRoutes(r, func() *Scheduler { return s })
+}
+
+func registerRoutes(r gin.IRouter, getScheduler func() *Scheduler) {
+	h := &handler{getScheduler: getScheduler}
+	r.GET("/tasks", h.listTasks)
+	r.GET("/tasks/:name/records", h.listTaskRecords)
+	r.POST("/tasks/:name/trigger", h.action((*Scheduler).TriggerTask))
+	r.POST("/tasks/:name/pause", h.action((*Scheduler).PauseTask))
+	r.POST("/tasks/:name/resume", h.action((*Scheduler).ResumeTask))
+	r.DELETE("/tasks/:name", h.action(func(s *Scheduler, name string) error {
+		if _, err := s.getEntry(name); err != nil {
+			return err
+		}
+		s.DeleteTask(name)
+		return nil
+	}))
+}
+
+type handler struct {
+	getScheduler func() *Scheduler
+}
+
+func (h *handler) listTasks(c *gin.Context) {
+	var tasks []*TaskInfo
+	if s := h.getScheduler(); s != nil {
+		tasks = s.GetTasks()
+	}
+	response.Success(c, gin.H{"tasks": tasks})
+}
+
+func (h *handler) listTaskRecords(c *gin.Context) {
+	s := h.getScheduler()
+	if s == nil {
+		response.Error(c, errcode.NotFound)
+		return
+	}
+	records, err := s.GetTaskRecords(c.Param("name"))
+	if err != nil {
+		response.Error(c, errcode.NotFound)
+		return
+	}
+	response.Success(c, gin.H{"records": records})
+}
+
+func (h *handler) action(fn func(s *Scheduler, name string) error) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		s := h.getScheduler()
+		if s == nil {
+			response.Error(c, errcode.NotFound)
+			return
+		}
+		if err := fn(s, c.Param("name")); err != nil {
+			response.Error(c, errcode.NotFound)
+			return
+		}
+		response.Success(c)
+	}
+}
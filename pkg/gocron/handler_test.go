@@ -0,0 +1,64 @@
+package gocron
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestRegisterRoutes(t *testing.T) {
+	s := NewScheduler(WithLog(zap.NewNop()))
+	defer s.Stop()
+	err := s.Run(&Task{Name: "api-task", TimeSpec: "@every 1h", Fn: func() {}})
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	s.RegisterRoutes(r.Group("/admin/cron"))
+	RegisterRoutes(r.Group("/default/cron"))
+
+	request := func(method string, path string) map[string]interface{} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		result := map[string]interface{}{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		return result
+	}
+
+	result := request(http.MethodGet, "/admin/cron/tasks")
+	assert.Equal(t, float64(0), result["code"])
+	assert.Len(t, result["data"].(map[string]interface{})["tasks"], 1)
+
+	for _, action := range []string{"pause", "resume", "trigger"} {
+		result = request(http.MethodPost, "/admin/cron/tasks/api-task/"+action)
+		assert.Equal(t, float64(0), result["code"])
+		result = request(http.MethodPost, "/admin/cron/tasks/not-found/"+action)
+		assert.NotEqual(t, float64(0), result["code"])
+	}
+
+	result = request(http.MethodGet, "/admin/cron/tasks/api-task/records")
+	assert.Equal(t, float64(0), result["code"])
+	result = request(http.MethodGet, "/admin/cron/tasks/not-found/records")
+	assert.NotEqual(t, float64(0), result["code"])
+
+	result = request(http.MethodDelete, "/admin/cron/tasks/api-task")
+	assert.Equal(t, float64(0), result["code"])
+	assert.False(t, s.IsRunningTask("api-task"))
+	result = request(http.MethodDelete, "/admin/cron/tasks/api-task")
+	assert.NotEqual(t, float64(0), result["code"])
+
+	// the default scheduler
+	defaultScheduler.Store(nil)
+	result = request(http.MethodGet, "/default/cron/tasks")
+	assert.Equal(t, float64(0), result["code"])
+	result = request(http.MethodPost, "/default/cron/tasks/api-task/trigger")
+	assert.NotEqual(t, float64(0), result["code"])
+	result = request(http.MethodGet, "/default/cron/tasks/api-task/records")
+	assert.NotEqual(t, float64(0), result["code"])
+}
@@ -1,19 +1,49 @@
 package gocron
 
 import (
+	"sync"
+	"sync/atomic"
+	"time"
+
 	"github.com/robfig/cron/v3"
 	"go.uber.org/zap"
 )
 
+const defaultStopTimeout = time.Second * 30
+
 var defaultLog, _ = zap.NewProduction()
 
+// the logger is also used by the electors running in background
+var currentLog atomic.Pointer[zap.Logger]
+
+func getLogger() *zap.Logger {
+	if l := currentLog.Load(); l != nil {
+		return l
+	}
+	return defaultLog
+}
+
+func setLogger(l *zap.Logger) {
+	currentLog.Store(l)
+}
+
 type options struct {
 	zapLog *zap.Logger
+
+	locker        Locker
+	elector       Elector
+	lockKeyPrefix string
+
+	historySize int
+	stopTimeout time.Duration
 }
 
 func defaultOptions() *options {
 	return &options{
-		zapLog: defaultLog,
+		zapLog:        defaultLog,
+		lockKeyPrefix: defaultLockKeyPrefix,
+		historySize:   defaultHistorySize,
+		stopTimeout:   defaultStopTimeout,
 	}
 }
 
@@ -33,8 +63,50 @@ func WithLog(log *zap.Logger) Option {
 	}
 }
 
+// WithDistributedLock set the distributed lock, each run of the task is locked, only one instance runs it,
+// the lock is held for the timeout of the task. the clocks of the instances should be synchronized, and the
+// time spec should be aligned to the clock, e.g. "0 */5 * * * *" rather than "@every 5m".
+func WithDistributedLock(locker Locker) Option {
+	return func(o *options) {
+		o.locker = locker
+	}
+}
+
+// WithLeaderElection set the leader election, the tasks run only on the leader instance.
+func WithLeaderElection(elector Elector) Option {
+	return func(o *options) {
+		o.elector = elector
+	}
+}
+
+// WithLockKeyPrefix set the prefix of the lock keys, default "gocron:".
+func WithLockKeyPrefix(prefix string) Option {
+	return func(o *options) {
+		o.lockKeyPrefix = prefix
+	}
+}
+
+// WithHistorySize set the number of execution records kept for each task, default 20.
+func WithHistorySize(size int) Option {
+	return func(o *options) {
+		if size > 0 {
+			o.historySize = size
+		}
+	}
+}
+
+// WithStopTimeout set the max time to wait for the runs in progress when stopping, default 30 seconds.
+func WithStopTimeout(d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.stopTimeout = d
+		}
+	}
+}
+
 type zapLog struct {
 	zapLog *zap.Logger
+	idName *sync.Map
 }
 
 // Info print info
@@ -43,19 +115,19 @@ func (l *zapLog) Info(msg string, keysAndValues ...interface{}) {
 		return
 	}
 	msg = "cron_" + msg
-	fields := parseKVs(keysAndValues)
+	fields := parseKVs(keysAndValues, l.idName)
 	l.zapLog.Info(msg, fields...)
 }
 
 // Error print error
 func (l *zapLog) Error(err error, msg string, keysAndValues ...interface{}) {
-	fields := parseKVs(keysAndValues)
+	fields := parseKVs(keysAndValues, l.idName)
 	fields = append(fields, zap.String("err", err.Error()))
 	msg = "cron_" + msg
 	l.zapLog.Error(msg, fields...)
 }
 
-func parseKVs(kvs interface{}) []zap.Field {
+func parseKVs(kvs interface{}, idName *sync.Map) []zap.Field {
 	var fields []zap.Field
 
 	infos, ok := kvs.([]interface{})
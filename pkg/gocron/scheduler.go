@@ -0,0 +1,239 @@
+package gocron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+)
+
+// Scheduler scheduled task manager, the schedulers are independent of each other.
+type Scheduler struct {
+	c   *cron.Cron
+	log *zap.Logger
+
+	// task name and id mapping, used to add, delete, modify and query tasks
+	nameID sync.Map
+	// id and task name mapping, used in log printing
+	idName sync.Map
+	// task name and runtime state mapping, used in task management
+	entries sync.Map
+
+	guard       *runGuard
+	historySize int
+	stopTimeout time.Duration
+
+	// the ctx of the running tasks, canceled if the tasks are not finished before the stop timeout
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu        sync.Mutex
+	isStopped bool
+	wg        sync.WaitGroup // in-flight runs
+}
+
+// NewScheduler create a scheduler and start it
+func NewScheduler(opts ...Option) *Scheduler {
+	o := defaultOptions()
+	o.apply(opts...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Scheduler{
+		log: o.zapLog,
+		guard: &runGuard{
+			locker:    o.locker,
+			elector:   o.elector,
+			keyPrefix: o.lockKeyPrefix,
+			log:       o.zapLog,
+		},
+		historySize: o.historySize,
+		stopTimeout: o.stopTimeout,
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+
+	log := &zapLog{zapLog: o.zapLog, idName: &s.idName}
+	cronOpts := []cron.Option{
+		cron.WithSeconds(), // second-level granularity, default is minute-level granularity
+		cron.WithLogger(log),
+		cron.WithChain(
+			cron.Recover(log),
+		),
+	}
+
+	s.c = cron.New(cronOpts...)
+	s.c.Start()
+
+	return s
+}
+
+// Run the tasks
+func (s *Scheduler) Run(tasks ...*Task) error {
+	var errs []string
+	for _, task := range tasks {
+		if s.IsRunningTask(task.Name) {
+			errs = append(errs, fmt.Sprintf("task '%s' is already exists", task.Name))
+			continue
+		}
+
+		if task.Fn == nil && task.ContextFn == nil {
+			errs = append(errs, fmt.Sprintf("task '%s' is nil", task.Name))
+			continue
+		}
+
+		entry := newTaskEntry(s, task)
+		id, err := s.c.AddFunc(task.TimeSpec, entry.job())
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("run task '%s' error: %v", task.Name, err))
+			continue
+		}
+		s.entries.Store(task.Name, entry)
+		s.idName.Store(id, task.Name)
+		s.nameID.Store(task.Name, id)
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, " || "))
+	}
+
+	return nil
+}
+
+// IsRunningTask determine if the task is running
+func (s *Scheduler) IsRunningTask(name string) bool {
+	_, ok := s.nameID.Load(name)
+	return ok
+}
+
+// GetRunningTasks gets a list of running task names
+func (s *Scheduler) GetRunningTasks() []string {
+	var names []string
+	s.nameID.Range(func(key, value interface{}) bool {
+		names = append(names, key.(string))
+		return true
+	})
+	return names
+}
+
+// DeleteTask stop and delete the specified task, the run in progress is not interrupted
+func (s *Scheduler) DeleteTask(name string) {
+	if id, ok := s.nameID.Load(name); ok {
+		entryID, isOk := id.(cron.EntryID)
+		if !isOk {
+			return
+		}
+		s.c.Remove(entryID)
+		s.nameID.Delete(name)
+		s.idName.Delete(entryID)
+		s.entries.Delete(name)
+	}
+}
+
+func (s *Scheduler) getEntry(name string) (*taskEntry, error) {
+	v, ok := s.entries.Load(name)
+	if !ok {
+		return nil, ErrTaskNotFound
+	}
+	return v.(*taskEntry), nil
+}
+
+// GetTasks gets the runtime information of the tasks
+func (s *Scheduler) GetTasks() []*TaskInfo {
+	var infos []*TaskInfo
+	s.entries.Range(func(key, value interface{}) bool {
+		infos = append(infos, value.(*taskEntry).info())
+		return true
+	})
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// GetTaskRecords gets the latest execution records of the task, newest first
+func (s *Scheduler) GetTaskRecords(name string) ([]*Record, error) {
+	entry, err := s.getEntry(name)
+	if err != nil {
+		return nil, err
+	}
+	return entry.history.list(), nil
+}
+
+// TriggerTask run the task immediately in background, the concurrency policy is applied,
+// the distributed lock and leader election are not.
+func (s *Scheduler) TriggerTask(name string) error {
+	entry, err := s.getEntry(name)
+	if err != nil {
+		return err
+	}
+	go entry.execute(TriggerManual)
+	return nil
+}
+
+// PauseTask pause the scheduled runs of the task, it can still be triggered manually
+func (s *Scheduler) PauseTask(name string) error {
+	entry, err := s.getEntry(name)
+	if err != nil {
+		return err
+	}
+	entry.isPaused.Store(true)
+	return nil
+}
+
+// ResumeTask resume the scheduled runs of the task
+func (s *Scheduler) ResumeTask(name string) error {
+	entry, err := s.getEntry(name)
+	if err != nil {
+		return err
+	}
+	entry.isPaused.Store(false)
+	return nil
+}
+
+// begin a run, return false if the scheduler is stopped
+func (s *Scheduler) begin() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.isStopped {
+		return false
+	}
+	s.wg.Add(1)
+	return true
+}
+
+func (s *Scheduler) end() {
+	s.wg.Done()
+}
+
+// Stop scheduling the tasks and wait for the runs in progress to finish, if they are not finished
+// within the stop timeout, their ctx are canceled and an error is returned. it can be added to the
+// closes of app.App.
+func (s *Scheduler) Stop() error {
+	s.mu.Lock()
+	if s.isStopped {
+		s.mu.Unlock()
+		return nil
+	}
+	s.isStopped = true
+	s.mu.Unlock()
+
+	cronCtx := s.c.Stop()
+	done := make(chan struct{})
+	go func() {
+		<-cronCtx.Done()
+		s.wg.Wait()
+		close(done)
+	}()
+
+	defer s.cancel()
+	select {
+	case <-done:
+		return nil
+	case <-time.After(s.stopTimeout):
+		return fmt.Errorf("stop timeout, the tasks are still running after %s", s.stopTimeout)
+	}
+}
@@ -0,0 +1,98 @@
+package gocron
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestScheduler(t *testing.T) {
+	s1 := NewScheduler(WithLog(zap.NewNop()))
+	defer s1.Stop()
+	s2 := NewScheduler(WithLog(zap.NewNop()))
+	defer s2.Stop()
+
+	// the schedulers are independent
+	var count1, count2 int32
+	assert.NoError(t, s1.Run(&Task{Name: "task", TimeSpec: "* * * * * *", Fn: func() { atomic.AddInt32(&count1, 1) }}))
+	assert.NoError(t, s2.Run(&Task{Name: "task", TimeSpec: "* * * * * *", Fn: func() { atomic.AddInt32(&count2, 1) }}))
+	assert.Error(t, s1.Run(&Task{Name: "task", TimeSpec: "* * * * * *", Fn: func() {}}))
+	assert.Error(t, s1.Run(&Task{Name: "invalid", TimeSpec: "invalid", Fn: func() {}}))
+	assert.Equal(t, []string{"task"}, s1.GetRunningTasks())
+
+	s1.DeleteTask("task")
+	assert.False(t, s1.IsRunningTask("task"))
+	assert.True(t, s2.IsRunningTask("task"))
+	time.Sleep(time.Millisecond * 1100)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&count1))
+	assert.True(t, atomic.LoadInt32(&count2) >= 1)
+}
+
+func TestScheduler_Stop(t *testing.T) {
+	// wait for the runs in progress
+	s := NewScheduler(WithLog(zap.NewNop()))
+	var isDone int32
+	assert.NoError(t, s.Run(&Task{Name: "task", TimeSpec: "@every 1h", Fn: func() {
+		time.Sleep(time.Millisecond * 100)
+		atomic.StoreInt32(&isDone, 1)
+	}}))
+	assert.NoError(t, s.TriggerTask("task"))
+	time.Sleep(time.Millisecond * 10)
+	assert.NoError(t, s.Stop())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&isDone))
+	assert.NoError(t, s.Stop())
+
+	// no more runs after stopping
+	assert.NoError(t, s.TriggerTask("task"))
+	time.Sleep(time.Millisecond * 10)
+	records, _ := s.GetTaskRecords("task")
+	assert.Len(t, records, 1)
+
+	// the ctx of the runs in progress is canceled after the stop timeout
+	s = NewScheduler(WithLog(zap.NewNop()), WithStopTimeout(time.Millisecond*50))
+	canceled := make(chan struct{})
+	assert.NoError(t, s.Run(&Task{Name: "task", TimeSpec: "@every 1h", ContextFn: func(ctx context.Context) error {
+		<-ctx.Done()
+		close(canceled)
+		return ctx.Err()
+	}}))
+	assert.NoError(t, s.TriggerTask("task"))
+	time.Sleep(time.Millisecond * 10)
+	assert.Error(t, s.Stop())
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("the ctx is not canceled")
+	}
+}
+
+func TestDefaultScheduler(t *testing.T) {
+	defaultScheduler.Store(nil)
+	assert.Nil(t, Default())
+	assert.Error(t, Run(&Task{Name: "task", TimeSpec: "@every 1h", Fn: func() {}}))
+	assert.False(t, IsRunningTask("task"))
+	assert.Empty(t, GetRunningTasks())
+	assert.Empty(t, GetTasks())
+	assert.ErrorIs(t, TriggerTask("task"), ErrTaskNotFound)
+	assert.ErrorIs(t, PauseTask("task"), ErrTaskNotFound)
+	assert.ErrorIs(t, ResumeTask("task"), ErrTaskNotFound)
+	_, err := GetTaskRecords("task")
+	assert.ErrorIs(t, err, ErrTaskNotFound)
+	DeleteTask("task")
+	assert.NoError(t, Stop())
+
+	assert.NoError(t, Init(WithLog(zap.NewNop())))
+	defer Stop()
+	assert.NotNil(t, Default())
+	assert.NoError(t, Run(&Task{Name: "task", TimeSpec: "@every 1h", Fn: func() {}}))
+	assert.True(t, IsRunningTask("task"))
+	assert.NoError(t, PauseTask("task"))
+	assert.NoError(t, ResumeTask("task"))
+	assert.NoError(t, TriggerTask("task"))
+	DeleteTask("task")
+	assert.False(t, IsRunningTask("task"))
+}
@@ -0,0 +1,265 @@
+package gocron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+)
+
+const defaultHistorySize = 20
+
+// ErrTaskNotFound the task does not exist
+var ErrTaskNotFound = errors.New("task not found")
+
+// ConcurrencyPolicy what to do when the task is triggered while the previous run is still running
+type ConcurrencyPolicy int
+
+const (
+	// AllowOverlap run the task concurrently, default
+	AllowOverlap ConcurrencyPolicy = iota
+	// SkipIfRunning skip this run if the previous run is still running
+	SkipIfRunning
+	// Queue wait for the previous run to finish, then run
+	Queue
+)
+
+// String policy name
+func (p ConcurrencyPolicy) String() string {
+	switch p {
+	case SkipIfRunning:
+		return "skip"
+	case Queue:
+		return "queue"
+	default:
+		return "overlap"
+	}
+}
+
+// the trigger of a run
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+// the status of a run
+const (
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+	StatusTimeout = "timeout"
+	StatusPanic   = "panic"
+	StatusSkipped = "skipped"
+)
+
+// Record execution record of the task
+type Record struct {
+	Name      string        `json:"name"`
+	Trigger   string        `json:"trigger"` // schedule or manual
+	Status    string        `json:"status"`  // success, failed, timeout, panic or skipped
+	StartTime time.Time     `json:"startTime"`
+	EndTime   time.Time     `json:"endTime"`
+	Duration  time.Duration `json:"duration"`
+	Error     string        `json:"error,omitempty"` // error or panic message
+}
+
+// TaskInfo runtime information of the task
+type TaskInfo struct {
+	Name      string        `json:"name"`
+	TimeSpec  string        `json:"timeSpec"`
+	Policy    string        `json:"policy"`
+	Timeout   time.Duration `json:"timeout"`
+	IsRunOnce bool          `json:"isRunOnce"`
+	IsPaused  bool          `json:"isPaused"`
+	Running   int32         `json:"running"` // number of runs in progress
+	Prev      time.Time     `json:"prev"`
+	Next      time.Time     `json:"next"`
+	Last      *Record       `json:"last,omitempty"`
+}
+
+// history keeps the latest records in a ring buffer
+type history struct {
+	mu      sync.Mutex
+	records []*Record
+	next    int
+	isFull  bool
+}
+
+func newHistory(size int) *history {
+	if size <= 0 {
+		size = defaultHistorySize
+	}
+	return &history{records: make([]*Record, size)}
+}
+
+func (h *history) add(r *Record) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records[h.next] = r
+	h.next = (h.next + 1) % len(h.records)
+	if h.next == 0 {
+		h.isFull = true
+	}
+}
+
+// list the records, newest first
+func (h *history) list() []*Record {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n := h.next
+	if h.isFull {
+		n = len(h.records)
+	}
+	records := make([]*Record, 0, n)
+	for i := 1; i <= n; i++ {
+		records = append(records, h.records[(h.next-i+len(h.records))%len(h.records)])
+	}
+	return records
+}
+
+func (h *history) last() *Record {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.isFull && h.next == 0 {
+		return nil
+	}
+	return h.records[(h.next-1+len(h.records))%len(h.records)]
+}
+
+// -------------------------------------------------------------------------------------------
+
+// taskEntry runtime state of the task
+type taskEntry struct {
+	s        *Scheduler
+	task     *Task
+	history  *history
+	isPaused atomic.Bool
+	running  int32
+	queueMu  sync.Mutex // used by Queue policy
+}
+
+func newTaskEntry(s *Scheduler, task *Task) *taskEntry {
+	return &taskEntry{
+		s:       s,
+		task:    task,
+		history: newHistory(s.historySize),
+	}
+}
+
+// job scheduled by cron
+func (e *taskEntry) job() func() {
+	run := e.s.guard.wrap(e.task, e.scheduledTime, func() { e.execute(TriggerSchedule) })
+	return func() {
+		if e.isPaused.Load() {
+			e.s.log.Info("cron_skip run", zap.String("task", e.task.Name), zap.String("reason", "paused"))
+			return
+		}
+		run()
+		if e.task.IsRunOnce {
+			e.s.DeleteTask(e.task.Name)
+		}
+	}
+}
+
+// the time the current run is scheduled for, robfig/cron sets Prev of the entry to it before starting the job
+func (e *taskEntry) scheduledTime() time.Time {
+	if id, ok := e.s.nameID.Load(e.task.Name); ok {
+		if prev := e.s.c.Entry(id.(cron.EntryID)).Prev; !prev.IsZero() {
+			return prev
+		}
+	}
+	return time.Now()
+}
+
+// execute the task according to the concurrency policy, and record the result
+func (e *taskEntry) execute(trigger string) {
+	if !e.s.begin() {
+		return
+	}
+	defer e.s.end()
+
+	switch e.task.Policy {
+	case SkipIfRunning:
+		if !atomic.CompareAndSwapInt32(&e.running, 0, 1) {
+			now := time.Now()
+			e.history.add(&Record{Name: e.task.Name, Trigger: trigger, Status: StatusSkipped, StartTime: now, EndTime: now,
+				Error: "the previous run is still running"})
+			e.s.log.Info("cron_skip run", zap.String("task", e.task.Name), zap.String("reason", "still running"))
+			return
+		}
+	case Queue:
+		e.queueMu.Lock()
+		defer e.queueMu.Unlock()
+		atomic.AddInt32(&e.running, 1)
+	default:
+		atomic.AddInt32(&e.running, 1)
+	}
+	defer atomic.AddInt32(&e.running, -1)
+
+	ctx := e.s.ctx
+	if e.task.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.task.Timeout)
+		defer cancel()
+	}
+
+	r := &Record{Name: e.task.Name, Trigger: trigger, StartTime: time.Now()}
+	err, isPanic := e.call(ctx)
+	r.EndTime = time.Now()
+	r.Duration = r.EndTime.Sub(r.StartTime)
+	switch {
+	case isPanic:
+		r.Status = StatusPanic
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		r.Status = StatusTimeout
+		if err == nil {
+			err = fmt.Errorf("exceeded the timeout %s", e.task.Timeout)
+		}
+	case err != nil:
+		r.Status = StatusFailed
+	default:
+		r.Status = StatusSuccess
+	}
+	if err != nil {
+		r.Error = err.Error()
+		e.s.log.Error("cron_run error", zap.String("task", e.task.Name), zap.String("status", r.Status),
+			zap.Duration("duration", r.Duration), zap.Error(err))
+	}
+	e.history.add(r)
+}
+
+func (e *taskEntry) call(ctx context.Context) (err error, isPanic bool) { //nolint
+	defer func() {
+		if v := recover(); v != nil {
+			err, isPanic = fmt.Errorf("panic: %v", v), true
+		}
+	}()
+
+	if e.task.ContextFn != nil {
+		return e.task.ContextFn(ctx), false
+	}
+	e.task.Fn()
+	return nil, false
+}
+
+func (e *taskEntry) info() *TaskInfo {
+	info := &TaskInfo{
+		Name:      e.task.Name,
+		TimeSpec:  e.task.TimeSpec,
+		Policy:    e.task.Policy.String(),
+		Timeout:   e.task.Timeout,
+		IsRunOnce: e.task.IsRunOnce,
+		IsPaused:  e.isPaused.Load(),
+		Running:   atomic.LoadInt32(&e.running),
+		Last:      e.history.last(),
+	}
+	if id, ok := e.s.nameID.Load(e.task.Name); ok {
+		entry := e.s.c.Entry(id.(cron.EntryID))
+		info.Prev, info.Next = entry.Prev, entry.Next
+	}
+	return info
+}
@@ -0,0 +1,150 @@
+package gocron
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestHistory(t *testing.T) {
+	h := newHistory(3)
+	assert.Nil(t, h.last())
+	assert.Empty(t, h.list())
+
+	for i := 1; i <= 5; i++ {
+		h.add(&Record{Name: strconv.Itoa(i)})
+	}
+	records := h.list()
+	assert.Len(t, records, 3)
+	assert.Equal(t, "5", records[0].Name)
+	assert.Equal(t, "3", records[2].Name)
+	assert.Equal(t, "5", h.last().Name)
+}
+
+func TestTaskEntry_Execute(t *testing.T) {
+	s := NewScheduler(WithLog(zap.NewNop()))
+	defer s.Stop()
+
+	// error, panic and timeout are recorded
+	e := newTaskEntry(s, &Task{Name: "task", ContextFn: func(ctx context.Context) error { return errors.New("failed") }})
+	e.execute(TriggerSchedule)
+	assert.Equal(t, StatusFailed, e.history.last().Status)
+	assert.Equal(t, "failed", e.history.last().Error)
+
+	e = newTaskEntry(s, &Task{Name: "task", Fn: func() { panic("oops") }})
+	e.execute(TriggerManual)
+	assert.Equal(t, StatusPanic, e.history.last().Status)
+	assert.Equal(t, TriggerManual, e.history.last().Trigger)
+
+	e = newTaskEntry(s, &Task{Name: "task", Timeout: time.Millisecond * 20, ContextFn: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+	e.execute(TriggerSchedule)
+	assert.Equal(t, StatusTimeout, e.history.last().Status)
+	assert.True(t, e.history.last().Duration >= time.Millisecond*20)
+
+	e = newTaskEntry(s, &Task{Name: "task", Timeout: time.Second, Fn: func() {}})
+	e.execute(TriggerSchedule)
+	assert.Equal(t, StatusSuccess, e.history.last().Status)
+}
+
+func TestTaskEntry_Policy(t *testing.T) {
+	s := NewScheduler(WithLog(zap.NewNop()))
+	defer s.Stop()
+
+	runConcurrently := func(policy ConcurrencyPolicy) (*taskEntry, int32) {
+		var current, max int32
+		e := newTaskEntry(s, &Task{Name: "task", Policy: policy, Fn: func() {
+			n := atomic.AddInt32(&current, 1)
+			for {
+				m := atomic.LoadInt32(&max)
+				if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond * 50)
+			atomic.AddInt32(&current, -1)
+		}})
+
+		var wg sync.WaitGroup
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				e.execute(TriggerSchedule)
+			}()
+			time.Sleep(time.Millisecond * 5)
+		}
+		wg.Wait()
+		return e, atomic.LoadInt32(&max)
+	}
+
+	e, max := runConcurrently(AllowOverlap)
+	assert.Equal(t, int32(3), max)
+	assert.Len(t, e.history.list(), 3)
+
+	e, max = runConcurrently(Queue)
+	assert.Equal(t, int32(1), max)
+	assert.Len(t, e.history.list(), 3)
+	assert.Equal(t, StatusSuccess, e.history.last().Status)
+
+	e, max = runConcurrently(SkipIfRunning)
+	assert.Equal(t, int32(1), max)
+	skipped := 0
+	for _, r := range e.history.list() {
+		if r.Status == StatusSkipped {
+			skipped++
+		}
+	}
+	assert.Equal(t, 2, skipped)
+	assert.Equal(t, "skip", SkipIfRunning.String())
+}
+
+func TestTaskManagement(t *testing.T) {
+	s := NewScheduler(WithLog(zap.NewNop()), WithHistorySize(5))
+	defer s.Stop()
+
+	var count int32
+	err := s.Run(&Task{Name: "manage-task", TimeSpec: "@every 1h", ContextFn: func(ctx context.Context) error {
+		atomic.AddInt32(&count, 1)
+		return nil
+	}})
+	assert.NoError(t, err)
+
+	tasks := s.GetTasks()
+	assert.Len(t, tasks, 1)
+	info := tasks[0]
+	assert.Equal(t, "overlap", info.Policy)
+	assert.False(t, info.Next.IsZero())
+
+	assert.NoError(t, s.TriggerTask("manage-task"))
+	time.Sleep(time.Millisecond * 50)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+	records, err := s.GetTaskRecords("manage-task")
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+
+	// the paused task skips the scheduled runs
+	assert.NoError(t, s.PauseTask("manage-task"))
+	assert.True(t, s.GetTasks()[0].IsPaused)
+	v, _ := s.entries.Load("manage-task")
+	v.(*taskEntry).job()()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+	assert.NoError(t, s.ResumeTask("manage-task"))
+	v.(*taskEntry).job()()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&count))
+
+	assert.ErrorIs(t, s.TriggerTask("not-found"), ErrTaskNotFound)
+	assert.ErrorIs(t, s.PauseTask("not-found"), ErrTaskNotFound)
+	assert.ErrorIs(t, s.ResumeTask("not-found"), ErrTaskNotFound)
+	_, err = s.GetTaskRecords("not-found")
+	assert.ErrorIs(t, err, ErrTaskNotFound)
+}
@@ -142,6 +142,11 @@ func unaryClientOptions(o *options) grpc.DialOption {
 		unaryClientInterceptors = append(unaryClientInterceptors, interceptor.UnaryClientTracing())
 	}
 
+	// signature
+	if o.signer != nil {
+		unaryClientInterceptors = append(unaryClientInterceptors, interceptor.UnaryClientSignature(o.signer))
+	}
+
 	// custom unary interceptors
 	unaryClientInterceptors = append(unaryClientInterceptors, o.unaryInterceptors...)
 
@@ -188,6 +193,11 @@ func streamClientOptions(o *options) grpc.DialOption {
 		streamClientInterceptors = append(streamClientInterceptors, interceptor.StreamClientTracing())
 	}
 
+	// signature
+	if o.signer != nil {
+		streamClientInterceptors = append(streamClientInterceptors, interceptor.StreamClientSignature(o.signer))
+	}
+
 	// custom stream interceptors
 	streamClientInterceptors = append(streamClientInterceptors, o.streamInterceptors...)
 
@@ -23,6 +23,7 @@ func TestDial2(t *testing.T) {
 		WithEnableLog(zap.NewNop()),
 		WithEnableMetrics(),
 		WithToken(true, "grpc", "123456"),
+		WithSignature("app1", "secret1"),
 		WithEnableLoadBalance(),
 		WithEnableCircuitBreaker(),
 		WithEnableRetry(),
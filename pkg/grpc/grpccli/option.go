@@ -7,6 +7,7 @@ import (
 	"google.golang.org/grpc"
 
 	"github.com/zhufuyi/sponge/pkg/servicerd/registry"
+	"github.com/zhufuyi/sponge/pkg/signature"
 )
 
 var (
@@ -33,6 +34,9 @@ type options struct {
 	appID       string
 	appKey      string
 
+	// signature setting
+	signer *signature.Signer // if not nil means sign the requests
+
 	// interceptor setting
 	enableLog            bool // whether to turn on the log
 	log                  *zap.Logger
@@ -200,6 +204,13 @@ func WithToken(enable bool, appID string, appKey string) Option {
 	}
 }
 
+// WithSignature sign the requests by HMAC signature with app id and secret
+func WithSignature(appID string, secret string) Option {
+	return func(o *options) {
+		o.signer = signature.NewSigner(appID, secret)
+	}
+}
+
 // WithDialOptions set dial options
 func WithDialOptions(dialOptions ...grpc.DialOption) Option {
 	return func(o *options) {
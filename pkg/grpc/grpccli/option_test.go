@@ -147,3 +147,10 @@ func TestWithSecure(t *testing.T) {
 	o.apply(opt)
 	assert.Equal(t, secureTwoWay, o.secureType)
 }
+
+func TestWithSignature(t *testing.T) {
+	opt := WithSignature("app1", "secret1")
+	o := new(options)
+	o.apply(opt)
+	assert.NotNil(t, o.signer)
+}
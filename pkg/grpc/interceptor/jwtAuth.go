@@ -50,9 +50,10 @@ type AuthOption func(*AuthOptions)
 
 // AuthOptions settings
 type AuthOptions struct {
-	authScheme    string
-	ctxClaimsName string
-	ignoreMethods map[string]struct{}
+	authScheme     string
+	ctxClaimsName  string
+	ignoreMethods  map[string]struct{}
+	isCustomClaims bool
 }
 
 func defaultAuthOptions() *AuthOptions {
@@ -94,6 +95,14 @@ func WithAuthIgnoreMethods(fullMethodNames ...string) AuthOption {
 	}
 }
 
+// WithAuthCustomClaims parse the token as custom claims, the *jwt.CustomClaims is stored in ctx,
+// which is used by the rbac interceptors to get the roles, see JwtVerifyCustom
+func WithAuthCustomClaims() AuthOption {
+	return func(o *AuthOptions) {
+		o.isCustomClaims = true
+	}
+}
+
 // GetAuthorization combining tokens into authentication information
 func GetAuthorization(token string) string {
 	return authScheme + " " + token
@@ -122,6 +131,29 @@ func JwtVerify(ctx context.Context) (context.Context, error) {
 	return newCtx, nil
 }
 
+// JwtVerifyCustom get authorization from context to verify legitimacy, the custom claims are stored in ctx,
+// get value by ctx.Value(interceptor.GetAuthCtxKey()).(*jwt.CustomClaims)
+func JwtVerifyCustom(ctx context.Context) (context.Context, error) {
+	token, err := grpc_auth.AuthFromMD(ctx, authScheme)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := jwt.ParseCustomToken(token)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "%v", err)
+	}
+
+	return context.WithValue(ctx, authCtxClaimsName, claims), nil //nolint
+}
+
+func (o *AuthOptions) verifyFn() func(ctx context.Context) (context.Context, error) {
+	if o.isCustomClaims {
+		return JwtVerifyCustom
+	}
+	return JwtVerify
+}
+
 // UnaryServerJwtAuth jwt unary interceptor
 func UnaryServerJwtAuth(opts ...AuthOption) grpc.UnaryServerInterceptor {
 	o := defaultAuthOptions()
@@ -129,6 +161,7 @@ func UnaryServerJwtAuth(opts ...AuthOption) grpc.UnaryServerInterceptor {
 	authScheme = o.authScheme
 	authCtxClaimsName = o.ctxClaimsName
 	authIgnoreMethods = o.ignoreMethods
+	verify := o.verifyFn()
 
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 		var newCtx context.Context
@@ -137,7 +170,7 @@ func UnaryServerJwtAuth(opts ...AuthOption) grpc.UnaryServerInterceptor {
 		if _, ok := authIgnoreMethods[info.FullMethod]; ok {
 			newCtx = ctx
 		} else {
-			newCtx, err = JwtVerify(ctx)
+			newCtx, err = verify(ctx)
 			if err != nil {
 				return nil, err
 			}
@@ -154,6 +187,7 @@ func StreamServerJwtAuth(opts ...AuthOption) grpc.StreamServerInterceptor {
 	authScheme = o.authScheme
 	authCtxClaimsName = o.ctxClaimsName
 	authIgnoreMethods = o.ignoreMethods
+	verify := o.verifyFn()
 
 	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
 		var newCtx context.Context
@@ -162,7 +196,7 @@ func StreamServerJwtAuth(opts ...AuthOption) grpc.StreamServerInterceptor {
 		if _, ok := authIgnoreMethods[info.FullMethod]; ok {
 			newCtx = stream.Context()
 		} else {
-			newCtx, err = JwtVerify(stream.Context())
+			newCtx, err = verify(stream.Context())
 			if err != nil {
 				return err
 			}
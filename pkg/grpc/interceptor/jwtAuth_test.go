@@ -2,6 +2,9 @@ package interceptor
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -28,6 +31,56 @@ func TestJwtVerify(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestJwtVerify_AsymmetricKey(t *testing.T) {
+	defer jwt.Init()
+	privateKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	key, err := jwt.NewKey("key-1", jwt.ES256, privateKey)
+	assert.NoError(t, err)
+	ks := jwt.NewKeySet(key)
+	jwt.Init(jwt.WithKeySet(ks))
+	token, _ := jwt.GenerateToken("100")
+
+	// verify only, e.g. the keys are loaded by jwt.NewJWKSKeySet
+	jwt.Init(jwt.WithVerifyKeys(ks))
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.MD{"authorization": []string{authScheme + " " + token}})
+	_, err = JwtVerify(ctx)
+	assert.NoError(t, err)
+}
+
+func TestJwtVerify_RevokedToken(t *testing.T) {
+	defer jwt.Init()
+	jwt.Init(jwt.WithRevocationStore(jwt.NewMemoryRevocationStore()))
+	pair, _ := jwt.GenerateTokenPair("100")
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.MD{"authorization": []string{authScheme + " " + pair.AccessToken}})
+	_, err := JwtVerify(ctx)
+	assert.NoError(t, err)
+
+	assert.NoError(t, jwt.RevokeToken(pair.AccessToken))
+	_, err = JwtVerify(ctx)
+	assert.Error(t, err)
+}
+
+func TestJwtVerifyCustom(t *testing.T) {
+	jwt.Init()
+	token, _ := jwt.GenerateCustomToken(jwt.KV{"roles": []string{"admin"}})
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.MD{"authorization": []string{authScheme + " " + token}})
+	newCtx, err := JwtVerifyCustom(ctx)
+	assert.NoError(t, err)
+	claims, ok := newCtx.Value(GetAuthCtxKey()).(*jwt.CustomClaims)
+	assert.True(t, ok)
+	roles, _ := claims.Get("roles")
+	assert.Equal(t, []interface{}{"admin"}, roles)
+
+	// refresh token is rejected
+	pair, _ := jwt.GenerateTokenPair("100")
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.MD{"authorization": []string{authScheme + " " + pair.RefreshToken}})
+	_, err = JwtVerifyCustom(ctx)
+	assert.Error(t, err)
+
+	_, err = JwtVerifyCustom(context.Background())
+	assert.Error(t, err)
+}
+
 func TestUnaryServerJwtAuth(t *testing.T) {
 	interceptor := UnaryServerJwtAuth()
 	assert.NotNil(t, interceptor)
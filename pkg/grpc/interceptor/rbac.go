@@ -0,0 +1,113 @@
+package interceptor
+
+import (
+	"context"
+
+	grpc_auth "github.com/grpc-ecosystem/go-grpc-middleware/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/zhufuyi/sponge/pkg/jwt"
+	"github.com/zhufuyi/sponge/pkg/rbac"
+)
+
+// RBACOption set the rbac options.
+type RBACOption func(*rbacOptions)
+
+type rbacOptions struct {
+	rolesFn       func(ctx context.Context) ([]string, error)
+	ignoreMethods map[string]struct{}
+}
+
+func defaultRBACOptions() *rbacOptions {
+	return &rbacOptions{
+		rolesFn:       rolesFromCtx,
+		ignoreMethods: make(map[string]struct{}),
+	}
+}
+
+func (o *rbacOptions) apply(opts ...RBACOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithRBACRolesFn set the function to get the roles of the request, by default the roles are got from
+// the custom claims stored by UnaryServerJwtAuth(WithAuthCustomClaims()), see rbac.RolesFromClaims.
+func WithRBACRolesFn(fn func(ctx context.Context) ([]string, error)) RBACOption {
+	return func(o *rbacOptions) {
+		if fn != nil {
+			o.rolesFn = fn
+		}
+	}
+}
+
+// WithRBACIgnoreMethods ignore the methods that do not need to be authorized
+// fullMethodName format: /packageName.serviceName/methodName,
+// example /api.userExample.v1.userExampleService/GetByID
+func WithRBACIgnoreMethods(fullMethodNames ...string) RBACOption {
+	return func(o *rbacOptions) {
+		for _, method := range fullMethodNames {
+			o.ignoreMethods[method] = struct{}{}
+		}
+	}
+}
+
+// get the roles from the custom claims stored in ctx by JwtVerifyCustom, if the jwt auth interceptor
+// does not store the custom claims, parse the token in metadata, the refresh token is rejected.
+func rolesFromCtx(ctx context.Context) ([]string, error) {
+	if claims, ok := ctx.Value(authCtxClaimsName).(*jwt.CustomClaims); ok { //nolint
+		return rbac.RolesFromClaims(claims), nil
+	}
+
+	token, err := grpc_auth.AuthFromMD(ctx, authScheme)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := jwt.ParseCustomToken(token)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "%v", err)
+	}
+	return rbac.RolesFromClaims(claims), nil
+}
+
+func authorize(ctx context.Context, e *rbac.Enforcer, o *rbacOptions, fullMethod string) error {
+	if _, ok := o.ignoreMethods[fullMethod]; ok {
+		return nil
+	}
+	roles, err := o.rolesFn(ctx)
+	if err != nil {
+		return err
+	}
+	if !e.Enforce(roles, fullMethod, "") {
+		return status.Errorf(codes.PermissionDenied, "permission denied to call %s", fullMethod)
+	}
+	return nil
+}
+
+// UnaryServerRBAC rbac unary interceptor, the policies of the enforcer map the roles to the grpc full method names
+func UnaryServerRBAC(e *rbac.Enforcer, opts ...RBACOption) grpc.UnaryServerInterceptor {
+	o := defaultRBACOptions()
+	o.apply(opts...)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := authorize(ctx, e, o, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerRBAC rbac stream interceptor, the policies of the enforcer map the roles to the grpc full method names
+func StreamServerRBAC(e *rbac.Enforcer, opts ...RBACOption) grpc.StreamServerInterceptor {
+	o := defaultRBACOptions()
+	o.apply(opts...)
+
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := authorize(stream.Context(), e, o, info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
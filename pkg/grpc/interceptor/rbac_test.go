@@ -0,0 +1,88 @@
+package interceptor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/zhufuyi/sponge/pkg/jwt"
+	"github.com/zhufuyi/sponge/pkg/rbac"
+)
+
+func newRBACCtx(roles ...string) context.Context {
+	token, _ := jwt.GenerateCustomToken(jwt.KV{"uid": "100", "roles": roles})
+	return metadata.NewIncomingContext(context.Background(), metadata.MD{"authorization": []string{authScheme + " " + token}})
+}
+
+func TestUnaryServerRBAC(t *testing.T) {
+	jwt.Init()
+	e := rbac.NewEnforcer(rbac.WithDenyByDefault(), rbac.WithPolicies(&rbac.Policy{Role: "admin", Resource: "/ping"}))
+	interceptor := UnaryServerRBAC(e)
+	assert.NotNil(t, interceptor)
+
+	_, err := interceptor(newRBACCtx("admin"), nil, unaryServerInfo, unaryServerHandler)
+	assert.NoError(t, err)
+
+	_, err = interceptor(newRBACCtx("user"), nil, unaryServerInfo, unaryServerHandler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// no token
+	_, err = interceptor(context.Background(), nil, unaryServerInfo, unaryServerHandler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// ignore method
+	interceptor = UnaryServerRBAC(e, WithRBACIgnoreMethods(unaryServerInfo.FullMethod))
+	_, err = interceptor(context.Background(), nil, unaryServerInfo, unaryServerHandler)
+	assert.NoError(t, err)
+}
+
+func TestStreamServerRBAC(t *testing.T) {
+	jwt.Init()
+	e := rbac.NewEnforcer(rbac.WithPolicies(&rbac.Policy{Role: "admin", Resource: "/test"}))
+	interceptor := StreamServerRBAC(e, WithRBACRolesFn(func(ctx context.Context) ([]string, error) {
+		roles, _ := ctx.Value("roles").([]string)
+		return roles, nil
+	}))
+	assert.NotNil(t, interceptor)
+
+	ctx := context.WithValue(context.Background(), "roles", []string{"admin"}) //nolint
+	err := interceptor(nil, newStreamServer(ctx), streamServerInfo, streamServerHandler)
+	assert.NoError(t, err)
+
+	err = interceptor(nil, newStreamServer(context.Background()), streamServerInfo, streamServerHandler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestRolesFromCtx(t *testing.T) {
+	claims := &jwt.CustomClaims{Fields: jwt.KV{"roles": []string{"admin"}}}
+	ctx := context.WithValue(context.Background(), authCtxClaimsName, claims) //nolint
+	roles, err := rolesFromCtx(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"admin"}, roles)
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.MD{"authorization": []string{authScheme + " token..."}})
+	_, err = rolesFromCtx(ctx)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// the custom claims stored by the jwt auth interceptor
+	jwt.Init()
+	token, _ := jwt.GenerateCustomToken(jwt.KV{"roles": []string{"admin"}})
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.MD{"authorization": []string{authScheme + " " + token}})
+	auth := UnaryServerJwtAuth(WithAuthCustomClaims())
+	_, err = auth(ctx, nil, unaryServerInfo, func(ctx context.Context, req interface{}) (interface{}, error) {
+		roles, err = rolesFromCtx(ctx)
+		return nil, err
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"admin"}, roles)
+
+	// the refresh token is rejected
+	pair, _ := jwt.GenerateTokenPair("100")
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.MD{"authorization": []string{authScheme + " " + pair.RefreshToken}})
+	_, err = rolesFromCtx(ctx)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
@@ -0,0 +1,128 @@
+package interceptor
+
+import (
+	"context"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/util/metautils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/zhufuyi/sponge/pkg/signature"
+)
+
+// the grpc requests are signed as POST requests, the path is the full method name, and the body is empty,
+// the payload is not covered by the signature because the protobuf encoding is not canonical across
+// languages and library versions, use TLS to protect the integrity of the payload.
+const signatureMethod = "POST"
+
+const (
+	mdAppID     = "x-app-id"
+	mdTimestamp = "x-timestamp"
+	mdNonce     = "x-nonce"
+	mdSignature = "x-signature"
+)
+
+// ---------------------------------- client interceptor ----------------------------------
+
+func setSignatureToCtx(ctx context.Context, c *signature.Credential) context.Context {
+	return metadata.AppendToOutgoingContext(ctx,
+		mdAppID, c.AppID,
+		mdTimestamp, c.Timestamp,
+		mdNonce, c.Nonce,
+		mdSignature, c.Signature,
+	)
+}
+
+// UnaryClientSignature sign the method, timestamp and nonce of the request by HMAC signature
+func UnaryClientSignature(s *signature.Signer) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		c := s.Sign(signatureMethod, method, nil, nil)
+		return invoker(setSignatureToCtx(ctx, c), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientSignature sign the stream by HMAC signature
+func StreamClientSignature(s *signature.Signer) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		c := s.Sign(signatureMethod, method, nil, nil)
+		return streamer(setSignatureToCtx(ctx, c), desc, cc, method, opts...)
+	}
+}
+
+// ---------------------------------- server interceptor ----------------------------------
+
+// SignatureOption set the signature options.
+type SignatureOption func(*signatureOptions)
+
+type signatureOptions struct {
+	ignoreMethods map[string]struct{}
+}
+
+func defaultSignatureOptions() *signatureOptions {
+	return &signatureOptions{
+		ignoreMethods: make(map[string]struct{}),
+	}
+}
+
+func (o *signatureOptions) apply(opts ...SignatureOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithSignatureIgnoreMethods ignore the methods that do not need to verify the signature
+// fullMethodName format: /packageName.serviceName/methodName,
+// example /api.userExample.v1.userExampleService/GetByID
+func WithSignatureIgnoreMethods(fullMethodNames ...string) SignatureOption {
+	return func(o *signatureOptions) {
+		for _, method := range fullMethodNames {
+			o.ignoreMethods[method] = struct{}{}
+		}
+	}
+}
+
+func verifySignature(ctx context.Context, v *signature.Verifier, fullMethod string) error {
+	md := metautils.ExtractIncoming(ctx)
+	c := &signature.Credential{
+		AppID:     md.Get(mdAppID),
+		Timestamp: md.Get(mdTimestamp),
+		Nonce:     md.Get(mdNonce),
+		Signature: md.Get(mdSignature),
+	}
+	if err := v.Verify(ctx, c, signatureMethod, fullMethod, nil, nil); err != nil {
+		return status.Errorf(codes.Unauthenticated, "%v", err)
+	}
+	return nil
+}
+
+// UnaryServerSignature verify the HMAC signature of the request
+func UnaryServerSignature(v *signature.Verifier, opts ...SignatureOption) grpc.UnaryServerInterceptor {
+	o := defaultSignatureOptions()
+	o.apply(opts...)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if _, ok := o.ignoreMethods[info.FullMethod]; !ok {
+			if err := verifySignature(ctx, v, info.FullMethod); err != nil {
+				return nil, err
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerSignature verify the HMAC signature of the stream
+func StreamServerSignature(v *signature.Verifier, opts ...SignatureOption) grpc.StreamServerInterceptor {
+	o := defaultSignatureOptions()
+	o.apply(opts...)
+
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if _, ok := o.ignoreMethods[info.FullMethod]; !ok {
+			if err := verifySignature(stream.Context(), v, info.FullMethod); err != nil {
+				return err
+			}
+		}
+		return handler(srv, stream)
+	}
+}
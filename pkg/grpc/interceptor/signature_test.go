@@ -0,0 +1,85 @@
+package interceptor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/zhufuyi/sponge/pkg/signature"
+)
+
+// convert the outgoing metadata of client to the incoming metadata of server
+func outgoingToIncoming(ctx context.Context) context.Context {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	return metadata.NewIncomingContext(context.Background(), md)
+}
+
+func TestUnaryServerSignature(t *testing.T) {
+	v := signature.NewVerifier(signature.StaticSecrets(map[string]string{"app1": "secret1"}))
+	serverInterceptor := UnaryServerSignature(v)
+	req := wrapperspb.String("foo")
+
+	var serverCtx context.Context
+	clientInterceptor := UnaryClientSignature(signature.NewSigner("app1", "secret1"))
+	err := clientInterceptor(context.Background(), unaryServerInfo.FullMethod, req, nil, nil,
+		func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			serverCtx = outgoingToIncoming(ctx)
+			return nil
+		})
+	assert.NoError(t, err)
+
+	_, err = serverInterceptor(serverCtx, req, unaryServerInfo, unaryServerHandler)
+	assert.NoError(t, err)
+
+	// replay
+	_, err = serverInterceptor(serverCtx, req, unaryServerInfo, unaryServerHandler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// signed for another method
+	_ = clientInterceptor(context.Background(), "/api.v1.Foo/Bar", req, nil, nil,
+		func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			serverCtx = outgoingToIncoming(ctx)
+			return nil
+		})
+	_, err = serverInterceptor(serverCtx, req, unaryServerInfo, unaryServerHandler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// without signature
+	_, err = serverInterceptor(context.Background(), req, unaryServerInfo, unaryServerHandler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// ignore method
+	serverInterceptor = UnaryServerSignature(v, WithSignatureIgnoreMethods(unaryServerInfo.FullMethod))
+	_, err = serverInterceptor(context.Background(), req, unaryServerInfo, unaryServerHandler)
+	assert.NoError(t, err)
+}
+
+func TestStreamServerSignature(t *testing.T) {
+	v := signature.NewVerifier(signature.StaticSecrets(map[string]string{"app1": "secret1"}))
+	serverInterceptor := StreamServerSignature(v)
+
+	var serverCtx context.Context
+	clientInterceptor := StreamClientSignature(signature.NewSigner("app1", "secret1"))
+	_, err := clientInterceptor(context.Background(), nil, nil, streamServerInfo.FullMethod,
+		func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			serverCtx = outgoingToIncoming(ctx)
+			return nil, nil
+		})
+	assert.NoError(t, err)
+
+	err = serverInterceptor(nil, newStreamServer(serverCtx), streamServerInfo, streamServerHandler)
+	assert.NoError(t, err)
+
+	err = serverInterceptor(nil, newStreamServer(context.Background()), streamServerInfo, streamServerHandler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	serverInterceptor = StreamServerSignature(v, WithSignatureIgnoreMethods(streamServerInfo.FullMethod))
+	err = serverInterceptor(nil, newStreamServer(context.Background()), streamServerInfo, streamServerHandler)
+	assert.NoError(t, err)
+}
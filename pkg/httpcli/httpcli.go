@@ -11,6 +11,8 @@ import (
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/zhufuyi/sponge/pkg/signature"
 )
 
 const defaultTimeout = 30 * time.Second
@@ -24,6 +26,7 @@ type Request struct {
 	bodyJSON      interface{}            // JSON marshal body data
 	timeout       time.Duration          // Client timeout
 	headers       map[string]string
+	signer        *signature.Signer // sign the request by HMAC signature
 
 	request  *http.Request
 	response *Response
@@ -136,6 +139,12 @@ func (req *Request) CustomRequest(f func(req *http.Request, data *bytes.Buffer))
 	return req
 }
 
+// SetSigner sign the request by HMAC signature, the server verifies it by the signature middleware
+func (req *Request) SetSigner(signer *signature.Signer) *Request {
+	req.signer = signer
+	return req
+}
+
 // GET send a GET request
 func (req *Request) GET() (*Response, error) {
 	req.method = http.MethodGet
@@ -254,6 +263,16 @@ func (req *Request) send(body io.Reader, buf *bytes.Buffer) (*Response, error) {
 		}
 	}
 
+	if req.signer != nil {
+		var data []byte
+		if body != nil {
+			data = buf.Bytes()
+		}
+		if req.err = req.signer.SignRequest(req.request, data); req.err != nil {
+			return nil, req.err
+		}
+	}
+
 	if req.timeout < 1 {
 		req.timeout = defaultTimeout
 	}
@@ -332,6 +351,7 @@ type options struct {
 	params  map[string]interface{}
 	headers map[string]string
 	timeout time.Duration
+	signer  *signature.Signer
 }
 
 func (o *options) apply(opts ...Option) {
@@ -369,39 +389,46 @@ func WithTimeout(t time.Duration) Option {
 	}
 }
 
+// WithSigner sign the request by HMAC signature
+func WithSigner(signer *signature.Signer) Option {
+	return func(o *options) {
+		o.signer = signer
+	}
+}
+
 // Get request, return custom json format
 func Get(result interface{}, urlStr string, opts ...Option) error {
 	o := defaultOptions()
 	o.apply(opts...)
-	return gDo("GET", result, urlStr, o.params, o.headers, o.timeout)
+	return gDo("GET", result, urlStr, o.params, o.headers, o.timeout, o.signer)
 }
 
 // Delete request, return custom json format
 func Delete(result interface{}, urlStr string, opts ...Option) error {
 	o := defaultOptions()
 	o.apply(opts...)
-	return gDo("DELETE", result, urlStr, o.params, o.headers, o.timeout)
+	return gDo("DELETE", result, urlStr, o.params, o.headers, o.timeout, o.signer)
 }
 
 // Post request, return custom json format
 func Post(result interface{}, urlStr string, body interface{}, opts ...Option) error {
 	o := defaultOptions()
 	o.apply(opts...)
-	return do("POST", result, urlStr, body, o.params, o.headers, o.timeout)
+	return do("POST", result, urlStr, body, o.params, o.headers, o.timeout, o.signer)
 }
 
 // Put request, return custom json format
 func Put(result interface{}, urlStr string, body interface{}, opts ...Option) error {
 	o := defaultOptions()
 	o.apply(opts...)
-	return do("PUT", result, urlStr, body, o.params, o.headers, o.timeout)
+	return do("PUT", result, urlStr, body, o.params, o.headers, o.timeout, o.signer)
 }
 
 // Patch request, return custom json format
 func Patch(result interface{}, urlStr string, body interface{}, opts ...Option) error {
 	o := defaultOptions()
 	o.apply(opts...)
-	return do("PATCH", result, urlStr, body, o.params, o.headers, o.timeout)
+	return do("PATCH", result, urlStr, body, o.params, o.headers, o.timeout, o.signer)
 }
 
 var requestErr = func(err error) error { return fmt.Errorf("request error, err=%v", err) }
@@ -417,7 +444,7 @@ var notOKErr = func(resp *Response) error {
 	return fmt.Errorf("statusCode=%d, body=%s", resp.StatusCode, body)
 }
 
-func do(method string, result interface{}, urlStr string, body interface{}, params KV, headers map[string]string, timeout time.Duration) error {
+func do(method string, result interface{}, urlStr string, body interface{}, params KV, headers map[string]string, timeout time.Duration, signer *signature.Signer) error {
 	if result == nil {
 		return fmt.Errorf("'result' can not be nil")
 	}
@@ -429,6 +456,7 @@ func do(method string, result interface{}, urlStr string, body interface{}, para
 	req.SetHeaders(headers)
 	req.SetBody(body)
 	req.SetTimeout(timeout)
+	req.SetSigner(signer)
 
 	var resp *Response
 	var err error
@@ -457,12 +485,13 @@ func do(method string, result interface{}, urlStr string, body interface{}, para
 	return nil
 }
 
-func gDo(method string, result interface{}, urlStr string, params KV, headers map[string]string, timeout time.Duration) error {
+func gDo(method string, result interface{}, urlStr string, params KV, headers map[string]string, timeout time.Duration, signer *signature.Signer) error {
 	req := &Request{}
 	req.SetURL(urlStr)
 	req.SetParams(params)
 	req.SetHeaders(headers)
 	req.SetTimeout(timeout)
+	req.SetSigner(signer)
 
 	var resp *Response
 	var err error
@@ -4,13 +4,16 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/zhufuyi/sponge/pkg/signature"
 	"github.com/zhufuyi/sponge/pkg/utils"
 )
 
@@ -720,11 +723,42 @@ func TestError(t *testing.T) {
 	err = notOKErr(resp)
 	assert.Error(t, err)
 
-	err = do(http.MethodPost, nil, "", nil, nil, nil, 0)
+	err = do(http.MethodPost, nil, "", nil, nil, nil, 0, nil)
 	assert.Error(t, err)
-	err = do(http.MethodPost, &StdResult{}, "http://127.0.0.1:0", nil, KV{"foo": "bar"}, nil, 0)
+	err = do(http.MethodPost, &StdResult{}, "http://127.0.0.1:0", nil, KV{"foo": "bar"}, nil, 0, nil)
 	assert.Error(t, err)
 
-	err = gDo(http.MethodGet, nil, "http://127.0.0.1:0", nil, nil, 0)
+	err = gDo(http.MethodGet, nil, "http://127.0.0.1:0", nil, nil, 0, nil)
+	assert.Error(t, err)
+}
+
+func TestSigner(t *testing.T) {
+	v := signature.NewVerifier(signature.StaticSecrets(map[string]string{"app1": "secret1"}))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := v.VerifyRequest(r, body); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"code":0,"msg":"ok"}`))
+	}))
+	defer server.Close()
+
+	signer := signature.NewSigner("app1", "secret1")
+	result := &StdResult{}
+	err := Get(result, server.URL+"/api/v1/user/1", WithSigner(signer))
+	assert.NoError(t, err)
+	err = Post(result, server.URL+"/api/v1/user", &myBody{Name: "foo"}, WithSigner(signer))
+	assert.NoError(t, err)
+
+	resp, err := New().SetURL(server.URL+"/api/v1/user").SetParam("page", 1).SetSigner(signer).GET()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	_ = resp.Body.Close()
+
+	// without signature
+	err = Put(result, server.URL+"/api/v1/user/1", &myBody{Name: "foo"})
+	assert.Error(t, err)
+	err = Put(result, server.URL+"/api/v1/user/1", &myBody{Name: "foo"}, WithSigner(signature.NewSigner("app1", "secret2")))
 	assert.Error(t, err)
 }
@@ -0,0 +1,97 @@
+// Package workerpool run tasks concurrently with a fixed number of workers, the tasks with
+// the same key are run in order by the same worker, used by the kafka and rabbitmq consumers.
+package workerpool
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+const queueSize = 8
+
+// Pool run tasks concurrently, the tasks with the same key are run in order by the same worker
+type Pool struct {
+	tasks      chan func() // tasks without key, run by any worker
+	keyedTasks []chan func()
+	wg         sync.WaitGroup
+	zapLog     *zap.Logger
+}
+
+// New create a pool with the number of workers, the panic of the task is recovered and logged
+func New(workers int, zapLog *zap.Logger) *Pool {
+	if workers < 1 {
+		workers = 1
+	}
+	if zapLog == nil {
+		zapLog = zap.NewNop()
+	}
+	p := &Pool{
+		tasks:      make(chan func()),
+		keyedTasks: make([]chan func(), workers),
+		zapLog:     zapLog,
+	}
+	for i := range p.keyedTasks {
+		p.keyedTasks[i] = make(chan func(), queueSize)
+		p.wg.Add(1)
+		go p.run(p.keyedTasks[i])
+	}
+	return p
+}
+
+func (p *Pool) run(keyedTasks chan func()) {
+	defer p.wg.Done()
+	tasks := p.tasks
+	for tasks != nil || keyedTasks != nil {
+		select {
+		case task, ok := <-tasks:
+			if !ok {
+				tasks = nil
+				continue
+			}
+			p.exec(task)
+		case task, ok := <-keyedTasks:
+			if !ok {
+				keyedTasks = nil
+				continue
+			}
+			p.exec(task)
+		}
+	}
+}
+
+func (p *Pool) exec(task func()) {
+	defer func() {
+		if e := recover(); e != nil {
+			p.zapLog.Error("panic occurred while handling message", zap.Any("error", e))
+		}
+	}()
+	task()
+}
+
+// Submit a task, block until a worker is available, return false if ctx is done
+func (p *Pool) Submit(ctx context.Context, key string, task func()) bool {
+	ch := p.tasks
+	if key != "" {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(key))
+		ch = p.keyedTasks[h.Sum32()%uint32(len(p.keyedTasks))]
+	}
+	select {
+	case ch <- task:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Close the pool, wait for the submitted tasks to finish
+func (p *Pool) Close() {
+	close(p.tasks)
+	for _, ch := range p.keyedTasks {
+		close(ch)
+	}
+	p.wg.Wait()
+}
@@ -0,0 +1,63 @@
+package workerpool
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestPool(t *testing.T) {
+	pool := New(4, zap.NewNop())
+	ctx := context.Background()
+
+	// the tasks with the same key are run in order
+	var mu sync.Mutex
+	results := map[string][]int{}
+	for i := 0; i < 20; i++ {
+		i := i
+		key := "key" + strconv.Itoa(i%3)
+		ok := pool.Submit(ctx, key, func() {
+			time.Sleep(time.Millisecond * time.Duration(5-i%5))
+			mu.Lock()
+			results[key] = append(results[key], i)
+			mu.Unlock()
+		})
+		assert.True(t, ok)
+	}
+
+	// the tasks without key are run concurrently, panic is recovered
+	var count int32
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		pool.Submit(ctx, "", func() {
+			time.Sleep(time.Millisecond * 50)
+			atomic.AddInt32(&count, 1)
+			panic("test panic")
+		})
+	}
+	pool.Close()
+
+	assert.Less(t, time.Since(start), time.Millisecond*180)
+	assert.Equal(t, int32(4), count)
+	for key, values := range results {
+		for i := 1; i < len(values); i++ {
+			assert.Less(t, values[i-1], values[i], key)
+		}
+	}
+
+	// submit canceled
+	pool = New(1, zap.NewNop())
+	block := make(chan struct{})
+	pool.Submit(ctx, "", func() { <-block })
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.False(t, pool.Submit(cctx, "", func() {}))
+	close(block)
+	pool.Close()
+}
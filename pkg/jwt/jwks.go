@@ -0,0 +1,418 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+// JWK json web key, only the public key fields are supported
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// EC and OKP
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS json web key set
+type JWKS struct {
+	Keys []*JWK `json:"keys"`
+}
+
+var b64 = base64.RawURLEncoding
+
+// ToJWK convert the public key to jwk
+func (k *Key) ToJWK() (*JWK, error) {
+	jwk := &JWK{Kid: k.ID, Use: "sig", Alg: k.Method.Alg()}
+	switch pub := k.PublicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = b64.EncodeToString(pub.N.Bytes())
+		jwk.E = b64.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		params := pub.Curve.Params()
+		size := (params.BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = params.Name
+		jwk.X = b64.EncodeToString(pub.X.FillBytes(make([]byte, size)))
+		jwk.Y = b64.EncodeToString(pub.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = b64.EncodeToString(pub)
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", k.PublicKey)
+	}
+	return jwk, nil
+}
+
+// ToKey convert the jwk to a key that is only used to verify tokens
+func (j *JWK) ToKey() (*Key, error) {
+	var method jwt.SigningMethod
+	if j.Alg != "" {
+		method = jwt.GetSigningMethod(j.Alg)
+		if method == nil {
+			return nil, fmt.Errorf("unsupported alg %s", j.Alg)
+		}
+	}
+
+	switch j.Kty {
+	case "RSA":
+		n, err := b64.DecodeString(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := b64.DecodeString(j.E)
+		if err != nil {
+			return nil, err
+		}
+		if method == nil {
+			method = RS256
+		}
+		pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		return NewPublicKey(j.Kid, method, pub)
+
+	case "EC":
+		var curve elliptic.Curve
+		switch j.Crv {
+		case "P-256":
+			curve, method = elliptic.P256(), defaultMethod(method, ES256)
+		case "P-384":
+			curve, method = elliptic.P384(), defaultMethod(method, ES384)
+		case "P-521":
+			curve, method = elliptic.P521(), defaultMethod(method, ES512)
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", j.Crv)
+		}
+		x, err := b64.DecodeString(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := b64.DecodeString(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		pub := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(pub.X, pub.Y) { //nolint
+			return nil, fmt.Errorf("invalid key '%s', the point is not on the curve", j.Kid)
+		}
+		return NewPublicKey(j.Kid, method, pub)
+
+	case "OKP":
+		if j.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %s", j.Crv)
+		}
+		x, err := b64.DecodeString(j.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid key '%s', wrong size", j.Kid)
+		}
+		return NewPublicKey(j.Kid, defaultMethod(method, EdDSA), ed25519.PublicKey(x))
+	}
+
+	return nil, fmt.Errorf("unsupported kty %s", j.Kty)
+}
+
+func defaultMethod(method jwt.SigningMethod, defaultValue jwt.SigningMethod) jwt.SigningMethod {
+	if method == nil {
+		return defaultValue
+	}
+	return method
+}
+
+// JWKS get the public keys of the key set
+func (s *KeySet) JWKS() (*JWKS, error) {
+	jwks := &JWKS{Keys: []*JWK{}}
+	for _, key := range s.Keys() {
+		jwk, err := key.ToJWK()
+		if err != nil {
+			return nil, err
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return jwks, nil
+}
+
+// JWKSHandler http handler that publishes the public keys of the key set, the verifiers load the keys
+// by NewJWKSKeySet. it is usually registered to "/.well-known/jwks.json", using gin.WrapF in gin.
+func JWKSHandler(ks *KeySet) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jwks, err := ks.JWKS()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		_ = json.NewEncoder(w).Encode(jwks)
+	}
+}
+
+// -------------------------------------------------------------------------------------------
+
+var defaultJWKSLogger, _ = zap.NewProduction()
+
+type jwksOptions struct {
+	refreshInterval    time.Duration
+	minRefreshInterval time.Duration
+	httpClient         *http.Client
+	zapLog             *zap.Logger
+}
+
+func defaultJWKSOptions() *jwksOptions {
+	return &jwksOptions{
+		refreshInterval:    time.Minute * 5,
+		minRefreshInterval: time.Second * 10,
+		httpClient:         &http.Client{Timeout: time.Second * 10},
+		zapLog:             defaultJWKSLogger,
+	}
+}
+
+// JWKSOption set the jwks key set options.
+type JWKSOption func(*jwksOptions)
+
+func (o *jwksOptions) apply(opts ...JWKSOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithJWKSRefreshInterval set the interval of refreshing the keys, default 5 minutes
+func WithJWKSRefreshInterval(d time.Duration) JWKSOption {
+	return func(o *jwksOptions) {
+		if d > 0 {
+			o.refreshInterval = d
+		}
+	}
+}
+
+// WithJWKSMinRefreshInterval set the min interval of refreshing the keys when the kid is not found, default 10 seconds
+func WithJWKSMinRefreshInterval(d time.Duration) JWKSOption {
+	return func(o *jwksOptions) {
+		o.minRefreshInterval = d
+	}
+}
+
+// WithJWKSHTTPClient set the http client used to get the keys from url
+func WithJWKSHTTPClient(client *http.Client) JWKSOption {
+	return func(o *jwksOptions) {
+		if client != nil {
+			o.httpClient = client
+		}
+	}
+}
+
+// WithJWKSLogger set logger
+func WithJWKSLogger(log *zap.Logger) JWKSOption {
+	return func(o *jwksOptions) {
+		if log != nil {
+			o.zapLog = log
+		}
+	}
+}
+
+// JWKSKeySet keys loaded from a jwks url or local file, the keys are refreshed periodically,
+// and also refreshed when the kid of the token is not found. it is used to verify tokens by
+// the services that do not hold the private keys.
+type JWKSKeySet struct {
+	source string
+	opts   *jwksOptions
+
+	mu          sync.RWMutex
+	keys        map[string]*Key
+	lastAttempt time.Time // the last time of refreshing, whether it succeeded or not
+	refreshes   uint64    // the number of refreshes finished
+	refreshMu   sync.Mutex
+
+	exit      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewJWKSKeySet load the keys from source and refresh them in background, the source is an
+// http(s) url or a local file path.
+func NewJWKSKeySet(source string, opts ...JWKSOption) (*JWKSKeySet, error) {
+	o := defaultJWKSOptions()
+	o.apply(opts...)
+
+	s := &JWKSKeySet{
+		source: source,
+		opts:   o,
+		keys:   make(map[string]*Key),
+		exit:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	if err := s.Refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	go s.run()
+	return s, nil
+}
+
+func (s *JWKSKeySet) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.opts.refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.exit:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+			if err := s.Refresh(ctx); err != nil {
+				s.opts.zapLog.Warn("[jwks] refresh keys error, keep the old keys", zap.String("source", s.source), zap.Error(err))
+			}
+			cancel()
+		}
+	}
+}
+
+// Refresh load the keys from source, the old keys are kept if failed
+func (s *JWKSKeySet) Refresh(ctx context.Context) error {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+	return s.refresh(ctx)
+}
+
+// refresh the keys for the kid not found, the concurrent requests waiting for refreshMu skip
+// refreshing if the keys have been refreshed after they found the kid missing.
+func (s *JWKSKeySet) refreshMissing(ctx context.Context, kid string, refreshes uint64) error {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	s.mu.RLock()
+	_, ok := s.keys[kid]
+	isRefreshed := s.refreshes != refreshes
+	s.mu.RUnlock()
+	if ok || isRefreshed {
+		return nil
+	}
+	return s.refresh(ctx)
+}
+
+// the caller must hold refreshMu
+func (s *JWKSKeySet) refresh(ctx context.Context) error {
+	s.mu.Lock()
+	s.lastAttempt = time.Now()
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.refreshes++
+		s.mu.Unlock()
+	}()
+
+	data, err := s.load(ctx)
+	if err != nil {
+		return err
+	}
+	jwks := &JWKS{}
+	if err = json.Unmarshal(data, jwks); err != nil {
+		return err
+	}
+
+	keys := make(map[string]*Key, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.ToKey()
+		if err != nil {
+			s.opts.zapLog.Warn("[jwks] ignore invalid key", zap.String("kid", jwk.Kid), zap.Error(err))
+			continue
+		}
+		keys[key.ID] = key
+	}
+	if len(keys) == 0 {
+		return errors.New("no valid keys in jwks")
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *JWKSKeySet) load(ctx context.Context) ([]byte, error) {
+	if !strings.HasPrefix(s.source, "http://") && !strings.HasPrefix(s.source, "https://") {
+		return os.ReadFile(s.source)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.opts.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() //nolint
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get jwks failed, status code %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// GetKey get the key by kid, if not found, refresh the keys at most once every min refresh interval
+func (s *JWKSKeySet) GetKey(kid string) (*Key, error) {
+	s.mu.RLock()
+	key, ok := s.keys[kid]
+	lastAttempt, refreshes := s.lastAttempt, s.refreshes
+	s.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+
+	if time.Since(lastAttempt) < s.opts.minRefreshInterval {
+		return nil, ErrKeyNotFound
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	if err := s.refreshMissing(ctx, kid, refreshes); err != nil {
+		return nil, fmt.Errorf("%w, refresh keys error: %v", ErrKeyNotFound, err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if key, ok = s.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, ErrKeyNotFound
+}
+
+// Close stop refreshing the keys
+func (s *JWKSKeySet) Close() {
+	s.closeOnce.Do(func() {
+		close(s.exit)
+		<-s.done
+	})
+}
@@ -0,0 +1,142 @@
+package jwt
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestJWK(t *testing.T) {
+	k1, k2, k3 := newTestKeys(t)
+	for _, key := range []*Key{k1, k2, k3} {
+		jwk, err := key.ToJWK()
+		assert.NoError(t, err)
+		assert.Equal(t, key.Method.Alg(), jwk.Alg)
+
+		pub, err := jwk.ToKey()
+		assert.NoError(t, err)
+		assert.Nil(t, pub.PrivateKey)
+		assert.Equal(t, key.PublicKey, pub.PublicKey)
+
+		// the method is derived from the key type without alg
+		jwk.Alg = ""
+		pub, err = jwk.ToKey()
+		assert.NoError(t, err)
+		assert.Equal(t, key.Method.Alg(), pub.Method.Alg())
+	}
+
+	invalidJWKs := []*JWK{
+		{Kty: "oct", Kid: "k"},
+		{Kty: "RSA", Kid: "k", Alg: "unknown"},
+		{Kty: "EC", Kid: "k", Crv: "P-224"},
+		{Kty: "EC", Kid: "k", Crv: "P-256", X: "AQ", Y: "AQ"},
+		{Kty: "OKP", Kid: "k", Crv: "X25519"},
+		{Kty: "OKP", Kid: "k", Crv: "Ed25519", X: "AQ"},
+	}
+	for _, jwk := range invalidJWKs {
+		_, err := jwk.ToKey()
+		assert.Error(t, err)
+	}
+}
+
+func TestJWKSKeySet(t *testing.T) {
+	defer func() { opt = nil }()
+	k1, k2, k3 := newTestKeys(t)
+	ks := NewKeySet(k1, k2)
+
+	var requests int32
+	handler := JWKSHandler(ks)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		handler(w, r)
+	}))
+	defer server.Close()
+
+	// the issuer signs the tokens, the verifier loads the public keys from the issuer
+	jwks, err := NewJWKSKeySet(server.URL, WithJWKSRefreshInterval(time.Hour),
+		WithJWKSMinRefreshInterval(0), WithJWKSLogger(zap.NewNop()), WithJWKSHTTPClient(http.DefaultClient))
+	assert.NoError(t, err)
+	defer jwks.Close()
+
+	Init(WithKeySet(ks))
+	token, err := GenerateToken("123")
+	assert.NoError(t, err)
+	Init(WithVerifyKeys(jwks))
+	claims, err := ParseToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "123", claims.UID)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	// the verifier refreshes the keys when it gets a token signed by a new key
+	ks.Add(k3)
+	assert.NoError(t, ks.SetCurrent("ed-1"))
+	Init(WithKeySet(ks))
+	token, err = GenerateToken("123")
+	assert.NoError(t, err)
+	Init(WithVerifyKeys(jwks))
+	_, err = ParseToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	// the concurrent requests of an unknown kid wait for the same refresh
+	atomic.StoreInt32(&requests, 0)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, _ = jwks.GetKey("not-found")
+		}()
+	}
+	close(start)
+	wg.Wait()
+	assert.LessOrEqual(t, atomic.LoadInt32(&requests), int32(2)) // a late request may start a new refresh
+
+	// the old keys are kept if refreshing failed
+	server.Close()
+	_, err = jwks.GetKey("not-found")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	_, err = jwks.GetKey("ed-1")
+	assert.NoError(t, err)
+}
+
+func TestJWKSKeySet_File(t *testing.T) {
+	k1, _, _ := newTestKeys(t)
+	jwks, err := NewKeySet(k1).JWKS()
+	assert.NoError(t, err)
+	data, _ := json.Marshal(jwks)
+	file := filepath.Join(t.TempDir(), "jwks.json")
+	assert.NoError(t, os.WriteFile(file, data, 0o600))
+
+	ks, err := NewJWKSKeySet(file, WithJWKSRefreshInterval(time.Millisecond*10))
+	assert.NoError(t, err)
+	defer ks.Close()
+	_, err = ks.GetKey("rsa-1")
+	assert.NoError(t, err)
+
+	// refresh periodically
+	_, _, k3 := newTestKeys(t)
+	jwks, _ = NewKeySet(k3).JWKS()
+	data, _ = json.Marshal(jwks)
+	assert.NoError(t, os.WriteFile(file, data, 0o600))
+	time.Sleep(time.Millisecond * 50)
+	_, err = ks.GetKey("ed-1")
+	assert.NoError(t, err)
+
+	_, err = NewJWKSKeySet(filepath.Join(t.TempDir(), "not-found.json"))
+	assert.Error(t, err)
+	assert.NoError(t, os.WriteFile(file, []byte(`{"keys":[]}`), 0o600))
+	_, err = NewJWKSKeySet(file)
+	assert.Error(t, err)
+}
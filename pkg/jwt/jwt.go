@@ -2,6 +2,7 @@
 package jwt
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -23,6 +24,9 @@ func Init(opts ...Option) {
 type Claims struct {
 	UID  string `json:"uid"`
 	Name string `json:"name"`
+
+	TokenType string `json:"tokenType,omitempty"` // empty for access token, "refresh" for refresh token
+	Family    string `json:"family,omitempty"`    // the tokens refreshed from the same token pair share the family
 	jwt.RegisteredClaims
 }
 
@@ -37,17 +41,17 @@ func GenerateToken(uid string, name ...string) (string, error) {
 		nameVal = name[0]
 	}
 	claims := Claims{
-		uid,
-		nameVal,
-		jwt.RegisteredClaims{
+		UID:  uid,
+		Name: nameVal,
+		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(opt.expire)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Issuer:    opt.issuer,
+			ID:        newTokenID(),
 		},
 	}
 
-	token := jwt.NewWithClaims(opt.signingMethod, claims)
-	return token.SignedString(opt.signingKey)
+	return signToken(claims)
 }
 
 // ParseToken parse token
@@ -56,9 +60,22 @@ func ParseToken(tokenString string) (*Claims, error) {
 		return nil, errInit
 	}
 
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return opt.signingKey, nil
-	})
+	claims, err := parseClaims(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenType != "" {
+		return nil, ErrInvalidTokenType
+	}
+	if err = checkRevoked(claims.ID, claims.Family); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func parseClaims(tokenString string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, keyFunc)
 	if err != nil {
 		return nil, err
 	}
@@ -78,10 +95,56 @@ func RefreshToken(tokenString string) (string, error) {
 	}
 	claims.RegisteredClaims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(opt.expire))
 	claims.RegisteredClaims.IssuedAt = jwt.NewNumericDate(time.Now())
+	return signToken(claims)
+}
+
+// -------------------------------------------------------------------------------------------
+
+func signToken(claims jwt.Claims) (string, error) {
+	if opt.keySet != nil {
+		key, err := opt.keySet.Current()
+		if err != nil {
+			return "", err
+		}
+		token := jwt.NewWithClaims(key.Method, claims)
+		token.Header["kid"] = key.ID
+		return token.SignedString(key.PrivateKey)
+	}
+	if opt.verifyKeys != nil {
+		return "", ErrNoSigningKey
+	}
+
 	token := jwt.NewWithClaims(opt.signingMethod, claims)
 	return token.SignedString(opt.signingKey)
 }
 
+// get the key to verify the token, the algorithm of the token must match the key
+func keyFunc(token *jwt.Token) (interface{}, error) {
+	var keys KeyGetter
+	if opt.verifyKeys != nil {
+		keys = opt.verifyKeys
+	} else if opt.keySet != nil {
+		keys = opt.keySet
+	}
+
+	if keys == nil {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+		return opt.signingKey, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, err := keys.GetKey(kid)
+	if err != nil {
+		return nil, err
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s, the key '%s' uses %s", token.Method.Alg(), kid, key.Method.Alg())
+	}
+	return key.PublicKey, nil
+}
+
 // -------------------------------------------------------------------------------------------
 
 // KV map type
@@ -90,6 +153,8 @@ type KV = map[string]interface{}
 // CustomClaims custom fields claims
 type CustomClaims struct {
 	Fields KV `json:"fields"`
+
+	TokenType string `json:"tokenType,omitempty"` // not empty if the token is not an access token, e.g. refresh token
 	jwt.RegisteredClaims
 }
 
@@ -109,16 +174,16 @@ func GenerateCustomToken(kv map[string]interface{}) (string, error) {
 	}
 
 	claims := CustomClaims{
-		kv,
-		jwt.RegisteredClaims{
+		Fields: kv,
+		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(opt.expire)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Issuer:    opt.issuer,
+			ID:        newTokenID(),
 		},
 	}
 
-	token := jwt.NewWithClaims(opt.signingMethod, claims)
-	return token.SignedString(opt.signingKey)
+	return signToken(claims)
 }
 
 // ParseCustomToken parse token
@@ -127,14 +192,18 @@ func ParseCustomToken(tokenString string) (*CustomClaims, error) {
 		return nil, errInit
 	}
 
-	token, err := jwt.ParseWithClaims(tokenString, &CustomClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return opt.signingKey, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &CustomClaims{}, keyFunc)
 	if err != nil {
 		return nil, err
 	}
 
 	if claims, ok := token.Claims.(*CustomClaims); ok && token.Valid {
+		if claims.TokenType != "" {
+			return nil, ErrInvalidTokenType
+		}
+		if err = checkRevoked(claims.ID); err != nil {
+			return nil, err
+		}
 		return claims, nil
 	}
 
@@ -149,6 +218,5 @@ func RefreshCustomToken(tokenString string) (string, error) {
 	}
 	claims.RegisteredClaims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(opt.expire))
 	claims.RegisteredClaims.IssuedAt = jwt.NewNumericDate(time.Now())
-	token := jwt.NewWithClaims(opt.signingMethod, claims)
-	return token.SignedString(opt.signingKey)
+	return signToken(claims)
 }
@@ -0,0 +1,233 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	// RS256 Method
+	RS256 = jwt.SigningMethodRS256
+	// RS384 Method
+	RS384 = jwt.SigningMethodRS384
+	// RS512 Method
+	RS512 = jwt.SigningMethodRS512
+	// PS256 Method
+	PS256 = jwt.SigningMethodPS256
+	// ES256 Method
+	ES256 = jwt.SigningMethodES256
+	// ES384 Method
+	ES384 = jwt.SigningMethodES384
+	// ES512 Method
+	ES512 = jwt.SigningMethodES512
+	// EdDSA Method, Ed25519
+	EdDSA = jwt.SigningMethodEdDSA
+)
+
+var (
+	// ErrKeyNotFound the key of the kid is not found
+	ErrKeyNotFound = errors.New("key not found")
+	// ErrNoSigningKey there is no private key to sign the token
+	ErrNoSigningKey = errors.New("no signing key")
+)
+
+// Key asymmetric key identified by kid, the private key is nil if the key is only used to verify tokens.
+type Key struct {
+	ID         string
+	Method     jwt.SigningMethod
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+}
+
+// NewKey create a signing key, the private key is *rsa.PrivateKey, *ecdsa.PrivateKey or ed25519.PrivateKey,
+// and it must match the method.
+func NewKey(kid string, method jwt.SigningMethod, privateKey crypto.Signer) (*Key, error) {
+	if privateKey == nil {
+		return nil, errors.New("private key is nil")
+	}
+	key := &Key{ID: kid, Method: method, PrivateKey: privateKey, PublicKey: privateKey.Public()}
+	if err := key.check(); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// NewPublicKey create a key that is only used to verify tokens, the public key is *rsa.PublicKey,
+// *ecdsa.PublicKey or ed25519.PublicKey, and it must match the method.
+func NewPublicKey(kid string, method jwt.SigningMethod, publicKey crypto.PublicKey) (*Key, error) {
+	key := &Key{ID: kid, Method: method, PublicKey: publicKey}
+	if err := key.check(); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// NewKeyFromPEM create a signing key from the PEM encoded private key, PKCS1, PKCS8 and SEC1 are supported.
+func NewKeyFromPEM(kid string, method jwt.SigningMethod, pemData []byte) (*Key, error) {
+	var privateKey crypto.PrivateKey
+	var err error
+	switch method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		privateKey, err = jwt.ParseRSAPrivateKeyFromPEM(pemData)
+	case *jwt.SigningMethodECDSA:
+		privateKey, err = jwt.ParseECPrivateKeyFromPEM(pemData)
+	case *jwt.SigningMethodEd25519:
+		privateKey, err = jwt.ParseEdPrivateKeyFromPEM(pemData)
+	default:
+		return nil, fmt.Errorf("unsupported signing method %s", method.Alg())
+	}
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("invalid private key")
+	}
+	return NewKey(kid, method, signer)
+}
+
+// NewKeyFromPEMFile create a signing key from the PEM file of the private key
+func NewKeyFromPEMFile(kid string, method jwt.SigningMethod, file string) (*Key, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return NewKeyFromPEM(kid, method, data)
+}
+
+// check whether the public key matches the method
+func (k *Key) check() error {
+	if k.Method == nil {
+		return errors.New("signing method is nil")
+	}
+
+	isMatch := false
+	switch m := k.Method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		_, isMatch = k.PublicKey.(*rsa.PublicKey)
+	case *jwt.SigningMethodECDSA:
+		if pub, ok := k.PublicKey.(*ecdsa.PublicKey); ok {
+			isMatch = pub.Curve.Params().BitSize == m.CurveBits
+		}
+	case *jwt.SigningMethodEd25519:
+		_, isMatch = k.PublicKey.(ed25519.PublicKey)
+	default:
+		return fmt.Errorf("unsupported signing method %s", k.Method.Alg())
+	}
+	if !isMatch {
+		return fmt.Errorf("the key of '%s' does not match the signing method %s", k.ID, k.Method.Alg())
+	}
+	return nil
+}
+
+// -------------------------------------------------------------------------------------------
+
+// KeyGetter gets the key to verify the token by the kid in the token header
+type KeyGetter interface {
+	GetKey(kid string) (*Key, error)
+}
+
+// KeySet multiple keys identified by kid, the tokens are signed by the current key, and verified by
+// the key of their kid. to rotate the keys, add a new key and set it as current, remove the old key
+// after the tokens signed by it are expired.
+type KeySet struct {
+	mu      sync.RWMutex
+	keys    map[string]*Key
+	order   []string
+	current string
+}
+
+// NewKeySet create a key set, the first key with private key is the current key
+func NewKeySet(keys ...*Key) *KeySet {
+	s := &KeySet{keys: make(map[string]*Key)}
+	for _, key := range keys {
+		s.Add(key)
+	}
+	return s
+}
+
+// Add the key, it replaces the key of the same kid
+func (s *KeySet) Add(key *Key) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.keys[key.ID]; !ok {
+		s.order = append(s.order, key.ID)
+	}
+	s.keys[key.ID] = key
+	if s.current == "" && key.PrivateKey != nil {
+		s.current = key.ID
+	}
+}
+
+// Remove the key, the current key can not be removed
+func (s *KeySet) Remove(kid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if kid == s.current {
+		return fmt.Errorf("the current key '%s' can not be removed", kid)
+	}
+	delete(s.keys, kid)
+	for i, id := range s.order {
+		if id == kid {
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// SetCurrent set the key used to sign tokens
+func (s *KeySet) SetCurrent(kid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := s.keys[kid]
+	if !ok {
+		return ErrKeyNotFound
+	}
+	if key.PrivateKey == nil {
+		return ErrNoSigningKey
+	}
+	s.current = kid
+	return nil
+}
+
+// Current get the key used to sign tokens
+func (s *KeySet) Current() (*Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.keys[s.current]
+	if !ok {
+		return nil, ErrNoSigningKey
+	}
+	return key, nil
+}
+
+// GetKey get the key by kid
+func (s *KeySet) GetKey(kid string) (*Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return key, nil
+}
+
+// Keys get all the keys in the order they were added
+func (s *KeySet) Keys() []*Key {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]*Key, 0, len(s.order))
+	for _, kid := range s.order {
+		keys = append(keys, s.keys[kid])
+	}
+	return keys
+}
@@ -0,0 +1,147 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestKeys(t *testing.T) (*Key, *Key, *Key) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k1, err := NewKey("rsa-1", RS256, rsaKey)
+	assert.NoError(t, err)
+	k2, err := NewKey("ec-1", ES256, ecKey)
+	assert.NoError(t, err)
+	k3, err := NewKey("ed-1", EdDSA, edKey)
+	assert.NoError(t, err)
+	return k1, k2, k3
+}
+
+func TestNewKey(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	// the key does not match the method
+	_, err := NewKey("k", ES256, rsaKey)
+	assert.Error(t, err)
+	_, err = NewKey("k", ES384, ecKey)
+	assert.Error(t, err)
+	_, err = NewKey("k", HS256, ecKey)
+	assert.Error(t, err)
+	_, err = NewKey("k", RS256, nil)
+	assert.Error(t, err)
+	_, err = NewPublicKey("k", nil, &rsaKey.PublicKey)
+	assert.Error(t, err)
+
+	// PEM
+	der, _ := x509.MarshalPKCS8PrivateKey(ecKey)
+	key, err := NewKeyFromPEM("k", ES256, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	assert.NoError(t, err)
+	assert.Equal(t, &ecKey.PublicKey, key.PublicKey)
+	_, err = NewKeyFromPEM("k", ES256, []byte("invalid"))
+	assert.Error(t, err)
+	_, err = NewKeyFromPEM("k", HS256, []byte("invalid"))
+	assert.Error(t, err)
+	_, err = NewKeyFromPEMFile("k", ES256, "not-found.pem")
+	assert.Error(t, err)
+}
+
+func TestKeySet(t *testing.T) {
+	k1, k2, k3 := newTestKeys(t)
+	pub, _ := NewPublicKey("pub", RS256, k1.PublicKey)
+	ks := NewKeySet(pub, k1, k2)
+
+	current, err := ks.Current()
+	assert.NoError(t, err)
+	assert.Equal(t, "rsa-1", current.ID)
+	assert.Len(t, ks.Keys(), 3)
+
+	ks.Add(k3)
+	assert.NoError(t, ks.SetCurrent("ed-1"))
+	assert.ErrorIs(t, ks.SetCurrent("pub"), ErrNoSigningKey)
+	assert.ErrorIs(t, ks.SetCurrent("not-found"), ErrKeyNotFound)
+	assert.Error(t, ks.Remove("ed-1"))
+	assert.NoError(t, ks.Remove("rsa-1"))
+	_, err = ks.GetKey("rsa-1")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	assert.Equal(t, []string{"pub", "ec-1", "ed-1"}, []string{ks.Keys()[0].ID, ks.Keys()[1].ID, ks.Keys()[2].ID})
+
+	_, err = NewKeySet(pub).Current()
+	assert.ErrorIs(t, err, ErrNoSigningKey)
+}
+
+func TestAsymmetricToken(t *testing.T) {
+	defer func() { opt = nil }()
+	k1, k2, k3 := newTestKeys(t)
+	ks := NewKeySet(k1, k2, k3)
+	Init(WithKeySet(ks))
+
+	for _, kid := range []string{"rsa-1", "ec-1", "ed-1"} {
+		assert.NoError(t, ks.SetCurrent(kid))
+		token, err := GenerateToken("123", "admin")
+		assert.NoError(t, err)
+		claims, err := ParseToken(token)
+		assert.NoError(t, err)
+		assert.Equal(t, "123", claims.UID)
+
+		token, err = GenerateCustomToken(KV{"id": 1})
+		assert.NoError(t, err)
+		_, err = ParseCustomToken(token)
+		assert.NoError(t, err)
+		_, err = RefreshCustomToken(token)
+		assert.NoError(t, err)
+	}
+
+	// the tokens signed by the old key are valid after rotation, until the key is removed
+	assert.NoError(t, ks.SetCurrent("rsa-1"))
+	token, _ := GenerateToken("123")
+	assert.NoError(t, ks.SetCurrent("ec-1"))
+	_, err := ParseToken(token)
+	assert.NoError(t, err)
+	newToken, err := RefreshToken(token)
+	assert.NoError(t, err)
+	parsed, _, _ := jwt.NewParser().ParseUnverified(newToken, &Claims{})
+	assert.Equal(t, "ec-1", parsed.Header["kid"])
+	assert.NoError(t, ks.Remove("rsa-1"))
+	_, err = ParseToken(token)
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	// the algorithm of the token must match the key, e.g. HS256 token signed by the public key
+	hsToken := jwt.NewWithClaims(HS256, &Claims{UID: "123"})
+	hsToken.Header["kid"] = "ed-1"
+	tokenStr, _ := hsToken.SignedString([]byte(k3.PublicKey.(ed25519.PublicKey)))
+	_, err = ParseToken(tokenStr)
+	assert.Error(t, err)
+
+	// the HMAC key does not accept asymmetric tokens
+	Init()
+	_, err = ParseToken(newToken)
+	assert.Error(t, err)
+
+	// verify only
+	Init(WithVerifyKeys(ks))
+	_, err = ParseToken(newToken)
+	assert.NoError(t, err)
+	_, err = GenerateToken("123")
+	assert.ErrorIs(t, err, ErrNoSigningKey)
+}
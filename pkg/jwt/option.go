@@ -20,6 +20,7 @@ var (
 	defaultSigningKey    = []byte("zaq12wsxmko0") // default key
 	defaultSigningMethod = HS256                  // default HS256
 	defaultExpire        = 24 * time.Hour         // default expiration
+	defaultRefreshExpire = 7 * 24 * time.Hour     // default expiration of refresh token
 	defaultIssuer        = ""
 )
 
@@ -28,6 +29,12 @@ type options struct {
 	expire        time.Duration
 	issuer        string
 	signingMethod *jwt.SigningMethodHMAC
+
+	keySet     *KeySet   // asymmetric keys to sign and verify tokens
+	verifyKeys KeyGetter // asymmetric keys only to verify tokens
+
+	refreshExpire   time.Duration
+	revocationStore RevocationStore
 }
 
 func defaultOptions() *options {
@@ -36,6 +43,7 @@ func defaultOptions() *options {
 		signingMethod: defaultSigningMethod,
 		expire:        defaultExpire,
 		issuer:        defaultIssuer,
+		refreshExpire: defaultRefreshExpire,
 	}
 }
 
@@ -62,6 +70,22 @@ func WithSigningMethod(sm *jwt.SigningMethodHMAC) Option {
 	}
 }
 
+// WithKeySet set the asymmetric keys (RSA, ECDSA or Ed25519) to sign and verify tokens instead of
+// the HMAC signing key, the tokens are signed by the current key with kid in the header.
+func WithKeySet(ks *KeySet) Option {
+	return func(o *options) {
+		o.keySet = ks
+	}
+}
+
+// WithVerifyKeys set the asymmetric keys only to verify tokens, e.g. the keys loaded by NewJWKSKeySet,
+// the tokens can not be generated by this instance.
+func WithVerifyKeys(keys KeyGetter) Option {
+	return func(o *options) {
+		o.verifyKeys = keys
+	}
+}
+
 // WithExpire set expire value
 func WithExpire(d time.Duration) Option {
 	return func(o *options) {
@@ -69,6 +93,21 @@ func WithExpire(d time.Duration) Option {
 	}
 }
 
+// WithRefreshExpire set the expiration of refresh token, default 7 days, the expiration of access token is set by WithExpire
+func WithRefreshExpire(d time.Duration) Option {
+	return func(o *options) {
+		o.refreshExpire = d
+	}
+}
+
+// WithRevocationStore set the store of the revoked tokens, the parsed tokens are checked whether they are revoked,
+// it is also required to detect the reuse of refresh tokens.
+func WithRevocationStore(store RevocationStore) Option {
+	return func(o *options) {
+		o.revocationStore = store
+	}
+}
+
 // WithIssuer set issuer value
 func WithIssuer(issuer string) Option {
 	return func(o *options) {
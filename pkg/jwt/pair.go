@@ -0,0 +1,211 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/zhufuyi/sponge/pkg/krand"
+)
+
+const tokenTypeRefresh = "refresh"
+
+var (
+	// ErrTokenRevoked the token has been revoked
+	ErrTokenRevoked = errors.New("token has been revoked")
+	// ErrTokenReused the refresh token has been used, all the tokens of its family are revoked
+	ErrTokenReused = errors.New("refresh token has been reused")
+	// ErrInvalidTokenType access token is used as refresh token, or vice versa
+	ErrInvalidTokenType = errors.New("invalid token type")
+)
+
+// TokenPair access token and refresh token, the access token is used to access the api,
+// the refresh token is only used to get a new token pair when the access token expires.
+type TokenPair struct {
+	AccessToken      string    `json:"accessToken"`
+	RefreshToken     string    `json:"refreshToken"`
+	AccessExpiresAt  time.Time `json:"accessExpiresAt"`
+	RefreshExpiresAt time.Time `json:"refreshExpiresAt"`
+}
+
+func newTokenID() string {
+	return krand.String(krand.R_All, 20)
+}
+
+// GenerateTokenPair generate access token and refresh token by uid and name, e.g. after login
+func GenerateTokenPair(uid string, name ...string) (*TokenPair, error) {
+	if opt == nil {
+		return nil, errInit
+	}
+
+	nameVal := ""
+	if len(name) > 0 {
+		nameVal = name[0]
+	}
+	return generateTokenPair(uid, nameVal, newTokenID())
+}
+
+func generateTokenPair(uid string, name string, family string) (*TokenPair, error) {
+	now := time.Now()
+	pair := &TokenPair{
+		AccessExpiresAt:  now.Add(opt.expire),
+		RefreshExpiresAt: now.Add(opt.refreshExpire),
+	}
+
+	var err error
+	pair.AccessToken, err = signToken(&Claims{
+		UID:    uid,
+		Name:   name,
+		Family: family,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(pair.AccessExpiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    opt.issuer,
+			ID:        newTokenID(),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pair.RefreshToken, err = signToken(&Claims{
+		UID:       uid,
+		Name:      name,
+		TokenType: tokenTypeRefresh,
+		Family:    family,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(pair.RefreshExpiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    opt.issuer,
+			ID:        newTokenID(),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pair, nil
+}
+
+// RefreshTokenPair get a new token pair by the refresh token, the refresh token can only be used once.
+// if the revocation store is set and the refresh token is reused, it is probably stolen, all the tokens
+// of its family are revoked, and ErrTokenReused is returned.
+func RefreshTokenPair(refreshToken string) (*TokenPair, error) {
+	if opt == nil {
+		return nil, errInit
+	}
+
+	claims, err := parseClaims(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenType != tokenTypeRefresh {
+		return nil, ErrInvalidTokenType
+	}
+
+	if store := opt.revocationStore; store != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancel()
+
+		isRevoked, err := store.IsRevoked(ctx, claims.Family)
+		if err != nil {
+			return nil, err
+		}
+		if isRevoked {
+			return nil, ErrTokenRevoked
+		}
+
+		// mark the refresh token as used
+		ok, err := store.Revoke(ctx, claims.ID, expiration(claims))
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			if _, err = store.Revoke(ctx, claims.Family, opt.refreshExpire); err != nil {
+				return nil, err
+			}
+			return nil, ErrTokenReused
+		}
+	}
+
+	return generateTokenPair(claims.UID, claims.Name, claims.Family)
+}
+
+// RevokeToken revoke the token and its refreshed copies until they expire, if the token belongs to a token pair, all the tokens
+// of its family are revoked, e.g. logout. the revocation store must be set.
+func RevokeToken(tokenString string) error {
+	if opt == nil {
+		return errInit
+	}
+	if opt.revocationStore == nil {
+		return errors.New("revocation store is not set, usage 'jwt.Init(jwt.WithRevocationStore(store))'")
+	}
+
+	claims, err := parseClaims(tokenString)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	if claims.Family != "" {
+		_, err = opt.revocationStore.Revoke(ctx, claims.Family, revocationExpiration(opt.refreshExpire))
+		return err
+	}
+	if claims.ID == "" {
+		return errors.New("the token without id can not be revoked")
+	}
+	_, err = opt.revocationStore.Revoke(ctx, claims.ID, revocationExpiration(expiration(claims)))
+	return err
+}
+
+// the refreshed copies of the token have the same id and a later expiration time, so the revocation
+// is kept for at least the expiration of a token refreshed now.
+func revocationExpiration(d time.Duration) time.Duration {
+	if d < opt.expire {
+		return opt.expire
+	}
+	return d
+}
+
+// the remaining time of the token
+func expiration(claims *Claims) time.Duration {
+	if claims.ExpiresAt == nil {
+		return opt.refreshExpire
+	}
+	d := time.Until(claims.ExpiresAt.Time)
+	if d < time.Second {
+		d = time.Second
+	}
+	return d
+}
+
+func checkRevoked(ids ...string) error {
+	store := opt.revocationStore
+	if store == nil {
+		return nil
+	}
+
+	var checkIDs []string
+	for _, id := range ids {
+		if id != "" {
+			checkIDs = append(checkIDs, id)
+		}
+	}
+	if len(checkIDs) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	isRevoked, err := store.IsRevoked(ctx, checkIDs...)
+	if err != nil {
+		return err
+	}
+	if isRevoked {
+		return ErrTokenRevoked
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package jwt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func testTokenPair(t *testing.T, store RevocationStore) {
+	defer func() { opt = nil }()
+	Init(WithExpire(time.Minute), WithRefreshExpire(time.Hour), WithRevocationStore(store))
+
+	pair, err := GenerateTokenPair("123", "admin")
+	assert.NoError(t, err)
+	assert.True(t, pair.RefreshExpiresAt.After(pair.AccessExpiresAt))
+	claims, err := ParseToken(pair.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "123", claims.UID)
+	assert.NotEmpty(t, claims.Family)
+
+	// the tokens can not be used as each other
+	_, err = ParseToken(pair.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidTokenType)
+	_, err = ParseCustomToken(pair.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidTokenType)
+	_, err = RefreshTokenPair(pair.AccessToken)
+	assert.ErrorIs(t, err, ErrInvalidTokenType)
+
+	// rotation
+	newPair, err := RefreshTokenPair(pair.RefreshToken)
+	assert.NoError(t, err)
+	newClaims, err := ParseToken(newPair.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "admin", newClaims.Name)
+	assert.Equal(t, claims.Family, newClaims.Family)
+
+	// reuse the old refresh token, all the tokens of the family are revoked
+	_, err = RefreshTokenPair(pair.RefreshToken)
+	assert.ErrorIs(t, err, ErrTokenReused)
+	_, err = ParseToken(newPair.AccessToken)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+	_, err = RefreshTokenPair(newPair.RefreshToken)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+
+	// logout, revoke the token pair
+	pair, _ = GenerateTokenPair("456")
+	assert.NoError(t, RevokeToken(pair.AccessToken))
+	_, err = ParseToken(pair.AccessToken)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+	_, err = RefreshTokenPair(pair.RefreshToken)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+
+	// revoke a single token
+	token, _ := GenerateToken("789")
+	_, err = ParseToken(token)
+	assert.NoError(t, err)
+	assert.NoError(t, RevokeToken(token))
+	_, err = ParseToken(token)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+
+	token, _ = GenerateCustomToken(KV{"id": 1})
+	_, err = ParseCustomToken(token)
+	assert.NoError(t, err)
+	assert.Error(t, RevokeToken("invalid"))
+}
+
+func TestTokenPair_MemoryStore(t *testing.T) {
+	testTokenPair(t, NewMemoryRevocationStore())
+}
+
+func TestTokenPair_RedisStore(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	testTokenPair(t, NewRedisRevocationStore(client))
+
+	// the store error is returned
+	Init(WithRevocationStore(NewRedisRevocationStore(client, "test:")))
+	defer func() { opt = nil }()
+	pair, _ := GenerateTokenPair("123")
+	mr.SetError("server error")
+	_, err = ParseToken(pair.AccessToken)
+	assert.Error(t, err)
+	_, err = RefreshTokenPair(pair.RefreshToken)
+	assert.Error(t, err)
+	mr.SetError("")
+}
+
+func TestRevokeToken_Refreshed(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	defer func() { opt = nil }()
+	Init(WithExpire(time.Minute), WithRevocationStore(NewRedisRevocationStore(client)))
+
+	// the old copy expires soon, the refreshed copy has the same id
+	oldToken, err := signToken(&Claims{
+		UID: "123",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Second * 2)),
+			ID:        newTokenID(),
+		},
+	})
+	assert.NoError(t, err)
+	newToken, err := RefreshToken(oldToken)
+	assert.NoError(t, err)
+
+	// revoke the old copy, the refreshed copy stays rejected after the old copy expires
+	assert.NoError(t, RevokeToken(oldToken))
+	mr.FastForward(time.Second * 10)
+	_, err = ParseToken(newToken)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+	_, err = RefreshToken(newToken)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+}
+
+func TestTokenPair_WithoutStore(t *testing.T) {
+	defer func() { opt = nil }()
+	_, err := GenerateTokenPair("123")
+	assert.Error(t, err)
+	_, err = RefreshTokenPair("token")
+	assert.Error(t, err)
+	assert.Error(t, RevokeToken("token"))
+
+	Init()
+	pair, err := GenerateTokenPair("123")
+	assert.NoError(t, err)
+	_, err = RefreshTokenPair(pair.RefreshToken)
+	assert.NoError(t, err)
+	assert.Error(t, RevokeToken(pair.AccessToken))
+}
+
+func TestMemoryRevocationStore(t *testing.T) {
+	s := NewMemoryRevocationStore()
+	ctx := context.Background()
+	ok, _ := s.Revoke(ctx, "id1", time.Millisecond*50)
+	assert.True(t, ok)
+	ok, _ = s.Revoke(ctx, "id1", time.Millisecond*50)
+	assert.False(t, ok)
+	isRevoked, _ := s.IsRevoked(ctx, "id0", "id1")
+	assert.True(t, isRevoked)
+
+	// expired
+	time.Sleep(time.Millisecond * 60)
+	isRevoked, _ = s.IsRevoked(ctx, "id1")
+	assert.False(t, isRevoked)
+	s.lastCleanup = time.Now().Add(-time.Hour)
+	ok, _ = s.Revoke(ctx, "id2", time.Minute)
+	assert.True(t, ok)
+	assert.Len(t, s.ids, 1)
+}
@@ -0,0 +1,105 @@
+package jwt
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RevocationStore stores the ids of the revoked tokens until they expire
+type RevocationStore interface {
+	// Revoke the id until expiration, return false if the id has been revoked
+	Revoke(ctx context.Context, id string, expiration time.Duration) (bool, error)
+	// IsRevoked return true if any of the ids is revoked
+	IsRevoked(ctx context.Context, ids ...string) (bool, error)
+}
+
+// MemoryRevocationStore revocation store in memory, used in single instance
+type MemoryRevocationStore struct {
+	mu          sync.Mutex
+	ids         map[string]time.Time // id and expiration time
+	lastCleanup time.Time
+}
+
+// NewMemoryRevocationStore create a memory revocation store
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		ids:         make(map[string]time.Time),
+		lastCleanup: time.Now(),
+	}
+}
+
+// Revoke the id until expiration
+func (s *MemoryRevocationStore) Revoke(_ context.Context, id string, expiration time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastCleanup) > time.Minute {
+		for k, expiredAt := range s.ids {
+			if now.After(expiredAt) {
+				delete(s.ids, k)
+			}
+		}
+		s.lastCleanup = now
+	}
+
+	if expiredAt, ok := s.ids[id]; ok && now.Before(expiredAt) {
+		return false, nil
+	}
+	s.ids[id] = now.Add(expiration)
+	return true, nil
+}
+
+// IsRevoked return true if any of the ids is revoked
+func (s *MemoryRevocationStore) IsRevoked(_ context.Context, ids ...string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for _, id := range ids {
+		if expiredAt, ok := s.ids[id]; ok && now.Before(expiredAt) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// RedisRevocationStore revocation store based on redis, used in multiple instances,
+// the client can be created by pkg/goredis.
+type RedisRevocationStore struct {
+	client    redis.UniversalClient
+	keyPrefix string
+}
+
+// NewRedisRevocationStore create a redis revocation store, the default key prefix is "jwt:revoked:"
+func NewRedisRevocationStore(client redis.UniversalClient, keyPrefix ...string) *RedisRevocationStore {
+	prefix := "jwt:revoked:"
+	if len(keyPrefix) > 0 && keyPrefix[0] != "" {
+		prefix = keyPrefix[0]
+	}
+	return &RedisRevocationStore{client: client, keyPrefix: prefix}
+}
+
+// Revoke the id until expiration
+func (s *RedisRevocationStore) Revoke(ctx context.Context, id string, expiration time.Duration) (bool, error) {
+	return s.client.SetNX(ctx, s.keyPrefix+id, 1, expiration).Result()
+}
+
+// IsRevoked return true if any of the ids is revoked
+func (s *RedisRevocationStore) IsRevoked(ctx context.Context, ids ...string) (bool, error) {
+	if len(ids) == 0 {
+		return false, nil
+	}
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, s.keyPrefix+id)
+	}
+	n, err := s.client.Exists(ctx, keys...).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
@@ -2,9 +2,12 @@ package kafka
 
 import (
 	"context"
+	"time"
 
 	"github.com/IBM/sarama"
 	"go.uber.org/zap"
+
+	"github.com/zhufuyi/sponge/pkg/internal/workerpool"
 )
 
 // ---------------------------------- consume group---------------------------------------
@@ -15,6 +18,17 @@ type ConsumerGroup struct {
 	groupID          string
 	zapLogger        *zap.Logger
 	autoCommitEnable bool
+
+	retry         *retryHandler // nil means no retry
+	retryProducer sarama.SyncProducer
+
+	batchSize   int
+	batchWindow time.Duration
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+
+	workers    int
+	orderKeyFn OrderKeyFn
 }
 
 // InitConsumerGroup init consumer group
@@ -38,9 +52,33 @@ func InitConsumerGroup(addrs []string, groupID string, opts ...ConsumerOption) (
 			config.Net.TLS.Enable = true
 		}
 	}
+	if o.manualCommit {
+		config.Consumer.Offsets.AutoCommit.Enable = false
+	}
+
+	// the producer created here is closed with the consumer group
+	var retryProducer sarama.SyncProducer
+	retry := newRetryHandler(groupID, o)
+	if retry != nil && retry.needProducer() && retry.producer == nil {
+		producerConfig := sarama.NewConfig()
+		producerConfig.Version = config.Version
+		producerConfig.ClientID = config.ClientID
+		producerConfig.Net = config.Net
+		producerConfig.Producer.RequiredAcks = sarama.WaitForAll
+		producerConfig.Producer.Return.Successes = true
+		producer, err := sarama.NewSyncProducer(addrs, producerConfig)
+		if err != nil {
+			return nil, err
+		}
+		retry.producer = producer
+		retryProducer = producer
+	}
 
 	consumer, err := sarama.NewConsumerGroup(addrs, groupID, config)
 	if err != nil {
+		if retryProducer != nil {
+			_ = retryProducer.Close()
+		}
 		return nil, err
 	}
 	return &ConsumerGroup{
@@ -48,16 +86,30 @@ func InitConsumerGroup(addrs []string, groupID string, opts ...ConsumerOption) (
 		groupID:          groupID,
 		zapLogger:        o.zapLogger,
 		autoCommitEnable: config.Consumer.Offsets.AutoCommit.Enable,
+		retry:            retry,
+		retryProducer:    retryProducer,
+		batchSize:        o.batchSize,
+		batchWindow:      o.batchWindow,
+		minBackoff:       o.retryMinBackoff,
+		maxBackoff:       o.retryMaxBackoff,
+		workers:          o.workers,
+		orderKeyFn:       o.orderKeyFn,
 	}, nil
 }
 
-// Consume consume messages
+// Consume consume messages, if the retry topics are set, the retry topics of the topics are also consumed.
 func (c *ConsumerGroup) Consume(ctx context.Context, topics []string, handleMessageFn HandleMessageFn) error {
 	handler := &defaultConsumerHandler{
 		ctx:              ctx,
 		handleMessageFn:  handleMessageFn,
 		zapLogger:        c.zapLogger,
 		autoCommitEnable: c.autoCommitEnable,
+		retry:            c.retry,
+		workers:          c.workers,
+		orderKeyFn:       c.orderKeyFn,
+	}
+	if c.retry != nil {
+		topics = c.retry.withRetryTopics(topics)
 	}
 
 	err := c.Group.Consume(ctx, topics, handler)
@@ -78,11 +130,16 @@ func (c *ConsumerGroup) ConsumeCustom(ctx context.Context, topics []string, hand
 	return nil
 }
 
+// Close the consumer group
 func (c *ConsumerGroup) Close() error {
 	if c == nil || c.Group == nil {
-		return c.Group.Close()
+		return nil
 	}
-	return nil
+	err := c.Group.Close()
+	if c.retryProducer != nil {
+		_ = c.retryProducer.Close()
+	}
+	return err
 }
 
 type defaultConsumerHandler struct {
@@ -90,6 +147,9 @@ type defaultConsumerHandler struct {
 	handleMessageFn  HandleMessageFn
 	zapLogger        *zap.Logger
 	autoCommitEnable bool
+	retry            *retryHandler
+	workers          int
+	orderKeyFn       OrderKeyFn
 }
 
 // Setup is run at the beginning of a new session, before ConsumeClaim
@@ -113,6 +173,10 @@ func (h *defaultConsumerHandler) ConsumeClaim(sess sarama.ConsumerGroupSession,
 		}
 	}()
 
+	if h.workers > 1 {
+		return h.consumeConcurrently(sess, claim)
+	}
+
 	for {
 		select {
 		case <-h.ctx.Done():
@@ -121,10 +185,16 @@ func (h *defaultConsumerHandler) ConsumeClaim(sess sarama.ConsumerGroupSession,
 			if !ok {
 				return nil
 			}
-			err := h.handleMessageFn(msg)
-			if err != nil {
-				h.zapLogger.Error("failed to handle message", zap.Error(err))
-				continue
+			if h.retry != nil {
+				if !h.retry.process(h.ctx, sess, msg, h.handleMessageFn) {
+					return nil
+				}
+			} else {
+				err := h.handleMessageFn(msg)
+				if err != nil {
+					h.zapLogger.Error("failed to handle message", zap.Error(err))
+					continue
+				}
 			}
 			sess.MarkMessage(msg, "")
 			if !h.autoCommitEnable {
@@ -134,6 +204,50 @@ func (h *defaultConsumerHandler) ConsumeClaim(sess sarama.ConsumerGroupSession,
 	}
 }
 
+// handle the messages by the workers, the submitted messages are drained when the consumption ends
+func (h *defaultConsumerHandler) consumeConcurrently(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	tracker := newOffsetTracker(sess, h.autoCommitEnable)
+	pool := workerpool.New(h.workers, h.zapLogger)
+	defer pool.Close()
+
+	for {
+		select {
+		case <-h.ctx.Done():
+			return nil
+		case msg, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+			m := tracker.add(msg)
+			key := ""
+			if h.orderKeyFn != nil {
+				key = h.orderKeyFn(msg)
+			}
+			if !pool.Submit(h.ctx, key, func() { h.handle(sess, tracker, m) }) {
+				return nil
+			}
+		}
+	}
+}
+
+func (h *defaultConsumerHandler) handle(sess sarama.ConsumerGroupSession, tracker *offsetTracker, m *trackedMessage) {
+	canceled := false
+	defer func() {
+		// the message that panics is also marked done, it is the same as consuming in one goroutine
+		if !canceled {
+			tracker.done(m)
+		}
+	}()
+
+	if h.retry != nil {
+		canceled = !h.retry.process(h.ctx, sess, m.msg, h.handleMessageFn)
+		return
+	}
+	if err := h.handleMessageFn(m.msg); err != nil {
+		h.zapLogger.Error("failed to handle message", zap.Error(err))
+	}
+}
+
 // ---------------------------------- consume partition------------------------------------
 
 // Consumer consume partition
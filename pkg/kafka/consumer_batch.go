@@ -0,0 +1,158 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/IBM/sarama"
+	"go.uber.org/zap"
+)
+
+// HandleBatchFn is a function that handles a batch of messages from the same partition
+type HandleBatchFn func(msgs []*sarama.ConsumerMessage) error
+
+// ConsumeBatch consume messages in batches, see ConsumerWithBatch, the offsets are marked only after the
+// batch is handled successfully, a failed or panicked batch is retried with the backoff of ConsumerWithRetryBackoff
+// until it succeeds or the session ends, so the delivery is at least once. when a rebalance occurs, the
+// messages already received are handled and committed before the partitions are revoked.
+func (c *ConsumerGroup) ConsumeBatch(ctx context.Context, topics []string, handleBatchFn HandleBatchFn) error {
+	handler := &batchConsumerHandler{
+		ctx:              ctx,
+		handleBatchFn:    handleBatchFn,
+		zapLogger:        c.zapLogger,
+		autoCommitEnable: c.autoCommitEnable,
+		size:             c.batchSize,
+		window:           c.batchWindow,
+		minBackoff:       c.minBackoff,
+		maxBackoff:       c.maxBackoff,
+	}
+
+	err := c.Group.Consume(ctx, topics, handler)
+	if err != nil {
+		c.zapLogger.Error("failed to consume messages", zap.String("group_id", c.groupID), zap.Strings("topics", topics), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+type batchConsumerHandler struct {
+	ctx              context.Context
+	handleBatchFn    HandleBatchFn
+	zapLogger        *zap.Logger
+	autoCommitEnable bool
+
+	size       int
+	window     time.Duration
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+// Setup is run at the beginning of a new session, before ConsumeClaim
+func (h *batchConsumerHandler) Setup(sess sarama.ConsumerGroupSession) error {
+	h.zapLogger.Info("consumer group session [setup]", zap.Any("claims", sess.Claims()))
+	return nil
+}
+
+// Cleanup is run at the end of a session, once all ConsumeClaim goroutines have exited,
+// commit the offsets of the drained batches before the partitions are revoked.
+func (h *batchConsumerHandler) Cleanup(sess sarama.ConsumerGroupSession) error {
+	sess.Commit()
+	h.zapLogger.Info("consumer group session [cleanup]", zap.Any("claims", sess.Claims()))
+	return nil
+}
+
+// ConsumeClaim consumes messages in batches
+func (h *batchConsumerHandler) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	var batch []*sarama.ConsumerMessage
+	var timer *time.Timer
+	var timeout <-chan time.Time
+	stopTimer := func() {
+		if timer != nil {
+			timer.Stop()
+			timer, timeout = nil, nil
+		}
+	}
+	defer stopTimer()
+
+	for {
+		select {
+		case <-h.ctx.Done():
+			// the messages not committed will be consumed again
+			return nil
+		case <-sess.Context().Done():
+			// rebalance, drain the messages received
+			h.handle(sess, batch)
+			return nil
+		case <-timeout:
+			stopTimer()
+			if !h.handle(sess, batch) {
+				return nil
+			}
+			batch = nil
+		case msg, ok := <-claim.Messages():
+			if !ok {
+				stopTimer()
+				h.handle(sess, batch)
+				return nil
+			}
+			batch = append(batch, msg)
+			if len(batch) == 1 {
+				timer = time.NewTimer(h.window)
+				timeout = timer.C
+			}
+			if len(batch) >= h.size {
+				stopTimer()
+				if !h.handle(sess, batch) {
+					return nil
+				}
+				batch = nil
+			}
+		}
+	}
+}
+
+// handle the batch until success, return false if the session ends before the batch succeeds,
+// a panic in handleBatchFn is treated as a failed batch, the batch is not marked and is retried.
+func (h *batchConsumerHandler) handle(sess sarama.ConsumerGroupSession, batch []*sarama.ConsumerMessage) bool {
+	if len(batch) == 0 {
+		return true
+	}
+	if h.ctx.Err() != nil {
+		return false
+	}
+
+	first, last := batch[0], batch[len(batch)-1]
+	backoff := h.minBackoff
+	for {
+		err := h.safeHandleBatch(batch)
+		if err == nil {
+			break
+		}
+		h.zapLogger.Error("failed to handle batch", zap.Error(err), zap.String("topic", first.Topic), zap.Int32("partition", first.Partition),
+			zap.Int64("first_offset", first.Offset), zap.Int64("last_offset", last.Offset))
+		if !sleep(h.ctx, sess.Context(), backoff) {
+			return false
+		}
+		if backoff *= 2; backoff > h.maxBackoff {
+			backoff = h.maxBackoff
+		}
+	}
+
+	sess.MarkMessage(last, "")
+	if !h.autoCommitEnable {
+		sess.Commit()
+	}
+	return true
+}
+
+// call handleBatchFn, recover the panic and return it as an error
+func (h *batchConsumerHandler) safeHandleBatch(batch []*sarama.ConsumerMessage) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			h.zapLogger.Error("panic occurred while handling batch", zap.Any("error", e))
+			err = fmt.Errorf("panic: %v", e)
+		}
+	}()
+	return h.handleBatchFn(batch)
+}
@@ -0,0 +1,196 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type batchRecorder struct {
+	mu      sync.Mutex
+	batches [][]int64
+	failFn  func(msgs []*sarama.ConsumerMessage) error
+}
+
+func (r *batchRecorder) handle(msgs []*sarama.ConsumerMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failFn != nil {
+		if err := r.failFn(msgs); err != nil {
+			return err
+		}
+	}
+	var offsets []int64
+	for _, msg := range msgs {
+		offsets = append(offsets, msg.Offset)
+	}
+	r.batches = append(r.batches, offsets)
+	return nil
+}
+
+func (r *batchRecorder) get() [][]int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([][]int64(nil), r.batches...)
+}
+
+func newBatchHandler(ctx context.Context, fn HandleBatchFn, size int, window time.Duration) *batchConsumerHandler {
+	return &batchConsumerHandler{
+		ctx:              ctx,
+		handleBatchFn:    fn,
+		zapLogger:        zap.NewNop(),
+		autoCommitEnable: false,
+		size:             size,
+		window:           window,
+		minBackoff:       time.Millisecond,
+		maxBackoff:       time.Millisecond * 5,
+	}
+}
+
+func newClaim(offsets ...int64) *fakeClaim {
+	claim := &fakeClaim{ch: make(chan *sarama.ConsumerMessage, 100)}
+	for _, offset := range offsets {
+		claim.ch <- &sarama.ConsumerMessage{Topic: "orders", Offset: offset}
+	}
+	return claim
+}
+
+func TestBatchConsumerHandler_Size(t *testing.T) {
+	rec := &batchRecorder{}
+	h := newBatchHandler(context.Background(), rec.handle, 2, time.Hour)
+	sess := &fakeSession{ctx: context.Background()}
+
+	claim := newClaim(1, 2, 3, 4, 5)
+	close(claim.ch)
+	err := h.ConsumeClaim(sess, claim)
+	assert.NoError(t, err)
+
+	// the last batch is drained when the claim is closed
+	assert.Equal(t, [][]int64{{1, 2}, {3, 4}, {5}}, rec.get())
+	assert.Equal(t, []int64{2, 4, 5}, sess.marked)
+	assert.Equal(t, 3, sess.commits)
+
+	assert.NoError(t, h.Setup(sess))
+	assert.NoError(t, h.Cleanup(sess))
+	assert.Equal(t, 4, sess.commits)
+}
+
+func TestBatchConsumerHandler_Window(t *testing.T) {
+	rec := &batchRecorder{}
+	h := newBatchHandler(context.Background(), rec.handle, 100, time.Millisecond*20)
+	sessCtx, rebalance := context.WithCancel(context.Background())
+	sess := &fakeSession{ctx: sessCtx}
+
+	claim := newClaim(1, 2)
+	done := make(chan struct{})
+	go func() {
+		_ = h.ConsumeClaim(sess, claim)
+		close(done)
+	}()
+
+	time.Sleep(time.Millisecond * 100)
+	assert.Equal(t, [][]int64{{1, 2}}, rec.get())
+
+	// rebalance, the messages received are drained
+	claim.ch <- &sarama.ConsumerMessage{Topic: "orders", Offset: 3}
+	time.Sleep(time.Millisecond * 5)
+	rebalance()
+	<-done
+	assert.Equal(t, [][]int64{{1, 2}, {3}}, rec.get())
+	assert.Equal(t, []int64{2, 3}, sess.marked)
+}
+
+func TestBatchConsumerHandler_Retry(t *testing.T) {
+	rec := &batchRecorder{}
+	count := 0
+	rec.failFn = func(msgs []*sarama.ConsumerMessage) error {
+		count++
+		if count <= 2 {
+			return errors.New("db error")
+		}
+		return nil
+	}
+	h := newBatchHandler(context.Background(), rec.handle, 2, time.Hour)
+	sess := &fakeSession{ctx: context.Background()}
+
+	claim := newClaim(1, 2)
+	close(claim.ch)
+	err := h.ConsumeClaim(sess, claim)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.Equal(t, [][]int64{{1, 2}}, rec.get())
+	assert.Equal(t, []int64{2}, sess.marked)
+
+	// the batch always fails, not marked when the session ends
+	rec.failFn = func(msgs []*sarama.ConsumerMessage) error { return errors.New("db error") }
+	sessCtx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	sess = &fakeSession{ctx: sessCtx}
+	err = h.ConsumeClaim(sess, newClaim(3, 4))
+	assert.NoError(t, err)
+	assert.Empty(t, sess.marked)
+}
+
+func TestBatchConsumerHandler_Panic(t *testing.T) {
+	rec := &batchRecorder{}
+	count := 0
+	rec.failFn = func(msgs []*sarama.ConsumerMessage) error {
+		count++
+		if count == 1 {
+			panic("nil pointer")
+		}
+		return nil
+	}
+	h := newBatchHandler(context.Background(), rec.handle, 2, time.Hour)
+	sess := &fakeSession{ctx: context.Background()}
+
+	// the batch is retried after panic, the messages in flight are not lost
+	claim := newClaim(1, 2, 3)
+	close(claim.ch)
+	err := h.ConsumeClaim(sess, claim)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]int64{{1, 2}, {3}}, rec.get())
+	assert.Equal(t, []int64{2, 3}, sess.marked)
+
+	// always panic, not marked when the session ends
+	rec.failFn = func(msgs []*sarama.ConsumerMessage) error { panic("nil pointer") }
+	sessCtx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	sess = &fakeSession{ctx: sessCtx}
+	err = h.ConsumeClaim(sess, newClaim(4, 5))
+	assert.NoError(t, err)
+	assert.Empty(t, sess.marked)
+}
+
+func TestBatchConsumerHandler_Stop(t *testing.T) {
+	rec := &batchRecorder{}
+	ctx, cancel := context.WithCancel(context.Background())
+	h := newBatchHandler(ctx, rec.handle, 100, time.Hour)
+	sess := &fakeSession{ctx: context.Background()}
+
+	claim := newClaim(1, 2)
+	go func() {
+		time.Sleep(time.Millisecond * 20)
+		cancel()
+	}()
+	err := h.ConsumeClaim(sess, claim)
+	assert.NoError(t, err)
+	// the messages not committed are consumed again after restarting
+	assert.Empty(t, rec.get())
+	assert.Empty(t, sess.marked)
+}
+
+func TestConsumerWithBatch(t *testing.T) {
+	o := defaultConsumerOptions()
+	o.apply(ConsumerWithBatch(10, time.Millisecond*100), ConsumerWithManualCommit())
+	assert.Equal(t, 10, o.batchSize)
+	assert.Equal(t, time.Millisecond*100, o.batchWindow)
+	assert.True(t, o.manualCommit)
+	assert.False(t, o.offsetsAutoCommitEnable)
+}
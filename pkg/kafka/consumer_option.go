@@ -12,6 +12,9 @@ import (
 // HandleMessageFn is a function that handles a message from a partition consumer
 type HandleMessageFn func(msg *sarama.ConsumerMessage) error
 
+// OrderKeyFn returns the key of the message, the messages with the same key are handled in order
+type OrderKeyFn func(msg *sarama.ConsumerMessage) string
+
 // ConsumerOption set options.
 type ConsumerOption func(*consumerOptions)
 
@@ -30,6 +33,23 @@ type consumerOptions struct {
 	config *sarama.Config // default nil
 
 	zapLogger *zap.Logger // default NewProduction
+
+	// retry options, only valid for consumer group
+	retryAttempts   int                 // default 0, number of in-place retries
+	retryMinBackoff time.Duration       // default 100ms
+	retryMaxBackoff time.Duration       // default 5s
+	retryDelays     []time.Duration     // default nil, delays of retry topics
+	deadLetter      bool                // default false
+	retryProducer   sarama.SyncProducer // default nil, created by the addresses of consumer group
+
+	// batch options, only valid for consumer group
+	batchSize    int           // default 100
+	batchWindow  time.Duration // default 1s
+	manualCommit bool          // default false
+
+	// concurrent options, only valid for consumer group
+	workers    int        // default 1
+	orderKeyFn OrderKeyFn // default nil
 }
 
 func (o *consumerOptions) apply(opts ...ConsumerOption) {
@@ -48,6 +68,11 @@ func defaultConsumerOptions() *consumerOptions {
 		offsetsAutoCommitInterval: time.Second,
 		clientID:                  "sarama",
 		zapLogger:                 zapLogger,
+		retryMinBackoff:           time.Millisecond * 100,
+		retryMaxBackoff:           time.Second * 5,
+		batchSize:                 100,
+		batchWindow:               time.Second,
+		workers:                   1,
 	}
 }
 
@@ -122,3 +147,79 @@ func ConsumerWithConfig(config *sarama.Config) ConsumerOption {
 		o.config = config
 	}
 }
+
+// ConsumerWithRetryBackoff set the number of in-place retries when the handler returns an error,
+// the interval starts from minBackoff and doubles after each retry, up to maxBackoff.
+func ConsumerWithRetryBackoff(attempts int, minBackoff time.Duration, maxBackoff time.Duration) ConsumerOption {
+	return func(o *consumerOptions) {
+		if attempts >= 0 {
+			o.retryAttempts = attempts
+		}
+		if minBackoff > 0 {
+			o.retryMinBackoff = minBackoff
+		}
+		if maxBackoff >= o.retryMinBackoff {
+			o.retryMaxBackoff = maxBackoff
+		}
+	}
+}
+
+// ConsumerWithRetryTopics set the delays of retry topics, when the message still fails after the in-place retries,
+// it is sent to the retry topic of the next level and consumed again after the delay, e.g. delays 10s, 1m, 10m
+// means three retry topics, see RetryTopicName. the retry topics must be created in advance, and they are
+// consumed by the same consumer group automatically.
+func ConsumerWithRetryTopics(delays ...time.Duration) ConsumerOption {
+	return func(o *consumerOptions) {
+		o.retryDelays = delays
+	}
+}
+
+// ConsumerWithDeadLetter send the message to the dead letter topic after all retries fail, the failure reason
+// is in the headers, see DeadLetterTopicName. if not enabled, the failed message is skipped.
+func ConsumerWithDeadLetter(enable bool) ConsumerOption {
+	return func(o *consumerOptions) {
+		o.deadLetter = enable
+	}
+}
+
+// ConsumerWithRetryProducer set the producer used to send messages to the retry topics and dead letter topic,
+// if not set, a producer is created with the addresses and TLS config of the consumer group.
+func ConsumerWithRetryProducer(producer sarama.SyncProducer) ConsumerOption {
+	return func(o *consumerOptions) {
+		o.retryProducer = producer
+	}
+}
+
+// ConsumerWithBatch set the batch size and time window of ConsumeBatch, a batch is handled when the number of
+// messages reaches size or the time since the first message of the batch reaches window.
+func ConsumerWithBatch(size int, window time.Duration) ConsumerOption {
+	return func(o *consumerOptions) {
+		if size > 0 {
+			o.batchSize = size
+		}
+		if window > 0 {
+			o.batchWindow = window
+		}
+	}
+}
+
+// ConsumerWithManualCommit disable auto commit, the offsets are committed synchronously only after the
+// message or batch is handled successfully, it also overrides the custom config set by ConsumerWithConfig.
+func ConsumerWithManualCommit() ConsumerOption {
+	return func(o *consumerOptions) {
+		o.manualCommit = true
+		o.offsetsAutoCommitEnable = false
+	}
+}
+
+// ConsumerWithWorkers set the number of workers that handle the messages of each partition concurrently in Consume,
+// if orderKeyFn is not nil, the messages with the same key are handled in order by the same worker, e.g. use the
+// message key. the offsets are still marked in order, a message is marked after all the previous messages are done.
+func ConsumerWithWorkers(workers int, orderKeyFn OrderKeyFn) ConsumerOption {
+	return func(o *consumerOptions) {
+		if workers > 0 {
+			o.workers = workers
+		}
+		o.orderKeyFn = orderKeyFn
+	}
+}
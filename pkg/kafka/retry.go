@@ -0,0 +1,212 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/IBM/sarama"
+	"go.uber.org/zap"
+)
+
+// headers of the messages sent to the retry topics and dead letter topic
+const (
+	HeaderOriginalTopic     = "x-original-topic"     // topic of the message consumed for the first time
+	HeaderOriginalPartition = "x-original-partition" // partition of the message consumed for the first time
+	HeaderOriginalOffset    = "x-original-offset"    // offset of the message consumed for the first time
+	HeaderRetryCount        = "x-retry-count"        // number of times the message has been sent to the retry topics
+	HeaderDeliverAt         = "x-deliver-at"         // unix milliseconds, the message in the retry topic is consumed after this time
+	HeaderErrorMessage      = "x-error-message"      // error returned by the handler at the last time
+	HeaderFailedAt          = "x-failed-at"          // unix milliseconds, the time of the last failure
+)
+
+// RetryTopicName the name of the retry topic, level starts from 1, e.g. orders.my-group.retry.1
+func RetryTopicName(topic string, groupID string, level int) string {
+	return fmt.Sprintf("%s.%s.retry.%d", topic, groupID, level)
+}
+
+// DeadLetterTopicName the name of the dead letter topic, e.g. orders.my-group.dlt
+func DeadLetterTopicName(topic string, groupID string) string {
+	return fmt.Sprintf("%s.%s.dlt", topic, groupID)
+}
+
+type retryHandler struct {
+	groupID    string
+	attempts   int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	delays     []time.Duration
+	deadLetter bool
+	producer   sarama.SyncProducer
+	zapLogger  *zap.Logger
+}
+
+func newRetryHandler(groupID string, o *consumerOptions) *retryHandler {
+	if o.retryAttempts == 0 && len(o.retryDelays) == 0 && !o.deadLetter {
+		return nil
+	}
+	return &retryHandler{
+		groupID:    groupID,
+		attempts:   o.retryAttempts,
+		minBackoff: o.retryMinBackoff,
+		maxBackoff: o.retryMaxBackoff,
+		delays:     o.retryDelays,
+		deadLetter: o.deadLetter,
+		producer:   o.retryProducer,
+		zapLogger:  o.zapLogger,
+	}
+}
+
+func (h *retryHandler) needProducer() bool {
+	return len(h.delays) > 0 || h.deadLetter
+}
+
+// add the retry topics of the topics
+func (h *retryHandler) withRetryTopics(topics []string) []string {
+	allTopics := append([]string{}, topics...)
+	for _, topic := range topics {
+		for i := range h.delays {
+			allTopics = append(allTopics, RetryTopicName(topic, h.groupID, i+1))
+		}
+	}
+	return allTopics
+}
+
+// process the message with retries, return false if the consumption is canceled, the message should not be marked
+func (h *retryHandler) process(ctx context.Context, sess sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage, fn HandleMessageFn) bool {
+	// the message in the retry topic is delayed
+	if deliverAt := getHeaderInt(msg, HeaderDeliverAt); deliverAt > 0 {
+		if !sleep(ctx, sess.Context(), time.Until(time.UnixMilli(deliverAt))) {
+			return false
+		}
+	}
+
+	// the handler always gets the original topic
+	m := msg
+	originalTopic := getHeader(msg, HeaderOriginalTopic)
+	if originalTopic != "" {
+		cp := *msg
+		cp.Topic = originalTopic
+		m = &cp
+	} else {
+		originalTopic = msg.Topic
+	}
+
+	err := fn(m)
+	backoff := h.minBackoff
+	for i := 0; err != nil && i < h.attempts; i++ {
+		if !sleep(ctx, sess.Context(), backoff) {
+			return false
+		}
+		if backoff *= 2; backoff > h.maxBackoff {
+			backoff = h.maxBackoff
+		}
+		err = fn(m)
+	}
+	if err == nil {
+		return true
+	}
+
+	retryCount := int(getHeaderInt(msg, HeaderRetryCount))
+	headers := map[string]string{
+		HeaderOriginalTopic:     originalTopic,
+		HeaderOriginalPartition: strconv.Itoa(int(msg.Partition)),
+		HeaderOriginalOffset:    strconv.FormatInt(msg.Offset, 10),
+		HeaderRetryCount:        strconv.Itoa(retryCount),
+		HeaderErrorMessage:      err.Error(),
+		HeaderFailedAt:          strconv.FormatInt(time.Now().UnixMilli(), 10),
+	}
+	if getHeader(msg, HeaderOriginalTopic) != "" { // keep the position of the first consumption
+		headers[HeaderOriginalPartition] = getHeader(msg, HeaderOriginalPartition)
+		headers[HeaderOriginalOffset] = getHeader(msg, HeaderOriginalOffset)
+	}
+
+	var target string
+	switch {
+	case retryCount < len(h.delays):
+		target = RetryTopicName(originalTopic, h.groupID, retryCount+1)
+		headers[HeaderRetryCount] = strconv.Itoa(retryCount + 1)
+		headers[HeaderDeliverAt] = strconv.FormatInt(time.Now().Add(h.delays[retryCount]).UnixMilli(), 10)
+	case h.deadLetter:
+		target = DeadLetterTopicName(originalTopic, h.groupID)
+	default:
+		h.zapLogger.Error("failed to handle message, discard it", zap.Error(err), zap.String("topic", originalTopic),
+			zap.Int32("partition", msg.Partition), zap.Int64("offset", msg.Offset))
+		return true
+	}
+
+	return h.forward(ctx, sess, msg, target, headers)
+}
+
+// forward the message to the retry topic or dead letter topic, keep trying until success or canceled
+func (h *retryHandler) forward(ctx context.Context, sess sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage, topic string, headers map[string]string) bool {
+	pm := &sarama.ProducerMessage{
+		Topic: topic,
+		Value: sarama.ByteEncoder(msg.Value),
+	}
+	if msg.Key != nil {
+		pm.Key = sarama.ByteEncoder(msg.Key)
+	}
+	for _, header := range msg.Headers {
+		if header == nil {
+			continue
+		}
+		if _, ok := headers[string(header.Key)]; ok {
+			continue
+		}
+		pm.Headers = append(pm.Headers, *header)
+	}
+	for k, v := range headers {
+		pm.Headers = append(pm.Headers, sarama.RecordHeader{Key: []byte(k), Value: []byte(v)})
+	}
+
+	backoff := h.minBackoff
+	for {
+		_, _, err := h.producer.SendMessage(pm)
+		if err == nil {
+			h.zapLogger.Warn("failed to handle message, forwarded", zap.String("to", topic), zap.String("error", headers[HeaderErrorMessage]),
+				zap.String("topic", msg.Topic), zap.Int32("partition", msg.Partition), zap.Int64("offset", msg.Offset))
+			return true
+		}
+		h.zapLogger.Error("failed to forward message", zap.Error(err), zap.String("to", topic),
+			zap.String("topic", msg.Topic), zap.Int32("partition", msg.Partition), zap.Int64("offset", msg.Offset))
+		if !sleep(ctx, sess.Context(), backoff) {
+			return false
+		}
+		if backoff *= 2; backoff > h.maxBackoff {
+			backoff = h.maxBackoff
+		}
+	}
+}
+
+func getHeader(msg *sarama.ConsumerMessage, key string) string {
+	for _, header := range msg.Headers {
+		if header != nil && string(header.Key) == key {
+			return string(header.Value)
+		}
+	}
+	return ""
+}
+
+func getHeaderInt(msg *sarama.ConsumerMessage, key string) int64 {
+	v, _ := strconv.ParseInt(getHeader(msg, key), 10, 64)
+	return v
+}
+
+// sleep for a while, return false if one of the contexts is done
+func sleep(ctx1 context.Context, ctx2 context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx1.Err() == nil && ctx2.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx1.Done():
+		return false
+	case <-ctx2.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,202 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type fakeSession struct {
+	ctx     context.Context
+	mu      sync.Mutex
+	marked  []int64
+	commits int
+}
+
+func (s *fakeSession) Claims() map[string][]int32                       { return nil }
+func (s *fakeSession) MemberID() string                                 { return "member" }
+func (s *fakeSession) GenerationID() int32                              { return 1 }
+func (s *fakeSession) MarkOffset(_ string, _ int32, _ int64, _ string)  {}
+func (s *fakeSession) ResetOffset(_ string, _ int32, _ int64, _ string) {}
+func (s *fakeSession) Context() context.Context                         { return s.ctx }
+func (s *fakeSession) Commit() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commits++
+}
+func (s *fakeSession) MarkMessage(msg *sarama.ConsumerMessage, _ string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.marked = append(s.marked, msg.Offset)
+}
+
+type fakeClaim struct {
+	ch chan *sarama.ConsumerMessage
+}
+
+func (c *fakeClaim) Topic() string                            { return "orders" }
+func (c *fakeClaim) Partition() int32                         { return 0 }
+func (c *fakeClaim) InitialOffset() int64                     { return 0 }
+func (c *fakeClaim) HighWaterMarkOffset() int64               { return 0 }
+func (c *fakeClaim) Messages() <-chan *sarama.ConsumerMessage { return c.ch }
+
+func newRetryOptions(producer sarama.SyncProducer, opts ...ConsumerOption) *consumerOptions {
+	o := defaultConsumerOptions()
+	o.apply(append([]ConsumerOption{ConsumerWithZapLogger(zap.NewNop()), ConsumerWithRetryProducer(producer)}, opts...)...)
+	return o
+}
+
+func TestRetryTopicName(t *testing.T) {
+	assert.Equal(t, "orders.g1.retry.1", RetryTopicName("orders", "g1", 1))
+	assert.Equal(t, "orders.g1.dlt", DeadLetterTopicName("orders", "g1"))
+
+	assert.Nil(t, newRetryHandler("g1", defaultConsumerOptions()))
+	h := newRetryHandler("g1", newRetryOptions(nil, ConsumerWithRetryTopics(time.Second, time.Minute)))
+	assert.Equal(t, []string{"orders", "orders.g1.retry.1", "orders.g1.retry.2"}, h.withRetryTopics([]string{"orders"}))
+}
+
+func TestRetryHandler_InPlace(t *testing.T) {
+	sess := &fakeSession{ctx: context.Background()}
+	h := newRetryHandler("g1", newRetryOptions(nil, ConsumerWithRetryBackoff(2, time.Millisecond, time.Millisecond*2)))
+	assert.False(t, h.needProducer())
+
+	count := 0
+	fn := func(msg *sarama.ConsumerMessage) error {
+		count++
+		if count < 3 {
+			return errors.New("error")
+		}
+		return nil
+	}
+	ok := h.process(context.Background(), sess, &sarama.ConsumerMessage{Topic: "orders"}, fn)
+	assert.True(t, ok)
+	assert.Equal(t, 3, count)
+
+	// exceed the attempts, no retry topic and dead letter, discard
+	count = -10
+	ok = h.process(context.Background(), sess, &sarama.ConsumerMessage{Topic: "orders"}, fn)
+	assert.True(t, ok)
+	assert.Equal(t, -7, count)
+
+	// canceled during backoff
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ok = h.process(ctx, sess, &sarama.ConsumerMessage{Topic: "orders"}, fn)
+	assert.False(t, ok)
+}
+
+func TestRetryHandler_RetryTopicAndDeadLetter(t *testing.T) {
+	sp := mocks.NewSyncProducer(t, nil)
+	defer sp.Close()
+	sess := &fakeSession{ctx: context.Background()}
+	h := newRetryHandler("g1", newRetryOptions(sp,
+		ConsumerWithRetryTopics(time.Millisecond*50),
+		ConsumerWithDeadLetter(true),
+		ConsumerWithRetryBackoff(0, time.Millisecond, time.Millisecond),
+	))
+	assert.True(t, h.needProducer())
+
+	var topics []string
+	fn := func(msg *sarama.ConsumerMessage) error {
+		topics = append(topics, msg.Topic)
+		return errors.New("db error")
+	}
+
+	// first failure, sent to the retry topic
+	var forwarded *sarama.ProducerMessage
+	checker := func(pm *sarama.ProducerMessage) error {
+		forwarded = pm
+		return nil
+	}
+	sp.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(checker)
+	msg := &sarama.ConsumerMessage{
+		Topic: "orders", Partition: 2, Offset: 10, Key: []byte("k1"), Value: []byte("v1"),
+		Headers: []*sarama.RecordHeader{{Key: []byte("trace-id"), Value: []byte("abc")}},
+	}
+	ok := h.process(context.Background(), sess, msg, fn)
+	assert.True(t, ok)
+	assert.Equal(t, "orders.g1.retry.1", forwarded.Topic)
+	retryMsg := toConsumerMessage(forwarded, 0, 5)
+	assert.Equal(t, "abc", getHeader(retryMsg, "trace-id"))
+	assert.Equal(t, "1", getHeader(retryMsg, HeaderRetryCount))
+	assert.Equal(t, "orders", getHeader(retryMsg, HeaderOriginalTopic))
+	assert.Equal(t, "db error", getHeader(retryMsg, HeaderErrorMessage))
+
+	// consumed from the retry topic after the delay, then sent to the dead letter topic
+	start := time.Now()
+	sp.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(checker)
+	ok = h.process(context.Background(), sess, retryMsg, fn)
+	assert.True(t, ok)
+	assert.True(t, time.Since(start) >= time.Millisecond*30)
+	assert.Equal(t, []string{"orders", "orders"}, topics)
+	assert.Equal(t, "orders.g1.dlt", forwarded.Topic)
+	dltMsg := toConsumerMessage(forwarded, 0, 1)
+	assert.Equal(t, "2", getHeader(dltMsg, HeaderOriginalPartition))
+	assert.Equal(t, "10", getHeader(dltMsg, HeaderOriginalOffset))
+	assert.Equal(t, "1", getHeader(dltMsg, HeaderRetryCount))
+	assert.Equal(t, "db error", getHeader(dltMsg, HeaderErrorMessage))
+
+	// failed to forward, retry until canceled
+	h.producer = &failedProducer{SyncProducer: sp}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	ok = h.process(ctx, sess, msg, fn)
+	assert.False(t, ok)
+}
+
+type failedProducer struct {
+	sarama.SyncProducer
+}
+
+func (p *failedProducer) SendMessage(_ *sarama.ProducerMessage) (int32, int64, error) {
+	return 0, 0, sarama.ErrOutOfBrokers
+}
+
+func TestDefaultConsumerHandler_Retry(t *testing.T) {
+	sp := mocks.NewSyncProducer(t, nil)
+	defer sp.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	h := &defaultConsumerHandler{
+		ctx: ctx,
+		handleMessageFn: func(msg *sarama.ConsumerMessage) error {
+			if string(msg.Value) == "bad" {
+				return errors.New("invalid message")
+			}
+			return nil
+		},
+		zapLogger:        zap.NewNop(),
+		autoCommitEnable: false,
+		retry:            newRetryHandler("g1", newRetryOptions(sp, ConsumerWithDeadLetter(true))),
+	}
+	sp.ExpectSendMessageAndSucceed()
+
+	sess := &fakeSession{ctx: ctx}
+	claim := &fakeClaim{ch: make(chan *sarama.ConsumerMessage, 3)}
+	claim.ch <- &sarama.ConsumerMessage{Topic: "orders", Offset: 1, Value: []byte("good")}
+	claim.ch <- &sarama.ConsumerMessage{Topic: "orders", Offset: 2, Value: []byte("bad")}
+	claim.ch <- &sarama.ConsumerMessage{Topic: "orders", Offset: 3, Value: []byte("good")}
+	close(claim.ch)
+
+	err := h.ConsumeClaim(sess, claim)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 2, 3}, sess.marked)
+}
+
+func toConsumerMessage(pm *sarama.ProducerMessage, partition int32, offset int64) *sarama.ConsumerMessage {
+	msg := &sarama.ConsumerMessage{Topic: pm.Topic, Partition: partition, Offset: offset}
+	msg.Key, _ = pm.Key.Encode()
+	msg.Value, _ = pm.Value.Encode()
+	for i := range pm.Headers {
+		msg.Headers = append(msg.Headers, &pm.Headers[i])
+	}
+	return msg
+}
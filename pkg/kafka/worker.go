@@ -0,0 +1,52 @@
+package kafka
+
+import (
+	"sync"
+
+	"github.com/IBM/sarama"
+)
+
+// offsetTracker mark the offsets in order when the messages are handled concurrently,
+// a message is marked only after all the previous messages of the partition are done.
+type offsetTracker struct {
+	mu               sync.Mutex
+	sess             sarama.ConsumerGroupSession
+	autoCommitEnable bool
+	pending          []*trackedMessage
+}
+
+type trackedMessage struct {
+	msg  *sarama.ConsumerMessage
+	done bool
+}
+
+func newOffsetTracker(sess sarama.ConsumerGroupSession, autoCommitEnable bool) *offsetTracker {
+	return &offsetTracker{sess: sess, autoCommitEnable: autoCommitEnable}
+}
+
+func (t *offsetTracker) add(msg *sarama.ConsumerMessage) *trackedMessage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	m := &trackedMessage{msg: msg}
+	t.pending = append(t.pending, m)
+	return m
+}
+
+func (t *offsetTracker) done(m *trackedMessage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	m.done = true
+
+	var last *sarama.ConsumerMessage
+	for len(t.pending) > 0 && t.pending[0].done {
+		last = t.pending[0].msg
+		t.pending[0] = nil
+		t.pending = t.pending[1:]
+	}
+	if last != nil {
+		t.sess.MarkMessage(last, "")
+		if !t.autoCommitEnable {
+			t.sess.Commit()
+		}
+	}
+}
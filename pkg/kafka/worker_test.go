@@ -0,0 +1,76 @@
+package kafka
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestDefaultConsumerHandler_Workers(t *testing.T) {
+	var handled int32
+	h := &defaultConsumerHandler{
+		ctx: context.Background(),
+		handleMessageFn: func(msg *sarama.ConsumerMessage) error {
+			// the later messages finish earlier
+			time.Sleep(time.Millisecond * time.Duration(20-msg.Offset))
+			atomic.AddInt32(&handled, 1)
+			if msg.Offset == 7 {
+				panic("test panic")
+			}
+			return nil
+		},
+		zapLogger:        zap.NewNop(),
+		autoCommitEnable: false,
+		workers:          5,
+		orderKeyFn: func(msg *sarama.ConsumerMessage) string {
+			return string(msg.Key)
+		},
+	}
+
+	sess := &fakeSession{ctx: context.Background()}
+	claim := &fakeClaim{ch: make(chan *sarama.ConsumerMessage, 20)}
+	for i := 0; i < 20; i++ {
+		key := ""
+		if i%2 == 0 {
+			key = "k" + strconv.Itoa(i%4)
+		}
+		claim.ch <- &sarama.ConsumerMessage{Topic: "orders", Offset: int64(i), Key: []byte(key)}
+	}
+	close(claim.ch)
+
+	err := h.ConsumeClaim(sess, claim)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(20), handled)
+
+	// the offsets are marked in order, and the last one is marked after draining
+	assert.NotEmpty(t, sess.marked)
+	for i := 1; i < len(sess.marked); i++ {
+		assert.Less(t, sess.marked[i-1], sess.marked[i])
+	}
+	assert.Equal(t, int64(19), sess.marked[len(sess.marked)-1])
+	assert.Equal(t, len(sess.marked), sess.commits)
+}
+
+func TestOffsetTracker(t *testing.T) {
+	sess := &fakeSession{ctx: context.Background()}
+	tracker := newOffsetTracker(sess, true)
+	var ms []*trackedMessage
+	for i := 0; i < 4; i++ {
+		ms = append(ms, tracker.add(&sarama.ConsumerMessage{Offset: int64(i)}))
+	}
+
+	tracker.done(ms[1])
+	tracker.done(ms[3])
+	assert.Empty(t, sess.marked)
+	tracker.done(ms[0])
+	assert.Equal(t, []int64{1}, sess.marked)
+	tracker.done(ms[2])
+	assert.Equal(t, []int64{1, 3}, sess.marked)
+	assert.Equal(t, 0, sess.commits)
+}
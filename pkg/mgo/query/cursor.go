@@ -0,0 +1,395 @@
+package query
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrInvalidCursor the cursor is malformed or does not match the sort fields,
+// use errors.Is(err, ErrInvalidCursor) to determine.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// CursorParams cursor (keyset) paging query parameters, unlike skip paging, the query
+// cost does not grow with the page number, it is suitable for collections with a large amount of data.
+//
+// the first page is requested with an empty cursor, the next or previous page is requested
+// with the nextCursor or prevCursor returned by the previous query, example:
+//
+//	{"limit": 20, "sort": "-created_at", "columns": [{"name": "status", "value": 1}]}
+//	{"cursor": "eyJzIjoiLWNyZWF0ZWRfYXQsLV9pZCIsInYiOlt7...", "limit": 20, "columns": [{"name": "status", "value": 1}]}
+type CursorParams struct {
+	Cursor string `json:"cursor" form:"cursor"`                  // opaque cursor returned by the previous query, empty means the first page
+	Limit  int    `json:"limit" form:"limit" binding:"gte=1"`    // number per page
+	Sort   string `json:"sort,omitempty" form:"sort" binding:""` // sort fields, default is -id, must be the same as the sort fields of the cursor
+
+	Columns []Column `json:"columns,omitempty" form:"columns"` // not required
+	Group   *Group   `json:"group,omitempty" form:"group"`     // nested conditions, not required, joined with Columns by $and
+}
+
+// CursorPage cursors of the adjacent pages, an empty cursor means there is no adjacent page
+type CursorPage struct {
+	NextCursor string `json:"nextCursor"`
+	PrevCursor string `json:"prevCursor"`
+}
+
+// CursorQuery mongo query parameters converted from CursorParams, example:
+//
+//	cq, err := params.ConvertToCursor()
+//	findOpts := options.Find().SetSort(cq.Sort).SetLimit(int64(cq.Limit))
+//	cur, err := collection.Find(ctx, cq.Filter, findOpts)
+//	records := []*model.UserExample{}
+//	err = cur.All(ctx, &records)
+//	page, err := cq.Paginate(&records)
+type CursorQuery struct {
+	Filter bson.M // query filter, including the keyset condition of the cursor
+	Sort   bson.D // sort fields
+	Limit  int    // number of records to query, one more than the number per page to determine whether there is an adjacent page
+
+	keys      []sortKey
+	limit     int
+	isPrev    bool
+	hasCursor bool
+}
+
+type sortKey struct {
+	name string
+	desc bool
+}
+
+// encoded content of the cursor
+type cursor struct {
+	Sort   string        `json:"s"`
+	Values []cursorValue `json:"v"`
+	IsPrev bool          `json:"p,omitempty"`
+}
+
+// typed value in the cursor, the type is kept to restore the value as it is
+type cursorValue struct {
+	Type  string `json:"t"`
+	Value string `json:"v"`
+}
+
+// the number per page if the limit is not set
+const defaultCursorLimit = 10
+
+// ConvertToCursor conversion to mongo-compliant parameters of cursor paging,
+// the allowed columns can be restricted by options, e.g. WithAllowedColumns("name", "age")
+func (p *CursorParams) ConvertToCursor(opts ...Option) (*CursorQuery, error) {
+	o := defaultOptions()
+	o.apply(opts...)
+
+	limit := p.Limit
+	if limit > defaultMaxSize {
+		limit = defaultMaxSize
+	} else if limit < 1 {
+		limit = defaultCursorLimit
+	}
+
+	sort := strings.Replace(p.Sort, " ", "", -1)
+	var c *cursor
+	if p.Cursor != "" {
+		var err error
+		c, err = decodeCursor(p.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if sort != "" && getSortKeysStr(sort) != c.Sort {
+			return nil, fmt.Errorf("%w, the sort fields '%s' do not match the cursor", ErrInvalidCursor, sort)
+		}
+		sort = c.Sort
+	}
+
+	keys, err := parseSortKeys(sort, o)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := (&Params{Columns: p.Columns, Group: p.Group}).ConvertToMongoFilter(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	cq := &CursorQuery{
+		Limit:     limit + 1,
+		keys:      keys,
+		limit:     limit,
+		hasCursor: c != nil,
+	}
+
+	if c != nil {
+		cq.isPrev = c.IsPrev
+		keysetFilter, err := keysetFilter(keys, c, c.IsPrev)
+		if err != nil {
+			return nil, err
+		}
+		if len(filter) == 0 {
+			filter = keysetFilter
+		} else {
+			filter = bson.M{"$and": []bson.M{filter, keysetFilter}}
+		}
+	}
+	cq.Filter = filter
+	cq.Sort = sortD(keys, cq.isPrev)
+
+	return cq, nil
+}
+
+// Paginate trim the records to the number per page and generate the cursors of the adjacent pages,
+// the parameter records must be a pointer to the slice of the queried documents, e.g. *[]*model.UserExample,
+// the records are restored to the order of the sort fields when querying the previous page.
+func (q *CursorQuery) Paginate(records interface{}) (*CursorPage, error) {
+	rv := reflect.ValueOf(records)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		return nil, fmt.Errorf("records must be a pointer to slice, but got '%T'", records)
+	}
+	list := rv.Elem()
+
+	hasMore := list.Len() > q.limit
+	if hasMore {
+		list.Set(list.Slice(0, q.limit))
+	}
+	if q.isPrev {
+		reverseSlice(list)
+	}
+
+	page := &CursorPage{}
+	if list.Len() == 0 {
+		return page, nil
+	}
+
+	var err error
+	hasNext, hasPrev := hasMore, q.hasCursor
+	if q.isPrev {
+		hasNext, hasPrev = true, hasMore
+	}
+	if hasNext {
+		page.NextCursor, err = q.encode(list.Index(list.Len()-1).Interface(), false)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if hasPrev {
+		page.PrevCursor, err = q.encode(list.Index(0).Interface(), true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return page, nil
+}
+
+func (q *CursorQuery) encode(record interface{}, isPrev bool) (string, error) {
+	data, err := bson.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+	doc := bson.Raw(data)
+
+	c := &cursor{Sort: sortKeysStr(q.keys), IsPrev: isPrev}
+	for _, key := range q.keys {
+		value, err := doc.LookupErr(strings.Split(key.name, ".")...)
+		if err != nil {
+			return "", fmt.Errorf("field '%s' not found in document", key.name)
+		}
+		cv, err := toCursorValue(value)
+		if err != nil {
+			return "", fmt.Errorf("field '%s': %v", key.name, err)
+		}
+		c.Values = append(c.Values, cv)
+	}
+	return encodeCursor(c)
+}
+
+// keyset filter, e.g. sort=-age,id: {"$or": [{"age": {"$lt": v1}}, {"age": v1, "_id": {"$gt": v2}}]}
+func keysetFilter(keys []sortKey, c *cursor, isPrev bool) (bson.M, error) {
+	if len(c.Values) != len(keys) {
+		return nil, fmt.Errorf("%w, the number of values does not match the sort fields", ErrInvalidCursor)
+	}
+	values := make([]interface{}, 0, len(c.Values))
+	for _, cv := range c.Values {
+		v, err := cv.parse()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+
+	items := make([]bson.M, 0, len(keys))
+	for i, key := range keys {
+		op := "$gt"
+		if key.desc != isPrev {
+			op = "$lt"
+		}
+		item := bson.M{}
+		for j := 0; j < i; j++ {
+			item[keys[j].name] = values[j]
+		}
+		item[key.name] = bson.M{op: values[i]}
+		items = append(items, item)
+	}
+
+	if len(items) == 1 {
+		return items[0], nil
+	}
+	return bson.M{"$or": items}, nil
+}
+
+// parse the sort fields, the _id is appended as a tie-breaker if it is not included
+func parseSortKeys(sort string, o *options) ([]sortKey, error) {
+	if sort == "" {
+		sort = "-" + oidName
+	}
+
+	keys := []sortKey{}
+	hasOID := false
+	for _, name := range strings.Split(sort, ",") {
+		if name == "" {
+			continue
+		}
+		key := sortKey{name: name}
+		if name[0] == '-' {
+			key.name, key.desc = name[1:], true
+		}
+		if key.name == "id" {
+			key.name = oidName
+		}
+		if key.name == "" || strings.HasPrefix(key.name, "$") {
+			return nil, fmt.Errorf("invalid sort field '%s'", name)
+		}
+		if err := o.checkColumn(key.name); err != nil && key.name != oidName {
+			return nil, err
+		}
+		if key.name == oidName {
+			hasOID = true
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		keys = append(keys, sortKey{name: oidName, desc: true})
+		hasOID = true
+	}
+	if !hasOID {
+		keys = append(keys, sortKey{name: oidName, desc: keys[len(keys)-1].desc})
+	}
+
+	return keys, nil
+}
+
+func getSortKeysStr(sort string) string {
+	keys, err := parseSortKeys(sort, &options{})
+	if err != nil {
+		return ""
+	}
+	return sortKeysStr(keys)
+}
+
+func sortKeysStr(keys []sortKey) string {
+	names := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if key.desc {
+			names = append(names, "-"+key.name)
+		} else {
+			names = append(names, key.name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// the sort direction is reversed when querying the previous page
+func sortD(keys []sortKey, isPrev bool) bson.D {
+	d := make(bson.D, 0, len(keys))
+	for _, key := range keys {
+		if key.desc != isPrev {
+			d = append(d, bson.E{Key: key.name, Value: -1})
+		} else {
+			d = append(d, bson.E{Key: key.name, Value: 1})
+		}
+	}
+	return d
+}
+
+func reverseSlice(list reflect.Value) {
+	swap := reflect.Swapper(list.Interface())
+	for i, j := 0, list.Len()-1; i < j; i, j = i+1, j-1 {
+		swap(i, j)
+	}
+}
+
+func encodeCursor(c *cursor) (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(str string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(str)
+	if err != nil {
+		return nil, fmt.Errorf("%w, %v", ErrInvalidCursor, err)
+	}
+	c := &cursor{}
+	if err = json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("%w, %v", ErrInvalidCursor, err)
+	}
+	return c, nil
+}
+
+func toCursorValue(value bson.RawValue) (cursorValue, error) {
+	switch value.Type {
+	case bsontype.ObjectID:
+		return cursorValue{Type: "o", Value: value.ObjectID().Hex()}, nil
+	case bsontype.String:
+		return cursorValue{Type: "s", Value: value.StringValue()}, nil
+	case bsontype.Int32:
+		return cursorValue{Type: "i", Value: strconv.FormatInt(int64(value.Int32()), 10)}, nil
+	case bsontype.Int64:
+		return cursorValue{Type: "i", Value: strconv.FormatInt(value.Int64(), 10)}, nil
+	case bsontype.Double:
+		return cursorValue{Type: "f", Value: strconv.FormatFloat(value.Double(), 'g', -1, 64)}, nil
+	case bsontype.Boolean:
+		return cursorValue{Type: "b", Value: strconv.FormatBool(value.Boolean())}, nil
+	case bsontype.DateTime:
+		return cursorValue{Type: "t", Value: value.Time().UTC().Format(time.RFC3339Nano)}, nil
+	case bsontype.Null, bsontype.Undefined:
+		return cursorValue{}, errors.New("null value cannot be used as cursor, the sort fields must be not null")
+	}
+	return cursorValue{}, fmt.Errorf("unsupported cursor value type '%s'", value.Type)
+}
+
+func (v cursorValue) parse() (interface{}, error) {
+	var (
+		value interface{}
+		err   error
+	)
+	switch v.Type {
+	case "o":
+		value, err = primitive.ObjectIDFromHex(v.Value)
+	case "s":
+		value = v.Value
+	case "i":
+		value, err = strconv.ParseInt(v.Value, 10, 64)
+	case "f":
+		value, err = strconv.ParseFloat(v.Value, 64)
+	case "b":
+		value, err = strconv.ParseBool(v.Value)
+	case "t":
+		value, err = time.Parse(time.RFC3339Nano, v.Value)
+	default:
+		return nil, fmt.Errorf("%w, unknown value type '%s'", ErrInvalidCursor, v.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w, %v", ErrInvalidCursor, err)
+	}
+	return value, nil
+}
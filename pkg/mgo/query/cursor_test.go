@@ -0,0 +1,132 @@
+package query
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type cursorUser struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	Name      string             `bson:"name"`
+	Age       int                `bson:"age"`
+	CreatedAt time.Time          `bson:"created_at"`
+}
+
+func TestCursorParams_ConvertToCursor(t *testing.T) {
+	oids := []primitive.ObjectID{primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()}
+
+	// first page
+	params := &CursorParams{Limit: 2, Sort: "-age", Columns: []Column{{Name: "name", Value: "LiSi"}}}
+	cq, err := params.ConvertToCursor()
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{"name": "LiSi"}, cq.Filter)
+	assert.Equal(t, bson.D{{Key: "age", Value: -1}, {Key: "_id", Value: -1}}, cq.Sort)
+	assert.Equal(t, 3, cq.Limit)
+
+	records := []*cursorUser{
+		{ID: oids[2], Name: "LiSi", Age: 30},
+		{ID: oids[1], Name: "LiSi", Age: 20},
+		{ID: oids[0], Name: "LiSi", Age: 20},
+	}
+	page, err := cq.Paginate(&records)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(records))
+	assert.NotEmpty(t, page.NextCursor)
+	assert.Empty(t, page.PrevCursor)
+
+	// next page
+	params.Cursor = page.NextCursor
+	cq, err = params.ConvertToCursor()
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{"$and": []bson.M{
+		{"name": "LiSi"},
+		{"$or": []bson.M{
+			{"age": bson.M{"$lt": int64(20)}},
+			{"age": int64(20), "_id": bson.M{"$lt": oids[1]}},
+		}},
+	}}, cq.Filter)
+
+	records = []*cursorUser{{ID: oids[0], Name: "LiSi", Age: 20}}
+	page, err = cq.Paginate(&records)
+	assert.NoError(t, err)
+	assert.Empty(t, page.NextCursor)
+	assert.NotEmpty(t, page.PrevCursor)
+
+	// previous page, the sort direction is reversed and the records are restored
+	params.Cursor = page.PrevCursor
+	cq, err = params.ConvertToCursor()
+	assert.NoError(t, err)
+	assert.Equal(t, bson.D{{Key: "age", Value: 1}, {Key: "_id", Value: 1}}, cq.Sort)
+
+	records = []*cursorUser{
+		{ID: oids[1], Name: "LiSi", Age: 20},
+		{ID: oids[2], Name: "LiSi", Age: 30},
+	}
+	page, err = cq.Paginate(&records)
+	assert.NoError(t, err)
+	assert.Equal(t, oids[2], records[0].ID)
+	assert.NotEmpty(t, page.NextCursor)
+	assert.Empty(t, page.PrevCursor)
+}
+
+func TestCursorParams_ConvertToCursor_Time(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 6000000, time.UTC)
+	oid := primitive.NewObjectID()
+	cq, err := (&CursorParams{Limit: 1, Sort: "created_at"}).ConvertToCursor()
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{}, cq.Filter)
+
+	records := []cursorUser{{ID: oid, CreatedAt: now}, {ID: primitive.NewObjectID(), CreatedAt: now}}
+	page, err := cq.Paginate(&records)
+	assert.NoError(t, err)
+
+	cq, err = (&CursorParams{Cursor: page.NextCursor, Limit: 1}).ConvertToCursor()
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{"$or": []bson.M{
+		{"created_at": bson.M{"$gt": now}},
+		{"created_at": now, "_id": bson.M{"$gt": oid}},
+	}}, cq.Filter)
+}
+
+func TestCursorParams_ConvertToCursor_Error(t *testing.T) {
+	// invalid cursor
+	_, err := (&CursorParams{Cursor: "abc"}).ConvertToCursor()
+	assert.True(t, errors.Is(err, ErrInvalidCursor))
+	c, _ := encodeCursor(&cursor{Sort: "-_id", Values: []cursorValue{{Type: "o", Value: "123"}}})
+	_, err = (&CursorParams{Cursor: c}).ConvertToCursor()
+	assert.True(t, errors.Is(err, ErrInvalidCursor))
+	c, _ = encodeCursor(&cursor{Sort: "-age,-_id", Values: []cursorValue{{Type: "i", Value: "1"}}})
+	_, err = (&CursorParams{Cursor: c}).ConvertToCursor()
+	assert.True(t, errors.Is(err, ErrInvalidCursor))
+
+	// sort does not match the cursor
+	c, _ = encodeCursor(&cursor{Sort: "-_id", Values: []cursorValue{{Type: "o", Value: primitive.NewObjectID().Hex()}}})
+	_, err = (&CursorParams{Cursor: c, Sort: "name"}).ConvertToCursor()
+	assert.True(t, errors.Is(err, ErrInvalidCursor))
+	_, err = (&CursorParams{Cursor: c, Sort: "-id"}).ConvertToCursor()
+	assert.NoError(t, err)
+
+	// the sort fields of the cursor are not allowed
+	c, _ = encodeCursor(&cursor{Sort: "password,_id", Values: []cursorValue{{Type: "s", Value: "a"}, {Type: "o", Value: primitive.NewObjectID().Hex()}}})
+	_, err = (&CursorParams{Cursor: c}).ConvertToCursor(WithAllowedColumns("name"))
+	assert.Error(t, err)
+	_, err = (&CursorParams{Sort: "$where"}).ConvertToCursor()
+	assert.Error(t, err)
+
+	// default limit
+	cq, err := (&CursorParams{}).ConvertToCursor()
+	assert.NoError(t, err)
+	assert.Equal(t, defaultCursorLimit+1, cq.Limit)
+
+	// invalid records
+	cq, _ = (&CursorParams{Limit: 1, Sort: "unknown"}).ConvertToCursor()
+	_, err = cq.Paginate([]*cursorUser{})
+	assert.Error(t, err)
+	_, err = cq.Paginate(&[]*cursorUser{{ID: primitive.NewObjectID()}, {ID: primitive.NewObjectID()}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,72 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+var defaultMaxDepth = 5
+
+// SetMaxDepth change the default maximum nesting depth of condition groups
+func SetMaxDepth(depth int) {
+	if depth < 1 {
+		depth = 1
+	}
+	defaultMaxDepth = depth
+}
+
+// Option set the conversion options.
+type Option func(*options)
+
+type options struct {
+	maxDepth       int
+	allowedColumns map[string]struct{}
+}
+
+func (o *options) apply(opts ...Option) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// default settings
+func defaultOptions() *options {
+	return &options{
+		maxDepth:       defaultMaxDepth, // maximum nesting depth of condition groups
+		allowedColumns: nil,             // if empty, column names are not restricted
+	}
+}
+
+// WithMaxDepth set the maximum nesting depth of condition groups
+func WithMaxDepth(depth int) Option {
+	return func(o *options) {
+		if depth < 1 {
+			depth = 1
+		}
+		o.maxDepth = depth
+	}
+}
+
+// WithAllowedColumns set the column names that are allowed to be queried,
+// an error is returned if a column name is not in the list.
+func WithAllowedColumns(names ...string) Option {
+	return func(o *options) {
+		if o.allowedColumns == nil {
+			o.allowedColumns = make(map[string]struct{}, len(names))
+		}
+		for _, name := range names {
+			o.allowedColumns[name] = struct{}{}
+		}
+	}
+}
+
+func (o *options) checkColumn(name string) error {
+	if len(o.allowedColumns) == 0 {
+		return nil
+	}
+	name = strings.Replace(name, ":oid", "", 1)
+	if _, ok := o.allowedColumns[name]; !ok {
+		return fmt.Errorf("column '%s' is not allowed", name)
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package query
 
 import (
 	"fmt"
+	"reflect"
 	"regexp"
 	"strings"
 
@@ -33,6 +34,20 @@ const (
 	Like = "like"
 	// In include
 	In = "in"
+	// NotIn not include
+	NotIn = "notin"
+	// NotLike fuzzy lookup that does not match
+	NotLike = "notlike"
+	// Prefix fuzzy lookup by prefix, case-sensitive so that the index can be used
+	Prefix = "prefix"
+	// Suffix fuzzy lookup by suffix, case-sensitive
+	Suffix = "suffix"
+	// Between in the range, including the boundary values
+	Between = "between"
+	// IsNull is null or does not exist, the value is ignored
+	IsNull = "isnull"
+	// IsNotNull is not null, the value is ignored
+	IsNotNull = "notnull"
 
 	// AND logic and
 	AND        string = "and" //nolint
@@ -62,6 +77,13 @@ var expMap = map[string]string{
 	lteSymbol: lteSymbol,
 	Like:      Like,
 	In:        In,
+	NotIn:     NotIn,
+	NotLike:   NotLike,
+	Prefix:    Prefix,
+	Suffix:    Suffix,
+	Between:   Between,
+	IsNull:    IsNull,
+	IsNotNull: IsNotNull,
 }
 
 var logicMap = map[string]string{
@@ -80,6 +102,7 @@ type Params struct {
 	Sort  string `json:"sort,omitempty" form:"sort" binding:""`
 
 	Columns []Column `json:"columns,omitempty" form:"columns"` // not required
+	Group   *Group   `json:"group,omitempty" form:"group"`     // nested conditions, not required, joined with Columns by and
 
 	// Deprecated: use Limit instead in sponge version v1.8.6, will remove in the future
 	Size int `json:"size" form:"size"`
@@ -88,7 +111,7 @@ type Params struct {
 // Column query info
 type Column struct {
 	Name  string      `json:"name" form:"name"`   // column name
-	Exp   string      `json:"exp" form:"exp"`     // expressions, which default to = when the value is null, have =, !=, >, >=, <, <=, like, in
+	Exp   string      `json:"exp" form:"exp"`     // expressions, which default to = when the value is null, have =, !=, >, >=, <, <=, like, in, notin, notlike, prefix, suffix, between, isnull, notnull
 	Value interface{} `json:"value" form:"value"` // column value
 	Logic string      `json:"logic" form:"logic"` // logical type, defaults to and when the value is null, with &(and), ||(or)
 }
@@ -97,12 +120,17 @@ func (c *Column) checkValid() error {
 	if c.Name == "" {
 		return fmt.Errorf("field 'name' cannot be empty")
 	}
-	if c.Value == nil {
+	if c.Value == nil && !isNullExp(c.Exp) {
 		return fmt.Errorf("field 'value' cannot be nil")
 	}
 	return nil
 }
 
+func isNullExp(exp string) bool {
+	exp = strings.ToLower(exp)
+	return exp == IsNull || exp == IsNotNull
+}
+
 func (c *Column) convertLogic() error {
 	if c.Logic == "" {
 		c.Logic = AND
@@ -120,22 +148,27 @@ func (c *Column) convert() error {
 		return err
 	}
 
+	isObjectID := false
 	if c.Name == "id" || c.Name == "_id" {
-		if str, ok := c.Value.(string); ok {
-			c.Name = "_id"
-			c.Value, _ = primitive.ObjectIDFromHex(str)
-		}
+		c.Name = "_id"
+		isObjectID = true
 	} else if strings.Contains(c.Name, ":oid") {
-		if str, ok := c.Value.(string); ok {
-			c.Name = strings.Replace(c.Name, ":oid", "", 1)
-			c.Value, _ = primitive.ObjectIDFromHex(str)
-		}
+		c.Name = strings.Replace(c.Name, ":oid", "", 1)
+		isObjectID = true
 	}
 
 	if c.Exp == "" {
 		c.Exp = Eq
 	}
-	if v, ok := expMap[strings.ToLower(c.Exp)]; ok { //nolint
+	exp := strings.ToLower(c.Exp)
+	isMultiValue := exp == In || exp == NotIn || exp == Between
+	if isObjectID && !isMultiValue {
+		if str, ok := c.Value.(string); ok {
+			c.Value, _ = primitive.ObjectIDFromHex(str)
+		}
+	}
+
+	if v, ok := expMap[exp]; ok { //nolint
 		c.Exp = v
 		switch c.Exp {
 		//case eqSymbol:
@@ -152,17 +185,38 @@ func (c *Column) convert() error {
 		case Like:
 			escapedValue := regexp.QuoteMeta(fmt.Sprintf("%v", c.Value))
 			c.Value = bson.M{"$regex": escapedValue, "$options": "i"}
-		case In:
-			val, ok := c.Value.(string)
-			if !ok {
-				return fmt.Errorf("invalid value type '%s'", c.Value)
+		case NotLike:
+			escapedValue := regexp.QuoteMeta(fmt.Sprintf("%v", c.Value))
+			c.Value = bson.M{"$not": primitive.Regex{Pattern: escapedValue, Options: "i"}}
+		case Prefix:
+			escapedValue := regexp.QuoteMeta(fmt.Sprintf("%v", c.Value))
+			c.Value = bson.M{"$regex": "^" + escapedValue}
+		case Suffix:
+			escapedValue := regexp.QuoteMeta(fmt.Sprintf("%v", c.Value))
+			c.Value = bson.M{"$regex": escapedValue + "$"}
+		case In, NotIn:
+			values, err := toSlice(c.Value, isObjectID)
+			if err != nil {
+				return err
+			}
+			if c.Exp == In {
+				c.Value = bson.M{"$in": values}
+			} else {
+				c.Value = bson.M{"$nin": values}
+			}
+		case Between:
+			values, err := toSlice(c.Value, isObjectID)
+			if err != nil {
+				return err
 			}
-			values := []interface{}{}
-			ss := strings.Split(val, ",")
-			for _, s := range ss {
-				values = append(values, s)
+			if len(values) != 2 {
+				return fmt.Errorf("exp type 'between' requires 2 values, but got %d", len(values))
 			}
-			c.Value = bson.M{"$in": values}
+			c.Value = bson.M{"$gte": values[0], "$lte": values[1]}
+		case IsNull:
+			c.Value = nil
+		case IsNotNull:
+			c.Value = bson.M{"$ne": nil}
 		}
 	} else {
 		return fmt.Errorf("unknown exp type '%s'", c.Exp)
@@ -171,6 +225,43 @@ func (c *Column) convert() error {
 	return c.convertLogic()
 }
 
+// convert the value to slice, the value can be a slice or a comma-separated string, e.g. "a,b,c",
+// if isObjectID is true, the string elements are converted to ObjectID.
+func toSlice(value interface{}, isObjectID bool) ([]interface{}, error) {
+	values := []interface{}{}
+	switch v := value.(type) {
+	case []interface{}:
+		values = v
+	case string:
+		for _, s := range strings.Split(v, ",") {
+			values = append(values, s)
+		}
+	default:
+		rv := reflect.ValueOf(value)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			return nil, fmt.Errorf("invalid value type '%T', must be a slice or a comma-separated string", value)
+		}
+		for i := 0; i < rv.Len(); i++ {
+			values = append(values, rv.Index(i).Interface())
+		}
+	}
+
+	if isObjectID {
+		oids := make([]interface{}, 0, len(values))
+		for _, v := range values {
+			if str, ok := v.(string); ok {
+				oid, _ := primitive.ObjectIDFromHex(str)
+				oids = append(oids, oid)
+			} else {
+				oids = append(oids, v)
+			}
+		}
+		return oids, nil
+	}
+
+	return values, nil
+}
+
 // ConvertToPage converted to page
 func (p *Params) ConvertToPage() (sort bson.D, limit int, skip int) { //nolint
 	page := NewPage(p.Page, p.Limit, p.Sort)
@@ -180,9 +271,42 @@ func (p *Params) ConvertToPage() (sort bson.D, limit int, skip int) { //nolint
 	return //nolint
 }
 
-// ConvertToMongoFilter conversion to mongo-compliant parameters based on the Columns parameter
-// ignore the logical type of the last column, whether it is a one-column or multi-column query
-func (p *Params) ConvertToMongoFilter() (bson.M, error) {
+// ConvertToMongoFilter conversion to mongo-compliant parameters based on the Columns and Group parameter,
+// ignore the logical type of the last column, whether it is a one-column or multi-column query,
+// if both Columns and Group are not empty, they are joined by $and.
+func (p *Params) ConvertToMongoFilter(opts ...Option) (bson.M, error) {
+	o := defaultOptions()
+	o.apply(opts...)
+
+	for _, column := range p.Columns {
+		if err := o.checkColumn(column.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	filter, err := p.convertColumns()
+	if err != nil {
+		return nil, err
+	}
+	if p.Group == nil {
+		return filter, nil
+	}
+
+	groupFilter, err := p.Group.convert(1, o)
+	if err != nil {
+		return nil, err
+	}
+	if len(groupFilter) == 0 {
+		return filter, nil
+	}
+	if len(filter) == 0 {
+		return groupFilter, nil
+	}
+
+	return bson.M{"$and": []bson.M{filter, groupFilter}}, nil
+}
+
+func (p *Params) convertColumns() (bson.M, error) {
 	filter := bson.M{}
 	l := len(p.Columns)
 	switch l {
@@ -371,7 +495,7 @@ func (c *Conditions) CheckValid() error {
 
 // ConvertToMongo conversion to mongo-compliant parameters based on the Columns parameter
 // ignore the logical type of the last column, whether it is a one-column or multi-column query
-func (c *Conditions) ConvertToMongo() (bson.M, error) {
+func (c *Conditions) ConvertToMongo(opts ...Option) (bson.M, error) {
 	p := &Params{Columns: c.Columns}
-	return p.ConvertToMongoFilter()
+	return p.ConvertToMongoFilter(opts...)
 }
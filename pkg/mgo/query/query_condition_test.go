@@ -153,6 +153,134 @@ func TestParams_ConvertToMongoFilter(t *testing.T) {
 			wantErr: false,
 		},
 
+		{
+			name: "1 column IN with slice value",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "age",
+						Exp:   In,
+						Value: []int{10, 20},
+					},
+				},
+			},
+			want:    bson.M{"age": bson.M{"$in": []interface{}{10, 20}}},
+			wantErr: false,
+		},
+		{
+			name: "1 column NOT IN",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "name",
+						Exp:   NotIn,
+						Value: []interface{}{"ab", "cd"},
+					},
+				},
+			},
+			want:    bson.M{"name": bson.M{"$nin": []interface{}{"ab", "cd"}}},
+			wantErr: false,
+		},
+		{
+			name: "1 column not like",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "name",
+						Exp:   NotLike,
+						Value: "Li",
+					},
+				},
+			},
+			want:    bson.M{"name": bson.M{"$not": primitive.Regex{Pattern: "Li", Options: "i"}}},
+			wantErr: false,
+		},
+		{
+			name: "1 column prefix",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "name",
+						Exp:   Prefix,
+						Value: "Li",
+					},
+				},
+			},
+			want:    bson.M{"name": bson.M{"$regex": "^Li"}},
+			wantErr: false,
+		},
+		{
+			name: "1 column suffix",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "name",
+						Exp:   Suffix,
+						Value: "Li",
+					},
+				},
+			},
+			want:    bson.M{"name": bson.M{"$regex": "Li$"}},
+			wantErr: false,
+		},
+		{
+			name: "1 column between",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "age",
+						Exp:   Between,
+						Value: []int{10, 20},
+					},
+				},
+			},
+			want:    bson.M{"age": bson.M{"$gte": 10, "$lte": 20}},
+			wantErr: false,
+		},
+		{
+			name: "1 column is null",
+			args: args{
+				columns: []Column{
+					{
+						Name: "deleted_at",
+						Exp:  IsNull,
+					},
+				},
+			},
+			want:    bson.M{"deleted_at": nil},
+			wantErr: false,
+		},
+		{
+			name: "1 column is not null",
+			args: args{
+				columns: []Column{
+					{
+						Name: "deleted_at",
+						Exp:  IsNotNull,
+					},
+				},
+			},
+			want:    bson.M{"deleted_at": bson.M{"$ne": nil}},
+			wantErr: false,
+		},
+		{
+			name: "1 column object id IN",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "id",
+						Exp:   In,
+						Value: "65ce48483f11aff697e30d6d,65ce48483f11aff697e30d6e",
+					},
+				},
+			},
+			want: bson.M{"_id": bson.M{"$in": []interface{}{
+				primitive.ObjectID{0x65, 0xce, 0x48, 0x48, 0x3f, 0x11, 0xaf, 0xf6, 0x97, 0xe3, 0xd, 0x6d},
+				primitive.ObjectID{0x65, 0xce, 0x48, 0x48, 0x3f, 0x11, 0xaf, 0xf6, 0x97, 0xe3, 0xd, 0x6e},
+			}}},
+			wantErr: false,
+		},
+
 		// --------------------------- query 2 columns  ------------------------------
 		{
 			name: "2 columns eq and",
@@ -472,6 +600,34 @@ func TestParams_ConvertToMongoFilter(t *testing.T) {
 			want:    nil,
 			wantErr: true,
 		},
+		{
+			name: "between value err",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "age",
+						Exp:   Between,
+						Value: []int{10},
+					},
+				},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "in value type err",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "age",
+						Exp:   In,
+						Value: 10,
+					},
+				},
+			},
+			want:    nil,
+			wantErr: true,
+		},
 		{
 			name: "logic type err",
 			args: args{
@@ -0,0 +1,93 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Group nested query conditions, the columns and sub groups in a group are joined by the logic of the group,
+// example: (a = 1 OR b = 2) AND c > 3
+//
+//	{
+//	    "logic": "and",
+//	    "columns": [{"name": "c", "exp": ">", "value": 3}],
+//	    "groups": [
+//	        {
+//	            "logic": "or",
+//	            "columns": [{"name": "a", "value": 1}, {"name": "b", "value": 2}]
+//	        }
+//	    ]
+//	}
+//
+// converted to mongo filter:
+//
+//	{"$and": [{"c": {"$gt": 3}}, {"$or": [{"a": 1}, {"b": 2}]}]}
+type Group struct {
+	Logic   string   `json:"logic" form:"logic"`               // logical type of the items in the group, defaults to and when the value is null, with &(and), ||(or)
+	Columns []Column `json:"columns,omitempty" form:"columns"` // column conditions, the logic field of the column is ignored
+	Groups  []Group  `json:"groups,omitempty" form:"groups"`   // sub groups
+}
+
+// CheckValid check valid
+func (g *Group) CheckValid(opts ...Option) error {
+	_, err := g.ConvertToMongo(opts...)
+	return err
+}
+
+// ConvertToMongo conversion to mongo-compliant parameters based on the group
+func (g *Group) ConvertToMongo(opts ...Option) (bson.M, error) {
+	o := defaultOptions()
+	o.apply(opts...)
+	return g.convert(1, o)
+}
+
+func (g *Group) convert(depth int, o *options) (bson.M, error) {
+	if depth > o.maxDepth {
+		return nil, fmt.Errorf("the nesting depth of the group exceeds the maximum %d", o.maxDepth)
+	}
+
+	logic := g.Logic
+	if logic == "" {
+		logic = AND
+	}
+	logicSymbol, ok := logicMap[strings.ToLower(logic)]
+	if !ok {
+		return nil, fmt.Errorf("unknown logic type '%s'", g.Logic)
+	}
+
+	conditions := []bson.M{}
+	for _, column := range g.Columns {
+		if err := o.checkColumn(column.Name); err != nil {
+			return nil, err
+		}
+		if err := column.convert(); err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, bson.M{column.Name: column.Value})
+	}
+
+	for _, group := range g.Groups {
+		filter, err := group.convert(depth+1, o)
+		if err != nil {
+			return nil, err
+		}
+		if len(filter) == 0 {
+			continue
+		}
+		conditions = append(conditions, filter)
+	}
+
+	switch len(conditions) {
+	case 0:
+		return bson.M{}, nil
+	case 1:
+		return conditions[0], nil
+	}
+
+	if logicSymbol == orSymbol1 {
+		return bson.M{"$or": conditions}, nil
+	}
+	return bson.M{"$and": conditions}, nil
+}
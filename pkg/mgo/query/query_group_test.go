@@ -0,0 +1,120 @@
+package query
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestGroup_ConvertToMongo(t *testing.T) {
+	// (a = 1 OR b = 2) AND c > 3
+	g := &Group{
+		Columns: []Column{
+			{Name: "c", Exp: Gt, Value: 3},
+		},
+		Groups: []Group{
+			{
+				Logic: OR,
+				Columns: []Column{
+					{Name: "a", Value: 1},
+					{Name: "b", Value: 2},
+				},
+			},
+		},
+	}
+	filter, err := g.ConvertToMongo()
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{"$and": []bson.M{
+		{"c": bson.M{"$gt": 3}},
+		{"$or": []bson.M{{"a": 1}, {"b": 2}}},
+	}}, filter)
+
+	// single condition in nested group
+	g = &Group{
+		Logic: "||",
+		Groups: []Group{
+			{Groups: []Group{{Columns: []Column{{Name: "name", Value: "ZhangSan"}}}}},
+			{Columns: []Column{{Name: "age", Exp: Lte, Value: 20}}},
+			{}, // empty group is ignored
+		},
+	}
+	filter, err = g.ConvertToMongo()
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{"$or": []bson.M{
+		{"name": "ZhangSan"},
+		{"age": bson.M{"$lte": 20}},
+	}}, filter)
+
+	// empty
+	filter, err = (&Group{}).ConvertToMongo()
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{}, filter)
+}
+
+func TestGroup_CheckValid(t *testing.T) {
+	g := &Group{
+		Groups: []Group{
+			{
+				Groups: []Group{
+					{Columns: []Column{{Name: "name", Value: "ZhangSan"}}},
+				},
+			},
+		},
+	}
+	assert.NoError(t, g.CheckValid())
+	// depth error
+	assert.Error(t, g.CheckValid(WithMaxDepth(2)))
+	// column not allowed
+	assert.Error(t, g.CheckValid(WithAllowedColumns("age")))
+	assert.NoError(t, g.CheckValid(WithAllowedColumns("name", "age")))
+
+	// logic error
+	g = &Group{Logic: "xor", Columns: []Column{{Name: "name", Value: "ZhangSan"}}}
+	assert.Error(t, g.CheckValid())
+
+	// value error
+	g = &Group{Columns: []Column{{Name: "name"}}}
+	assert.Error(t, g.CheckValid())
+
+	SetMaxDepth(0)
+	assert.Equal(t, 1, defaultMaxDepth)
+	SetMaxDepth(5)
+}
+
+func TestParams_ConvertToMongoFilter_Group(t *testing.T) {
+	data := `{
+	"page": 0,
+	"limit": 10,
+	"columns": [{"name": "status", "value": 1}],
+	"group": {
+		"logic": "or",
+		"columns": [{"name": "a", "value": 1}, {"name": "b", "value": 2}]
+	}
+}`
+	params := &Params{}
+	err := json.Unmarshal([]byte(data), params)
+	assert.NoError(t, err)
+
+	filter, err := params.ConvertToMongoFilter()
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{"$and": []bson.M{
+		{"status": float64(1)},
+		{"$or": []bson.M{{"a": float64(1)}, {"b": float64(2)}}},
+	}}, filter)
+
+	// only group
+	params.Columns = nil
+	filter, err = params.ConvertToMongoFilter()
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{"$or": []bson.M{{"a": float64(1)}, {"b": float64(2)}}}, filter)
+
+	// column not allowed
+	_, err = params.ConvertToMongoFilter(WithAllowedColumns("a"))
+	assert.Error(t, err)
+	params.Columns = []Column{{Name: "status", Value: 1}}
+	params.Group = nil
+	_, err = params.ConvertToMongoFilter(WithAllowedColumns("a"))
+	assert.Error(t, err)
+}
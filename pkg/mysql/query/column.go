@@ -0,0 +1,63 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm/schema"
+)
+
+// ErrInvalidColumn the column name or sort field is invalid or not in the allowed columns,
+// use errors.Is(err, ErrInvalidColumn) to determine.
+var ErrInvalidColumn = errors.New("invalid column")
+
+// column name, the table name prefix is supported, e.g. name, user.name
+var columnNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*(\.[a-zA-Z_][a-zA-Z0-9_]*)?$`)
+
+// the special sort value "ignore count" is used by dao to skip counting, it is not a column name
+const ignoreCount = "ignorecount"
+
+// cache of the parsed gorm model schema
+var schemaCache = &sync.Map{}
+
+func isValidColumnName(name string) bool {
+	return columnNameRegexp.MatchString(name)
+}
+
+// GetColumnNames get the column names of the gorm model, which can be used as the allowed columns
+func GetColumnNames(model interface{}) ([]string, error) {
+	s, err := schema.Parse(model, schemaCache, schema.NamingStrategy{})
+	if err != nil {
+		return nil, fmt.Errorf("parse model error: %v", err)
+	}
+
+	names := make([]string, 0, len(s.DBNames))
+	names = append(names, s.DBNames...)
+	return names, nil
+}
+
+// CheckSort check that the sort fields are valid and in the allowed columns,
+// the sort fields are separated by commas, a '-' sign in front of the column name indicates descending order.
+func CheckSort(sort string, opts ...Option) error {
+	o := defaultOptions()
+	o.apply(opts...)
+	return checkSort(sort, o)
+}
+
+func checkSort(sort string, o *options) error {
+	sort = strings.Replace(sort, " ", "", -1)
+	if sort == "" || sort == ignoreCount {
+		return nil
+	}
+
+	for _, name := range strings.Split(sort, ",") {
+		name = strings.TrimPrefix(name, "-")
+		if err := o.checkColumn(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package query
+
+import "fmt"
+
+var defaultMaxDepth = 5
+
+// SetMaxDepth change the default maximum nesting depth of condition groups
+func SetMaxDepth(depth int) {
+	if depth < 1 {
+		depth = 1
+	}
+	defaultMaxDepth = depth
+}
+
+// Option set the conversion options.
+type Option func(*options)
+
+type options struct {
+	maxDepth       int
+	allowedColumns map[string]struct{}
+	err            error
+}
+
+func (o *options) apply(opts ...Option) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// default settings
+func defaultOptions() *options {
+	return &options{
+		maxDepth:       defaultMaxDepth, // maximum nesting depth of condition groups
+		allowedColumns: nil,             // if empty, column names are not restricted
+	}
+}
+
+// WithMaxDepth set the maximum nesting depth of condition groups
+func WithMaxDepth(depth int) Option {
+	return func(o *options) {
+		if depth < 1 {
+			depth = 1
+		}
+		o.maxDepth = depth
+	}
+}
+
+// WithAllowedColumns set the column names that are allowed to be queried,
+// an error is returned if a column name is not in the list.
+func WithAllowedColumns(names ...string) Option {
+	return func(o *options) {
+		if o.allowedColumns == nil {
+			o.allowedColumns = make(map[string]struct{}, len(names))
+		}
+		for _, name := range names {
+			o.allowedColumns[name] = struct{}{}
+		}
+	}
+}
+
+// WithModelColumns set the column names of the gorm model as the allowed columns, example:
+//
+//	WithModelColumns(&model.UserExample{})
+func WithModelColumns(model interface{}) Option {
+	return func(o *options) {
+		names, err := GetColumnNames(model)
+		if err != nil {
+			o.err = err
+			return
+		}
+		WithAllowedColumns(names...)(o)
+	}
+}
+
+func (o *options) checkColumn(name string) error {
+	if o.err != nil {
+		return o.err
+	}
+	if !isValidColumnName(name) {
+		return fmt.Errorf("%w '%s'", ErrInvalidColumn, name)
+	}
+	if len(o.allowedColumns) == 0 {
+		return nil
+	}
+	if _, ok := o.allowedColumns[name]; !ok {
+		return fmt.Errorf("%w '%s', not in the allowed columns", ErrInvalidColumn, name)
+	}
+	return nil
+}
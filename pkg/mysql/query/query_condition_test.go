@@ -166,6 +166,111 @@ func TestParams_ConvertToGormConditions(t *testing.T) {
 			wantErr: false,
 		},
 
+		{
+			name: "1 column IN with slice value",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "age",
+						Value: []int{10, 20, 30},
+						Exp:   In,
+					},
+				},
+			},
+			want:    "age IN (?)",
+			want1:   []interface{}{[]interface{}{10, 20, 30}},
+			wantErr: false,
+		},
+		{
+			name: "1 column NOT IN",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "name",
+						Value: []interface{}{"ab", "cd"},
+						Exp:   NotIn,
+					},
+				},
+			},
+			want:    "name NOT IN (?)",
+			want1:   []interface{}{[]interface{}{"ab", "cd"}},
+			wantErr: false,
+		},
+		{
+			name: "1 column not like",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "name",
+						Value: "Li",
+						Exp:   NotLike,
+					},
+				},
+			},
+			want:    "name NOT LIKE ?",
+			want1:   []interface{}{"%Li%"},
+			wantErr: false,
+		},
+		{
+			name: "1 column prefix",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "name",
+						Value: "Li",
+						Exp:   Prefix,
+					},
+				},
+			},
+			want:    "name LIKE ?",
+			want1:   []interface{}{"Li%"},
+			wantErr: false,
+		},
+		{
+			name: "1 column suffix",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "name",
+						Value: "Li",
+						Exp:   Suffix,
+					},
+				},
+			},
+			want:    "name LIKE ?",
+			want1:   []interface{}{"%Li"},
+			wantErr: false,
+		},
+		{
+			name: "1 column between",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "created_at",
+						Value: []string{"2024-01-01", "2024-02-01"},
+						Exp:   Between,
+					},
+				},
+			},
+			want:    "created_at BETWEEN ? AND ?",
+			want1:   []interface{}{"2024-01-01", "2024-02-01"},
+			wantErr: false,
+		},
+		{
+			name: "1 column is null",
+			args: args{
+				columns: []Column{
+					{
+						Name: "deleted_at",
+						Exp:  IsNull,
+					},
+				},
+			},
+			want:    "deleted_at IS NULL",
+			want1:   []interface{}{},
+			wantErr: false,
+		},
+
 		// --------------------------- query 2 columns  ------------------------------
 		{
 			name: "2 columns eq and",
@@ -396,6 +501,30 @@ func TestParams_ConvertToGormConditions(t *testing.T) {
 			wantErr: false,
 		},
 
+		{
+			name: "3 columns between and not null",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "age",
+						Value: "10,20",
+						Exp:   Between,
+					},
+					{
+						Name: "email",
+						Exp:  IsNotNull,
+					},
+					{
+						Name:  "gender",
+						Value: "male",
+					},
+				},
+			},
+			want:    "age BETWEEN ? AND ? AND email IS NOT NULL AND gender = ?",
+			want1:   []interface{}{"10", "20", "male"},
+			wantErr: false,
+		},
+
 		// ---------------------------- error ----------------------------------------------
 		{
 			name: "exp type err",
@@ -412,6 +541,36 @@ func TestParams_ConvertToGormConditions(t *testing.T) {
 			want1:   nil,
 			wantErr: true,
 		},
+		{
+			name: "between value err",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "age",
+						Value: []int{10},
+						Exp:   Between,
+					},
+				},
+			},
+			want:    "",
+			want1:   nil,
+			wantErr: true,
+		},
+		{
+			name: "in value type err",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "age",
+						Value: 10,
+						Exp:   In,
+					},
+				},
+			},
+			want:    "",
+			want1:   nil,
+			wantErr: true,
+		},
 		{
 			name: "logic type err",
 			args: args{
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/nacos-group/nacos-sdk-go/v2/clients"
+	"github.com/nacos-group/nacos-sdk-go/v2/clients/config_client"
 	"github.com/nacos-group/nacos-sdk-go/v2/clients/naming_client"
 	"github.com/nacos-group/nacos-sdk-go/v2/common/constant"
 	"github.com/nacos-group/nacos-sdk-go/v2/vo"
@@ -86,22 +87,27 @@ func setParams(params *Params, opts ...Option) {
 	}
 }
 
-// GetConfig get configuration from nacos
-func GetConfig(params *Params, opts ...Option) (string, []byte, error) {
+// NewConfigClient create a configuration client of nacos, it is used to get and listen the configuration.
+func NewConfigClient(params *Params, opts ...Option) (config_client.IConfigClient, error) {
 	err := params.valid()
 	if err != nil {
-		return "", nil, err
+		return nil, err
 	}
 
 	setParams(params, opts...)
 
-	// create a dynamic configuration client
-	configClient, err := clients.NewConfigClient(
+	return clients.NewConfigClient(
 		vo.NacosClientParam{
 			ClientConfig:  params.clientConfig,
 			ServerConfigs: params.serverConfigs,
 		},
 	)
+}
+
+// GetConfig get configuration from nacos
+func GetConfig(params *Params, opts ...Option) (string, []byte, error) {
+	// create a dynamic configuration client
+	configClient, err := NewConfigClient(params, opts...)
 	if err != nil {
 		return "", nil, err
 	}
@@ -101,4 +101,6 @@ func TestError(t *testing.T) {
 
 	_, _, err = GetConfig(&Params{})
 	assert.Error(t, err)
+	_, err = NewConfigClient(&Params{})
+	assert.Error(t, err)
 }
@@ -0,0 +1,121 @@
+package outbox
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RelayOption set the relay options.
+type RelayOption func(*relayOptions)
+
+type relayOptions struct {
+	pollInterval    time.Duration // default 1s
+	batchSize       int           // default 100
+	maxRetries      int           // default 10
+	minBackoff      time.Duration // default 1s
+	maxBackoff      time.Duration // default 5m
+	lockTimeout     time.Duration // default 30s
+	publishTimeout  time.Duration // default 10s
+	retention       time.Duration // default 0, keep the sent messages
+	cleanupInterval time.Duration // default 1h
+	zapLog          *zap.Logger   // default NewProduction
+}
+
+func (o *relayOptions) apply(opts ...RelayOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+func defaultRelayOptions() *relayOptions {
+	zapLog, _ := zap.NewProduction()
+	return &relayOptions{
+		pollInterval:    time.Second,
+		batchSize:       100,
+		maxRetries:      10,
+		minBackoff:      time.Second,
+		maxBackoff:      time.Minute * 5,
+		lockTimeout:     time.Second * 30,
+		publishTimeout:  time.Second * 10,
+		cleanupInterval: time.Hour,
+		zapLog:          zapLog,
+	}
+}
+
+// WithPollInterval set the interval of polling the pending messages when there are no messages to publish.
+func WithPollInterval(d time.Duration) RelayOption {
+	return func(o *relayOptions) {
+		if d > 0 {
+			o.pollInterval = d
+		}
+	}
+}
+
+// WithBatchSize set the maximum number of messages claimed in one poll.
+func WithBatchSize(size int) RelayOption {
+	return func(o *relayOptions) {
+		if size > 0 {
+			o.batchSize = size
+		}
+	}
+}
+
+// WithMaxRetries set the maximum number of retries, after that the message is marked as failed.
+func WithMaxRetries(n int) RelayOption {
+	return func(o *relayOptions) {
+		if n >= 0 {
+			o.maxRetries = n
+		}
+	}
+}
+
+// WithBackoff set the minimum and maximum interval of retries, the interval doubles after each failure.
+func WithBackoff(min time.Duration, max time.Duration) RelayOption {
+	return func(o *relayOptions) {
+		if min > 0 {
+			o.minBackoff = min
+		}
+		if max >= o.minBackoff {
+			o.maxBackoff = max
+		}
+	}
+}
+
+// WithLockTimeout set the lease time of the claimed messages, if the relay crashes before publishing,
+// the messages can be claimed by other relays after the lease expires, it should be longer than the time
+// of publishing a batch of messages.
+func WithLockTimeout(d time.Duration) RelayOption {
+	return func(o *relayOptions) {
+		if d > 0 {
+			o.lockTimeout = d
+		}
+	}
+}
+
+// WithPublishTimeout set the timeout of publishing a message.
+func WithPublishTimeout(d time.Duration) RelayOption {
+	return func(o *relayOptions) {
+		if d > 0 {
+			o.publishTimeout = d
+		}
+	}
+}
+
+// WithRetention delete the sent messages older than the retention time, 0 means keep them.
+func WithRetention(d time.Duration) RelayOption {
+	return func(o *relayOptions) {
+		if d >= 0 {
+			o.retention = d
+		}
+	}
+}
+
+// WithLogger set logger.
+func WithLogger(zapLog *zap.Logger) RelayOption {
+	return func(o *relayOptions) {
+		if zapLog != nil {
+			o.zapLog = zapLog
+		}
+	}
+}
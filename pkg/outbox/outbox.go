@@ -0,0 +1,99 @@
+// Package outbox is a transactional outbox library based on gorm, the messages are written to the outbox table
+// in the same transaction as the business data, and then published to kafka or rabbitmq by the relay.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// status of the message
+const (
+	StatusPending = 0 // waiting to be published
+	StatusSent    = 1 // published successfully
+	StatusFailed  = 2 // the number of retries exceeds the limit, no longer published
+)
+
+// Message outbox table
+type Message struct {
+	ID           uint64            `gorm:"column:id;AUTO_INCREMENT;primary_key" json:"id"`
+	Topic        string            `gorm:"column:topic;type:varchar(255);not null" json:"topic"`                      // kafka topic or rabbitmq routing key
+	AggregateKey string            `gorm:"column:aggregate_key;type:varchar(255);not null;index" json:"aggregateKey"` // messages of the same key are published in order, empty means no order
+	Payload      []byte            `gorm:"column:payload" json:"payload"`
+	Headers      map[string]string `gorm:"column:headers;type:text;serializer:json" json:"headers"`
+	Status       int               `gorm:"column:status;not null;index:idx_outbox_status_retry" json:"status"`
+	Retries      int               `gorm:"column:retries;not null" json:"retries"`
+	LastError    string            `gorm:"column:last_error;type:text" json:"lastError"`
+	NextRetryAt  time.Time         `gorm:"column:next_retry_at;not null;index:idx_outbox_status_retry" json:"nextRetryAt"`
+	LockedBy     string            `gorm:"column:locked_by;type:varchar(64);not null" json:"lockedBy"`
+	LockedUntil  *time.Time        `gorm:"column:locked_until" json:"lockedUntil"`
+	SentAt       *time.Time        `gorm:"column:sent_at" json:"sentAt"`
+	CreatedAt    time.Time         `gorm:"column:created_at" json:"createdAt"`
+	UpdatedAt    time.Time         `gorm:"column:updated_at" json:"updatedAt"`
+}
+
+// TableName table name
+func (m *Message) TableName() string {
+	return "outbox_message"
+}
+
+// NewMessage create a message, the data is encoded to json if it is not []byte or string,
+// the messages of the same aggregate key (e.g. the id of the record) are published in the order they are enqueued.
+func NewMessage(topic string, aggregateKey string, data interface{}) (*Message, error) {
+	if topic == "" {
+		return nil, errors.New("topic cannot be empty")
+	}
+
+	var payload []byte
+	switch v := data.(type) {
+	case []byte:
+		payload = v
+	case string:
+		payload = []byte(v)
+	default:
+		var err error
+		payload, err = json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Message{
+		Topic:        topic,
+		AggregateKey: aggregateKey,
+		Payload:      payload,
+	}, nil
+}
+
+// Enqueue write the messages to the outbox table, tx should be the transaction that writes the business data,
+// so that the messages are committed or rolled back together with the business data.
+func Enqueue(ctx context.Context, tx *gorm.DB, messages ...*Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	for _, m := range messages {
+		if m.Topic == "" {
+			return errors.New("topic cannot be empty")
+		}
+		m.ID = 0
+		m.Status = StatusPending
+		m.Retries = 0
+		m.NextRetryAt = now
+		m.LockedBy = ""
+		m.LockedUntil = nil
+		m.SentAt = nil
+	}
+
+	return tx.WithContext(ctx).Create(messages).Error
+}
+
+// Migrate create or update the outbox table
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(&Message{})
+}
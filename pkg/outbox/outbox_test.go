@@ -0,0 +1,97 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"github.com/zhufuyi/sponge/pkg/ggorm"
+)
+
+type order struct {
+	ID    uint64 `gorm:"column:id;AUTO_INCREMENT;primary_key"`
+	Price int    `gorm:"column:price"`
+}
+
+func newTestDB(t *testing.T) *gorm.DB {
+	db, err := ggorm.InitSqlite(filepath.Join(t.TempDir(), "outbox.db"), ggorm.WithMaxOpenConns(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Migrate(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.AutoMigrate(&order{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ggorm.CloseDB(db) })
+	return db
+}
+
+func TestNewMessage(t *testing.T) {
+	m, err := NewMessage("order", "1", map[string]int{"price": 10})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"price":10}`, string(m.Payload))
+	assert.Equal(t, "1", m.AggregateKey)
+
+	m, err = NewMessage("order", "", "hello")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(m.Payload))
+
+	m, err = NewMessage("order", "", []byte("hello"))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(m.Payload))
+
+	_, err = NewMessage("", "", "hello")
+	assert.Error(t, err)
+	_, err = NewMessage("order", "", make(chan int))
+	assert.Error(t, err)
+}
+
+func TestEnqueue(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	// commit
+	err := db.Transaction(func(tx *gorm.DB) error {
+		o := &order{Price: 10}
+		if err := tx.Create(o).Error; err != nil {
+			return err
+		}
+		m, _ := NewMessage("order.created", "1", o)
+		m.Headers = map[string]string{"source": "test"}
+		return Enqueue(ctx, tx, m)
+	})
+	assert.NoError(t, err)
+
+	// rollback
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&order{Price: 20}).Error; err != nil {
+			return err
+		}
+		m, _ := NewMessage("order.created", "2", "data")
+		if err := Enqueue(ctx, tx, m); err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+	assert.Error(t, err)
+
+	var messages []*Message
+	err = db.Find(&messages).Error
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, StatusPending, messages[0].Status)
+	assert.Equal(t, "test", messages[0].Headers["source"])
+
+	err = Enqueue(ctx, db)
+	assert.NoError(t, err)
+	err = Enqueue(ctx, db, &Message{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,85 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/IBM/sarama"
+
+	"github.com/zhufuyi/sponge/pkg/kafka"
+	"github.com/zhufuyi/sponge/pkg/rabbitmq"
+)
+
+// Publisher publish the outbox message to the broker
+type Publisher interface {
+	Publish(ctx context.Context, msg *Message) error
+}
+
+// PublisherFunc function as a Publisher
+type PublisherFunc func(ctx context.Context, msg *Message) error
+
+// Publish message
+func (f PublisherFunc) Publish(ctx context.Context, msg *Message) error {
+	return f(ctx, msg)
+}
+
+// -------------------------------------------------------------------------------------------
+
+type kafkaPublisher struct {
+	producer *kafka.SyncProducer
+}
+
+// NewKafkaPublisher create a kafka publisher, the topic of the message is the kafka topic, the aggregate key
+// is the kafka message key, so that the messages of the same key are sent to the same partition in order.
+func NewKafkaPublisher(producer *kafka.SyncProducer) Publisher {
+	return &kafkaPublisher{producer: producer}
+}
+
+// Publish message
+func (p *kafkaPublisher) Publish(_ context.Context, msg *Message) error {
+	pm := &sarama.ProducerMessage{
+		Topic: msg.Topic,
+		Value: sarama.ByteEncoder(msg.Payload),
+	}
+	if msg.AggregateKey != "" {
+		pm.Key = sarama.StringEncoder(msg.AggregateKey)
+	}
+	for k, v := range msg.Headers {
+		pm.Headers = append(pm.Headers, sarama.RecordHeader{Key: []byte(k), Value: []byte(v)})
+	}
+
+	_, _, err := p.producer.SendMessage(pm)
+	return err
+}
+
+// -------------------------------------------------------------------------------------------
+
+type rabbitmqPublisher struct {
+	producer *rabbitmq.Producer
+}
+
+// NewRabbitmqPublisher create a rabbitmq publisher, the message is published according to the exchange type
+// of the producer, the topic of the message is used as the topic key if the exchange type is topic,
+// and the headers of the message are used as the headers keys if the exchange type is headers.
+func NewRabbitmqPublisher(producer *rabbitmq.Producer) Publisher {
+	return &rabbitmqPublisher{producer: producer}
+}
+
+// Publish message
+func (p *rabbitmqPublisher) Publish(ctx context.Context, msg *Message) error {
+	switch p.producer.Exchange.Type() {
+	case "direct":
+		return p.producer.PublishDirect(ctx, msg.Payload)
+	case "fanout":
+		return p.producer.PublishFanout(ctx, msg.Payload)
+	case "topic":
+		return p.producer.PublishTopic(ctx, msg.Topic, msg.Payload)
+	case "headers":
+		headers := make(map[string]interface{}, len(msg.Headers))
+		for k, v := range msg.Headers {
+			headers[k] = v
+		}
+		return p.producer.PublishHeaders(ctx, headers, msg.Payload)
+	}
+	return fmt.Errorf("unsupported exchange type %s", p.producer.Exchange.Type())
+}
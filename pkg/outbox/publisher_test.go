@@ -0,0 +1,45 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zhufuyi/sponge/pkg/kafka"
+)
+
+func TestKafkaPublisher(t *testing.T) {
+	sp := mocks.NewSyncProducer(t, nil)
+	defer sp.Close()
+	p := NewKafkaPublisher(&kafka.SyncProducer{Producer: sp})
+
+	msg := &Message{Topic: "order", AggregateKey: "1", Payload: []byte("hello"), Headers: map[string]string{"source": "test"}}
+	sp.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(pm *sarama.ProducerMessage) error {
+		key, _ := pm.Key.Encode()
+		if pm.Topic != "order" || string(key) != "1" || len(pm.Headers) != 1 {
+			return errors.New("unexpected message")
+		}
+		return nil
+	})
+	err := p.Publish(context.Background(), msg)
+	assert.NoError(t, err)
+
+	sp.ExpectSendMessageAndFail(sarama.ErrOutOfBrokers)
+	err = p.Publish(context.Background(), &Message{Topic: "order", Payload: []byte("hello")})
+	assert.ErrorIs(t, err, sarama.ErrOutOfBrokers)
+}
+
+func TestPublisherFunc(t *testing.T) {
+	var topic string
+	p := PublisherFunc(func(ctx context.Context, msg *Message) error {
+		topic = msg.Topic
+		return nil
+	})
+	err := p.Publish(context.Background(), &Message{Topic: "order"})
+	assert.NoError(t, err)
+	assert.Equal(t, "order", topic)
+}
@@ -0,0 +1,234 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/zhufuyi/sponge/pkg/app"
+	"github.com/zhufuyi/sponge/pkg/krand"
+)
+
+var _ app.IServer = (*Relay)(nil)
+
+// Relay poll the pending messages from the outbox table and publish them to the broker.
+//
+//   - multiple relays (e.g. replicas of the service) can run at the same time, a message is claimed by
+//     only one relay with a lease, see WithLockTimeout.
+//   - the messages of the same aggregate key are published in order, a message is not published until
+//     the previous messages of the same key are sent or failed.
+//   - the failed messages are retried with exponential backoff, see WithBackoff and WithMaxRetries.
+//   - the delivery is at least once, the consumer should be idempotent.
+type Relay struct {
+	db         *gorm.DB
+	publisher  Publisher
+	instanceID string
+	opts       *relayOptions
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	started atomic.Bool
+	done    chan struct{}
+}
+
+// NewRelay create a relay, it can be run as an app.IServer
+func NewRelay(db *gorm.DB, publisher Publisher, opts ...RelayOption) *Relay {
+	o := defaultRelayOptions()
+	o.apply(opts...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Relay{
+		db:         db,
+		publisher:  publisher,
+		instanceID: krand.String(krand.R_All, 16),
+		opts:       o,
+		ctx:        ctx,
+		cancel:     cancel,
+		done:       make(chan struct{}),
+	}
+}
+
+// Start polling and publishing messages until Stop is called
+func (r *Relay) Start() error {
+	if !r.started.CompareAndSwap(false, true) {
+		return fmt.Errorf("outbox relay has been started")
+	}
+	defer close(r.done)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	lastCleanup := time.Now()
+
+	for {
+		select {
+		case <-r.ctx.Done():
+			return nil
+		case <-timer.C:
+		}
+
+		n, err := r.RunOnce(r.ctx)
+		if err != nil && r.ctx.Err() == nil {
+			r.opts.zapLog.Error("[outbox relay] run error", zap.Error(err))
+		}
+
+		if r.opts.retention > 0 && time.Since(lastCleanup) >= r.opts.cleanupInterval {
+			lastCleanup = time.Now()
+			if err := r.Cleanup(r.ctx, lastCleanup.Add(-r.opts.retention)); err != nil {
+				r.opts.zapLog.Error("[outbox relay] cleanup error", zap.Error(err))
+			}
+		}
+
+		// continue immediately if there may be more messages
+		if err == nil && n > 0 {
+			timer.Reset(0)
+		} else {
+			timer.Reset(r.opts.pollInterval)
+		}
+	}
+}
+
+// Stop the relay, wait for the messages being published to finish
+func (r *Relay) Stop() error {
+	r.cancel()
+	if r.started.Load() {
+		<-r.done
+	}
+	return nil
+}
+
+// String description
+func (r *Relay) String() string {
+	return fmt.Sprintf("outbox relay, poll interval: %s, batch size: %d", r.opts.pollInterval, r.opts.batchSize)
+}
+
+// RunOnce claim a batch of pending messages and publish them, return the number of claimed messages
+func (r *Relay) RunOnce(ctx context.Context) (int, error) {
+	messages, err := r.claim(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, msg := range messages {
+		if ctx.Err() != nil { // release the lease of the messages not published
+			r.release(msg)
+			continue
+		}
+
+		pubCtx, cancel := context.WithTimeout(ctx, r.opts.publishTimeout)
+		err = r.publisher.Publish(pubCtx, msg)
+		cancel()
+		if err != nil {
+			r.fail(msg, err)
+			continue
+		}
+		r.succeed(msg)
+	}
+
+	return len(messages), nil
+}
+
+// Cleanup delete the sent messages before the time
+func (r *Relay) Cleanup(ctx context.Context, before time.Time) error {
+	return r.db.WithContext(ctx).Where("status = ? AND sent_at < ?", StatusSent, before).Delete(&Message{}).Error
+}
+
+// claim the pending messages, only the first pending message of each aggregate key can be claimed
+func (r *Relay) claim(ctx context.Context) ([]*Message, error) {
+	now := time.Now()
+	db := r.db.WithContext(ctx)
+	tableName := (&Message{}).TableName()
+
+	var ids []uint64
+	previous := db.Table(tableName+" AS prev").Select("1").
+		Where(fmt.Sprintf("prev.aggregate_key = %s.aggregate_key AND prev.status = ? AND prev.id < %s.id", tableName, tableName), StatusPending)
+	err := db.Model(&Message{}).
+		Where("status = ? AND next_retry_at <= ?", StatusPending, now).
+		Where("(locked_until IS NULL OR locked_until < ?)", now).
+		Where("(aggregate_key = '' OR NOT EXISTS (?))", previous).
+		Order("id").Limit(r.opts.batchSize).Pluck("id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	// the messages claimed by other relays at the same time are skipped
+	lockedUntil := now.Add(r.opts.lockTimeout)
+	err = db.Model(&Message{}).
+		Where("id IN ? AND status = ? AND (locked_until IS NULL OR locked_until < ?)", ids, StatusPending, now).
+		Updates(map[string]interface{}{"locked_by": r.instanceID, "locked_until": lockedUntil}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []*Message
+	err = db.Where("id IN ? AND locked_by = ? AND status = ?", ids, r.instanceID, StatusPending).
+		Order("id").Find(&messages).Error
+	return messages, err
+}
+
+func (r *Relay) succeed(msg *Message) {
+	now := time.Now()
+	err := r.update(msg, map[string]interface{}{
+		"status":       StatusSent,
+		"sent_at":      now,
+		"locked_by":    "",
+		"locked_until": nil,
+	})
+	if err != nil {
+		// the message will be published again after the lease expires
+		r.opts.zapLog.Error("[outbox relay] mark message sent error", zap.Uint64("id", msg.ID), zap.Error(err))
+	}
+}
+
+func (r *Relay) fail(msg *Message, pubErr error) {
+	retries := msg.Retries + 1
+	values := map[string]interface{}{
+		"retries":       retries,
+		"last_error":    pubErr.Error(),
+		"next_retry_at": time.Now().Add(r.backoff(msg.Retries)),
+		"locked_by":     "",
+		"locked_until":  nil,
+	}
+	fields := []zap.Field{zap.Uint64("id", msg.ID), zap.String("topic", msg.Topic),
+		zap.String("aggregateKey", msg.AggregateKey), zap.Int("retries", retries), zap.Error(pubErr)}
+	if retries > r.opts.maxRetries {
+		values["status"] = StatusFailed
+		r.opts.zapLog.Error("[outbox relay] publish message failed, exceeded the maximum number of retries", fields...)
+	} else {
+		r.opts.zapLog.Warn("[outbox relay] publish message error, retry later", fields...)
+	}
+
+	if err := r.update(msg, values); err != nil {
+		r.opts.zapLog.Error("[outbox relay] mark message retry error", zap.Uint64("id", msg.ID), zap.Error(err))
+	}
+}
+
+func (r *Relay) release(msg *Message) {
+	_ = r.update(msg, map[string]interface{}{"locked_by": "", "locked_until": nil})
+}
+
+// update the message claimed by this relay, use a new context so that the result is saved when stopping
+func (r *Relay) update(msg *Message, values map[string]interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.opts.publishTimeout)
+	defer cancel()
+	return r.db.WithContext(ctx).Model(&Message{}).
+		Where("id = ? AND locked_by = ?", msg.ID, r.instanceID).
+		Updates(values).Error
+}
+
+func (r *Relay) backoff(retries int) time.Duration {
+	d := r.opts.minBackoff
+	for i := 0; i < retries && d < r.opts.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > r.opts.maxBackoff {
+		d = r.opts.maxBackoff
+	}
+	return d
+}
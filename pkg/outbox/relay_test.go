@@ -0,0 +1,178 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type recorder struct {
+	mu       sync.Mutex
+	payloads map[string][]string // aggregate key --> payloads
+	count    int
+	failFn   func(msg *Message) error
+}
+
+func newRecorder() *recorder {
+	return &recorder{payloads: make(map[string][]string)}
+}
+
+func (r *recorder) Publish(_ context.Context, msg *Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failFn != nil {
+		if err := r.failFn(msg); err != nil {
+			return err
+		}
+	}
+	r.payloads[msg.AggregateKey] = append(r.payloads[msg.AggregateKey], string(msg.Payload))
+	r.count++
+	return nil
+}
+
+func (r *recorder) get(key string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.payloads[key]...)
+}
+
+func (r *recorder) total() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.count
+}
+
+func enqueue(t *testing.T, db *gorm.DB, key string, payloads ...string) {
+	var messages []*Message
+	for _, p := range payloads {
+		m, err := NewMessage("order", key, p)
+		assert.NoError(t, err)
+		messages = append(messages, m)
+	}
+	err := Enqueue(context.Background(), db, messages...)
+	assert.NoError(t, err)
+}
+
+func TestRelay_RunOnce(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	enqueue(t, db, "a", "a1")
+	enqueue(t, db, "b", "b1")
+	enqueue(t, db, "a", "a2", "a3")
+	enqueue(t, db, "", "x1", "x2")
+
+	rec := newRecorder()
+	relay := NewRelay(db, rec, WithLogger(zap.NewNop()), WithBatchSize(10))
+
+	// only the first message of each key is claimed
+	n, err := relay.RunOnce(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, n)
+	assert.Equal(t, []string{"a1"}, rec.get("a"))
+
+	for i := 0; i < 5; i++ {
+		_, err = relay.RunOnce(ctx)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, []string{"a1", "a2", "a3"}, rec.get("a"))
+	assert.Equal(t, []string{"b1"}, rec.get("b"))
+	assert.Equal(t, []string{"x1", "x2"}, rec.get(""))
+
+	var count int64
+	db.Model(&Message{}).Where("status = ?", StatusSent).Count(&count)
+	assert.Equal(t, int64(6), count)
+
+	// cleanup
+	err = relay.Cleanup(ctx, time.Now().Add(time.Second))
+	assert.NoError(t, err)
+	db.Model(&Message{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestRelay_Retry(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	enqueue(t, db, "a", "a1", "a2")
+	enqueue(t, db, "b", "b1", "b2")
+
+	rec := newRecorder()
+	failures := map[string]int{"a1": 1} // a1 fails once
+	rec.failFn = func(msg *Message) error {
+		p := string(msg.Payload)
+		if p == "b1" || failures[p] > 0 { // b1 always fails
+			failures[p]--
+			return errors.New("broker unavailable")
+		}
+		return nil
+	}
+	relay := NewRelay(db, rec, WithLogger(zap.NewNop()), WithMaxRetries(2), WithBackoff(time.Millisecond, time.Millisecond*2))
+
+	// a2 is not published before a1 is sent
+	_, err := relay.RunOnce(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, rec.get("a"), 0)
+
+	for i := 0; i < 10; i++ {
+		time.Sleep(time.Millisecond * 5)
+		_, err = relay.RunOnce(ctx)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, []string{"a1", "a2"}, rec.get("a"))
+	// b1 is marked as failed after 3 attempts, then b2 is published
+	assert.Equal(t, []string{"b2"}, rec.get("b"))
+
+	m := &Message{}
+	err = db.Where("payload = ?", []byte("b1")).First(m).Error
+	assert.NoError(t, err)
+	assert.Equal(t, StatusFailed, m.Status)
+	assert.Equal(t, 3, m.Retries)
+	assert.Equal(t, "broker unavailable", m.LastError)
+
+	assert.Equal(t, time.Millisecond, relay.backoff(0))
+	assert.Equal(t, time.Millisecond*2, relay.backoff(5))
+}
+
+func TestRelay_StartStop(t *testing.T) {
+	db := newTestDB(t)
+	rec := newRecorder()
+
+	// two relays simulate two replicas
+	relays := []*Relay{
+		NewRelay(db, rec, WithLogger(zap.NewNop()), WithPollInterval(time.Millisecond*10), WithBatchSize(5), WithRetention(time.Hour)),
+		NewRelay(db, rec, WithLogger(zap.NewNop()), WithPollInterval(time.Millisecond*10), WithBatchSize(5), WithLockTimeout(time.Second)),
+	}
+	for _, relay := range relays {
+		t.Log(relay.String())
+		go func(relay *Relay) {
+			err := relay.Start()
+			assert.NoError(t, err)
+		}(relay)
+	}
+
+	for i := 0; i < 10; i++ {
+		enqueue(t, db, "a", "a"+string(rune('0'+i)))
+		enqueue(t, db, "b", "b"+string(rune('0'+i)))
+	}
+
+	deadline := time.Now().Add(time.Second * 5)
+	for rec.total() < 20 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond * 10)
+	}
+	for _, relay := range relays {
+		assert.NoError(t, relay.Stop())
+	}
+
+	assert.Equal(t, 20, rec.total())
+	assert.Equal(t, []string{"a0", "a1", "a2", "a3", "a4", "a5", "a6", "a7", "a8", "a9"}, rec.get("a"))
+	assert.Equal(t, []string{"b0", "b1", "b2", "b3", "b4", "b5", "b6", "b7", "b8", "b9"}, rec.get("b"))
+
+	// stop without start
+	relay := NewRelay(db, rec)
+	assert.NoError(t, relay.Stop())
+}
@@ -5,6 +5,9 @@ import amqp "github.com/rabbitmq/amqp091-go"
 // ErrClosed closed
 var ErrClosed = amqp.ErrClosed
 
+// HeaderRetryCount the header of the number of times the message has been retried
+const HeaderRetryCount = "x-retry-count"
+
 const (
 	exchangeTypeDirect         = "direct"
 	exchangeTypeTopic          = "topic"
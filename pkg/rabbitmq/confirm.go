@@ -0,0 +1,442 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+
+	"github.com/zhufuyi/sponge/pkg/krand"
+)
+
+var (
+	// ErrNacked the message is nacked by the broker
+	ErrNacked = errors.New("message is nacked by broker")
+	// ErrConfirmTimeout timeout waiting for the broker to confirm the message
+	ErrConfirmTimeout = errors.New("wait for broker confirm timeout")
+	// ErrBufferFull the buffer of messages published during reconnection is full
+	ErrBufferFull = errors.New("publish buffer is full")
+)
+
+// ReturnedError the message is returned by the broker because it cannot be routed to any queue,
+// only when mandatory is true, use errors.As to get the details.
+type ReturnedError struct {
+	ReplyCode  uint16
+	ReplyText  string
+	Exchange   string
+	RoutingKey string
+	MessageID  string
+}
+
+// Error message
+func (e *ReturnedError) Error() string {
+	return fmt.Sprintf("message is returned by broker, code=%d, text=%s, exchange=%s, routingKey=%s, messageID=%s",
+		e.ReplyCode, e.ReplyText, e.Exchange, e.RoutingKey, e.MessageID)
+}
+
+// ConfirmCallback is called when the message is confirmed in asynchronous confirm mode, err is nil if
+// the message is acked, otherwise it is *ReturnedError, ErrNacked or ErrClosed. it should not block.
+type ConfirmCallback func(msg amqp.Publishing, err error)
+
+const confirmChanSize = 1024
+
+type publishChannel interface {
+	PublishWithDeferredConfirmWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) (*amqp.DeferredConfirmation, error)
+}
+
+type pendingPublish struct {
+	routingKey string
+	msg        amqp.Publishing
+	result     chan error // nil in asynchronous mode
+}
+
+// bufferedPublisher publish messages, the messages published during reconnection are buffered and
+// republished after reconnecting. in confirm mode, the messages not confirmed when the channel is closed
+// are also republished.
+type bufferedPublisher struct {
+	exchange   string
+	mandatory  bool
+	isConfirm  bool
+	connection *Connection
+	declare    func(ch *amqp.Channel) error // declare the exchange and queue after reconnecting
+	timeout    time.Duration
+	callback   ConfirmCallback
+	bufferSize int
+	idPrefix   string
+	idSeq      uint64
+	zapLog     *zap.Logger
+
+	pubMu    sync.Mutex // keep the order of publishing
+	mu       sync.Mutex
+	ch       publishChannel // nil when disconnected
+	pending  map[uint64]*pendingPublish
+	early    map[uint64]bool           // confirmations arrived before the pending message is recorded
+	returned map[string]*ReturnedError // message id --> returned error
+	buffer   []*pendingPublish
+	isClosed bool
+
+	exit      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newBufferedPublisher(exchange string, connection *Connection, o *producerOptions, declare func(ch *amqp.Channel) error) *bufferedPublisher {
+	return &bufferedPublisher{
+		exchange:   exchange,
+		mandatory:  o.mandatory,
+		isConfirm:  o.isConfirm,
+		connection: connection,
+		declare:    declare,
+		timeout:    o.confirmTimeout,
+		callback:   o.confirmCallback,
+		bufferSize: o.bufferSize,
+		idPrefix:   krand.String(krand.R_All, 10) + "-",
+		zapLog:     connection.zapLog,
+		pending:    make(map[uint64]*pendingPublish),
+		early:      make(map[uint64]bool),
+		returned:   make(map[string]*ReturnedError),
+		exit:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// publish the message, in synchronous confirm mode, wait for the confirmation of the broker
+func (c *bufferedPublisher) publish(ctx context.Context, routingKey string, msg amqp.Publishing) error {
+	if msg.MessageId == "" {
+		msg.MessageId = c.idPrefix + strconv.FormatUint(atomic.AddUint64(&c.idSeq, 1), 10)
+	}
+	pub := &pendingPublish{routingKey: routingKey, msg: msg}
+	if c.isConfirm && c.callback == nil {
+		pub.result = make(chan error, 1)
+	}
+
+	c.pubMu.Lock()
+	err := c.sendOrBuffer(ctx, pub)
+	c.pubMu.Unlock()
+	if err != nil || pub.result == nil {
+		return err
+	}
+
+	var timeout <-chan time.Time
+	if c.timeout > 0 {
+		timer := time.NewTimer(c.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case err = <-pub.result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timeout:
+		return ErrConfirmTimeout
+	}
+}
+
+// send the message if connected, otherwise buffer it, the caller must hold pubMu
+func (c *bufferedPublisher) sendOrBuffer(ctx context.Context, pub *pendingPublish) error {
+	c.mu.Lock()
+	if c.isClosed {
+		c.mu.Unlock()
+		return ErrClosed
+	}
+	ch := c.ch
+	if ch == nil || len(c.buffer) > 0 { // keep the order of the buffered messages
+		if len(c.buffer) >= c.bufferSize {
+			c.mu.Unlock()
+			return ErrBufferFull
+		}
+		c.buffer = append(c.buffer, pub)
+		c.mu.Unlock()
+		return nil
+	}
+	c.mu.Unlock()
+
+	err := c.send(ctx, ch, pub)
+	if errors.Is(err, amqp.ErrClosed) {
+		c.mu.Lock()
+		c.buffer = append(c.buffer, pub)
+		c.mu.Unlock()
+		return nil
+	}
+	return err
+}
+
+// the caller must hold pubMu
+func (c *bufferedPublisher) send(ctx context.Context, ch publishChannel, pub *pendingPublish) error {
+	dc, err := ch.PublishWithDeferredConfirmWithContext(ctx, c.exchange, pub.routingKey, c.mandatory, false, pub.msg)
+	if err != nil {
+		return err
+	}
+	if !c.isConfirm {
+		return nil
+	}
+	if dc == nil {
+		return errors.New("channel is not in confirm mode")
+	}
+
+	c.mu.Lock()
+	if ack, ok := c.early[dc.DeliveryTag]; ok {
+		delete(c.early, dc.DeliveryTag)
+		c.mu.Unlock()
+		c.resolve(pub, ack)
+		return nil
+	}
+	c.pending[dc.DeliveryTag] = pub
+	c.mu.Unlock()
+	return nil
+}
+
+// in confirm mode, the returned error is reported with the confirmation of the message,
+// otherwise there is no confirmation and the returned message is logged.
+func (c *bufferedPublisher) onReturn(r amqp.Return) {
+	returnedErr := &ReturnedError{
+		ReplyCode:  r.ReplyCode,
+		ReplyText:  r.ReplyText,
+		Exchange:   r.Exchange,
+		RoutingKey: r.RoutingKey,
+		MessageID:  r.MessageId,
+	}
+	if !c.isConfirm {
+		c.zapLog.Warn("[rabbit producer] message is returned by broker", zap.String("err", returnedErr.Error()))
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.returned[r.MessageId] = returnedErr
+}
+
+func (c *bufferedPublisher) onConfirm(confirmation amqp.Confirmation) {
+	c.mu.Lock()
+	pub, ok := c.pending[confirmation.DeliveryTag]
+	if !ok {
+		c.early[confirmation.DeliveryTag] = confirmation.Ack
+		c.mu.Unlock()
+		return
+	}
+	delete(c.pending, confirmation.DeliveryTag)
+	c.mu.Unlock()
+
+	c.resolve(pub, confirmation.Ack)
+}
+
+func (c *bufferedPublisher) resolve(pub *pendingPublish, ack bool) {
+	c.mu.Lock()
+	returnedErr, isReturned := c.returned[pub.msg.MessageId]
+	delete(c.returned, pub.msg.MessageId)
+	c.mu.Unlock()
+
+	var err error
+	switch {
+	case isReturned:
+		err = returnedErr
+	case !ack:
+		err = ErrNacked
+	}
+	c.finish(pub, err)
+}
+
+func (c *bufferedPublisher) finish(pub *pendingPublish, err error) {
+	if pub.result != nil {
+		pub.result <- err
+		return
+	}
+	if c.callback != nil {
+		c.callback(pub.msg, err)
+	}
+}
+
+// the messages not confirmed are republished after reconnecting
+func (c *bufferedPublisher) onChannelClosed() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tags := make([]uint64, 0, len(c.pending))
+	for tag := range c.pending {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i] < tags[j] })
+	unconfirmed := make([]*pendingPublish, 0, len(tags)+len(c.buffer))
+	for _, tag := range tags {
+		unconfirmed = append(unconfirmed, c.pending[tag])
+	}
+
+	c.buffer = append(unconfirmed, c.buffer...)
+	c.ch = nil
+	c.pending = make(map[uint64]*pendingPublish)
+	c.early = make(map[uint64]bool)
+	c.returned = make(map[string]*ReturnedError)
+}
+
+// republish the buffered messages in order
+func (c *bufferedPublisher) flush(ch publishChannel) {
+	for {
+		c.pubMu.Lock()
+		c.mu.Lock()
+		if c.ch != ch || len(c.buffer) == 0 {
+			c.mu.Unlock()
+			c.pubMu.Unlock()
+			return
+		}
+		pub := c.buffer[0]
+		c.buffer = c.buffer[1:]
+		c.mu.Unlock()
+
+		err := c.send(context.Background(), ch, pub)
+		if err != nil {
+			c.mu.Lock()
+			c.buffer = append([]*pendingPublish{pub}, c.buffer...)
+			c.mu.Unlock()
+			c.pubMu.Unlock()
+			c.zapLog.Warn("[rabbit producer] republish buffered message error", zap.String("err", err.Error()))
+			return
+		}
+		c.pubMu.Unlock()
+	}
+}
+
+func (c *bufferedPublisher) run(ch *amqp.Channel) {
+	defer func() {
+		c.mu.Lock()
+		c.isClosed = true
+		c.ch = nil
+		pubs := c.buffer
+		for _, pub := range c.pending {
+			pubs = append(pubs, pub)
+		}
+		c.buffer = nil
+		c.pending = make(map[uint64]*pendingPublish)
+		c.mu.Unlock()
+
+		for _, pub := range pubs {
+			c.finish(pub, ErrClosed)
+		}
+		close(c.done)
+	}()
+
+	for {
+		if ch == nil {
+			ch = c.reopen()
+			if ch == nil {
+				return
+			}
+		}
+		if !c.serve(ch) {
+			return
+		}
+		ch = nil
+	}
+}
+
+// handle the confirmations and returns until the channel is closed, return false if exit
+func (c *bufferedPublisher) serve(ch *amqp.Channel) bool {
+	var (
+		confirms chan amqp.Confirmation
+		returns  chan amqp.Return
+		closes   chan *amqp.Error // only used when not in confirm mode
+	)
+	if c.isConfirm {
+		if err := ch.Confirm(false); err != nil {
+			c.zapLog.Warn("[rabbit producer] set confirm mode error", zap.String("err", err.Error()))
+			_ = ch.Close()
+			return true
+		}
+		confirms = ch.NotifyPublish(make(chan amqp.Confirmation, confirmChanSize))
+	} else {
+		closes = ch.NotifyClose(make(chan *amqp.Error, 1))
+	}
+	returns = ch.NotifyReturn(make(chan amqp.Return, confirmChanSize))
+
+	c.mu.Lock()
+	c.ch = ch
+	c.mu.Unlock()
+	go c.flush(ch)
+
+	for {
+		select {
+		case <-c.exit:
+			_ = ch.Close()
+			return false
+		case <-closes:
+			c.onChannelClosed()
+			c.zapLog.Warn("[rabbit producer] channel closed, the messages are buffered until reconnected")
+			return true
+		case r, ok := <-returns:
+			if !ok {
+				returns = nil
+				continue
+			}
+			c.onReturn(r)
+		case confirmation, ok := <-confirms:
+			if !ok {
+				c.onChannelClosed()
+				c.zapLog.Warn("[rabbit producer] channel closed, the messages are buffered until reconnected")
+				return true
+			}
+			// the return of a message is always sent before its confirmation
+			for isDrained := false; !isDrained; {
+				select {
+				case r, ok := <-returns:
+					if ok {
+						c.onReturn(r)
+					} else {
+						returns, isDrained = nil, true
+					}
+				default:
+					isDrained = true
+				}
+			}
+			c.onConfirm(confirmation)
+		}
+	}
+}
+
+// open a new channel after the connection is reconnected, return nil if exit
+func (c *bufferedPublisher) reopen() *amqp.Channel {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.exit:
+			return nil
+		case <-c.connection.exit:
+			return nil
+		case <-ticker.C:
+		}
+
+		if !c.connection.CheckConnected() {
+			continue
+		}
+		c.connection.mutex.Lock()
+		conn := c.connection.conn
+		c.connection.mutex.Unlock()
+
+		ch, err := conn.Channel()
+		if err != nil {
+			c.zapLog.Warn("[rabbit producer] create channel error", zap.String("err", err.Error()))
+			continue
+		}
+		if err = c.declare(ch); err != nil {
+			c.zapLog.Warn("[rabbit producer] declare error", zap.String("err", err.Error()))
+			_ = ch.Close()
+			continue
+		}
+		c.zapLog.Info("[rabbit producer] channel reopened", zap.String("exchange", c.exchange))
+		return ch
+	}
+}
+
+func (c *bufferedPublisher) close() {
+	c.closeOnce.Do(func() {
+		close(c.exit)
+	})
+	<-c.done
+}
@@ -0,0 +1,214 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type fakePublishChannel struct {
+	mu        sync.Mutex
+	seq       uint64
+	published []amqp.Publishing
+	err       error
+}
+
+func (f *fakePublishChannel) PublishWithDeferredConfirmWithContext(_ context.Context, _, _ string, _, _ bool, msg amqp.Publishing) (*amqp.DeferredConfirmation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.seq++
+	f.published = append(f.published, msg)
+	return &amqp.DeferredConfirmation{DeliveryTag: f.seq}, nil
+}
+
+func (f *fakePublishChannel) get(i int) amqp.Publishing {
+	for {
+		f.mu.Lock()
+		if len(f.published) > i {
+			msg := f.published[i]
+			f.mu.Unlock()
+			return msg
+		}
+		f.mu.Unlock()
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func newTestBufferedPublisher(opts ...ProducerOption) (*bufferedPublisher, *fakePublishChannel) {
+	o := defaultProducerOptions()
+	o.apply(opts...)
+	connection := &Connection{zapLog: zap.NewNop(), exit: make(chan struct{})}
+	c := newBufferedPublisher("foo", connection, o, nil)
+	ch := &fakePublishChannel{}
+	c.ch = ch
+	return c, ch
+}
+
+func asyncPublish(c *bufferedPublisher, body string) chan error {
+	result := make(chan error, 1)
+	go func() {
+		result <- c.publish(context.Background(), "bar", amqp.Publishing{Body: []byte(body)})
+	}()
+	return result
+}
+
+func TestBufferedPublisher_sync(t *testing.T) {
+	c, ch := newTestBufferedPublisher(WithProducerConfirm(time.Millisecond*200), WithProducerBufferSize(2))
+
+	// acked
+	result := asyncPublish(c, "1")
+	ch.get(0)
+	c.onConfirm(amqp.Confirmation{DeliveryTag: 1, Ack: true})
+	assert.NoError(t, <-result)
+
+	// returned
+	result = asyncPublish(c, "2")
+	msg := ch.get(1)
+	assert.NotEmpty(t, msg.MessageId)
+	c.onReturn(amqp.Return{ReplyCode: 312, ReplyText: "NO_ROUTE", Exchange: "foo", RoutingKey: "bar", MessageId: msg.MessageId})
+	c.onConfirm(amqp.Confirmation{DeliveryTag: 2, Ack: true})
+	err := <-result
+	var returnedErr *ReturnedError
+	assert.True(t, errors.As(err, &returnedErr))
+	assert.Equal(t, uint16(312), returnedErr.ReplyCode)
+	t.Log(err)
+
+	// nacked
+	result = asyncPublish(c, "3")
+	ch.get(2)
+	c.onConfirm(amqp.Confirmation{DeliveryTag: 3, Ack: false})
+	assert.ErrorIs(t, <-result, ErrNacked)
+
+	// confirmed before recorded
+	c.onConfirm(amqp.Confirmation{DeliveryTag: 4, Ack: true})
+	err = c.publish(context.Background(), "bar", amqp.Publishing{Body: []byte("4")})
+	assert.NoError(t, err)
+
+	// timeout
+	err = c.publish(context.Background(), "bar", amqp.Publishing{Body: []byte("5")})
+	assert.ErrorIs(t, err, ErrConfirmTimeout)
+
+	// publish error
+	ch.err = errors.New("invalid headers")
+	err = c.publish(context.Background(), "bar", amqp.Publishing{Body: []byte("6")})
+	assert.Error(t, err)
+}
+
+func TestBufferedPublisher_reconnect(t *testing.T) {
+	c, ch := newTestBufferedPublisher(WithProducerConfirm(0), WithProducerBufferSize(2))
+
+	result1 := asyncPublish(c, "1")
+	ch.get(0)
+
+	// disconnected, the message not confirmed and the new messages are buffered
+	c.onChannelClosed()
+	result2 := asyncPublish(c, "2")
+	time.Sleep(time.Millisecond * 10)
+	err := c.publish(context.Background(), "bar", amqp.Publishing{Body: []byte("3")})
+	assert.ErrorIs(t, err, ErrBufferFull)
+
+	// reconnected, the buffered messages are republished in order
+	ch2 := &fakePublishChannel{}
+	c.mu.Lock()
+	c.ch = ch2
+	c.mu.Unlock()
+	c.flush(ch2)
+	assert.Equal(t, "1", string(ch2.get(0).Body))
+	assert.Equal(t, "2", string(ch2.get(1).Body))
+	c.onConfirm(amqp.Confirmation{DeliveryTag: 1, Ack: true})
+	c.onConfirm(amqp.Confirmation{DeliveryTag: 2, Ack: true})
+	assert.NoError(t, <-result1)
+	assert.NoError(t, <-result2)
+
+	// the channel is closed when publishing
+	ch2.err = amqp.ErrClosed
+	result3 := asyncPublish(c, "3")
+	time.Sleep(time.Millisecond * 10)
+	c.mu.Lock()
+	assert.Len(t, c.buffer, 1)
+	c.mu.Unlock()
+
+	// the producer is closed, the buffered messages are failed
+	go c.run(nil)
+	c.close()
+	assert.ErrorIs(t, <-result3, ErrClosed)
+	assert.ErrorIs(t, c.publish(context.Background(), "bar", amqp.Publishing{}), ErrClosed)
+}
+
+func TestBufferedPublisher_async(t *testing.T) {
+	var mu sync.Mutex
+	var results []error
+	c, ch := newTestBufferedPublisher(WithProducerConfirmCallback(func(msg amqp.Publishing, err error) {
+		mu.Lock()
+		results = append(results, err)
+		mu.Unlock()
+	}))
+
+	for i := 0; i < 3; i++ {
+		err := c.publish(context.Background(), "bar", amqp.Publishing{Body: []byte("hello")})
+		assert.NoError(t, err)
+	}
+	c.onReturn(amqp.Return{ReplyCode: 312, MessageId: ch.get(1).MessageId})
+	c.onConfirm(amqp.Confirmation{DeliveryTag: 1, Ack: true})
+	c.onConfirm(amqp.Confirmation{DeliveryTag: 2, Ack: true})
+	c.onConfirm(amqp.Confirmation{DeliveryTag: 3, Ack: false})
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Len(t, results, 3)
+	assert.NoError(t, results[0])
+	var returnedErr *ReturnedError
+	assert.True(t, errors.As(results[1], &returnedErr))
+	assert.ErrorIs(t, results[2], ErrNacked)
+}
+
+func TestBufferedPublisher_withoutConfirm(t *testing.T) {
+	c, ch := newTestBufferedPublisher(WithProducerBufferSize(2))
+
+	// connected, publishing returns without waiting for confirmation
+	err := c.publish(context.Background(), "bar", amqp.Publishing{Body: []byte("1")})
+	assert.NoError(t, err)
+	assert.Equal(t, "1", string(ch.get(0).Body))
+	assert.Empty(t, c.pending)
+
+	// disconnected, the messages are buffered
+	c.onChannelClosed()
+	assert.NoError(t, c.publish(context.Background(), "bar", amqp.Publishing{Body: []byte("2")}))
+	assert.NoError(t, c.publish(context.Background(), "bar", amqp.Publishing{Body: []byte("3")}))
+	err = c.publish(context.Background(), "bar", amqp.Publishing{Body: []byte("4")})
+	assert.ErrorIs(t, err, ErrBufferFull)
+
+	// reconnected, the buffered messages are republished in order
+	ch2 := &fakePublishChannel{}
+	c.mu.Lock()
+	c.ch = ch2
+	c.mu.Unlock()
+	c.flush(ch2)
+	assert.Equal(t, "2", string(ch2.get(0).Body))
+	assert.Equal(t, "3", string(ch2.get(1).Body))
+	c.mu.Lock()
+	assert.Empty(t, c.buffer)
+	assert.Empty(t, c.pending)
+	c.mu.Unlock()
+}
+
+func TestBufferedPublisher_returnWithoutConfirm(t *testing.T) {
+	c, _ := newTestBufferedPublisher()
+	core, logs := observer.New(zap.WarnLevel)
+	c.zapLog = zap.New(core)
+
+	// the returned message is logged, not recorded
+	c.onReturn(amqp.Return{ReplyCode: 312, ReplyText: "NO_ROUTE", Exchange: "foo", RoutingKey: "bar", MessageId: "1"})
+	assert.Empty(t, c.returned)
+	assert.Equal(t, 1, logs.FilterMessage("[rabbit producer] message is returned by broker").Len())
+}
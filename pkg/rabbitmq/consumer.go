@@ -9,8 +9,13 @@ import (
 
 	amqp "github.com/rabbitmq/amqp091-go"
 	"go.uber.org/zap"
+
+	"github.com/zhufuyi/sponge/pkg/internal/workerpool"
 )
 
+// the max time to wait for the broker to confirm the retry message
+const retryConfirmTimeout = time.Second * 10
+
 // ConsumerOption consumer option.
 type ConsumerOption func(*consumerOptions)
 
@@ -23,6 +28,14 @@ type consumerOptions struct {
 
 	isPersistent bool // persistent or not
 	isAutoAck    bool // auto-answer or not, if false, manual ACK required
+
+	// failure handling, only valid when manual ACK is required
+	isRequeue   bool            // requeue or not when nack
+	maxRetries  int             // max number of delayed retries, 0 means no retry
+	retryDelays []time.Duration // delays of retries
+
+	workers    int        // number of workers that handle messages concurrently
+	orderKeyFn OrderKeyFn // messages with the same key are handled in order
 }
 
 func (o *consumerOptions) apply(opts ...ConsumerOption) {
@@ -42,6 +55,9 @@ func defaultConsumerOptions() *consumerOptions {
 
 		isPersistent: true,
 		isAutoAck:    true,
+		isRequeue:    true,
+
+		workers: 1,
 	}
 }
 
@@ -94,6 +110,44 @@ func WithConsumerPersistent(enable bool) ConsumerOption {
 	}
 }
 
+// WithConsumerRequeue set whether the message is requeued when the handler returns an error, default true,
+// if false, the message is routed to the dead letter exchange if the queue has one, otherwise it is dropped.
+// it is ignored when WithConsumerRetry is set.
+func WithConsumerRequeue(enable bool) ConsumerOption {
+	return func(o *consumerOptions) {
+		o.isRequeue = enable
+	}
+}
+
+// WithConsumerRetry set the delayed retries when the handler returns an error, the message is republished
+// to the retry exchange (a delayed message exchange bound only to the consumer queue, see RetryExchangeName)
+// with the header x-retry-count on a confirm mode channel, the original message is acked after the broker confirms
+// the copy, otherwise it is requeued, and the copy is consumed again after the delay. if the number of delays is less than
+// maxRetries, the last delay is used for the rest retries, default delay is 1s. after all retries fail,
+// the message is nacked without requeue and routed to the dead letter exchange if the queue has one.
+// the rabbitmq_delayed_message_exchange plugin must be installed.
+func WithConsumerRetry(maxRetries int, delays ...time.Duration) ConsumerOption {
+	return func(o *consumerOptions) {
+		if maxRetries < 0 {
+			maxRetries = 0
+		}
+		o.maxRetries = maxRetries
+		o.retryDelays = delays
+	}
+}
+
+// WithConsumerWorkers set the number of workers that handle messages concurrently, if orderKeyFn is not nil,
+// the messages with the same key are handled in order by the same worker. the prefetch count of qos should
+// be set to no less than workers, otherwise the workers are not fully used.
+func WithConsumerWorkers(workers int, orderKeyFn OrderKeyFn) ConsumerOption {
+	return func(o *consumerOptions) {
+		if workers > 0 {
+			o.workers = workers
+		}
+		o.orderKeyFn = orderKeyFn
+	}
+}
+
 // -------------------------------------------------------------------------------------------
 
 // ConsumeOption consume option.
@@ -233,6 +287,15 @@ type Consumer struct {
 	isPersistent bool // persistent or not
 	isAutoAck    bool // auto ack or not
 
+	isRequeue   bool
+	maxRetries  int
+	retryDelays []time.Duration
+	retryCh     *amqp.Channel // confirm mode channel for publishing retry messages
+	publishFn   func(ctx context.Context, exchange string, key string, mandatory bool, immediate bool, msg amqp.Publishing) error
+
+	workers    int
+	orderKeyFn OrderKeyFn
+
 	zapLog *zap.Logger
 
 	count int64 // consumer success message number
@@ -241,6 +304,9 @@ type Consumer struct {
 // Handler message
 type Handler func(ctx context.Context, data []byte, tagID string) error
 
+// OrderKeyFn returns the key of the message, the messages with the same key are handled in order
+type OrderKeyFn func(d *amqp.Delivery) string
+
 //type Handler func(ctx context.Context, d *amqp.Delivery, isAutoAck bool) error
 
 // NewConsumer create a consumer
@@ -262,6 +328,13 @@ func NewConsumer(exchange *Exchange, queueName string, connection *Connection, o
 		isPersistent: o.isPersistent,
 		isAutoAck:    o.isAutoAck,
 
+		isRequeue:   o.isRequeue,
+		maxRetries:  o.maxRetries,
+		retryDelays: o.retryDelays,
+
+		workers:    o.workers,
+		orderKeyFn: o.orderKeyFn,
+
 		zapLog: connection.zapLog,
 	}
 
@@ -335,6 +408,27 @@ func (c *Consumer) initialize() error {
 		return err
 	}
 
+	// declare the retry exchange, it is bound only to the consumer queue
+	if c.maxRetries > 0 {
+		retryExchange := NewDelayedMessageExchange(RetryExchangeName(c.QueueName), NewDirectExchange("", c.QueueName))
+		err = ch.ExchangeDeclare(retryExchange.name, retryExchange.eType, c.isPersistent, false, false, false,
+			amqp.Table{"x-delayed-type": retryExchange.delayedMessageType})
+		if err != nil {
+			_ = ch.Close()
+			return err
+		}
+		err = ch.QueueBind(queue.Name, retryExchange.routingKey, retryExchange.name, false, nil)
+		if err != nil {
+			_ = ch.Close()
+			return err
+		}
+		err = c.openRetryChannel()
+		if err != nil {
+			_ = ch.Close()
+			return err
+		}
+	}
+
 	// setting the prefetch value, set channel.Qos on the consumer side to limit the number of messages consumed at a time,
 	// balancing message throughput and fairness, and prevent consumers from being hit by sudden bursts of information traffic.
 	if c.qosOption.enable {
@@ -347,10 +441,54 @@ func (c *Consumer) initialize() error {
 
 	fields := logFields(c.QueueName, c.Exchange)
 	fields = append(fields, zap.Bool("autoAck", c.isAutoAck))
+	if c.maxRetries > 0 {
+		fields = append(fields, zap.Int("maxRetries", c.maxRetries))
+	}
 	c.zapLog.Info("[rabbitmq consumer] initialized", fields...)
 	return nil
 }
 
+// open a confirm mode channel for publishing retry messages
+func (c *Consumer) openRetryChannel() error {
+	if c.retryCh != nil {
+		_ = c.retryCh.Close()
+	}
+
+	c.connection.mutex.Lock()
+	ch, err := c.connection.conn.Channel()
+	c.connection.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	if err = ch.Confirm(false); err != nil {
+		_ = ch.Close()
+		return err
+	}
+
+	c.retryCh = ch
+	c.publishFn = c.publishWithConfirm
+	return nil
+}
+
+// publish the message on the retry channel and wait for the broker to confirm it
+func (c *Consumer) publishWithConfirm(ctx context.Context, exchange string, key string, mandatory bool, immediate bool, msg amqp.Publishing) error {
+	ctx, cancel := context.WithTimeout(ctx, retryConfirmTimeout)
+	defer cancel()
+
+	dc, err := c.retryCh.PublishWithDeferredConfirmWithContext(ctx, exchange, key, mandatory, immediate, msg)
+	if err != nil {
+		return err
+	}
+	ok, err := dc.WaitContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNacked
+	}
+	return nil
+}
+
 func (c *Consumer) consumeWithContext(ctx context.Context) (<-chan amqp.Delivery, error) {
 	return c.ch.ConsumeWithContext(
 		ctx,
@@ -402,40 +540,165 @@ func (c *Consumer) Consume(ctx context.Context, handler Handler) {
 			}
 			c.zapLog.Info("[rabbitmq consumer] queue is ready and waiting for messages, queue=" + c.QueueName)
 
-			isContinueConsume := false
-			for {
-				select {
-				case <-c.connection.exit:
-					c.Close()
-					return
-				case d, ok := <-delivery:
-					if !ok {
-						c.zapLog.Warn("[rabbitmq consumer] exit consume message, queue=" + c.QueueName)
-						isContinueConsume = true
-						break
-					}
-					tagID := strings.Join([]string{d.Exchange, c.QueueName, strconv.FormatUint(d.DeliveryTag, 10)}, "/")
-					err = handler(ctx, d.Body, tagID)
-					if err != nil {
-						c.zapLog.Warn("[rabbitmq consumer] handle message error", zap.String("err", err.Error()), zap.String("tagID", tagID))
-						continue
-					}
-					if !c.isAutoAck {
-						if err = d.Ack(false); err != nil {
-							c.zapLog.Warn("[rabbitmq consumer] manual ack error", zap.String("err", err.Error()), zap.String("tagID", tagID))
-							continue
-						}
-						c.zapLog.Info("[rabbitmq consumer] manual ack done", zap.String("tagID", tagID))
-					}
-					atomic.AddInt64(&c.count, 1)
-				}
+			if c.handleDeliveries(ctx, delivery, handler) {
+				c.Close()
+				return
+			}
+		}
+	}()
+}
 
-				if isContinueConsume {
-					break
+// handle the deliveries until the delivery channel is closed, return true if the consumption should exit,
+// the messages submitted to the workers are drained before returning.
+func (c *Consumer) handleDeliveries(ctx context.Context, delivery <-chan amqp.Delivery, handler Handler) bool {
+	var pool *workerpool.Pool
+	if c.workers > 1 {
+		pool = workerpool.New(c.workers, c.zapLog)
+		defer pool.Close()
+	}
+
+	for {
+		select {
+		case <-c.connection.exit:
+			return true
+		case d, ok := <-delivery:
+			if !ok {
+				c.zapLog.Warn("[rabbitmq consumer] exit consume message, queue=" + c.QueueName)
+				return ctx.Err() != nil
+			}
+			if pool == nil {
+				c.handleDelivery(ctx, d, handler)
+				continue
+			}
+			key := ""
+			if c.orderKeyFn != nil {
+				key = c.orderKeyFn(&d)
+			}
+			if !pool.Submit(ctx, key, func() { c.handleDelivery(ctx, d, handler) }) {
+				// the message not acked is redelivered after the channel is closed
+				return true
+			}
+		}
+	}
+}
+
+func (c *Consumer) handleDelivery(ctx context.Context, d amqp.Delivery, handler Handler) {
+	tagID := strings.Join([]string{d.Exchange, c.QueueName, strconv.FormatUint(d.DeliveryTag, 10)}, "/")
+	err := handler(ctx, d.Body, tagID)
+	if err != nil {
+		c.zapLog.Warn("[rabbitmq consumer] handle message error", zap.String("err", err.Error()), zap.String("tagID", tagID))
+		if !c.isAutoAck {
+			c.handleFailure(ctx, d, tagID)
+		}
+		return
+	}
+	if !c.isAutoAck {
+		if err = d.Ack(false); err != nil {
+			c.zapLog.Warn("[rabbitmq consumer] manual ack error", zap.String("err", err.Error()), zap.String("tagID", tagID))
+			return
+		}
+		c.zapLog.Info("[rabbitmq consumer] manual ack done", zap.String("tagID", tagID))
+	}
+	atomic.AddInt64(&c.count, 1)
+}
+
+// nack the message, or republish it to the retry exchange
+func (c *Consumer) handleFailure(ctx context.Context, d amqp.Delivery, tagID string) {
+	requeue := c.isRequeue
+	if c.maxRetries > 0 {
+		requeue = false
+		retryCount := getRetryCount(d.Headers)
+		if retryCount < c.maxRetries {
+			delay := c.retryDelay(retryCount)
+			err := c.republish(ctx, d, retryCount+1, delay)
+			if err != nil {
+				// requeue the message so that it will not be lost
+				c.zapLog.Warn("[rabbitmq consumer] republish message error", zap.String("err", err.Error()), zap.String("tagID", tagID))
+				requeue = true
+			} else {
+				if err = d.Ack(false); err != nil {
+					c.zapLog.Warn("[rabbitmq consumer] manual ack error", zap.String("err", err.Error()), zap.String("tagID", tagID))
+					return
 				}
+				c.zapLog.Info("[rabbitmq consumer] message will be retried", zap.String("tagID", tagID),
+					zap.Int("retryCount", retryCount+1), zap.Duration("delay", delay))
+				return
 			}
 		}
-	}()
+	}
+
+	if err := d.Nack(false, requeue); err != nil {
+		c.zapLog.Warn("[rabbitmq consumer] manual nack error", zap.String("err", err.Error()), zap.String("tagID", tagID))
+		return
+	}
+	c.zapLog.Info("[rabbitmq consumer] manual nack done", zap.String("tagID", tagID), zap.Bool("requeue", requeue))
+}
+
+func (c *Consumer) retryDelay(retryCount int) time.Duration {
+	if len(c.retryDelays) == 0 {
+		return time.Second
+	}
+	if retryCount >= len(c.retryDelays) {
+		return c.retryDelays[len(c.retryDelays)-1]
+	}
+	return c.retryDelays[retryCount]
+}
+
+// republish a copy of the message to the retry exchange, it returns after the broker confirms the message,
+// mandatory is not set because the delayed message exchange does not support it, the retry exchange is bound
+// to the queue when initializing.
+func (c *Consumer) republish(ctx context.Context, d amqp.Delivery, retryCount int, delay time.Duration) error {
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	headers[HeaderRetryCount] = retryCount
+	headers["x-delay"] = int(delay / time.Millisecond) // delay time: milliseconds
+
+	return c.publishFn(ctx, RetryExchangeName(c.QueueName), c.QueueName, false, false, amqp.Publishing{
+		Headers:         headers,
+		ContentType:     d.ContentType,
+		ContentEncoding: d.ContentEncoding,
+		DeliveryMode:    d.DeliveryMode,
+		Priority:        d.Priority,
+		CorrelationId:   d.CorrelationId,
+		ReplyTo:         d.ReplyTo,
+		MessageId:       d.MessageId,
+		Timestamp:       d.Timestamp,
+		Type:            d.Type,
+		AppId:           d.AppId,
+		Body:            d.Body,
+	})
+}
+
+// RetryExchangeName the name of the retry exchange of the queue
+func RetryExchangeName(queueName string) string {
+	return "retry-" + queueName
+}
+
+func getRetryCount(headers amqp.Table) int {
+	switch v := headers[HeaderRetryCount].(type) {
+	case int:
+		return v
+	case int8:
+		return int(v)
+	case int16:
+		return int(v)
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case uint8:
+		return int(v)
+	case uint16:
+		return int(v)
+	case uint32:
+		return int(v)
+	case string:
+		n, _ := strconv.Atoi(v)
+		return n
+	}
+	return 0
 }
 
 // Close consumer
@@ -443,6 +706,9 @@ func (c *Consumer) Close() {
 	if c.ch != nil {
 		_ = c.ch.Close()
 	}
+	if c.retryCh != nil {
+		_ = c.retryCh.Close()
+	}
 }
 
 // Count consumer success message number
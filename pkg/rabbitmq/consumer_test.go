@@ -3,6 +3,8 @@ package rabbitmq
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 
@@ -66,6 +68,7 @@ func TestConsumerOptions(t *testing.T) {
 
 	assert.True(t, o.isPersistent)
 	assert.True(t, o.isAutoAck)
+	assert.True(t, o.isRequeue)
 }
 
 var handler = func(ctx context.Context, data []byte, tagID string) error {
@@ -382,3 +385,173 @@ func TestConsumerErr(t *testing.T) {
 	time.Sleep(time.Millisecond * 2500)
 	close(c.connection.exit)
 }
+
+type fakeAcknowledger struct {
+	mu      sync.Mutex
+	acks    int
+	nacks   int
+	requeue bool
+}
+
+func (a *fakeAcknowledger) Ack(tag uint64, multiple bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.acks++
+	return nil
+}
+
+func (a *fakeAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.nacks++
+	a.requeue = requeue
+	return nil
+}
+
+func (a *fakeAcknowledger) Reject(tag uint64, requeue bool) error {
+	return a.Nack(tag, false, requeue)
+}
+
+func TestConsumer_handleDelivery(t *testing.T) {
+	connection := &Connection{zapLog: zap.NewNop()}
+	failHandler := func(ctx context.Context, data []byte, tagID string) error {
+		if string(data) == "bad" {
+			return fmt.Errorf("invalid data")
+		}
+		return nil
+	}
+	newDelivery := func(body string, headers amqp.Table) (amqp.Delivery, *fakeAcknowledger) {
+		a := &fakeAcknowledger{}
+		return amqp.Delivery{Acknowledger: a, Body: []byte(body), Headers: headers, MessageId: "1"}, a
+	}
+
+	// ack
+	c, _ := NewConsumer(NewDirectExchange("foo", "bar"), "test", connection, WithConsumerAutoAck(false))
+	d, a := newDelivery("good", nil)
+	c.handleDelivery(context.Background(), d, failHandler)
+	assert.Equal(t, 1, a.acks)
+	assert.Equal(t, int64(1), c.Count())
+
+	// nack with requeue by default
+	d, a = newDelivery("bad", nil)
+	c.handleDelivery(context.Background(), d, failHandler)
+	assert.Equal(t, 1, a.nacks)
+	assert.True(t, a.requeue)
+
+	// nack without requeue
+	c, _ = NewConsumer(NewDirectExchange("foo", "bar"), "test", connection, WithConsumerAutoAck(false), WithConsumerRequeue(false))
+	d, a = newDelivery("bad", nil)
+	c.handleDelivery(context.Background(), d, failHandler)
+	assert.Equal(t, 1, a.nacks)
+	assert.False(t, a.requeue)
+
+	// retry with delay
+	c, _ = NewConsumer(NewDirectExchange("foo", "bar"), "test", connection, WithConsumerAutoAck(false),
+		WithConsumerRequeue(true), WithConsumerRetry(3, time.Second, time.Second*5))
+	var published []amqp.Publishing
+	var publishErr error
+	c.publishFn = func(ctx context.Context, exchange string, key string, mandatory bool, immediate bool, msg amqp.Publishing) error {
+		assert.Equal(t, "retry-test", exchange)
+		assert.Equal(t, "test", key)
+		if publishErr != nil {
+			return publishErr
+		}
+		published = append(published, msg)
+		return nil
+	}
+	d, a = newDelivery("bad", amqp.Table{"foo": "bar"})
+	c.handleDelivery(context.Background(), d, failHandler)
+	assert.Equal(t, 1, a.acks)
+	assert.Len(t, published, 1)
+	assert.Equal(t, 1, published[0].Headers[HeaderRetryCount])
+	assert.Equal(t, 1000, published[0].Headers["x-delay"])
+	assert.Equal(t, "bar", published[0].Headers["foo"])
+	assert.Equal(t, "1", published[0].MessageId)
+	assert.Equal(t, "bad", string(published[0].Body))
+
+	d, a = newDelivery("bad", amqp.Table{HeaderRetryCount: int32(2)})
+	c.handleDelivery(context.Background(), d, failHandler)
+	assert.Equal(t, 1, a.acks)
+	assert.Equal(t, 3, published[1].Headers[HeaderRetryCount])
+	assert.Equal(t, 5000, published[1].Headers["x-delay"])
+
+	// failed to republish, requeue
+	publishErr = fmt.Errorf("channel closed")
+	d, a = newDelivery("bad", amqp.Table{HeaderRetryCount: int64(1)})
+	c.handleDelivery(context.Background(), d, failHandler)
+	assert.Equal(t, 1, a.nacks)
+	assert.True(t, a.requeue)
+
+	// the retry message is nacked by the broker, requeue
+	publishErr = ErrNacked
+	d, a = newDelivery("bad", amqp.Table{HeaderRetryCount: int64(1)})
+	c.handleDelivery(context.Background(), d, failHandler)
+	assert.Equal(t, 0, a.acks)
+	assert.Equal(t, 1, a.nacks)
+	assert.True(t, a.requeue)
+
+	// exceed the max retries, route to the dead letter exchange
+	d, a = newDelivery("bad", amqp.Table{HeaderRetryCount: "3"})
+	c.handleDelivery(context.Background(), d, failHandler)
+	assert.Equal(t, 1, a.nacks)
+	assert.False(t, a.requeue)
+	assert.Len(t, published, 2)
+}
+
+func TestConsumer_handleDeliveries(t *testing.T) {
+	connection := &Connection{zapLog: zap.NewNop(), exit: make(chan struct{})}
+	c, _ := NewConsumer(NewDirectExchange("foo", "bar"), "test", connection, WithConsumerAutoAck(false),
+		WithConsumerWorkers(4, func(d *amqp.Delivery) string {
+			return d.MessageId
+		}),
+	)
+
+	var mu sync.Mutex
+	results := map[string][]string{}
+	h := func(ctx context.Context, data []byte, tagID string) error {
+		time.Sleep(time.Millisecond * 20)
+		key, value := string(data[:1]), string(data)
+		if value == "a3" {
+			panic("test panic")
+		}
+		mu.Lock()
+		results[key] = append(results[key], value)
+		mu.Unlock()
+		return nil
+	}
+
+	delivery := make(chan amqp.Delivery, 20)
+	acks := &fakeAcknowledger{}
+	for i := 0; i < 5; i++ {
+		for _, key := range []string{"a", "b", ""} {
+			body := "c" + strconv.Itoa(i)
+			if key != "" {
+				body = key + strconv.Itoa(i)
+			}
+			delivery <- amqp.Delivery{Acknowledger: acks, MessageId: key, Body: []byte(body)}
+		}
+	}
+
+	close(delivery)
+
+	start := time.Now()
+	isExit := c.handleDeliveries(context.Background(), delivery, h)
+	assert.False(t, isExit)
+	// the submitted messages are drained, the sequential handling takes 300ms
+	assert.Equal(t, 14, acks.acks)
+	assert.Less(t, time.Since(start), time.Millisecond*250)
+	assert.Equal(t, []string{"a0", "a1", "a2", "a4"}, results["a"])
+	assert.Equal(t, []string{"b0", "b1", "b2", "b3", "b4"}, results["b"])
+	assert.Len(t, results["c"], 5)
+
+	// exit by canceling
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	delivery = make(chan amqp.Delivery)
+	close(delivery)
+	assert.True(t, c.handleDeliveries(ctx, delivery, h))
+
+	// exit by connection
+	close(connection.exit)
+	assert.True(t, c.handleDeliveries(context.Background(), make(chan amqp.Delivery), h))
+}
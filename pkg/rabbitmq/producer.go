@@ -23,6 +23,12 @@ type producerOptions struct {
 	// If true, the message will be returned to the sender if the queue cannot be
 	// found according to its own exchange type and routeKey rules.
 	mandatory bool
+
+	// confirm mode
+	isConfirm       bool
+	confirmTimeout  time.Duration   // timeout of waiting for confirmation in synchronous mode, 0 means no timeout
+	confirmCallback ConfirmCallback // if not nil, asynchronous mode
+	bufferSize      int             // max number of messages buffered during reconnection
 }
 
 func (o *producerOptions) apply(opts ...ProducerOption) {
@@ -41,6 +47,8 @@ func defaultProducerOptions() *producerOptions {
 
 		isPersistent: true,
 		mandatory:    true,
+
+		bufferSize: 1000,
 	}
 }
 
@@ -79,13 +87,44 @@ func WithProducerPersistent(enable bool) ProducerOption {
 	}
 }
 
-// WithProducerMandatory set producer mandatory option.
+// WithProducerMandatory set producer mandatory option, default true, the message that cannot be routed to
+// any queue is returned by the broker, it is reported as *ReturnedError in confirm mode, otherwise it is logged.
 func WithProducerMandatory(enable bool) ProducerOption {
 	return func(o *producerOptions) {
 		o.mandatory = enable
 	}
 }
 
+// WithProducerConfirm enable confirm mode, publishing waits for the broker to confirm the message,
+// returns *ReturnedError if the message is returned (mandatory is true), ErrNacked if the message is nacked,
+// ErrConfirmTimeout if there is no confirmation within timeout (0 means waiting until ctx is done),
+// the messages not confirmed when the channel is closed are republished after reconnecting.
+func WithProducerConfirm(timeout time.Duration) ProducerOption {
+	return func(o *producerOptions) {
+		o.isConfirm = true
+		o.confirmTimeout = timeout
+	}
+}
+
+// WithProducerConfirmCallback enable asynchronous confirm mode, publishing returns after the message is sent
+// or buffered, and fn is called when the message is confirmed.
+func WithProducerConfirmCallback(fn ConfirmCallback) ProducerOption {
+	return func(o *producerOptions) {
+		o.isConfirm = true
+		o.confirmCallback = fn
+	}
+}
+
+// WithProducerBufferSize set the max number of messages buffered during reconnection, the buffered messages
+// are republished in order after reconnecting, default 1000, publishing returns ErrBufferFull if the buffer is full.
+func WithProducerBufferSize(size int) ProducerOption {
+	return func(o *producerOptions) {
+		if size > 0 {
+			o.bufferSize = size
+		}
+	}
+}
+
 // -------------------------------------------------------------------------------------------
 
 // Producer session
@@ -108,6 +147,8 @@ type Producer struct {
 	exchangeArgs  amqp.Table
 	queueArgs     amqp.Table
 	queueBindArgs amqp.Table
+
+	buffered *bufferedPublisher // buffer the messages published during reconnection
 }
 
 // NewProducer create a producer
@@ -121,6 +162,58 @@ func NewProducer(exchange *Exchange, queueName string, connection *Connection, o
 		return nil, err
 	}
 
+	err = declareProducer(ch, exchange, queueName, o)
+	if err != nil {
+		_ = ch.Close()
+		return nil, err
+	}
+
+	fields := logFields(queueName, exchange)
+	fields = append(fields, zap.Bool("isPersistent", o.isPersistent))
+
+	if o.deadLetter.isEnabled() {
+		fields = append(fields, zap.Any("deadLetter", map[string]string{
+			"exchange":   o.deadLetter.exchangeName,
+			"queue":      o.deadLetter.queueName,
+			"routingKey": o.deadLetter.routingKey,
+			"type":       exchangeTypeDirect,
+		}))
+	}
+
+	deliveryMode := amqp.Persistent
+	if !o.isPersistent {
+		deliveryMode = amqp.Transient
+	}
+
+	if o.isConfirm {
+		fields = append(fields, zap.Bool("confirm", true))
+	}
+	connection.zapLog.Info("[rabbit producer] initialized", fields...)
+
+	p := &Producer{
+		QueueName:    queueName,
+		conn:         connection.conn,
+		ch:           ch,
+		Exchange:     exchange,
+		isPersistent: o.isPersistent,
+		deliveryMode: deliveryMode,
+		mandatory:    o.mandatory,
+		zapLog:       connection.zapLog,
+
+		exchangeArgs:  o.exchangeDeclare.args,
+		queueArgs:     o.queueDeclare.args,
+		queueBindArgs: o.queueBind.args,
+	}
+	p.buffered = newBufferedPublisher(exchange.name, connection, o, func(ch *amqp.Channel) error {
+		return declareProducer(ch, exchange, queueName, o)
+	})
+	go p.buffered.run(ch)
+
+	return p, nil
+}
+
+// declare the exchange, queue, binding and dead letter
+func declareProducer(ch *amqp.Channel, exchange *Exchange, queueName string, o *producerOptions) error {
 	if exchange.eType == exchangeTypeDelayedMessage {
 		if o.exchangeDeclare.args == nil {
 			o.exchangeDeclare.args = amqp.Table{
@@ -131,7 +224,7 @@ func NewProducer(exchange *Exchange, queueName string, connection *Connection, o
 		}
 	}
 	// declare the exchange type
-	err = ch.ExchangeDeclare(
+	err := ch.ExchangeDeclare(
 		exchange.name,
 		exchange.eType,
 		o.isPersistent,
@@ -141,8 +234,7 @@ func NewProducer(exchange *Exchange, queueName string, connection *Connection, o
 		o.exchangeDeclare.args,
 	)
 	if err != nil {
-		_ = ch.Close()
-		return nil, err
+		return err
 	}
 
 	// declare a queue and create it automatically if it doesn't exist, or skip creation if it does.
@@ -166,8 +258,7 @@ func NewProducer(exchange *Exchange, queueName string, connection *Connection, o
 		o.queueDeclare.args,
 	)
 	if err != nil {
-		_ = ch.Close()
-		return nil, err
+		return err
 	}
 
 	args := o.queueBind.args
@@ -183,49 +274,14 @@ func NewProducer(exchange *Exchange, queueName string, connection *Connection, o
 		args,
 	)
 	if err != nil {
-		_ = ch.Close()
-		return nil, err
+		return err
 	}
 
-	fields := logFields(queueName, exchange)
-	fields = append(fields, zap.Bool("isPersistent", o.isPersistent))
-
 	// create dead letter exchange and queue if enabled
 	if o.deadLetter.isEnabled() {
-		err = createDeadLetter(ch, o.deadLetter)
-		if err != nil {
-			_ = ch.Close()
-			return nil, err
-		}
-		fields = append(fields, zap.Any("deadLetter", map[string]string{
-			"exchange":   o.deadLetter.exchangeName,
-			"queue":      o.deadLetter.queueName,
-			"routingKey": o.deadLetter.routingKey,
-			"type":       exchangeTypeDirect,
-		}))
+		return createDeadLetter(ch, o.deadLetter)
 	}
-
-	deliveryMode := amqp.Persistent
-	if !o.isPersistent {
-		deliveryMode = amqp.Transient
-	}
-
-	connection.zapLog.Info("[rabbit producer] initialized", fields...)
-
-	return &Producer{
-		QueueName:    queueName,
-		conn:         connection.conn,
-		ch:           ch,
-		Exchange:     exchange,
-		isPersistent: o.isPersistent,
-		deliveryMode: deliveryMode,
-		mandatory:    o.mandatory,
-		zapLog:       connection.zapLog,
-
-		exchangeArgs:  o.exchangeDeclare.args,
-		queueArgs:     o.queueDeclare.args,
-		queueBindArgs: o.queueBind.args,
-	}, nil
+	return nil
 }
 
 // PublishDirect send direct type message
@@ -233,18 +289,11 @@ func (p *Producer) PublishDirect(ctx context.Context, body []byte) error {
 	if p.Exchange.eType != exchangeTypeDirect {
 		return fmt.Errorf("invalid exchange type (%s), only supports direct type", p.Exchange.eType)
 	}
-	return p.ch.PublishWithContext(
-		ctx,
-		p.Exchange.name,
-		p.Exchange.routingKey,
-		p.mandatory,
-		false,
-		amqp.Publishing{
-			DeliveryMode: p.deliveryMode,
-			ContentType:  "text/plain",
-			Body:         body,
-		},
-	)
+	return p.publish(ctx, p.Exchange.routingKey, amqp.Publishing{
+		DeliveryMode: p.deliveryMode,
+		ContentType:  "text/plain",
+		Body:         body,
+	})
 }
 
 // PublishFanout send fanout type message
@@ -252,18 +301,11 @@ func (p *Producer) PublishFanout(ctx context.Context, body []byte) error {
 	if p.Exchange.eType != exchangeTypeFanout {
 		return fmt.Errorf("invalid exchange type (%s), only supports fanout type", p.Exchange.eType)
 	}
-	return p.ch.PublishWithContext(
-		ctx,
-		p.Exchange.name,
-		p.Exchange.routingKey,
-		p.mandatory,
-		false,
-		amqp.Publishing{
-			DeliveryMode: p.deliveryMode,
-			ContentType:  "text/plain",
-			Body:         body,
-		},
-	)
+	return p.publish(ctx, p.Exchange.routingKey, amqp.Publishing{
+		DeliveryMode: p.deliveryMode,
+		ContentType:  "text/plain",
+		Body:         body,
+	})
 }
 
 // PublishTopic send topic type message
@@ -271,18 +313,11 @@ func (p *Producer) PublishTopic(ctx context.Context, topicKey string, body []byt
 	if p.Exchange.eType != exchangeTypeTopic {
 		return fmt.Errorf("invalid exchange type (%s), only supports topic type", p.Exchange.eType)
 	}
-	return p.ch.PublishWithContext(
-		ctx,
-		p.Exchange.name,
-		topicKey,
-		p.mandatory,
-		false,
-		amqp.Publishing{
-			DeliveryMode: p.deliveryMode,
-			ContentType:  "text/plain",
-			Body:         body,
-		},
-	)
+	return p.publish(ctx, topicKey, amqp.Publishing{
+		DeliveryMode: p.deliveryMode,
+		ContentType:  "text/plain",
+		Body:         body,
+	})
 }
 
 // PublishHeaders send headers type message
@@ -290,19 +325,12 @@ func (p *Producer) PublishHeaders(ctx context.Context, headersKeys map[string]in
 	if p.Exchange.eType != exchangeTypeHeaders {
 		return fmt.Errorf("invalid exchange type (%s), only supports headers type", p.Exchange.eType)
 	}
-	return p.ch.PublishWithContext(
-		ctx,
-		p.Exchange.name,
-		p.Exchange.routingKey,
-		p.mandatory,
-		false,
-		amqp.Publishing{
-			DeliveryMode: p.deliveryMode,
-			Headers:      headersKeys,
-			ContentType:  "text/plain",
-			Body:         body,
-		},
-	)
+	return p.publish(ctx, p.Exchange.routingKey, amqp.Publishing{
+		DeliveryMode: p.deliveryMode,
+		Headers:      headersKeys,
+		ContentType:  "text/plain",
+		Body:         body,
+	})
 }
 
 // PublishDelayedMessage send delayed type message
@@ -329,23 +357,27 @@ func (p *Producer) PublishDelayedMessage(ctx context.Context, delayTime time.Dur
 	}
 	headersKeys["x-delay"] = int(delayTime / time.Millisecond) // delay time: milliseconds
 
-	return p.ch.PublishWithContext(
-		ctx,
-		p.Exchange.name,
-		routingKey,
-		p.mandatory,
-		false,
-		amqp.Publishing{
-			DeliveryMode: p.deliveryMode,
-			Headers:      headersKeys,
-			ContentType:  "text/plain",
-			Body:         body,
-		},
-	)
+	return p.publish(ctx, routingKey, amqp.Publishing{
+		DeliveryMode: p.deliveryMode,
+		Headers:      headersKeys,
+		ContentType:  "text/plain",
+		Body:         body,
+	})
+}
+
+func (p *Producer) publish(ctx context.Context, routingKey string, msg amqp.Publishing) error {
+	if p.buffered != nil {
+		return p.buffered.publish(ctx, routingKey, msg)
+	}
+	return p.ch.PublishWithContext(ctx, p.Exchange.name, routingKey, p.mandatory, false, msg)
 }
 
-// Close the consumer
+// Close the producer, the buffered messages and the messages not confirmed are failed with ErrClosed
 func (p *Producer) Close() {
+	if p.buffered != nil {
+		p.buffered.close()
+		return
+	}
 	if p.ch != nil {
 		_ = p.ch.Close()
 	}
@@ -25,16 +25,7 @@ func NewPublisher(channelName string, connection *Connection, opts ...ProducerOp
 		return nil, err
 	}
 
-	// declare the exchange type
-	err = ch.ExchangeDeclare(
-		channelName,
-		exchangeTypeFanout,
-		o.isPersistent,
-		o.exchangeDeclare.autoDelete,
-		o.exchangeDeclare.internal,
-		o.exchangeDeclare.noWait,
-		o.exchangeDeclare.args,
-	)
+	err = declarePublisher(ch, channelName, o)
 	if err != nil {
 		_ = ch.Close()
 		return nil, err
@@ -56,27 +47,42 @@ func NewPublisher(channelName string, connection *Connection, opts ...ProducerOp
 		mandatory:    o.mandatory,
 		zapLog:       connection.zapLog,
 	}
+	p.buffered = newBufferedPublisher(channelName, connection, o, func(ch *amqp.Channel) error {
+		return declarePublisher(ch, channelName, o)
+	})
+	go p.buffered.run(ch)
 
 	return &Publisher{p}, nil
 }
 
-func (p *Publisher) Publish(ctx context.Context, body []byte) error {
-	return p.ch.PublishWithContext(
-		ctx,
-		p.Exchange.name,
-		p.Exchange.routingKey,
-		p.mandatory,
-		false,
-		amqp.Publishing{
-			DeliveryMode: p.deliveryMode,
-			ContentType:  "text/plain",
-			Body:         body,
-		},
+func declarePublisher(ch *amqp.Channel, channelName string, o *producerOptions) error {
+	// declare the exchange type
+	return ch.ExchangeDeclare(
+		channelName,
+		exchangeTypeFanout,
+		o.isPersistent,
+		o.exchangeDeclare.autoDelete,
+		o.exchangeDeclare.internal,
+		o.exchangeDeclare.noWait,
+		o.exchangeDeclare.args,
 	)
 }
 
+// Publish message, in confirm mode, wait for the broker to confirm the message, see WithProducerConfirm
+func (p *Publisher) Publish(ctx context.Context, body []byte) error {
+	return p.publish(ctx, p.Exchange.routingKey, amqp.Publishing{
+		DeliveryMode: p.deliveryMode,
+		ContentType:  "text/plain",
+		Body:         body,
+	})
+}
+
 // Close publisher
 func (p *Publisher) Close() {
+	if p.buffered != nil {
+		p.buffered.close()
+		return
+	}
 	if p.ch != nil {
 		_ = p.ch.Close()
 	}
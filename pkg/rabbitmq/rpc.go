@@ -0,0 +1,619 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+
+	"github.com/zhufuyi/sponge/pkg/internal/workerpool"
+	"github.com/zhufuyi/sponge/pkg/krand"
+)
+
+// HeaderRPCError the header of the reply, the error returned by the rpc handler
+const HeaderRPCError = "x-rpc-error"
+
+// RPCError the error returned by the rpc handler of the server
+type RPCError struct {
+	Message string
+}
+
+// Error message
+func (e *RPCError) Error() string {
+	return "rpc server error: " + e.Message
+}
+
+// RPCHandler handle the request and return the reply, the error is returned to the client as *RPCError
+type RPCHandler func(ctx context.Context, data []byte) ([]byte, error)
+
+type publishFunc func(ctx context.Context, exchange string, key string, mandatory bool, immediate bool, msg amqp.Publishing) error
+
+// RPCClientOption rpc client option.
+type RPCClientOption func(*rpcClientOptions)
+
+type rpcClientOptions struct {
+	timeout time.Duration
+}
+
+func (o *rpcClientOptions) apply(opts ...RPCClientOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// default rpc client settings
+func defaultRPCClientOptions() *rpcClientOptions {
+	return &rpcClientOptions{
+		timeout: time.Second * 10,
+	}
+}
+
+// WithRPCClientTimeout set the timeout of the call when the context has no deadline, default 10s.
+func WithRPCClientTimeout(d time.Duration) RPCClientOption {
+	return func(o *rpcClientOptions) {
+		if d > 0 {
+			o.timeout = d
+		}
+	}
+}
+
+type rpcResult struct {
+	data []byte
+	err  error
+}
+
+// RPCClient send requests to the queue of the rpc server and wait for the replies from an exclusive callback queue
+type RPCClient struct {
+	QueueName  string // the request queue of the rpc server
+	connection *Connection
+	timeout    time.Duration
+	idPrefix   string
+	idSeq      uint64
+	zapLog     *zap.Logger
+
+	mu         sync.Mutex
+	ch         *amqp.Channel
+	publishFn  publishFunc   // nil when disconnected
+	replyQueue string        // the name of the callback queue generated by the broker
+	ready      chan struct{} // closed when the callback queue is ready
+	pending    map[string]chan rpcResult
+	isClosed   bool
+
+	exit      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewRPCClient create a rpc client, queueName is the request queue of the rpc server,
+// the callback queue is redeclared automatically after the connection is reconnected.
+func NewRPCClient(queueName string, connection *Connection, opts ...RPCClientOption) (*RPCClient, error) {
+	o := defaultRPCClientOptions()
+	o.apply(opts...)
+
+	c := &RPCClient{
+		QueueName:  queueName,
+		connection: connection,
+		timeout:    o.timeout,
+		idPrefix:   krand.String(krand.R_All, 10) + "-",
+		zapLog:     connection.zapLog,
+		ready:      make(chan struct{}),
+		pending:    make(map[string]chan rpcResult),
+		exit:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+
+	deliveries, returns, err := c.initialize()
+	if err != nil {
+		return nil, err
+	}
+	go c.run(deliveries, returns)
+
+	return c, nil
+}
+
+// declare the callback queue and consume the replies
+func (c *RPCClient) initialize() (<-chan amqp.Delivery, <-chan amqp.Return, error) {
+	c.connection.mutex.Lock()
+	ch, err := c.connection.conn.Channel()
+	c.connection.mutex.Unlock()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the queue is deleted by the broker when the channel is closed
+	queue, err := ch.QueueDeclare("", false, true, true, false, nil)
+	if err != nil {
+		_ = ch.Close()
+		return nil, nil, err
+	}
+	deliveries, err := ch.Consume(queue.Name, "", true, true, false, false, nil)
+	if err != nil {
+		_ = ch.Close()
+		return nil, nil, err
+	}
+	returns := ch.NotifyReturn(make(chan amqp.Return, 16))
+
+	c.mu.Lock()
+	c.ch = ch
+	c.publishFn = ch.PublishWithContext
+	c.replyQueue = queue.Name
+	close(c.ready)
+	c.mu.Unlock()
+
+	c.zapLog.Info("[rabbitmq rpc client] callback queue is ready", zap.String("queue", c.QueueName), zap.String("replyQueue", queue.Name))
+	return deliveries, returns, nil
+}
+
+func (c *RPCClient) run(deliveries <-chan amqp.Delivery, returns <-chan amqp.Return) {
+	defer func() {
+		c.mu.Lock()
+		c.isClosed = true
+		if c.ch != nil {
+			_ = c.ch.Close()
+		}
+		c.mu.Unlock()
+		c.disconnect(ErrClosed)
+		close(c.done)
+	}()
+
+	for {
+		if !c.serve(deliveries, returns) {
+			return
+		}
+
+		// the replies of the calls in flight are lost with the callback queue
+		c.disconnect(ErrClosed)
+		c.zapLog.Warn("[rabbitmq rpc client] channel closed, wait for reconnection", zap.String("queue", c.QueueName))
+
+		var ok bool
+		deliveries, returns, ok = c.reconnect()
+		if !ok {
+			return
+		}
+	}
+}
+
+// dispatch the replies until the channel is closed, return false if exit
+func (c *RPCClient) serve(deliveries <-chan amqp.Delivery, returns <-chan amqp.Return) bool {
+	for {
+		select {
+		case <-c.exit:
+			return false
+		case <-c.connection.exit:
+			return false
+		case r, ok := <-returns:
+			if !ok {
+				returns = nil
+				continue
+			}
+			c.resolve(r.CorrelationId, rpcResult{err: &ReturnedError{
+				ReplyCode:  r.ReplyCode,
+				ReplyText:  r.ReplyText,
+				Exchange:   r.Exchange,
+				RoutingKey: r.RoutingKey,
+				MessageID:  r.MessageId,
+			}})
+		case d, ok := <-deliveries:
+			if !ok {
+				return true
+			}
+			c.resolve(d.CorrelationId, toRPCResult(d))
+		}
+	}
+}
+
+// redeclare the callback queue after the connection is reconnected, return false if exit
+func (c *RPCClient) reconnect() (<-chan amqp.Delivery, <-chan amqp.Return, bool) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.exit:
+			return nil, nil, false
+		case <-c.connection.exit:
+			return nil, nil, false
+		case <-ticker.C:
+		}
+
+		if !c.connection.CheckConnected() {
+			continue
+		}
+		deliveries, returns, err := c.initialize()
+		if err != nil {
+			c.zapLog.Warn("[rabbitmq rpc client] initialize error", zap.String("err", err.Error()), zap.String("queue", c.QueueName))
+			continue
+		}
+		return deliveries, returns, true
+	}
+}
+
+// mark as disconnected and fail all the calls in flight
+func (c *RPCClient) disconnect(err error) {
+	c.mu.Lock()
+	select {
+	case <-c.ready:
+		c.ready = make(chan struct{})
+	default:
+	}
+	c.publishFn = nil
+	pending := c.pending
+	c.pending = make(map[string]chan rpcResult)
+	c.mu.Unlock()
+
+	for _, result := range pending {
+		result <- rpcResult{err: err}
+	}
+}
+
+func (c *RPCClient) resolve(correlationID string, r rpcResult) {
+	c.mu.Lock()
+	result, ok := c.pending[correlationID]
+	delete(c.pending, correlationID)
+	c.mu.Unlock()
+
+	if !ok {
+		c.zapLog.Warn("[rabbitmq rpc client] discard the reply of unknown or expired call", zap.String("correlationID", correlationID))
+		return
+	}
+	result <- r
+}
+
+// wait until the callback queue is ready
+func (c *RPCClient) waitReady(ctx context.Context) (publishFunc, string, error) {
+	for {
+		c.mu.Lock()
+		if c.isClosed {
+			c.mu.Unlock()
+			return nil, "", ErrClosed
+		}
+		ready, publishFn, replyQueue := c.ready, c.publishFn, c.replyQueue
+		c.mu.Unlock()
+
+		if publishFn != nil {
+			return publishFn, replyQueue, nil
+		}
+		select {
+		case <-ready:
+		case <-c.done:
+			return nil, "", ErrClosed
+		case <-ctx.Done():
+			return nil, "", ctx.Err()
+		}
+	}
+}
+
+// Call send the request and wait for the reply until the context is done, if the context has no deadline,
+// the timeout of WithRPCClientTimeout is used. the request expires in the queue after the deadline. the error
+// returned by the handler of the server is *RPCError, if no queue receives the request, it is *ReturnedError.
+func (c *RPCClient) Call(ctx context.Context, data []byte) ([]byte, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	publishFn, replyQueue, err := c.waitReady(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	correlationID := c.idPrefix + strconv.FormatUint(atomic.AddUint64(&c.idSeq, 1), 10)
+	result := make(chan rpcResult, 1)
+	c.mu.Lock()
+	c.pending[correlationID] = result
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, correlationID)
+		c.mu.Unlock()
+	}()
+
+	msg := amqp.Publishing{
+		ContentType:   "text/plain",
+		CorrelationId: correlationID,
+		ReplyTo:       replyQueue,
+		Body:          data,
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		ms := time.Until(deadline).Milliseconds()
+		if ms <= 0 {
+			return nil, context.DeadlineExceeded
+		}
+		msg.Expiration = strconv.FormatInt(ms, 10)
+	}
+
+	// publish to the default exchange, the routing key is the name of the request queue
+	err = publishFn(ctx, "", c.QueueName, true, false, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case r := <-result:
+		return r.data, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Close the rpc client, the calls in flight return ErrClosed
+func (c *RPCClient) Close() {
+	c.closeOnce.Do(func() {
+		close(c.exit)
+	})
+	<-c.done
+}
+
+func toRPCResult(d amqp.Delivery) rpcResult {
+	if d.Headers != nil {
+		if v, ok := d.Headers[HeaderRPCError]; ok {
+			return rpcResult{err: &RPCError{Message: fmt.Sprint(v)}}
+		}
+	}
+	return rpcResult{data: d.Body}
+}
+
+// -------------------------------------------------------------------------------------------
+
+// RPCServerOption rpc server option.
+type RPCServerOption func(*rpcServerOptions)
+
+type rpcServerOptions struct {
+	queueDeclare *queueDeclareOptions
+	qos          *qosOptions
+
+	isPersistent bool
+	workers      int
+}
+
+func (o *rpcServerOptions) apply(opts ...RPCServerOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// default rpc server settings
+func defaultRPCServerOptions() *rpcServerOptions {
+	return &rpcServerOptions{
+		queueDeclare: defaultQueueDeclareOptions(),
+		qos:          defaultQosOptions(),
+
+		isPersistent: false,
+		workers:      1,
+	}
+}
+
+// WithRPCServerQueueDeclareOptions set queue declare option.
+func WithRPCServerQueueDeclareOptions(opts ...QueueDeclareOption) RPCServerOption {
+	return func(o *rpcServerOptions) {
+		o.queueDeclare.apply(opts...)
+	}
+}
+
+// WithRPCServerQosOptions set consume qos option.
+func WithRPCServerQosOptions(opts ...QosOption) RPCServerOption {
+	return func(o *rpcServerOptions) {
+		o.qos.apply(opts...)
+	}
+}
+
+// WithRPCServerPersistent set whether the request queue is durable, default false.
+func WithRPCServerPersistent(enable bool) RPCServerOption {
+	return func(o *rpcServerOptions) {
+		o.isPersistent = enable
+	}
+}
+
+// WithRPCServerWorkers set the number of workers that handle requests concurrently, default 1.
+func WithRPCServerWorkers(workers int) RPCServerOption {
+	return func(o *rpcServerOptions) {
+		if workers > 0 {
+			o.workers = workers
+		}
+	}
+}
+
+// RPCServer consume the requests from the queue and publish the replies to the callback queues of the clients
+type RPCServer struct {
+	QueueName  string
+	connection *Connection
+	ch         *amqp.Channel
+
+	queueDeclareOption *queueDeclareOptions
+	qosOption          *qosOptions
+	isPersistent       bool
+	workers            int
+
+	zapLog *zap.Logger
+
+	count int64 // number of requests replied
+}
+
+// NewRPCServer create a rpc server
+func NewRPCServer(queueName string, connection *Connection, opts ...RPCServerOption) (*RPCServer, error) {
+	if queueName == "" {
+		return nil, errors.New("queue name is empty")
+	}
+	o := defaultRPCServerOptions()
+	o.apply(opts...)
+
+	return &RPCServer{
+		QueueName:  queueName,
+		connection: connection,
+
+		queueDeclareOption: o.queueDeclare,
+		qosOption:          o.qos,
+		isPersistent:       o.isPersistent,
+		workers:            o.workers,
+
+		zapLog: connection.zapLog,
+	}, nil
+}
+
+// declare the request queue and consume the requests
+func (s *RPCServer) initialize(ctx context.Context) (<-chan amqp.Delivery, error) {
+	s.connection.mutex.Lock()
+	ch, err := s.connection.conn.Channel()
+	if err != nil {
+		s.connection.mutex.Unlock()
+		return nil, err
+	}
+	s.ch = ch
+	s.connection.mutex.Unlock()
+
+	_, err = ch.QueueDeclare(
+		s.QueueName,
+		s.isPersistent,
+		s.queueDeclareOption.autoDelete,
+		s.queueDeclareOption.exclusive,
+		s.queueDeclareOption.noWait,
+		s.queueDeclareOption.args,
+	)
+	if err != nil {
+		_ = ch.Close()
+		return nil, err
+	}
+
+	if s.qosOption.enable {
+		err = ch.Qos(s.qosOption.prefetchCount, s.qosOption.prefetchSize, s.qosOption.global)
+		if err != nil {
+			_ = ch.Close()
+			return nil, err
+		}
+	}
+
+	delivery, err := ch.ConsumeWithContext(ctx, s.QueueName, "", false, false, false, false, nil)
+	if err != nil {
+		_ = ch.Close()
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// Serve consume the requests for loop in goroutine, the request is acked after the reply is published
+func (s *RPCServer) Serve(ctx context.Context, handler RPCHandler) {
+	go func() {
+		ticker := time.NewTicker(time.Second * 2)
+		isFirst := true
+		for {
+			if isFirst {
+				isFirst = false
+				ticker.Reset(time.Millisecond * 10)
+			} else {
+				ticker.Reset(time.Second * 2)
+			}
+
+			// check connection for loop
+			select {
+			case <-ticker.C:
+				if !s.connection.CheckConnected() {
+					continue
+				}
+			case <-s.connection.exit:
+				s.Close()
+				return
+			}
+			ticker.Stop()
+
+			delivery, err := s.initialize(ctx)
+			if err != nil {
+				s.zapLog.Warn("[rabbitmq rpc server] initialize error", zap.String("err", err.Error()), zap.String("queue", s.QueueName))
+				continue
+			}
+			s.zapLog.Info("[rabbitmq rpc server] queue is ready and waiting for requests, queue=" + s.QueueName)
+
+			if s.handleDeliveries(ctx, delivery, s.ch.PublishWithContext, handler) {
+				s.Close()
+				return
+			}
+		}
+	}()
+}
+
+// handle the requests until the delivery channel is closed, return true if the server should exit
+func (s *RPCServer) handleDeliveries(ctx context.Context, delivery <-chan amqp.Delivery, publishFn publishFunc, handler RPCHandler) bool {
+	var pool *workerpool.Pool
+	if s.workers > 1 {
+		pool = workerpool.New(s.workers, s.zapLog)
+		defer pool.Close()
+	}
+
+	for {
+		select {
+		case <-s.connection.exit:
+			return true
+		case d, ok := <-delivery:
+			if !ok {
+				s.zapLog.Warn("[rabbitmq rpc server] exit consume request, queue=" + s.QueueName)
+				return ctx.Err() != nil
+			}
+			if pool == nil {
+				s.handleDelivery(ctx, d, publishFn, handler)
+				continue
+			}
+			if !pool.Submit(ctx, "", func() { s.handleDelivery(ctx, d, publishFn, handler) }) {
+				return true
+			}
+		}
+	}
+}
+
+func (s *RPCServer) handleDelivery(ctx context.Context, d amqp.Delivery, publishFn publishFunc, handler RPCHandler) {
+	data, err := s.call(ctx, d.Body, handler)
+
+	if d.ReplyTo != "" {
+		msg := amqp.Publishing{
+			ContentType:   "text/plain",
+			CorrelationId: d.CorrelationId,
+			Body:          data,
+		}
+		if err != nil {
+			msg.Headers = amqp.Table{HeaderRPCError: err.Error()}
+			msg.Body = nil
+		}
+		// the callback queue is bound to the default exchange
+		if pubErr := publishFn(ctx, "", d.ReplyTo, false, false, msg); pubErr != nil {
+			s.zapLog.Warn("[rabbitmq rpc server] publish reply error", zap.String("err", pubErr.Error()),
+				zap.String("replyTo", d.ReplyTo), zap.String("correlationID", d.CorrelationId))
+			_ = d.Nack(false, true)
+			return
+		}
+	} else {
+		s.zapLog.Warn("[rabbitmq rpc server] request without reply_to, the reply is discarded", zap.String("correlationID", d.CorrelationId))
+	}
+
+	if ackErr := d.Ack(false); ackErr != nil {
+		s.zapLog.Warn("[rabbitmq rpc server] ack error", zap.String("err", ackErr.Error()), zap.String("correlationID", d.CorrelationId))
+		return
+	}
+	atomic.AddInt64(&s.count, 1)
+}
+
+// call the handler, the panic is returned as error so that the client does not wait until timeout
+func (s *RPCServer) call(ctx context.Context, data []byte, handler RPCHandler) (reply []byte, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			s.zapLog.Error("[rabbitmq rpc server] panic occurred while handling request", zap.Any("error", e))
+			err = fmt.Errorf("panic: %v", e)
+		}
+	}()
+	return handler(ctx, data)
+}
+
+// Close rpc server
+func (s *RPCServer) Close() {
+	if s.ch != nil {
+		_ = s.ch.Close()
+	}
+}
+
+// Count number of requests replied
+func (s *RPCServer) Count() int64 {
+	return atomic.LoadInt64(&s.count)
+}
@@ -0,0 +1,195 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/zhufuyi/sponge/pkg/utils"
+)
+
+func rpcHandler(ctx context.Context, data []byte) ([]byte, error) {
+	switch string(data) {
+	case "bad":
+		return nil, errors.New("invalid request")
+	case "panic":
+		panic("oops")
+	case "slow":
+		time.Sleep(time.Millisecond * 100)
+	}
+	return []byte(strings.ToUpper(string(data))), nil
+}
+
+// the requests are handled by the server directly, the replies are dispatched to the client
+func newLocalRPCClient(s *RPCServer, handler RPCHandler) (*RPCClient, *fakeAcknowledger) {
+	c := &RPCClient{
+		QueueName:  s.QueueName,
+		connection: s.connection,
+		timeout:    time.Second,
+		idPrefix:   "test-",
+		zapLog:     zap.NewNop(),
+		replyQueue: "amq.gen-reply",
+		ready:      make(chan struct{}),
+		pending:    make(map[string]chan rpcResult),
+		done:       make(chan struct{}),
+	}
+	close(c.ready)
+
+	a := &fakeAcknowledger{}
+	reply := func(ctx context.Context, exchange string, key string, mandatory bool, immediate bool, msg amqp.Publishing) error {
+		c.resolve(msg.CorrelationId, toRPCResult(amqp.Delivery{Headers: msg.Headers, Body: msg.Body, CorrelationId: msg.CorrelationId}))
+		return nil
+	}
+	c.publishFn = func(ctx context.Context, exchange string, key string, mandatory bool, immediate bool, msg amqp.Publishing) error {
+		d := amqp.Delivery{Acknowledger: a, Body: msg.Body, CorrelationId: msg.CorrelationId, ReplyTo: msg.ReplyTo}
+		go s.handleDelivery(context.Background(), d, reply, handler)
+		return nil
+	}
+	return c, a
+}
+
+func TestRPCOptions(t *testing.T) {
+	o := defaultRPCClientOptions()
+	o.apply(WithRPCClientTimeout(time.Second * 3))
+	assert.Equal(t, time.Second*3, o.timeout)
+
+	so := defaultRPCServerOptions()
+	so.apply(
+		WithRPCServerQueueDeclareOptions(WithQueueDeclareAutoDelete(true)),
+		WithRPCServerQosOptions(WithQosEnable(), WithQosPrefetchCount(10)),
+		WithRPCServerPersistent(true),
+		WithRPCServerWorkers(5),
+	)
+	assert.True(t, so.queueDeclare.autoDelete)
+	assert.Equal(t, 10, so.qos.prefetchCount)
+	assert.True(t, so.isPersistent)
+	assert.Equal(t, 5, so.workers)
+
+	_, err := NewRPCServer("", &Connection{zapLog: zap.NewNop()})
+	assert.Error(t, err)
+}
+
+func TestRPCClient_Call(t *testing.T) {
+	s, err := NewRPCServer("rpc-queue", &Connection{zapLog: zap.NewNop()})
+	assert.NoError(t, err)
+	c, a := newLocalRPCClient(s, rpcHandler)
+
+	reply, err := c.Call(context.Background(), []byte("hello"))
+	assert.NoError(t, err)
+	assert.Equal(t, "HELLO", string(reply))
+
+	// the error of handler
+	_, err = c.Call(context.Background(), []byte("bad"))
+	var rpcErr *RPCError
+	assert.True(t, errors.As(err, &rpcErr))
+	assert.Equal(t, "invalid request", rpcErr.Message)
+
+	// the panic of handler
+	_, err = c.Call(context.Background(), []byte("panic"))
+	assert.True(t, errors.As(err, &rpcErr))
+	assert.Equal(t, "panic: oops", rpcErr.Message)
+
+	// timeout, the late reply is discarded
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	_, err = c.Call(ctx, []byte("slow"))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	time.Sleep(time.Millisecond * 150)
+	a.mu.Lock()
+	assert.Equal(t, 4, a.acks)
+	a.mu.Unlock()
+	assert.Equal(t, int64(4), s.Count())
+	c.mu.Lock()
+	assert.Empty(t, c.pending)
+	c.mu.Unlock()
+}
+
+func TestRPCClient_disconnect(t *testing.T) {
+	s, _ := NewRPCServer("rpc-queue", &Connection{zapLog: zap.NewNop()})
+	c, _ := newLocalRPCClient(s, func(ctx context.Context, data []byte) ([]byte, error) {
+		time.Sleep(time.Millisecond * 100)
+		return data, nil
+	})
+
+	// the calls in flight fail when the channel is closed
+	go func() {
+		time.Sleep(time.Millisecond * 20)
+		c.disconnect(ErrClosed)
+	}()
+	_, err := c.Call(context.Background(), []byte("hello"))
+	assert.ErrorIs(t, err, ErrClosed)
+
+	// wait for reconnection until the context is done
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	_, err = c.Call(ctx, []byte("hello"))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// closed
+	c.mu.Lock()
+	c.isClosed = true
+	c.mu.Unlock()
+	_, err = c.Call(context.Background(), []byte("hello"))
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestRPCServer_handleDelivery(t *testing.T) {
+	s, _ := NewRPCServer("rpc-queue", &Connection{zapLog: zap.NewNop()})
+	failedPublish := func(ctx context.Context, exchange string, key string, mandatory bool, immediate bool, msg amqp.Publishing) error {
+		return amqp.ErrClosed
+	}
+
+	// requeue the request if the reply is not published
+	a := &fakeAcknowledger{}
+	s.handleDelivery(context.Background(), amqp.Delivery{Acknowledger: a, ReplyTo: "reply"}, failedPublish, rpcHandler)
+	assert.Equal(t, 1, a.nacks)
+	assert.True(t, a.requeue)
+
+	// no reply_to, ack only
+	a = &fakeAcknowledger{}
+	s.handleDelivery(context.Background(), amqp.Delivery{Acknowledger: a}, failedPublish, rpcHandler)
+	assert.Equal(t, 1, a.acks)
+}
+
+func TestRPC(t *testing.T) {
+	utils.SafeRunWithTimeout(time.Second*3, func(cancel context.CancelFunc) {
+		defer cancel()
+		connection, err := NewConnection(url)
+		if err != nil {
+			t.Log(err)
+			return
+		}
+		defer connection.Close()
+
+		ctx, cancelServe := context.WithCancel(context.Background())
+		defer cancelServe()
+		queueName := "rpc-queue-demo"
+		s, err := NewRPCServer(queueName, connection, WithRPCServerWorkers(2))
+		if err != nil {
+			t.Log(err)
+			return
+		}
+		s.Serve(ctx, rpcHandler)
+
+		c, err := NewRPCClient(queueName, connection, WithRPCClientTimeout(time.Second))
+		if err != nil {
+			t.Log(err)
+			return
+		}
+		defer c.Close()
+
+		time.Sleep(time.Millisecond * 100)
+		reply, err := c.Call(ctx, []byte("hello"))
+		if err != nil {
+			t.Log(err)
+			return
+		}
+		t.Log(string(reply))
+	})
+}
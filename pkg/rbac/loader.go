@@ -0,0 +1,87 @@
+package rbac
+
+import (
+	"context"
+	"io"
+
+	"gorm.io/gorm"
+
+	"github.com/zhufuyi/sponge/pkg/conf"
+)
+
+// DefaultTableName the default table name of the policies
+const DefaultTableName = "rbac_policy"
+
+// File the policies file, yaml, json and toml are supported, e.g.
+//
+//	policies:
+//	  - role: admin
+//	    resource: "*"
+//	  - role: user
+//	    resource: /api/v1/user/:id
+//	    action: GET
+//	  - role: user
+//	    resource: user:read
+type File struct {
+	Policies []*Policy `json:"policies" yaml:"policies"`
+}
+
+// LoadFile load the policies from file, if isWatch is true, the policies are reloaded after the file changes,
+// call Close to stop watching.
+func (e *Enforcer) LoadFile(file string, isWatch bool) error {
+	opts := []conf.Option{conf.WithFile(file)}
+	if isWatch {
+		opts = append(opts, conf.WithWatch(func(newObj interface{}) {
+			e.SetPolicies(newObj.(*File).Policies)
+		}))
+	}
+
+	f := &File{}
+	c, err := conf.New(f, opts...)
+	if err != nil {
+		return err
+	}
+	e.SetPolicies(f.Policies)
+
+	if isWatch {
+		e.setWatcher(c)
+	} else {
+		e.setWatcher(nil)
+	}
+	return nil
+}
+
+// Close stop watching the policies file
+func (e *Enforcer) Close() error {
+	e.setWatcher(nil)
+	return nil
+}
+
+// replace the watcher of the policies file, the old one is closed
+func (e *Enforcer) setWatcher(w io.Closer) {
+	e.watchMu.Lock()
+	old := e.watcher
+	e.watcher = w
+	e.watchMu.Unlock()
+
+	if old != nil {
+		_ = old.Close()
+	}
+}
+
+// LoadDB load the policies from the database table, the table has columns role, resource and action,
+// default table name is rbac_policy. call it again to reload the policies after they are changed.
+func (e *Enforcer) LoadDB(ctx context.Context, db *gorm.DB, tableName ...string) error {
+	table := DefaultTableName
+	if len(tableName) > 0 && tableName[0] != "" {
+		table = tableName[0]
+	}
+
+	var policies []*Policy
+	err := db.WithContext(ctx).Table(table).Select("role", "resource", "action").Find(&policies).Error
+	if err != nil {
+		return err
+	}
+	e.SetPolicies(policies)
+	return nil
+}
@@ -0,0 +1,227 @@
+// Package rbac is role based access control, the policies map the roles to http routes,
+// grpc methods and permissions, they can be loaded from yaml file or database table.
+package rbac
+
+import (
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/zhufuyi/sponge/pkg/jwt"
+)
+
+// Policy allows the role to access the resource
+type Policy struct {
+	Role string `json:"role" yaml:"role" gorm:"column:role"`
+
+	// the resource can be:
+	//   http route, e.g. "/api/v1/user/:id", "/api/v1/user/{id}", "/api/v1/*"
+	//   grpc full method name, e.g. "/api.user.v1.user/GetByID", "/api.user.v1.user/*"
+	//   permission, e.g. "user:read", "user:*"
+	// "*" means all resources
+	Resource string `json:"resource" yaml:"resource" gorm:"column:resource"`
+
+	// http method of the route, e.g. "GET", empty or "*" means all methods, it is ignored for grpc and permission
+	Action string `json:"action" yaml:"action" gorm:"column:action"`
+}
+
+// Option set the enforcer options.
+type Option func(*options)
+
+type options struct {
+	isDenyByDefault bool
+	policies        []*Policy
+}
+
+func (o *options) apply(opts ...Option) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithDenyByDefault deny the resources that are not matched by any policy, by default they are allowed,
+// only the resources matched by the policies are protected.
+func WithDenyByDefault() Option {
+	return func(o *options) {
+		o.isDenyByDefault = true
+	}
+}
+
+// WithPolicies set the initial policies
+func WithPolicies(policies ...*Policy) Option {
+	return func(o *options) {
+		o.policies = append(o.policies, policies...)
+	}
+}
+
+// Enforcer decides whether the roles can access the resources, the policies can be replaced at runtime.
+type Enforcer struct {
+	isDenyByDefault bool
+
+	mu       sync.RWMutex
+	policies []*Policy
+
+	watchMu sync.Mutex
+	watcher io.Closer // watch the policies file
+}
+
+// NewEnforcer create an enforcer
+func NewEnforcer(opts ...Option) *Enforcer {
+	o := &options{}
+	o.apply(opts...)
+
+	e := &Enforcer{isDenyByDefault: o.isDenyByDefault}
+	e.SetPolicies(o.policies)
+	return e
+}
+
+// SetPolicies replace all the policies
+func (e *Enforcer) SetPolicies(policies []*Policy) {
+	ps := make([]*Policy, 0, len(policies))
+	for _, p := range policies {
+		if p == nil || p.Role == "" || p.Resource == "" {
+			continue
+		}
+		ps = append(ps, &Policy{
+			Role:     p.Role,
+			Resource: p.Resource,
+			Action:   strings.ToUpper(p.Action),
+		})
+	}
+
+	e.mu.Lock()
+	e.policies = ps
+	e.mu.Unlock()
+}
+
+// Policies get all the policies
+func (e *Enforcer) Policies() []*Policy {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return append([]*Policy{}, e.policies...)
+}
+
+// Enforce whether the roles can access the resource with the action, the action is the http method for
+// http routes, and empty for grpc methods.
+func (e *Enforcer) Enforce(roles []string, resource string, action string) bool {
+	action = strings.ToUpper(action)
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	isMatched := false
+	for _, p := range e.policies {
+		if !matchAction(p.Action, action) || !matchResource(p.Resource, resource) {
+			continue
+		}
+		if containsRole(roles, p.Role) {
+			return true
+		}
+		isMatched = true
+	}
+
+	// the resource is protected by other roles
+	if isMatched {
+		return false
+	}
+	return !e.isDenyByDefault
+}
+
+// HasPermissions whether the roles have all the permissions, the permissions are denied if no policy grants them.
+func (e *Enforcer) HasPermissions(roles []string, permissions ...string) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	for _, permission := range permissions {
+		isGranted := false
+		for _, p := range e.policies {
+			if containsRole(roles, p.Role) && matchResource(p.Resource, permission) {
+				isGranted = true
+				break
+			}
+		}
+		if !isGranted {
+			return false
+		}
+	}
+	return true
+}
+
+func containsRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAction(pattern string, action string) bool {
+	return pattern == "" || pattern == "*" || action == "" || pattern == action
+}
+
+// match the resource with the pattern, the pattern supports:
+//
+//	"*" all resources
+//	"/api/v1/*" the prefix
+//	"/api/v1/user/:id" or "/api/v1/user/{id}" a path segment
+func matchResource(pattern string, resource string) bool {
+	if pattern == "*" || pattern == resource {
+		return true
+	}
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(resource, pattern[:len(pattern)-1])
+	}
+	if !strings.Contains(pattern, ":") && !strings.Contains(pattern, "{") {
+		return false
+	}
+
+	ps, rs := strings.Split(pattern, "/"), strings.Split(resource, "/")
+	if len(ps) != len(rs) {
+		return false
+	}
+	for i, p := range ps {
+		if p == rs[i] {
+			continue
+		}
+		isParam := len(p) > 1 && (p[0] == ':' || (p[0] == '{' && p[len(p)-1] == '}'))
+		if !isParam || rs[i] == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// RolesFromClaims get the roles from the custom fields "roles" or "role" of the claims, e.g.
+// jwt.GenerateCustomToken(jwt.KV{"uid": "1", "roles": []string{"admin"}})
+func RolesFromClaims(claims *jwt.CustomClaims) []string {
+	if claims == nil {
+		return nil
+	}
+
+	var roles []string
+	if v, ok := claims.Get("roles"); ok {
+		switch vs := v.(type) {
+		case []string:
+			roles = append(roles, vs...)
+		case []interface{}:
+			for _, r := range vs {
+				if s, isStr := r.(string); isStr {
+					roles = append(roles, s)
+				}
+			}
+		case string:
+			for _, r := range strings.Split(vs, ",") {
+				if r = strings.TrimSpace(r); r != "" {
+					roles = append(roles, r)
+				}
+			}
+		}
+	}
+	if v, ok := claims.Get("role"); ok {
+		if s, isStr := v.(string); isStr && s != "" {
+			roles = append(roles, s)
+		}
+	}
+	return roles
+}
@@ -0,0 +1,123 @@
+package rbac
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/zhufuyi/sponge/pkg/jwt"
+)
+
+var testPolicies = []*Policy{
+	{Role: "admin", Resource: "*"},
+	{Role: "user", Resource: "/api/v1/user/:id", Action: "get"},
+	{Role: "user", Resource: "/api/v1/order/*", Action: "*"},
+	{Role: "user", Resource: "/api.user.v1.user/GetByID"},
+	{Role: "user", Resource: "user:read"},
+	{Role: "editor", Resource: "user:*"},
+	{Role: "", Resource: "ignored"},
+}
+
+func TestEnforcer(t *testing.T) {
+	e := NewEnforcer(WithPolicies(testPolicies...))
+	assert.Len(t, e.Policies(), 6)
+
+	assert.True(t, e.Enforce([]string{"admin"}, "/api/v1/user/1", "DELETE"))
+	assert.True(t, e.Enforce([]string{"user"}, "/api/v1/user/1", "GET"))
+	assert.False(t, e.Enforce([]string{"user"}, "/api/v1/user/1", "DELETE"))
+	assert.False(t, e.Enforce([]string{"user"}, "/api/v1/user/", "GET"))
+	assert.True(t, e.Enforce([]string{"guest", "user"}, "/api/v1/order/1/items", "POST"))
+	assert.True(t, e.Enforce([]string{"user"}, "/api.user.v1.user/GetByID", ""))
+	assert.False(t, e.Enforce([]string{"user"}, "/api.user.v1.user/DeleteByID", ""))
+	assert.False(t, e.Enforce(nil, "/api/v1/order/1", "GET"))
+
+	// the resource that is not matched by any policy
+	e = NewEnforcer(WithPolicies(&Policy{Role: "user", Resource: "/api/v1/user/{id}", Action: "GET"}))
+	assert.True(t, e.Enforce([]string{"user"}, "/api/v1/user/1", "GET"))
+	assert.True(t, e.Enforce(nil, "/api/v1/health", "GET"))
+	e = NewEnforcer(WithDenyByDefault(), WithPolicies(&Policy{Role: "user", Resource: "/api/v1/user/{id}", Action: "GET"}))
+	assert.False(t, e.Enforce(nil, "/api/v1/health", "GET"))
+}
+
+func TestEnforcer_HasPermissions(t *testing.T) {
+	e := NewEnforcer(WithPolicies(testPolicies...))
+	assert.True(t, e.HasPermissions([]string{"user"}, "user:read"))
+	assert.False(t, e.HasPermissions([]string{"user"}, "user:read", "user:write"))
+	assert.True(t, e.HasPermissions([]string{"editor"}, "user:read", "user:write"))
+	assert.True(t, e.HasPermissions([]string{"admin"}, "order:delete"))
+	assert.False(t, e.HasPermissions(nil, "order:read"))
+	assert.True(t, e.HasPermissions(nil))
+}
+
+func TestRolesFromClaims(t *testing.T) {
+	assert.Nil(t, RolesFromClaims(nil))
+	claims := &jwt.CustomClaims{Fields: jwt.KV{"roles": []interface{}{"admin", "user", 1}, "role": "editor"}}
+	assert.Equal(t, []string{"admin", "user", "editor"}, RolesFromClaims(claims))
+	claims = &jwt.CustomClaims{Fields: jwt.KV{"roles": "admin, user"}}
+	assert.Equal(t, []string{"admin", "user"}, RolesFromClaims(claims))
+	claims = &jwt.CustomClaims{Fields: jwt.KV{"roles": []string{"admin"}}}
+	assert.Equal(t, []string{"admin"}, RolesFromClaims(claims))
+}
+
+func TestEnforcer_LoadFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "rbac.yml")
+	_ = os.WriteFile(file, []byte(`policies:
+  - role: user
+    resource: /api/v1/user/:id
+    action: GET
+`), 0o600)
+
+	e := NewEnforcer(WithDenyByDefault())
+	assert.NoError(t, e.LoadFile(file, true))
+	defer e.Close() //nolint
+	assert.True(t, e.Enforce([]string{"user"}, "/api/v1/user/1", "GET"))
+	assert.False(t, e.Enforce([]string{"user"}, "/api/v1/order/1", "GET"))
+
+	// hot reload
+	time.Sleep(time.Millisecond * 100)
+	_ = os.WriteFile(file, []byte(`policies:
+  - role: user
+    resource: /api/v1/order/*
+`), 0o600)
+	for i := 0; i < 100 && !e.Enforce([]string{"user"}, "/api/v1/order/1", "GET"); i++ {
+		time.Sleep(time.Millisecond * 20)
+	}
+	assert.True(t, e.Enforce([]string{"user"}, "/api/v1/order/1", "GET"))
+	assert.False(t, e.Enforce([]string{"user"}, "/api/v1/user/1", "GET"))
+
+	// stop watching
+	assert.NoError(t, e.Close())
+	time.Sleep(time.Millisecond * 100)
+	_ = os.WriteFile(file, []byte(`policies:
+  - role: user
+    resource: "*"
+`), 0o600)
+	time.Sleep(time.Millisecond * 300)
+	assert.False(t, e.Enforce([]string{"user"}, "/api/v1/user/1", "GET"))
+
+	assert.Error(t, e.LoadFile("not-found.yml", false))
+}
+
+func TestEnforcer_LoadDB(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "rbac.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Exec("CREATE TABLE rbac_policy (id INTEGER PRIMARY KEY, role TEXT, resource TEXT, action TEXT)").Error
+	assert.NoError(t, err)
+	err = db.Exec("INSERT INTO rbac_policy (role, resource, action) VALUES ('user', '/api/v1/user/:id', 'GET'), ('user', 'user:read', '')").Error
+	assert.NoError(t, err)
+
+	e := NewEnforcer(WithDenyByDefault())
+	assert.NoError(t, e.LoadDB(context.Background(), db))
+	assert.True(t, e.Enforce([]string{"user"}, "/api/v1/user/1", "GET"))
+	assert.True(t, e.HasPermissions([]string{"user"}, "user:read"))
+
+	assert.Error(t, e.LoadDB(context.Background(), db, "not_found"))
+}
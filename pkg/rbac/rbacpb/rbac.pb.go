@@ -0,0 +1,179 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.2
+// 	protoc        v4.25.2
+// source: rbac/rbac.proto
+
+package rbacpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	descriptorpb "google.golang.org/protobuf/types/descriptorpb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Permission the permissions required to call the method
+type Permission struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// all of the permissions are required, e.g. "user:read"
+	Required []string `protobuf:"bytes,1,rep,name=required,proto3" json:"required,omitempty"`
+}
+
+func (x *Permission) Reset() {
+	*x = Permission{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rbac_rbac_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Permission) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Permission) ProtoMessage() {}
+
+func (x *Permission) ProtoReflect() protoreflect.Message {
+	mi := &file_rbac_rbac_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Permission.ProtoReflect.Descriptor instead.
+func (*Permission) Descriptor() ([]byte, []int) {
+	return file_rbac_rbac_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Permission) GetRequired() []string {
+	if x != nil {
+		return x.Required
+	}
+	return nil
+}
+
+var file_rbac_rbac_proto_extTypes = []protoimpl.ExtensionInfo{
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: (*Permission)(nil),
+		Field:         50301,
+		Name:          "rbac.permission",
+		Tag:           "bytes,50301,opt,name=permission",
+		Filename:      "rbac/rbac.proto",
+	},
+}
+
+// Extension fields to descriptorpb.MethodOptions.
+var (
+	// the permissions required to call the method, the routes generated by protoc-gen-go-gin check them.
+	// e.g. option (rbac.permission) = {required: ["user:read"]};
+	//
+	// optional rbac.Permission permission = 50301;
+	E_Permission = &file_rbac_rbac_proto_extTypes[0]
+)
+
+var File_rbac_rbac_proto protoreflect.FileDescriptor
+
+var file_rbac_rbac_proto_rawDesc = []byte{
+	0x0a, 0x0f, 0x72, 0x62, 0x61, 0x63, 0x2f, 0x72, 0x62, 0x61, 0x63, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x12, 0x04, 0x72, 0x62, 0x61, 0x63, 0x1a, 0x20, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70,
+	0x74, 0x6f, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x28, 0x0a, 0x0a, 0x50, 0x65, 0x72,
+	0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x1a, 0x0a, 0x08, 0x72, 0x65, 0x71, 0x75, 0x69,
+	0x72, 0x65, 0x64, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x08, 0x72, 0x65, 0x71, 0x75, 0x69,
+	0x72, 0x65, 0x64, 0x3a, 0x52, 0x0a, 0x0a, 0x70, 0x65, 0x72, 0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f,
+	0x6e, 0x12, 0x1e, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x62, 0x75, 0x66, 0x2e, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e,
+	0x73, 0x18, 0xfd, 0x88, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x72, 0x62, 0x61, 0x63,
+	0x2e, 0x50, 0x65, 0x72, 0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x0a, 0x70, 0x65, 0x72,
+	0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x42, 0x32, 0x5a, 0x30, 0x67, 0x69, 0x74, 0x68, 0x75,
+	0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x7a, 0x68, 0x75, 0x66, 0x75, 0x79, 0x69, 0x2f, 0x73, 0x70,
+	0x6f, 0x6e, 0x67, 0x65, 0x2f, 0x70, 0x6b, 0x67, 0x2f, 0x72, 0x62, 0x61, 0x63, 0x2f, 0x72, 0x62,
+	0x61, 0x63, 0x70, 0x62, 0x3b, 0x72, 0x62, 0x61, 0x63, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x33,
+}
+
+var (
+	file_rbac_rbac_proto_rawDescOnce sync.Once
+	file_rbac_rbac_proto_rawDescData = file_rbac_rbac_proto_rawDesc
+)
+
+func file_rbac_rbac_proto_rawDescGZIP() []byte {
+	file_rbac_rbac_proto_rawDescOnce.Do(func() {
+		file_rbac_rbac_proto_rawDescData = protoimpl.X.CompressGZIP(file_rbac_rbac_proto_rawDescData)
+	})
+	return file_rbac_rbac_proto_rawDescData
+}
+
+var file_rbac_rbac_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_rbac_rbac_proto_goTypes = []any{
+	(*Permission)(nil),                 // 0: rbac.Permission
+	(*descriptorpb.MethodOptions)(nil), // 1: google.protobuf.MethodOptions
+}
+var file_rbac_rbac_proto_depIdxs = []int32{
+	1, // 0: rbac.permission:extendee -> google.protobuf.MethodOptions
+	0, // 1: rbac.permission:type_name -> rbac.Permission
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	1, // [1:2] is the sub-list for extension type_name
+	0, // [0:1] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_rbac_rbac_proto_init() }
+func file_rbac_rbac_proto_init() {
+	if File_rbac_rbac_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_rbac_rbac_proto_msgTypes[0].Exporter = func(v any, i int) any {
+			switch v := v.(*Permission); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_rbac_rbac_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 1,
+			NumServices:   0,
+		},
+		GoTypes:           file_rbac_rbac_proto_goTypes,
+		DependencyIndexes: file_rbac_rbac_proto_depIdxs,
+		MessageInfos:      file_rbac_rbac_proto_msgTypes,
+		ExtensionInfos:    file_rbac_rbac_proto_extTypes,
+	}.Build()
+	File_rbac_rbac_proto = out.File
+	file_rbac_rbac_proto_rawDesc = nil
+	file_rbac_rbac_proto_goTypes = nil
+	file_rbac_rbac_proto_depIdxs = nil
+}
@@ -0,0 +1,73 @@
+package signature
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// NonceStore stores the used nonces until they expire
+type NonceStore interface {
+	// Add the nonce until expiration, return false if the nonce exists
+	Add(ctx context.Context, nonce string, expiration time.Duration) (bool, error)
+}
+
+// MemoryNonceStore nonce store in memory, used in single instance
+type MemoryNonceStore struct {
+	mu          sync.Mutex
+	nonces      map[string]time.Time // nonce and expiration time
+	lastCleanup time.Time
+}
+
+// NewMemoryNonceStore create a memory nonce store
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{
+		nonces:      make(map[string]time.Time),
+		lastCleanup: time.Now(),
+	}
+}
+
+// Add the nonce until expiration
+func (s *MemoryNonceStore) Add(_ context.Context, nonce string, expiration time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastCleanup) > time.Minute {
+		for k, expiredAt := range s.nonces {
+			if now.After(expiredAt) {
+				delete(s.nonces, k)
+			}
+		}
+		s.lastCleanup = now
+	}
+
+	if expiredAt, ok := s.nonces[nonce]; ok && now.Before(expiredAt) {
+		return false, nil
+	}
+	s.nonces[nonce] = now.Add(expiration)
+	return true, nil
+}
+
+// RedisNonceStore nonce store based on redis, used in multiple instances,
+// the client can be created by pkg/goredis.
+type RedisNonceStore struct {
+	client    redis.UniversalClient
+	keyPrefix string
+}
+
+// NewRedisNonceStore create a redis nonce store, the default key prefix is "signature:nonce:"
+func NewRedisNonceStore(client redis.UniversalClient, keyPrefix ...string) *RedisNonceStore {
+	prefix := "signature:nonce:"
+	if len(keyPrefix) > 0 && keyPrefix[0] != "" {
+		prefix = keyPrefix[0]
+	}
+	return &RedisNonceStore{client: client, keyPrefix: prefix}
+}
+
+// Add the nonce until expiration
+func (s *RedisNonceStore) Add(ctx context.Context, nonce string, expiration time.Duration) (bool, error) {
+	return s.client.SetNX(ctx, s.keyPrefix+nonce, 1, expiration).Result()
+}
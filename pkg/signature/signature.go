@@ -0,0 +1,266 @@
+// Package signature is HMAC request signature authentication for server-to-server callers,
+// the canonical string of the request is signed by the secret of the app id, the timestamp
+// and nonce prevent the request from being replayed.
+package signature
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zhufuyi/sponge/pkg/krand"
+)
+
+// the headers of the signature, they are lowercase in grpc metadata
+const (
+	HeaderAppID     = "X-App-Id"
+	HeaderTimestamp = "X-Timestamp"
+	HeaderNonce     = "X-Nonce"
+	HeaderSignature = "X-Signature"
+)
+
+var (
+	// ErrMissingSignature the signature headers are missing
+	ErrMissingSignature = errors.New("missing signature")
+	// ErrInvalidAppID the app id is not found
+	ErrInvalidAppID = errors.New("invalid app id")
+	// ErrTimestampExpired the timestamp is out of the allowed time window
+	ErrTimestampExpired = errors.New("timestamp expired")
+	// ErrInvalidSignature the signature does not match
+	ErrInvalidSignature = errors.New("invalid signature")
+	// ErrNonceReused the nonce has been used, the request is replayed
+	ErrNonceReused = errors.New("nonce has been used")
+)
+
+// Credential the signature of the request
+type Credential struct {
+	AppID     string
+	Timestamp string
+	Nonce     string
+	Signature string
+}
+
+// Headers convert the credential to request headers
+func (c *Credential) Headers() map[string]string {
+	return map[string]string{
+		HeaderAppID:     c.AppID,
+		HeaderTimestamp: c.Timestamp,
+		HeaderNonce:     c.Nonce,
+		HeaderSignature: c.Signature,
+	}
+}
+
+// CredentialFromHeader get the credential from request headers
+func CredentialFromHeader(h http.Header) *Credential {
+	return &Credential{
+		AppID:     h.Get(HeaderAppID),
+		Timestamp: h.Get(HeaderTimestamp),
+		Nonce:     h.Get(HeaderNonce),
+		Signature: h.Get(HeaderSignature),
+	}
+}
+
+// CanonicalString the string to sign, each line is:
+//
+//	upper case http method
+//	escaped path, the full method name for grpc
+//	query sorted by key and value, e.g. a=1&a=2&b=3
+//	hex sha256 of the body
+//	unix timestamp in seconds
+//	nonce
+func CanonicalString(method string, path string, query url.Values, body []byte, timestamp string, nonce string) string {
+	bodyHash := sha256.Sum256(body)
+	return strings.Join([]string{
+		strings.ToUpper(method),
+		path,
+		canonicalQuery(query),
+		hex.EncodeToString(bodyHash[:]),
+		timestamp,
+		nonce,
+	}, "\n")
+}
+
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, k := range keys {
+		values := append([]string{}, query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			pairs = append(pairs, url.QueryEscape(k)+"="+url.QueryEscape(v))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// Sign the canonical string by HMAC-SHA256, return hex string
+func Sign(secret string, canonical string) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(canonical))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ------------------------------------------------------------------------------------------
+
+// Signer sign the requests by app id and secret, used in client side
+type Signer struct {
+	appID  string
+	secret string
+}
+
+// NewSigner create a signer
+func NewSigner(appID string, secret string) *Signer {
+	return &Signer{appID: appID, secret: secret}
+}
+
+// Sign the request, return the credential
+func (s *Signer) Sign(method string, path string, query url.Values, body []byte) *Credential {
+	c := &Credential{
+		AppID:     s.appID,
+		Timestamp: strconv.FormatInt(time.Now().Unix(), 10),
+		Nonce:     krand.String(krand.R_All, 16),
+	}
+	c.Signature = Sign(s.secret, CanonicalString(method, path, query, body, c.Timestamp, c.Nonce))
+	return c
+}
+
+// SignRequest sign the http request and set the signature headers, body is the request body
+func (s *Signer) SignRequest(req *http.Request, body []byte) error {
+	if req == nil || req.URL == nil {
+		return errors.New("request is nil")
+	}
+	c := s.Sign(req.Method, req.URL.EscapedPath(), req.URL.Query(), body)
+	for k, v := range c.Headers() {
+		req.Header.Set(k, v)
+	}
+	return nil
+}
+
+// ------------------------------------------------------------------------------------------
+
+// SecretGetter get the secret of the app id, return ErrInvalidAppID if not found
+type SecretGetter func(ctx context.Context, appID string) (string, error)
+
+// StaticSecrets secrets of the app ids in memory
+func StaticSecrets(secrets map[string]string) SecretGetter {
+	return func(_ context.Context, appID string) (string, error) {
+		secret, ok := secrets[appID]
+		if !ok {
+			return "", ErrInvalidAppID
+		}
+		return secret, nil
+	}
+}
+
+type verifierOptions struct {
+	maxSkew    time.Duration
+	nonceStore NonceStore
+}
+
+func defaultVerifierOptions() *verifierOptions {
+	return &verifierOptions{
+		maxSkew: time.Minute * 5,
+	}
+}
+
+// VerifierOption set the verifier options.
+type VerifierOption func(*verifierOptions)
+
+func (o *verifierOptions) apply(opts ...VerifierOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithMaxSkew set the max difference between the timestamp of the request and the server time, default 5 minutes
+func WithMaxSkew(d time.Duration) VerifierOption {
+	return func(o *verifierOptions) {
+		if d > 0 {
+			o.maxSkew = d
+		}
+	}
+}
+
+// WithNonceStore set the nonce store, default is memory store, use redis store in multiple instances
+func WithNonceStore(store NonceStore) VerifierOption {
+	return func(o *verifierOptions) {
+		if store != nil {
+			o.nonceStore = store
+		}
+	}
+}
+
+// Verifier verify the signature of the requests, used in server side
+type Verifier struct {
+	getSecret  SecretGetter
+	maxSkew    time.Duration
+	nonceStore NonceStore
+}
+
+// NewVerifier create a verifier
+func NewVerifier(getSecret SecretGetter, opts ...VerifierOption) *Verifier {
+	o := defaultVerifierOptions()
+	o.apply(opts...)
+	if o.nonceStore == nil {
+		o.nonceStore = NewMemoryNonceStore()
+	}
+
+	return &Verifier{
+		getSecret:  getSecret,
+		maxSkew:    o.maxSkew,
+		nonceStore: o.nonceStore,
+	}
+}
+
+// Verify the signature of the request, the nonce can only be used once within the time window
+func (v *Verifier) Verify(ctx context.Context, c *Credential, method string, path string, query url.Values, body []byte) error {
+	if c == nil || c.AppID == "" || c.Timestamp == "" || c.Nonce == "" || c.Signature == "" {
+		return ErrMissingSignature
+	}
+
+	ts, err := strconv.ParseInt(c.Timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w, invalid timestamp %s", ErrTimestampExpired, c.Timestamp)
+	}
+	if d := time.Since(time.Unix(ts, 0)); d > v.maxSkew || d < -v.maxSkew {
+		return ErrTimestampExpired
+	}
+
+	secret, err := v.getSecret(ctx, c.AppID)
+	if err != nil {
+		return err
+	}
+	expected := Sign(secret, CanonicalString(method, path, query, body, c.Timestamp, c.Nonce))
+	if !hmac.Equal([]byte(expected), []byte(c.Signature)) {
+		return ErrInvalidSignature
+	}
+
+	// the requests out of the time window are rejected by timestamp, so the nonce only needs to be kept within it
+	ok, err := v.nonceStore.Add(ctx, c.AppID+":"+c.Nonce, v.maxSkew*2)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNonceReused
+	}
+	return nil
+}
+
+// VerifyRequest verify the signature of the http request, body is the request body
+func (v *Verifier) VerifyRequest(req *http.Request, body []byte) error {
+	return v.Verify(req.Context(), CredentialFromHeader(req.Header), req.Method, req.URL.EscapedPath(), req.URL.Query(), body)
+}
@@ -0,0 +1,124 @@
+package signature
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalString(t *testing.T) {
+	query := url.Values{"b": {"3"}, "a": {"2", "1"}, "c d": {"x&y"}}
+	s := CanonicalString("post", "/api/v1/user", query, []byte(`{"name":"foo"}`), "1700000000", "abc")
+	lines := bytes.Split([]byte(s), []byte("\n"))
+	assert.Len(t, lines, 6)
+	assert.Equal(t, "POST", string(lines[0]))
+	assert.Equal(t, "a=1&a=2&b=3&c+d=x%26y", string(lines[2]))
+	assert.Len(t, lines[3], 64)
+	assert.Equal(t, s, CanonicalString("POST", "/api/v1/user", url.Values{"c d": {"x&y"}, "a": {"1", "2"}, "b": {"3"}},
+		[]byte(`{"name":"foo"}`), "1700000000", "abc"))
+
+	assert.Equal(t, Sign("secret", s), Sign("secret", s))
+	assert.NotEqual(t, Sign("secret", s), Sign("secret2", s))
+}
+
+func testVerify(t *testing.T, store NonceStore) {
+	secrets := StaticSecrets(map[string]string{"app1": "secret1"})
+	v := NewVerifier(secrets, WithNonceStore(store), WithMaxSkew(time.Minute))
+	s := NewSigner("app1", "secret1")
+	ctx := context.Background()
+	query := url.Values{"id": {"1"}}
+	body := []byte(`{"name":"foo"}`)
+
+	c := s.Sign(http.MethodPost, "/api/v1/user", query, body)
+	assert.NoError(t, v.Verify(ctx, c, http.MethodPost, "/api/v1/user", query, body))
+
+	// replay
+	err := v.Verify(ctx, c, http.MethodPost, "/api/v1/user", query, body)
+	assert.True(t, errors.Is(err, ErrNonceReused))
+
+	// tampered
+	c = s.Sign(http.MethodPost, "/api/v1/user", query, body)
+	err = v.Verify(ctx, c, http.MethodPost, "/api/v1/user", query, []byte(`{"name":"bar"}`))
+	assert.True(t, errors.Is(err, ErrInvalidSignature))
+	err = v.Verify(ctx, c, http.MethodPost, "/api/v1/user", url.Values{"id": {"2"}}, body)
+	assert.True(t, errors.Is(err, ErrInvalidSignature))
+
+	// wrong secret
+	c = NewSigner("app1", "secret2").Sign(http.MethodGet, "/api/v1/user", nil, nil)
+	err = v.Verify(ctx, c, http.MethodGet, "/api/v1/user", nil, nil)
+	assert.True(t, errors.Is(err, ErrInvalidSignature))
+
+	// unknown app id
+	c = NewSigner("app2", "secret1").Sign(http.MethodGet, "/api/v1/user", nil, nil)
+	err = v.Verify(ctx, c, http.MethodGet, "/api/v1/user", nil, nil)
+	assert.True(t, errors.Is(err, ErrInvalidAppID))
+
+	// expired
+	c = s.Sign(http.MethodGet, "/api/v1/user", nil, nil)
+	c.Timestamp = strconv.FormatInt(time.Now().Add(-time.Minute*2).Unix(), 10)
+	err = v.Verify(ctx, c, http.MethodGet, "/api/v1/user", nil, nil)
+	assert.True(t, errors.Is(err, ErrTimestampExpired))
+	c.Timestamp = "abc"
+	err = v.Verify(ctx, c, http.MethodGet, "/api/v1/user", nil, nil)
+	assert.True(t, errors.Is(err, ErrTimestampExpired))
+
+	// missing
+	err = v.Verify(ctx, &Credential{AppID: "app1"}, http.MethodGet, "/api/v1/user", nil, nil)
+	assert.True(t, errors.Is(err, ErrMissingSignature))
+	err = v.Verify(ctx, nil, http.MethodGet, "/api/v1/user", nil, nil)
+	assert.True(t, errors.Is(err, ErrMissingSignature))
+}
+
+func TestVerify(t *testing.T) {
+	testVerify(t, NewMemoryNonceStore())
+}
+
+func TestVerify_Redis(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	testVerify(t, NewRedisNonceStore(client))
+}
+
+func TestSignRequest(t *testing.T) {
+	v := NewVerifier(StaticSecrets(map[string]string{"app1": "secret1"}))
+	s := NewSigner("app1", "secret1")
+
+	body := []byte(`{"name":"foo"}`)
+	req, _ := http.NewRequest(http.MethodPut, "http://localhost:8080/api/v1/user/1?b=2&a=1", bytes.NewReader(body))
+	assert.NoError(t, s.SignRequest(req, body))
+	assert.NotEmpty(t, req.Header.Get(HeaderSignature))
+	assert.NoError(t, v.VerifyRequest(req, body))
+	assert.Error(t, v.VerifyRequest(req, body))
+
+	assert.Error(t, s.SignRequest(nil, nil))
+}
+
+func TestMemoryNonceStore(t *testing.T) {
+	s := NewMemoryNonceStore()
+	ctx := context.Background()
+	ok, _ := s.Add(ctx, "n1", time.Millisecond*50)
+	assert.True(t, ok)
+	ok, _ = s.Add(ctx, "n1", time.Millisecond*50)
+	assert.False(t, ok)
+
+	time.Sleep(time.Millisecond * 100)
+	s.lastCleanup = time.Now().Add(-time.Minute * 2)
+	ok, _ = s.Add(ctx, "n1", time.Millisecond*50)
+	assert.True(t, ok)
+	assert.Len(t, s.nonces, 1)
+}
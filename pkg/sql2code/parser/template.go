@@ -121,6 +121,9 @@ service {{.TName}} {
 
   // list {{.TName}} by last id
   rpc ListByLastID(List{{.TableName}}ByLastIDRequest) returns (List{{.TableName}}ByLastIDReply) {}
+
+  // list {{.TName}} by cursor
+  rpc ListByCursor(List{{.TableName}}ByCursorRequest) returns (List{{.TableName}}ByCursorReply) {}
 }
 
 // Some notes on defining fields under message:
@@ -198,6 +201,19 @@ message List{{.TableName}}ByLastIDRequest {
 message List{{.TableName}}ByLastIDReply {
   repeated {{.TableName}} {{.TName}}s = 1;
 }
+
+message List{{.TableName}}ByCursorRequest {
+  string cursor = 1; // cursor returned by the previous query, empty means the first page
+  uint32 limit = 2 [(validate.rules).uint32.gt = 0]; // limit size per page
+  string sort = 3; // sort by column name of table, default is -id, must be the same as the sort fields of the cursor
+  repeated api.types.Column columns = 4; // query conditions
+}
+
+message List{{.TableName}}ByCursorReply {
+  repeated {{.TableName}} {{.TName}}s = 1;
+  string nextCursor = 2; // cursor of the next page, empty means there is no next page
+  string prevCursor = 3; // cursor of the previous page, empty means there is no previous page
+}
 `
 
 	protoFileSimpleTmpl    *template.Template
@@ -469,6 +485,24 @@ service {{.TName}} {
       //}
     };
   }
+
+  // list {{.TName}} by cursor
+  rpc ListByCursor(List{{.TableName}}ByCursorRequest) returns (List{{.TableName}}ByCursorReply) {
+    option (google.api.http) = {
+      post: "/api/v1/{{.TName}}/list/cursor"
+      body: "*"
+    };
+    option (grpc.gateway.protoc_gen_openapiv2.options.openapiv2_operation) = {
+      summary: "list of {{.TName}} by cursor",
+      description: "list of {{.TName}} by cursor, the cursor is empty when querying the first page, query the next or previous page with the returned nextCursor or prevCursor",
+      //security: {
+      //  security_requirement: {
+      //    key: "BearerAuth";
+      //    value: {}
+      //  }
+      //}
+    };
+  }
 }
 
 
@@ -559,6 +593,19 @@ message List{{.TableName}}ByLastIDRequest {
 message List{{.TableName}}ByLastIDReply {
   repeated {{.TableName}} {{.TName}}s = 1;
 }
+
+message List{{.TableName}}ByCursorRequest {
+  string cursor = 1; // cursor returned by the previous query, empty means the first page
+  uint32 limit = 2 [(validate.rules).uint32.gt = 0]; // limit size per page
+  string sort = 3; // sort by column name of table, default is -id, must be the same as the sort fields of the cursor
+  repeated api.types.Column columns = 4; // query conditions
+}
+
+message List{{.TableName}}ByCursorReply {
+  repeated {{.TableName}} {{.TName}}s = 1;
+  string nextCursor = 2; // cursor of the next page, empty means there is no next page
+  string prevCursor = 3; // cursor of the previous page, empty means there is no previous page
+}
 `
 
 	protoFileForSimpleWebTmpl    *template.Template
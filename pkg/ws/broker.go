@@ -0,0 +1,117 @@
+package ws
+
+import (
+	"context"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Broker fans out the messages broadcast by the hub to the hubs of all instances.
+type Broker interface {
+	// Publish the message to all subscribers, including the publisher itself
+	Publish(ctx context.Context, data []byte) error
+	// Subscribe returns after the subscription is established, fn is called
+	// for each message received in background until ctx is done.
+	Subscribe(ctx context.Context, fn func(data []byte)) error
+}
+
+// DefaultBrokerChannel default channel of the redis broker
+const DefaultBrokerChannel = "ws:broadcast"
+
+// RedisBroker broker based on redis pub/sub, the client can be created by pkg/goredis.
+type RedisBroker struct {
+	client  redis.UniversalClient
+	channel string
+}
+
+// NewRedisBroker creates a redis broker, the hubs of all instances must use the same channel,
+// if channel is empty, DefaultBrokerChannel is used.
+func NewRedisBroker(client redis.UniversalClient, channel string) *RedisBroker {
+	if channel == "" {
+		channel = DefaultBrokerChannel
+	}
+	return &RedisBroker{
+		client:  client,
+		channel: channel,
+	}
+}
+
+// Publish the message to the channel
+func (b *RedisBroker) Publish(ctx context.Context, data []byte) error {
+	return b.client.Publish(ctx, b.channel, data).Err()
+}
+
+// Subscribe the channel, the subscription is restored automatically after the connection is broken,
+// the messages published during disconnection are lost.
+func (b *RedisBroker) Subscribe(ctx context.Context, fn func(data []byte)) error {
+	pubsub := b.client.Subscribe(ctx, b.channel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		_ = pubsub.Close()
+		return err
+	}
+
+	ch := pubsub.Channel()
+	go func() {
+		defer pubsub.Close() //nolint
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+				fn([]byte(msg.Payload))
+			}
+		}
+	}()
+	return nil
+}
+
+// MemoryBroker in-memory broker, the hubs in the same process share it, used for testing.
+type MemoryBroker struct {
+	mu          sync.RWMutex
+	seq         int
+	subscribers map[int]func(data []byte)
+}
+
+// NewMemoryBroker creates an in-memory broker
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		subscribers: make(map[int]func(data []byte)),
+	}
+}
+
+// Publish the message to the subscribers synchronously
+func (b *MemoryBroker) Publish(ctx context.Context, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, fn := range b.subscribers {
+		fn(data)
+	}
+	return nil
+}
+
+// Subscribe the messages until ctx is done
+func (b *MemoryBroker) Subscribe(ctx context.Context, fn func(data []byte)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	b.seq++
+	id := b.seq
+	b.subscribers[id] = fn
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		delete(b.subscribers, id)
+		b.mu.Unlock()
+	}()
+	return nil
+}
@@ -0,0 +1,102 @@
+package ws
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func testBroker(t *testing.T, broker1 Broker, broker2 Broker) {
+	auth := WithHubAuth(func(r *http.Request) (string, error) {
+		return r.Header.Get("X-User-Id"), nil
+	})
+	hub1, server1 := newTestHub(WithHubBroker(broker1), auth)
+	defer server1.Close()
+	defer hub1.Close()
+	hub2, server2 := newTestHub(WithHubBroker(broker2), auth)
+	defer server2.Close()
+	defer hub2.Close()
+
+	c1 := dial(t, server1, "/", http.Header{"X-User-Id": []string{"u1"}})
+	defer c1.Close()
+	c2 := dial(t, server2, "/", http.Header{"X-User-Id": []string{"u2"}})
+	defer c2.Close()
+	writeMessage(t, c1, "join", "room1")
+	assert.Equal(t, "joined", readMessage(t, c1).Type)
+	writeMessage(t, c2, "join", "room1")
+	assert.Equal(t, "joined", readMessage(t, c2).Type)
+
+	// the message is delivered to the sessions of both instances, only once
+	data, _ := NewMessage("notice", "room message")
+	hub2.BroadcastToRoom("room1", data)
+	assert.Equal(t, "notice", readMessage(t, c1).Type)
+	assert.Equal(t, "notice", readMessage(t, c2).Type)
+	noMessage(t, c2)
+
+	data, _ = NewMessage("notice", "to u1")
+	hub2.SendToUser("u1", data)
+	msg := readMessage(t, c1)
+	assert.Equal(t, `"to u1"`, string(msg.Data))
+	noMessage(t, c2)
+
+	data, _ = NewMessage("notice", "all")
+	hub1.Broadcast(data)
+	assert.Equal(t, "notice", readMessage(t, c1).Type)
+	assert.Equal(t, "notice", readMessage(t, c2).Type)
+	noMessage(t, c1)
+}
+
+func TestMemoryBroker(t *testing.T) {
+	broker := NewMemoryBroker()
+	testBroker(t, broker, broker)
+
+	// unsubscribed when ctx is done
+	ctx, cancel := context.WithCancel(context.Background())
+	var count int32
+	err := broker.Subscribe(ctx, func(data []byte) { atomic.AddInt32(&count, 1) })
+	assert.NoError(t, err)
+	assert.NoError(t, broker.Publish(context.Background(), []byte("1")))
+	cancel()
+	time.Sleep(time.Millisecond * 10)
+	assert.NoError(t, broker.Publish(context.Background(), []byte("2")))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+	assert.Error(t, broker.Subscribe(ctx, func(data []byte) {}))
+	assert.Error(t, broker.Publish(ctx, []byte("3")))
+}
+
+func TestRedisBroker(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	client1 := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client1.Close()
+	client2 := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client2.Close()
+
+	testBroker(t, NewRedisBroker(client1, ""), NewRedisBroker(client2, ""))
+}
+
+func TestRedisBroker_SubscribeError(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	defer client.Close()
+
+	broker := NewRedisBroker(client, "test")
+	err := broker.Subscribe(context.Background(), func(data []byte) {})
+	assert.Error(t, err)
+
+	// the hub works locally and retries subscribing in background
+	hub := NewHub(WithHubBroker(broker), WithHubLogger(zap.NewNop()))
+	data, _ := NewMessage("notice", "all")
+	hub.Broadcast(data)
+	hub.Close()
+}
@@ -2,7 +2,9 @@ package ws
 
 import (
 	"context"
+	"errors"
 	"log"
+	"math/rand"
 	"net/http"
 	"strings"
 	"sync"
@@ -13,8 +15,38 @@ import (
 
 var (
 	pingData = []byte("ping")
+
+	// ErrClientClosed the client is closed
+	ErrClientClosed = errors.New("websocket client is closed")
+	// ErrBufferFull the buffer of messages sent during reconnection is full
+	ErrBufferFull = errors.New("websocket send buffer is full")
+)
+
+// ConnState the state of the connection in auto reconnect mode
+type ConnState int
+
+const (
+	// StateConnected connected, or reconnected successfully
+	StateConnected ConnState = iota + 1
+	// StateDisconnected the connection is broken
+	StateDisconnected
+	// StateReconnecting trying to reconnect
+	StateReconnecting
 )
 
+// String state name
+func (s ConnState) String() string {
+	switch s {
+	case StateConnected:
+		return "connected"
+	case StateDisconnected:
+		return "disconnected"
+	case StateReconnecting:
+		return "reconnecting"
+	}
+	return "unknown"
+}
+
 // ClientOption is a functional option for the client.
 type ClientOption func(*clientOptions)
 
@@ -22,11 +54,20 @@ type clientOptions struct {
 	dialer           *websocket.Dialer
 	requestHeader    http.Header
 	pingDialInterval time.Duration
+
+	autoReconnect bool
+	minBackoff    time.Duration
+	maxBackoff    time.Duration
+	bufferSize    int
+	onMessage     func(messageType int, data []byte)
+	onStateChange func(state ConnState, err error)
+	onReconnect   func(conn *websocket.Conn) error
 }
 
 func defaultClientOptions() *clientOptions {
 	return &clientOptions{
-		dialer: websocket.DefaultDialer,
+		dialer:     websocket.DefaultDialer,
+		bufferSize: 100,
 	}
 }
 
@@ -57,8 +98,62 @@ func WithPing(interval time.Duration) ClientOption {
 	}
 }
 
+// WithAutoReconnect enables auto reconnect mode, when the connection is broken, the client reconnects with
+// exponential backoff from minBackoff to maxBackoff with jitter. in this mode, the messages are read by the client
+// and passed to the handler of WithOnMessage, the messages should be sent by Send instead of GetConn.
+func WithAutoReconnect(minBackoff time.Duration, maxBackoff time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		if minBackoff <= 0 {
+			minBackoff = time.Second
+		}
+		if maxBackoff < minBackoff {
+			maxBackoff = minBackoff
+		}
+		o.autoReconnect = true
+		o.minBackoff = minBackoff
+		o.maxBackoff = maxBackoff
+	}
+}
+
+// WithSendBufferSize sets the max number of messages buffered during reconnection, default 100.
+func WithSendBufferSize(size int) ClientOption {
+	return func(o *clientOptions) {
+		if size >= 0 {
+			o.bufferSize = size
+		}
+	}
+}
+
+// WithOnMessage sets the handler of the messages received in auto reconnect mode.
+func WithOnMessage(fn func(messageType int, data []byte)) ClientOption {
+	return func(o *clientOptions) {
+		o.onMessage = fn
+	}
+}
+
+// WithOnStateChange sets the callback of the connection state in auto reconnect mode,
+// err is the reason of disconnection or the error of the last reconnection.
+func WithOnStateChange(fn func(state ConnState, err error)) ClientOption {
+	return func(o *clientOptions) {
+		o.onStateChange = fn
+	}
+}
+
+// WithOnReconnect sets the hook called after reconnecting and before the buffered messages are sent,
+// e.g. re-send the subscriptions by conn.WriteMessage, if an error is returned, the client reconnects again.
+func WithOnReconnect(fn func(conn *websocket.Conn) error) ClientOption {
+	return func(o *clientOptions) {
+		o.onReconnect = fn
+	}
+}
+
 // ----------------------------------------------------------------------------------
 
+type bufferedMessage struct {
+	messageType int
+	data        []byte
+}
+
 // Client is a wrapper of gorilla/websocket.
 type Client struct {
 	dialer        *websocket.Dialer
@@ -71,6 +166,19 @@ type Client struct {
 	cancel           context.CancelFunc
 
 	once sync.Once
+
+	autoReconnect bool
+	minBackoff    time.Duration
+	maxBackoff    time.Duration
+	bufferSize    int
+	onMessage     func(messageType int, data []byte)
+	onStateChange func(state ConnState, err error)
+	onReconnect   func(conn *websocket.Conn) error
+
+	mu      sync.Mutex // protects conn, state and buffer
+	writeMu sync.Mutex // only one concurrent writer is allowed
+	state   ConnState
+	buffer  []bufferedMessage
 }
 
 // NewClient creates a new client.
@@ -87,18 +195,33 @@ func NewClient(url string, opts ...ClientOption) (*Client, error) {
 		pingDialInterval: o.pingDialInterval,
 		ctx:              ctx,
 		cancel:           cancel,
+
+		autoReconnect: o.autoReconnect,
+		minBackoff:    o.minBackoff,
+		maxBackoff:    o.maxBackoff,
+		bufferSize:    o.bufferSize,
+		onMessage:     o.onMessage,
+		onStateChange: o.onStateChange,
+		onReconnect:   o.onReconnect,
 	}
 
 	err := c.Reconnect()
 	if err != nil {
+		cancel()
 		return nil, err
 	}
 
+	if c.autoReconnect {
+		c.setState(StateConnected, nil)
+		go c.run()
+	}
+
 	return c, nil
 }
 
+// GetConn gets the connection, in auto reconnect mode, the connection is replaced after reconnecting.
 func (c *Client) GetConn() *websocket.Conn {
-	if c.conn == nil {
+	if c.getConn() == nil {
 		defer func() {
 			if err := recover(); err != nil {
 				log.Printf("get conn panic, %v\n", err)
@@ -110,16 +233,24 @@ func (c *Client) GetConn() *websocket.Conn {
 		}
 	}
 
+	return c.getConn()
+}
+
+func (c *Client) getConn() *websocket.Conn {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	return c.conn
 }
 
 // Reconnect the websocket server.
 func (c *Client) Reconnect() error {
-	conn, _, err := c.dialer.Dial(c.url, c.requestHeader)
+	conn, _, err := c.dialer.DialContext(c.ctx, c.url, c.requestHeader)
 	if err != nil {
 		return err
 	}
+	c.mu.Lock()
 	c.conn = conn
+	c.mu.Unlock()
 
 	if c.pingDialInterval > 0 {
 		c.once.Do(func() {
@@ -154,7 +285,11 @@ func (c *Client) ping() {
 		for {
 			select {
 			case <-ticker.C:
-				if err := c.conn.WriteControl(websocket.PingMessage, pingData, time.Now().Add(5*time.Second)); err != nil {
+				conn := c.getConn()
+				if conn == nil {
+					continue
+				}
+				if err := conn.WriteControl(websocket.PingMessage, pingData, time.Now().Add(5*time.Second)); err != nil {
 					log.Printf("ping server err, %v\n", err)
 					continue
 				}
@@ -167,14 +302,168 @@ func (c *Client) ping() {
 	}()
 }
 
+// Send a message, in auto reconnect mode, the message is buffered during reconnection and sent in order
+// after reconnecting, return ErrBufferFull if the buffer is full.
+func (c *Client) Send(messageType int, data []byte) error {
+	c.mu.Lock()
+	if c.ctx.Err() != nil {
+		c.mu.Unlock()
+		return ErrClientClosed
+	}
+	if c.autoReconnect && c.state != StateConnected {
+		defer c.mu.Unlock()
+		return c.bufferLocked(messageType, data)
+	}
+	conn := c.conn
+	c.mu.Unlock()
+
+	c.writeMu.Lock()
+	err := conn.WriteMessage(messageType, data)
+	c.writeMu.Unlock()
+	if err != nil && c.autoReconnect && c.ctx.Err() == nil {
+		// the connection is broken, the read loop will reconnect
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return c.bufferLocked(messageType, data)
+	}
+	return err
+}
+
+func (c *Client) bufferLocked(messageType int, data []byte) error {
+	if len(c.buffer) >= c.bufferSize {
+		return ErrBufferFull
+	}
+	c.buffer = append(c.buffer, bufferedMessage{messageType: messageType, data: data})
+	return nil
+}
+
+// State gets the connection state in auto reconnect mode
+func (c *Client) State() ConnState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state
+}
+
+func (c *Client) setState(state ConnState, err error) {
+	c.mu.Lock()
+	c.state = state
+	c.mu.Unlock()
+	if c.onStateChange != nil {
+		c.onStateChange(state, err)
+	}
+}
+
+// read messages and reconnect when the connection is broken, until the client is closed
+func (c *Client) run() {
+	defer func() {
+		// the connection may be reconnected while closing
+		if conn := c.getConn(); conn != nil {
+			_ = conn.Close()
+		}
+	}()
+	for {
+		err := c.readLoop(c.getConn())
+		if c.ctx.Err() != nil {
+			return
+		}
+		c.setState(StateDisconnected, err)
+		log.Printf("websocket connection is broken, %v\n", err)
+
+		if !c.reconnectWithBackoff() {
+			return
+		}
+	}
+}
+
+func (c *Client) readLoop(conn *websocket.Conn) error {
+	for {
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		if c.onMessage != nil {
+			c.onMessage(messageType, data)
+		}
+	}
+}
+
+// reconnect until success, return false if the client is closed
+func (c *Client) reconnectWithBackoff() bool {
+	backoff := c.minBackoff
+	for attempt := 1; ; attempt++ {
+		// equal jitter, wait for a random time in [backoff/2, backoff)
+		wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		timer := time.NewTimer(wait)
+		select {
+		case <-c.ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+
+		c.setState(StateReconnecting, nil)
+		err := c.Reconnect()
+		if err == nil && c.onReconnect != nil {
+			if err = c.onReconnect(c.getConn()); err != nil {
+				_ = c.getConn().Close()
+			}
+		}
+		if err == nil && c.flush() {
+			log.Printf("websocket reconnected after %d attempts\n", attempt)
+			return true
+		}
+		if c.ctx.Err() != nil {
+			return false
+		}
+		if err != nil {
+			log.Printf("websocket reconnect error, attempt=%d, %v\n", attempt, err)
+		}
+
+		if backoff *= 2; backoff > c.maxBackoff {
+			backoff = c.maxBackoff
+		}
+	}
+}
+
+// send the buffered messages in order, the state is set to connected after all of them are sent
+func (c *Client) flush() bool {
+	for {
+		c.mu.Lock()
+		conn := c.conn
+		if len(c.buffer) == 0 {
+			// set the state in the same lock, so that no message is buffered after flushing
+			c.state = StateConnected
+			c.mu.Unlock()
+			if c.onStateChange != nil {
+				c.onStateChange(StateConnected, nil)
+			}
+			return true
+		}
+		msg := c.buffer[0]
+		c.buffer = c.buffer[1:]
+		c.mu.Unlock()
+
+		c.writeMu.Lock()
+		err := conn.WriteMessage(msg.messageType, msg.data)
+		c.writeMu.Unlock()
+		if err != nil {
+			c.mu.Lock()
+			c.buffer = append([]bufferedMessage{msg}, c.buffer...)
+			c.mu.Unlock()
+			_ = conn.Close()
+			return false
+		}
+	}
+}
+
 // Close closes the connection.
-// Note: if set pingDialInterval, the Close method must be called, otherwise it will cause the goroutine to leak
+// Note: if set pingDialInterval or auto reconnect, the Close method must be called, otherwise it will cause the goroutine to leak
 func (c *Client) Close() error {
 	if c.cancel != nil {
 		c.cancel()
 	}
-	if c.conn != nil {
-		return c.conn.Close()
+	if conn := c.getConn(); conn != nil {
+		return conn.Close()
 	}
 
 	return nil
@@ -0,0 +1,154 @@
+package ws
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+// echo server, it can drop the connections and refuse new connections
+type echoServer struct {
+	mu       sync.Mutex
+	conns    []*websocket.Conn
+	received []string
+	refuse   int32
+}
+
+func (s *echoServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if atomic.LoadInt32(&s.refuse) == 1 {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	s.conns = append(s.conns, conn)
+	s.mu.Unlock()
+
+	for {
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.received = append(s.received, string(data))
+		s.mu.Unlock()
+		_ = conn.WriteMessage(messageType, data)
+	}
+}
+
+func (s *echoServer) drop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.conns = nil
+}
+
+func (s *echoServer) getReceived() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.received...)
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	for i := 0; i < 100; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	t.Fatal("timeout")
+}
+
+func TestClient_AutoReconnect(t *testing.T) {
+	es := &echoServer{}
+	server := httptest.NewServer(es)
+	defer server.Close()
+
+	var mu sync.Mutex
+	var states []ConnState
+	var messages []string
+	c, err := NewClient("ws"+strings.TrimPrefix(server.URL, "http"),
+		WithAutoReconnect(time.Millisecond*10, time.Millisecond*40),
+		WithSendBufferSize(1),
+		WithOnMessage(func(messageType int, data []byte) {
+			mu.Lock()
+			messages = append(messages, string(data))
+			mu.Unlock()
+		}),
+		WithOnStateChange(func(state ConnState, err error) {
+			mu.Lock()
+			states = append(states, state)
+			mu.Unlock()
+		}),
+		WithOnReconnect(func(conn *websocket.Conn) error {
+			return conn.WriteMessage(websocket.TextMessage, []byte("subscribe"))
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, StateConnected, c.State())
+
+	assert.NoError(t, c.Send(websocket.TextMessage, []byte("a")))
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(messages) == 1
+	})
+
+	// the messages are buffered during reconnection
+	atomic.StoreInt32(&es.refuse, 1)
+	es.drop()
+	waitFor(t, func() bool { return c.State() == StateReconnecting })
+	assert.NoError(t, c.Send(websocket.TextMessage, []byte("b")))
+	assert.ErrorIs(t, c.Send(websocket.TextMessage, []byte("c")), ErrBufferFull)
+
+	// the subscriptions are sent before the buffered messages after reconnecting
+	atomic.StoreInt32(&es.refuse, 0)
+	waitFor(t, func() bool { return c.State() == StateConnected })
+	waitFor(t, func() bool { return len(es.getReceived()) == 3 })
+	assert.Equal(t, []string{"a", "subscribe", "b"}, es.getReceived())
+
+	mu.Lock()
+	assert.Equal(t, StateConnected, states[0])
+	assert.Equal(t, StateDisconnected, states[1])
+	assert.Equal(t, StateReconnecting, states[2])
+	assert.Equal(t, StateConnected, states[len(states)-1])
+	mu.Unlock()
+
+	assert.NoError(t, c.Close())
+	assert.ErrorIs(t, c.Send(websocket.TextMessage, []byte("d")), ErrClientClosed)
+	assert.Equal(t, "reconnecting", StateReconnecting.String())
+}
+
+func TestClient_Send(t *testing.T) {
+	es := &echoServer{}
+	server := httptest.NewServer(es)
+	defer server.Close()
+
+	// without auto reconnect, the error is returned
+	c, err := NewClient("ws" + strings.TrimPrefix(server.URL, "http"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, c.Send(websocket.TextMessage, []byte("a")))
+	_ = c.GetConn().Close()
+	assert.Error(t, c.Send(websocket.TextMessage, []byte("b")))
+	_ = c.Close()
+
+	atomic.StoreInt32(&es.refuse, 1)
+	_, err = NewClient("ws"+strings.TrimPrefix(server.URL, "http"), WithAutoReconnect(0, 0))
+	assert.Error(t, err)
+}
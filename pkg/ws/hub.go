@@ -0,0 +1,583 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+
+	"github.com/zhufuyi/sponge/pkg/jwt"
+	"github.com/zhufuyi/sponge/pkg/krand"
+)
+
+var defaultLogger, _ = zap.NewProduction()
+
+// Message is the typed JSON message, the message received is routed to the handler by Type.
+type Message struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// NewMessage marshal a typed JSON message, data is marshaled to JSON, []byte and json.RawMessage are used as is.
+func NewMessage(msgType string, data interface{}) ([]byte, error) {
+	msg := Message{Type: msgType}
+	switch v := data.(type) {
+	case nil:
+	case json.RawMessage:
+		msg.Data = v
+	case []byte:
+		msg.Data = v
+	default:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		msg.Data = raw
+	}
+	return json.Marshal(msg)
+}
+
+// HandlerFn handle the message of the type, the error is logged
+type HandlerFn func(ctx context.Context, s *Session, msg *Message) error
+
+// AuthFn authenticate the request before upgrading, return the user id of the connection,
+// if an error is returned, the request is rejected with 401.
+type AuthFn func(r *http.Request) (userID string, err error)
+
+// JWTAuth authenticate the request by the token of pkg/jwt, the token is got from the header
+// "Authorization: Bearer <token>" first, then from the query parameter queryKey (e.g. token),
+// because browsers can not set headers for WebSocket, the uid in the claims is the user id.
+func JWTAuth(queryKey string) AuthFn {
+	return func(r *http.Request) (string, error) {
+		token := ""
+		if authorization := r.Header.Get("Authorization"); strings.HasPrefix(authorization, "Bearer ") {
+			token = authorization[7:]
+		} else if queryKey != "" {
+			token = r.URL.Query().Get(queryKey)
+		}
+		if token == "" {
+			return "", errors.New("token is empty")
+		}
+		claims, err := jwt.ParseToken(token)
+		if err != nil {
+			return "", err
+		}
+		return claims.UID, nil
+	}
+}
+
+// HubOption is a functional option for the Hub.
+type HubOption func(*hubOptions)
+
+type hubOptions struct {
+	upgrader       *websocket.Upgrader
+	responseHeader http.Header
+	authFn         AuthFn
+	onConnect      func(s *Session)
+	onDisconnect   func(s *Session)
+
+	sendQueueSize  int
+	sendPolicy     SendPolicy
+	writeTimeout   time.Duration
+	pingInterval   time.Duration
+	maxMessageSize int64
+	broker         Broker
+
+	zapLog *zap.Logger
+}
+
+func defaultHubOptions() *hubOptions {
+	return &hubOptions{
+		upgrader: &websocket.Upgrader{ // default upgrader
+			CheckOrigin: func(r *http.Request) bool { // allow all origins
+				return true
+			},
+		},
+		sendQueueSize: 256,
+		sendPolicy:    DropNewest,
+		writeTimeout:  time.Second * 10,
+		pingInterval:  time.Second * 30,
+		zapLog:        defaultLogger,
+	}
+}
+
+func (o *hubOptions) apply(opts ...HubOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithHubUpgrader sets the WebSocket upgrader for the hub.
+func WithHubUpgrader(upgrader *websocket.Upgrader) HubOption {
+	return func(o *hubOptions) {
+		o.upgrader = upgrader
+	}
+}
+
+// WithHubResponseHeader sets the response header for the WebSocket upgrade response.
+func WithHubResponseHeader(header http.Header) HubOption {
+	return func(o *hubOptions) {
+		o.responseHeader = header
+	}
+}
+
+// WithHubAuth sets the auth function called before upgrading, e.g. JWTAuth("token").
+func WithHubAuth(fn AuthFn) HubOption {
+	return func(o *hubOptions) {
+		o.authFn = fn
+	}
+}
+
+// WithHubOnConnect sets the function called after the session is registered, e.g. join the default rooms.
+func WithHubOnConnect(fn func(s *Session)) HubOption {
+	return func(o *hubOptions) {
+		o.onConnect = fn
+	}
+}
+
+// WithHubOnDisconnect sets the function called after the session is unregistered.
+func WithHubOnDisconnect(fn func(s *Session)) HubOption {
+	return func(o *hubOptions) {
+		o.onDisconnect = fn
+	}
+}
+
+// WithHubSendQueue sets the size of the send queue of each session and the policy when the queue is full,
+// default 256 and DropNewest.
+func WithHubSendQueue(size int, policy SendPolicy) HubOption {
+	return func(o *hubOptions) {
+		if size > 0 {
+			o.sendQueueSize = size
+		}
+		o.sendPolicy = policy
+	}
+}
+
+// WithHubWriteTimeout sets the timeout of writing a message, default 10s.
+func WithHubWriteTimeout(d time.Duration) HubOption {
+	return func(o *hubOptions) {
+		if d > 0 {
+			o.writeTimeout = d
+		}
+	}
+}
+
+// WithHubPingInterval sets the interval of sending ping to the client, default 30s, the connection is closed
+// if nothing is received from the client within 2 intervals, 0 means disable.
+func WithHubPingInterval(d time.Duration) HubOption {
+	return func(o *hubOptions) {
+		o.pingInterval = d
+	}
+}
+
+// WithHubMaxMessageSize sets the max size of the message received, 0 means no limit.
+func WithHubMaxMessageSize(size int64) HubOption {
+	return func(o *hubOptions) {
+		o.maxMessageSize = size
+	}
+}
+
+// WithHubBroker sets the broker to fan out the broadcast messages to the hubs of all instances,
+// e.g. NewRedisBroker, so that the sessions connected to other instances receive them too.
+func WithHubBroker(broker Broker) HubOption {
+	return func(o *hubOptions) {
+		o.broker = broker
+	}
+}
+
+// WithHubLogger sets the logger for the hub.
+func WithHubLogger(zapLog *zap.Logger) HubOption {
+	return func(o *hubOptions) {
+		if zapLog != nil {
+			o.zapLog = zapLog
+		}
+	}
+}
+
+// --------------------------------------------------------------------------------------
+
+// Hub manages the WebSocket sessions, supports rooms, broadcasting and routing messages by type.
+type Hub struct {
+	upgrader       *websocket.Upgrader
+	responseHeader http.Header
+	authFn         AuthFn
+	onConnect      func(s *Session)
+	onDisconnect   func(s *Session)
+
+	sendQueueSize  int
+	sendPolicy     SendPolicy
+	writeTimeout   time.Duration
+	pingInterval   time.Duration
+	maxMessageSize int64
+
+	zapLog *zap.Logger
+
+	id     string // instance id, used to skip the messages published by itself
+	idSeq  uint64
+	broker Broker
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	handlerMu sync.RWMutex
+	handlers  map[string]HandlerFn
+
+	mu       sync.RWMutex
+	sessions map[string]*Session
+	rooms    map[string]map[string]*Session // room --> session id --> session
+	users    map[string]map[string]*Session // user id --> session id --> session
+	joined   map[string]map[string]struct{} // session id --> rooms
+	isClosed bool
+}
+
+// NewHub creates a new hub.
+func NewHub(opts ...HubOption) *Hub {
+	o := defaultHubOptions()
+	o.apply(opts...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h := &Hub{
+		upgrader:       o.upgrader,
+		responseHeader: o.responseHeader,
+		authFn:         o.authFn,
+		onConnect:      o.onConnect,
+		onDisconnect:   o.onDisconnect,
+
+		sendQueueSize:  o.sendQueueSize,
+		sendPolicy:     o.sendPolicy,
+		writeTimeout:   o.writeTimeout,
+		pingInterval:   o.pingInterval,
+		maxMessageSize: o.maxMessageSize,
+
+		zapLog: o.zapLog,
+
+		id:       krand.String(krand.R_All, 8),
+		broker:   o.broker,
+		ctx:      ctx,
+		cancel:   cancel,
+		handlers: make(map[string]HandlerFn),
+		sessions: make(map[string]*Session),
+		rooms:    make(map[string]map[string]*Session),
+		users:    make(map[string]map[string]*Session),
+		joined:   make(map[string]map[string]struct{}),
+	}
+
+	if h.broker != nil {
+		if err := h.broker.Subscribe(ctx, h.onBrokerMessage); err != nil {
+			h.zapLog.Warn("[ws hub] subscribe broker error, retry later", zap.String("err", err.Error()))
+			go h.resubscribe()
+		}
+	}
+
+	return h
+}
+
+// retry subscribing the broker until success or the hub is closed
+func (h *Hub) resubscribe() {
+	ticker := time.NewTicker(time.Second * 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-h.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := h.broker.Subscribe(h.ctx, h.onBrokerMessage); err != nil {
+			h.zapLog.Warn("[ws hub] subscribe broker error, retry later", zap.String("err", err.Error()))
+			continue
+		}
+		h.zapLog.Info("[ws hub] subscribe broker successfully")
+		return
+	}
+}
+
+// Handle registers the handler of the message type
+func (h *Hub) Handle(msgType string, fn HandlerFn) {
+	h.handlerMu.Lock()
+	defer h.handlerMu.Unlock()
+	h.handlers[msgType] = fn
+}
+
+// Serve upgrades the connection and serves it until the connection is closed or ctx is done.
+func (h *Hub) Serve(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	userID := ""
+	if h.authFn != nil {
+		var err error
+		userID, err = h.authFn(r)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return err
+		}
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, h.responseHeader)
+	if err != nil {
+		return err
+	}
+
+	s := newSession(h, conn, h.id+"-"+strconv.FormatUint(atomic.AddUint64(&h.idSeq, 1), 10), userID)
+	if !h.register(s) {
+		_ = conn.Close()
+		return ErrSessionClosed
+	}
+	defer h.unregister(s)
+
+	go s.writeLoop()
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.Close()
+		case <-s.done:
+		}
+	}()
+
+	if h.onConnect != nil {
+		h.onConnect(s)
+	}
+	s.readLoop(ctx)
+	s.Close()
+
+	return nil
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	err := h.Serve(r.Context(), w, r)
+	if err != nil {
+		h.zapLog.Warn("[ws hub] serve error", zap.String("err", err.Error()), zap.String("remoteAddr", r.RemoteAddr))
+	}
+}
+
+func (h *Hub) route(ctx context.Context, s *Session, data []byte) {
+	msg := &Message{}
+	if err := json.Unmarshal(data, msg); err != nil {
+		h.zapLog.Warn("[ws hub] invalid message", zap.String("err", err.Error()), zap.String("id", s.ID))
+		return
+	}
+
+	h.handlerMu.RLock()
+	fn, ok := h.handlers[msg.Type]
+	h.handlerMu.RUnlock()
+	if !ok {
+		h.zapLog.Warn("[ws hub] no handler for message type", zap.String("type", msg.Type), zap.String("id", s.ID))
+		return
+	}
+
+	if err := fn(ctx, s, msg); err != nil {
+		h.zapLog.Warn("[ws hub] handle message error", zap.String("err", err.Error()), zap.String("type", msg.Type), zap.String("id", s.ID))
+	}
+}
+
+func (h *Hub) register(s *Session) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.isClosed {
+		return false
+	}
+
+	h.sessions[s.ID] = s
+	if s.UserID != "" {
+		if h.users[s.UserID] == nil {
+			h.users[s.UserID] = make(map[string]*Session)
+		}
+		h.users[s.UserID][s.ID] = s
+	}
+	return true
+}
+
+func (h *Hub) unregister(s *Session) {
+	h.mu.Lock()
+	delete(h.sessions, s.ID)
+	if sessions, ok := h.users[s.UserID]; ok {
+		delete(sessions, s.ID)
+		if len(sessions) == 0 {
+			delete(h.users, s.UserID)
+		}
+	}
+	for room := range h.joined[s.ID] {
+		h.leaveLocked(s, room)
+	}
+	h.mu.Unlock()
+
+	if h.onDisconnect != nil {
+		h.onDisconnect(s)
+	}
+}
+
+func (h *Hub) join(s *Session, room string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.sessions[s.ID]; !ok {
+		return
+	}
+
+	if h.rooms[room] == nil {
+		h.rooms[room] = make(map[string]*Session)
+	}
+	h.rooms[room][s.ID] = s
+	if h.joined[s.ID] == nil {
+		h.joined[s.ID] = make(map[string]struct{})
+	}
+	h.joined[s.ID][room] = struct{}{}
+}
+
+func (h *Hub) leave(s *Session, room string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.leaveLocked(s, room)
+}
+
+func (h *Hub) leaveLocked(s *Session, room string) {
+	if sessions, ok := h.rooms[room]; ok {
+		delete(sessions, s.ID)
+		if len(sessions) == 0 {
+			delete(h.rooms, room)
+		}
+	}
+	if rooms, ok := h.joined[s.ID]; ok {
+		delete(rooms, room)
+		if len(rooms) == 0 {
+			delete(h.joined, s.ID)
+		}
+	}
+}
+
+func (h *Hub) sessionRooms(s *Session) []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	rooms := make([]string, 0, len(h.joined[s.ID]))
+	for room := range h.joined[s.ID] {
+		rooms = append(rooms, room)
+	}
+	return rooms
+}
+
+// Session gets the session by id
+func (h *Hub) Session(id string) (*Session, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	s, ok := h.sessions[id]
+	return s, ok
+}
+
+// Count the number of sessions
+func (h *Hub) Count() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.sessions)
+}
+
+// RoomCount the number of sessions in the room
+func (h *Hub) RoomCount(room string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.rooms[room])
+}
+
+// the targets of the broadcast message
+const (
+	targetAll  = "all"
+	targetRoom = "room"
+	targetUser = "user"
+)
+
+// the message published to the broker
+type brokerMessage struct {
+	Origin string `json:"origin"`
+	Target string `json:"target"`
+	Key    string `json:"key,omitempty"`
+	Data   []byte `json:"data"`
+}
+
+// Broadcast sends the message to all sessions
+func (h *Hub) Broadcast(data []byte) {
+	h.broadcast(targetAll, "", data)
+}
+
+// BroadcastToRoom sends the message to the sessions in the room
+func (h *Hub) BroadcastToRoom(room string, data []byte) {
+	h.broadcast(targetRoom, room, data)
+}
+
+// SendToUser sends the message to all sessions of the user
+func (h *Hub) SendToUser(userID string, data []byte) {
+	h.broadcast(targetUser, userID, data)
+}
+
+// deliver the message to the local sessions, then publish it to the hubs of other instances
+func (h *Hub) broadcast(target string, key string, data []byte) {
+	h.deliver(target, key, data)
+	if h.broker == nil {
+		return
+	}
+
+	msg, err := json.Marshal(&brokerMessage{Origin: h.id, Target: target, Key: key, Data: data})
+	if err != nil {
+		h.zapLog.Warn("[ws hub] marshal broker message error", zap.String("err", err.Error()))
+		return
+	}
+	ctx, cancel := context.WithTimeout(h.ctx, h.writeTimeout)
+	defer cancel()
+	if err = h.broker.Publish(ctx, msg); err != nil {
+		h.zapLog.Warn("[ws hub] publish to broker error", zap.String("err", err.Error()), zap.String("target", target), zap.String("key", key))
+	}
+}
+
+func (h *Hub) onBrokerMessage(data []byte) {
+	msg := &brokerMessage{}
+	if err := json.Unmarshal(data, msg); err != nil {
+		h.zapLog.Warn("[ws hub] invalid broker message", zap.String("err", err.Error()))
+		return
+	}
+	if msg.Origin == h.id { // delivered already
+		return
+	}
+	h.deliver(msg.Target, msg.Key, msg.Data)
+}
+
+func (h *Hub) deliver(target string, key string, data []byte) {
+	h.mu.RLock()
+	var group map[string]*Session
+	switch target {
+	case targetAll:
+		group = h.sessions
+	case targetRoom:
+		group = h.rooms[key]
+	case targetUser:
+		group = h.users[key]
+	}
+	sessions := make([]*Session, 0, len(group))
+	for _, s := range group {
+		sessions = append(sessions, s)
+	}
+	h.mu.RUnlock()
+
+	for _, s := range sessions {
+		if err := s.Send(data); err != nil {
+			h.zapLog.Warn("[ws hub] send message error", zap.String("err", err.Error()), zap.String("id", s.ID), zap.String("userID", s.UserID))
+		}
+	}
+}
+
+// Close closes all sessions and unsubscribes the broker, new connections are rejected
+func (h *Hub) Close() {
+	h.cancel()
+	h.mu.Lock()
+	h.isClosed = true
+	sessions := make([]*Session, 0, len(h.sessions))
+	for _, s := range h.sessions {
+		sessions = append(sessions, s)
+	}
+	h.mu.Unlock()
+
+	for _, s := range sessions {
+		s.Close()
+	}
+}
@@ -0,0 +1,241 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/zhufuyi/sponge/pkg/jwt"
+)
+
+func newTestHub(opts ...HubOption) (*Hub, *httptest.Server) {
+	hub := NewHub(append([]HubOption{WithHubLogger(zap.NewNop())}, opts...)...)
+	hub.Handle("join", func(ctx context.Context, s *Session, msg *Message) error {
+		var room string
+		if err := json.Unmarshal(msg.Data, &room); err != nil {
+			return err
+		}
+		s.Join(room)
+		return s.SendMessage("joined", room)
+	})
+	hub.Handle("echo", func(ctx context.Context, s *Session, msg *Message) error {
+		return s.SendMessage("echo", msg.Data)
+	})
+	server := httptest.NewServer(hub)
+	return hub, server
+}
+
+func dial(t *testing.T, server *httptest.Server, path string, header http.Header) *websocket.Conn {
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + path
+	conn, _, err := websocket.DefaultDialer.Dial(url, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func readMessage(t *testing.T, conn *websocket.Conn) *Message {
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := &Message{}
+	assert.NoError(t, json.Unmarshal(data, msg))
+	return msg
+}
+
+// the connection is broken after read timeout, check that the next message is the echo instead
+func noMessage(t *testing.T, conn *websocket.Conn) {
+	writeMessage(t, conn, "echo", "check")
+	assert.Equal(t, "echo", readMessage(t, conn).Type)
+}
+
+func writeMessage(t *testing.T, conn *websocket.Conn, msgType string, data interface{}) {
+	msg, err := NewMessage(msgType, data)
+	assert.NoError(t, err)
+	assert.NoError(t, conn.WriteMessage(websocket.TextMessage, msg))
+}
+
+func TestNewMessage(t *testing.T) {
+	msg, err := NewMessage("chat", map[string]string{"text": "hi"})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"type":"chat","data":{"text":"hi"}}`, string(msg))
+
+	msg, err = NewMessage("ping", nil)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"type":"ping"}`, string(msg))
+
+	msg, err = NewMessage("raw", json.RawMessage(`[1,2]`))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"type":"raw","data":[1,2]}`, string(msg))
+
+	_, err = NewMessage("bad", make(chan int))
+	assert.Error(t, err)
+}
+
+func TestHub_RoomsAndRouting(t *testing.T) {
+	hub, server := newTestHub()
+	defer server.Close()
+	defer hub.Close()
+
+	c1 := dial(t, server, "/", nil)
+	defer c1.Close()
+	c2 := dial(t, server, "/", nil)
+	defer c2.Close()
+
+	writeMessage(t, c1, "echo", "hello")
+	msg := readMessage(t, c1)
+	assert.Equal(t, "echo", msg.Type)
+	assert.Equal(t, `"hello"`, string(msg.Data))
+
+	// unknown type and invalid message are ignored
+	writeMessage(t, c1, "unknown", nil)
+	assert.NoError(t, c1.WriteMessage(websocket.TextMessage, []byte("not json")))
+
+	writeMessage(t, c1, "join", "room1")
+	assert.Equal(t, "joined", readMessage(t, c1).Type)
+	assert.Equal(t, 2, hub.Count())
+	assert.Equal(t, 1, hub.RoomCount("room1"))
+
+	data, _ := NewMessage("notice", "room message")
+	hub.BroadcastToRoom("room1", data)
+	assert.Equal(t, "notice", readMessage(t, c1).Type)
+	noMessage(t, c2)
+
+	data, _ = NewMessage("notice", "all")
+	hub.Broadcast(data)
+	assert.Equal(t, "notice", readMessage(t, c2).Type)
+
+	// the session leaves the rooms when disconnected
+	_ = c1.Close()
+	time.Sleep(time.Millisecond * 100)
+	assert.Equal(t, 1, hub.Count())
+	assert.Equal(t, 0, hub.RoomCount("room1"))
+}
+
+func TestHub_Auth(t *testing.T) {
+	var connected, disconnected []string
+	done := make(chan struct{})
+	hub, server := newTestHub(
+		WithHubAuth(func(r *http.Request) (string, error) {
+			userID := r.Header.Get("X-User-Id")
+			if userID == "" {
+				return "", errors.New("unauthorized")
+			}
+			return userID, nil
+		}),
+		WithHubOnConnect(func(s *Session) {
+			connected = append(connected, s.UserID)
+			s.Join("lobby")
+		}),
+		WithHubOnDisconnect(func(s *Session) {
+			disconnected = append(disconnected, s.UserID)
+			close(done)
+		}),
+	)
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	c1 := dial(t, server, "/", http.Header{"X-User-Id": []string{"u1"}})
+	defer c1.Close()
+	time.Sleep(time.Millisecond * 50)
+
+	data, _ := NewMessage("notice", "to u1")
+	hub.SendToUser("u1", data)
+	assert.Equal(t, "notice", readMessage(t, c1).Type)
+	hub.SendToUser("u2", data)
+	noMessage(t, c1)
+	assert.Equal(t, 1, hub.RoomCount("lobby"))
+
+	// the client receives close message
+	hub.Close()
+	<-done
+	assert.Equal(t, []string{"u1"}, connected)
+	assert.Equal(t, []string{"u1"}, disconnected)
+	_, _, err = c1.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure))
+}
+
+func TestJWTAuth(t *testing.T) {
+	jwt.Init()
+	token, err := jwt.GenerateToken("100")
+	assert.NoError(t, err)
+	auth := JWTAuth("token")
+
+	r := httptest.NewRequest(http.MethodGet, "/ws?token="+token, nil)
+	userID, err := auth(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "100", userID)
+
+	r = httptest.NewRequest(http.MethodGet, "/ws", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	userID, err = auth(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "100", userID)
+
+	r = httptest.NewRequest(http.MethodGet, "/ws", nil)
+	_, err = auth(r)
+	assert.Error(t, err)
+
+	r = httptest.NewRequest(http.MethodGet, "/ws?token=invalid", nil)
+	_, err = auth(r)
+	assert.Error(t, err)
+}
+
+func TestSession_SendPolicy(t *testing.T) {
+	hub := NewHub(WithHubLogger(zap.NewNop()), WithHubSendQueue(2, DropNewest))
+	s := newSession(hub, nil, "1", "")
+	assert.NoError(t, s.Send([]byte("1")))
+	assert.NoError(t, s.Send([]byte("2")))
+	assert.ErrorIs(t, s.Send([]byte("3")), ErrSendQueueFull)
+	assert.Equal(t, "1", string(<-s.send))
+
+	hub = NewHub(WithHubLogger(zap.NewNop()), WithHubSendQueue(2, DropOldest))
+	s = newSession(hub, nil, "2", "")
+	assert.NoError(t, s.Send([]byte("1")))
+	assert.NoError(t, s.Send([]byte("2")))
+	assert.NoError(t, s.Send([]byte("3")))
+	assert.Equal(t, "2", string(<-s.send))
+	assert.Equal(t, "3", string(<-s.send))
+
+	hub = NewHub(WithHubLogger(zap.NewNop()), WithHubSendQueue(1, CloseSlow))
+	s = newSession(hub, nil, "3", "")
+	assert.NoError(t, s.Send([]byte("1")))
+	assert.ErrorIs(t, s.Send([]byte("2")), ErrSendQueueFull)
+	<-s.Done()
+	assert.ErrorIs(t, s.Send([]byte("3")), ErrSessionClosed)
+
+	s.Set("k", "v")
+	v, ok := s.Get("k")
+	assert.True(t, ok)
+	assert.Equal(t, "v", v)
+}
+
+func TestHubOptions(t *testing.T) {
+	o := defaultHubOptions()
+	o.apply(
+		WithHubUpgrader(&websocket.Upgrader{}),
+		WithHubResponseHeader(http.Header{"Foo": []string{"bar"}}),
+		WithHubWriteTimeout(time.Second),
+		WithHubPingInterval(time.Second*5),
+		WithHubMaxMessageSize(1024),
+	)
+	assert.Equal(t, time.Second, o.writeTimeout)
+	assert.Equal(t, time.Second*5, o.pingInterval)
+	assert.Equal(t, int64(1024), o.maxMessageSize)
+	assert.Equal(t, "bar", o.responseHeader.Get("foo"))
+}
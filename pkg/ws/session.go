@@ -0,0 +1,238 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrSessionClosed the session is closed
+	ErrSessionClosed = errors.New("websocket session is closed")
+	// ErrSendQueueFull the send queue of the session is full
+	ErrSendQueueFull = errors.New("websocket send queue is full")
+)
+
+// SendPolicy the policy when the send queue of the session is full
+type SendPolicy int
+
+const (
+	// DropNewest discard the message being sent, Send returns ErrSendQueueFull
+	DropNewest SendPolicy = iota
+	// DropOldest discard the oldest message in the queue to make room for the message being sent
+	DropOldest
+	// CloseSlow close the slow connection, Send returns ErrSendQueueFull
+	CloseSlow
+)
+
+// Session is a WebSocket connection registered in the hub.
+type Session struct {
+	ID     string
+	UserID string // empty if no auth
+
+	hub  *Hub
+	conn *Conn
+
+	mu       sync.Mutex
+	send     chan []byte
+	isClosed bool
+	values   map[string]interface{}
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newSession(hub *Hub, conn *Conn, id string, userID string) *Session {
+	return &Session{
+		ID:     id,
+		UserID: userID,
+		hub:    hub,
+		conn:   conn,
+		send:   make(chan []byte, hub.sendQueueSize),
+		values: make(map[string]interface{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// Send a text message, it is queued and written by the write goroutine of the session,
+// if the queue is full, it is handled by the send policy of the hub.
+func (s *Session) Send(data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.isClosed {
+		return ErrSessionClosed
+	}
+	select {
+	case s.send <- data:
+		return nil
+	default:
+	}
+
+	switch s.hub.sendPolicy {
+	case DropOldest:
+		select {
+		case <-s.send:
+		default:
+		}
+		// only the senders holding the lock put messages into the queue
+		s.send <- data
+		return nil
+	case CloseSlow:
+		s.hub.zapLog.Warn("[ws hub] send queue is full, close the slow connection", zap.String("id", s.ID), zap.String("userID", s.UserID))
+		s.closeLocked()
+		return ErrSendQueueFull
+	default:
+		return ErrSendQueueFull
+	}
+}
+
+// SendMessage send a typed JSON message, see NewMessage
+func (s *Session) SendMessage(msgType string, data interface{}) error {
+	msg, err := NewMessage(msgType, data)
+	if err != nil {
+		return err
+	}
+	return s.Send(msg)
+}
+
+// Join the room
+func (s *Session) Join(room string) {
+	s.hub.join(s, room)
+}
+
+// Leave the room
+func (s *Session) Leave(room string) {
+	s.hub.leave(s, room)
+}
+
+// Rooms the rooms joined by the session
+func (s *Session) Rooms() []string {
+	return s.hub.sessionRooms(s)
+}
+
+// Set a value to the session
+func (s *Session) Set(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+}
+
+// Get a value from the session
+func (s *Session) Get(key string) (interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.values[key]
+	return value, ok
+}
+
+// Close the session, the messages queued are written before the connection is closed
+func (s *Session) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeLocked()
+}
+
+func (s *Session) closeLocked() {
+	s.closeOnce.Do(func() {
+		s.isClosed = true
+		close(s.done)
+	})
+}
+
+// Done is closed when the session is closed
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// write the messages in the queue, the only goroutine that writes to the connection
+func (s *Session) writeLoop() {
+	var pingC <-chan time.Time
+	if s.hub.pingInterval > 0 {
+		ticker := time.NewTicker(s.hub.pingInterval)
+		defer ticker.Stop()
+		pingC = ticker.C
+	}
+	defer func() {
+		_ = s.conn.Close()
+		s.Close()
+	}()
+
+	for {
+		select {
+		case data := <-s.send:
+			if err := s.write(data); err != nil {
+				return
+			}
+		case <-pingC:
+			if err := s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(s.hub.writeTimeout)); err != nil {
+				return
+			}
+		case <-s.done:
+			if !s.drain() {
+				return
+			}
+			_ = s.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+				time.Now().Add(s.hub.writeTimeout))
+			return
+		}
+	}
+}
+
+// write the messages left in the queue, return false if failed
+func (s *Session) drain() bool {
+	for {
+		select {
+		case data := <-s.send:
+			if err := s.write(data); err != nil {
+				return false
+			}
+		default:
+			return true
+		}
+	}
+}
+
+func (s *Session) write(data []byte) error {
+	_ = s.conn.SetWriteDeadline(time.Now().Add(s.hub.writeTimeout))
+	err := s.conn.WriteMessage(websocket.TextMessage, data)
+	if err != nil {
+		s.hub.zapLog.Warn("[ws hub] write message error", zap.String("err", err.Error()), zap.String("id", s.ID))
+	}
+	return err
+}
+
+// read the messages and route them to the handlers until the connection is closed
+func (s *Session) readLoop(ctx context.Context) {
+	if s.hub.maxMessageSize > 0 {
+		s.conn.SetReadLimit(s.hub.maxMessageSize)
+	}
+	pongWait := s.hub.pingInterval * 2
+	if pongWait > 0 {
+		_ = s.conn.SetReadDeadline(time.Now().Add(pongWait))
+		s.conn.SetPongHandler(func(string) error {
+			return s.conn.SetReadDeadline(time.Now().Add(pongWait))
+		})
+	}
+
+	for {
+		messageType, data, err := s.conn.ReadMessage()
+		if err != nil {
+			if !IsClientClose(err) && !errors.Is(err, websocket.ErrCloseSent) {
+				s.hub.zapLog.Debug("[ws hub] read message error", zap.String("err", err.Error()), zap.String("id", s.ID))
+			}
+			return
+		}
+		if pongWait > 0 {
+			_ = s.conn.SetReadDeadline(time.Now().Add(pongWait))
+		}
+		if messageType != websocket.TextMessage {
+			continue
+		}
+		s.hub.route(ctx, s, data)
+	}
+}